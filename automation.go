@@ -0,0 +1,192 @@
+//go:build windows || linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"goclip/ipc"
+)
+
+var (
+	errAlreadyTyping = errors.New("goclip is already typing")
+	errNoTarget      = errors.New("no target window: select one in goclip, or give a handle, window or process")
+)
+
+// typingProgress is the progress of the current or last typing run, as
+// reported by the automation API.
+type typingProgress struct {
+	mu sync.Mutex
+	p  ipc.Progress
+
+	// onStep, if set, runs at most once a second as characters are counted
+	onStep   func()
+	lastStep time.Time
+}
+
+func newTypingProgress() *typingProgress {
+	return &typingProgress{p: ipc.Progress{State: ipc.StateIdle}}
+}
+
+// start begins a new run of total characters.
+func (tp *typingProgress) start(total int) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	tp.p = ipc.Progress{Run: tp.p.Run + 1, State: ipc.StateTyping, Total: total}
+}
+
+// step counts a character that is about to be typed.
+func (tp *typingProgress) step() {
+	tp.mu.Lock()
+	if tp.p.Typed < tp.p.Total {
+		tp.p.Typed++
+	}
+	notify := tp.onStep != nil && time.Since(tp.lastStep) >= time.Second
+	if notify {
+		tp.lastStep = time.Now()
+	}
+	tp.mu.Unlock()
+	if notify {
+		tp.onStep()
+	}
+}
+
+// finish ends the run. With hideError the error is not reported, as it may
+// quote a character of a secret.
+func (tp *typingProgress) finish(canceled bool, err error, hideError bool) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	switch {
+	case canceled:
+		tp.p.State = ipc.StateStopped
+	case err != nil:
+		tp.p.State = ipc.StateFailed
+		tp.p.Error = err.Error()
+		if hideError {
+			tp.p.Error = "typing failed"
+		}
+	default:
+		tp.p.State = ipc.StateCompleted
+		tp.p.Typed = tp.p.Total
+	}
+}
+
+func (tp *typingProgress) get() ipc.Progress {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	return tp.p
+}
+
+// automationHandler answers automation API requests with the typing session
+// of the window, so the API types like the Type button does.
+type automationHandler struct {
+	selfExeLower string
+	progress     *typingProgress
+
+	// start types text into target (0 for the selected or last active
+	// window) with the window's settings, changed by override. It returns
+	// once typing has started.
+	start func(target windowHandle, text string, override func(*typingSettings)) error
+	// stop stops the typing run, as the Stop button does.
+	stop func()
+}
+
+func (h *automationHandler) Windows() ([]ipc.Window, error) {
+	var list []ipc.Window
+	for _, w := range enumWindows(h.selfExeLower) {
+		list = append(list, ipc.Window{
+			Handle:  uint64(w.Hwnd),
+			Title:   w.Title,
+			Process: getWindowProcessName(w.Hwnd),
+		})
+	}
+	return list, nil
+}
+
+func (h *automationHandler) Type(req ipc.Request) (ipc.Progress, error) {
+	text := strings.ReplaceAll(req.Text, "\r\n", "\n")
+	if text == "" {
+		return ipc.Progress{}, errors.New("nothing to type")
+	}
+
+	var (
+		target windowHandle
+		err    error
+	)
+	switch {
+	case req.Handle != 0:
+		for _, w := range enumWindows(h.selfExeLower) {
+			if uint64(w.Hwnd) == req.Handle {
+				target = w.Hwnd
+				break
+			}
+		}
+		if target == 0 {
+			return ipc.Progress{}, fmt.Errorf("no window with handle %d", req.Handle)
+		}
+	case req.Window != "" || req.Process != "":
+		var titleRe *regexp.Regexp
+		if req.Window != "" {
+			if titleRe, err = regexp.Compile(req.Window); err != nil {
+				return ipc.Progress{}, fmt.Errorf("window: %w", err)
+			}
+		}
+		if target, err = findTargetWindow(titleRe, req.Process); err != nil {
+			return ipc.Progress{}, err
+		}
+	}
+
+	var (
+		layoutName string
+		delayFor   func(string) time.Duration
+		compat     compatibilityModeSetting
+	)
+	if req.Layout != "" {
+		if layoutName, err = cliLayout(req.Layout); err != nil {
+			return ipc.Progress{}, err
+		}
+	}
+	if req.Speed != "" || req.DelayMs != 0 {
+		if delayFor, err = parseTypingDelay(req.Speed, req.DelayMs); err != nil {
+			return ipc.Progress{}, err
+		}
+	}
+	if req.Compat != "" {
+		if compat, err = parseCompatibility(req.Compat); err != nil {
+			return ipc.Progress{}, err
+		}
+	}
+
+	err = h.start(target, text, func(ts *typingSettings) {
+		if layoutName != "" {
+			ts.layout = layoutName
+		}
+		if delayFor != nil {
+			ts.delay = delayFor
+		}
+		if compat != "" {
+			ts.compat = compat
+		}
+		if req.Markup != nil {
+			ts.markup = *req.Markup
+		}
+	})
+	if err != nil {
+		return ipc.Progress{}, err
+	}
+	return h.progress.get(), nil
+}
+
+func (h *automationHandler) Progress() (ipc.Progress, error) {
+	return h.progress.get(), nil
+}
+
+func (h *automationHandler) Stop() (ipc.Progress, error) {
+	h.stop()
+	return h.progress.get(), nil
+}
@@ -0,0 +1,162 @@
+//go:build darwin
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"goclip/config"
+	"goclip/ipc"
+)
+
+var (
+	errAlreadyTyping = errors.New("goclip is already typing")
+	errNoTarget      = errors.New("no target window: select one in goclip, or give a handle, window or process")
+
+	// the macOS window types without a session that keeps track of runs
+	errNoProgress = errors.New("progress is not available on macOS")
+	errNoStop     = errors.New("stop is not available on macOS; press Stop in the goclip window")
+)
+
+// macSpeedLabels maps the API's speed names to the entries of the speed
+// dropdown.
+var macSpeedLabels = map[config.SpeedOption]string{
+	config.SpeedDefault:   "Default (Auto)",
+	config.SpeedMedium:    "Medium (50 ms)",
+	config.SpeedSlow:      "Slow (100 ms)",
+	config.SpeedSuperSlow: "Super Slow (250 ms)",
+}
+
+// automationHandler answers automation API requests on macOS. It lists
+// windows and types like the Type button; the window has no key markup,
+// compatibility mode or progress to offer.
+type automationHandler struct {
+	selfAppNameLower string
+
+	// start types text into target (nil for the selected or last active
+	// window) with layoutName and speed, or the window's choices where
+	// they are empty. A delay above 0 wins over speed. It returns once
+	// typing has started.
+	start func(target *windowInfo, text, layoutName, speed string, delay time.Duration) error
+}
+
+func (h *automationHandler) Windows() ([]ipc.Window, error) {
+	var list []ipc.Window
+	for _, w := range enumWindows(h.selfAppNameLower) {
+		list = append(list, ipc.Window{
+			Handle:  uint64(w.WindowNumber),
+			Title:   w.Title,
+			Process: w.AppName,
+		})
+	}
+	return list, nil
+}
+
+func (h *automationHandler) Type(req ipc.Request) (ipc.Progress, error) {
+	text := strings.ReplaceAll(req.Text, "\r\n", "\n")
+	if text == "" {
+		return ipc.Progress{}, errors.New("nothing to type")
+	}
+	if req.Compat != "" {
+		return ipc.Progress{}, errors.New("compat is not available on macOS")
+	}
+	if req.Markup != nil && *req.Markup {
+		return ipc.Progress{}, errors.New("key markup is not available on macOS")
+	}
+
+	var target *windowInfo
+	if req.Handle != 0 || req.Window != "" || req.Process != "" {
+		w, err := h.findWindow(req)
+		if err != nil {
+			return ipc.Progress{}, err
+		}
+		target = &w
+	}
+
+	var (
+		layoutName string
+		speed      string
+		err        error
+	)
+	if req.Layout != "" {
+		if layoutName, err = cliLayout(req.Layout); err != nil {
+			return ipc.Progress{}, err
+		}
+	}
+	if req.DelayMs < 0 || req.DelayMs > 10000 {
+		return ipc.Progress{}, errors.New("the delay must be from 0 to 10000 milliseconds")
+	}
+	switch option := config.SpeedOption(req.Speed); {
+	case req.Speed == "":
+	case option == config.SpeedCustom:
+		if req.DelayMs == 0 {
+			return ipc.Progress{}, errors.New("the custom speed needs delayMs")
+		}
+	default:
+		var ok bool
+		if speed, ok = macSpeedLabels[option]; !ok {
+			return ipc.Progress{}, fmt.Errorf("unknown speed %q", req.Speed)
+		}
+	}
+
+	delay := time.Duration(req.DelayMs) * time.Millisecond
+	if err := h.start(target, text, layoutName, speed, delay); err != nil {
+		return ipc.Progress{}, err
+	}
+	return ipc.Progress{State: ipc.StateTyping, Total: len([]rune(text))}, nil
+}
+
+// findWindow returns the window with req.Handle, or the one window whose
+// title matches req.Window and/or whose app is req.Process.
+func (h *automationHandler) findWindow(req ipc.Request) (windowInfo, error) {
+	wins := enumWindows(h.selfAppNameLower)
+	if req.Handle != 0 {
+		for _, w := range wins {
+			if uint64(w.WindowNumber) == req.Handle {
+				return w, nil
+			}
+		}
+		return windowInfo{}, fmt.Errorf("no window with handle %d", req.Handle)
+	}
+
+	var titleRe *regexp.Regexp
+	if req.Window != "" {
+		var err error
+		if titleRe, err = regexp.Compile(req.Window); err != nil {
+			return windowInfo{}, fmt.Errorf("window: %w", err)
+		}
+	}
+	var found []windowInfo
+	for _, w := range wins {
+		if titleRe != nil && !titleRe.MatchString(w.Title) {
+			continue
+		}
+		if req.Process != "" && !strings.EqualFold(w.AppName, req.Process) {
+			continue
+		}
+		found = append(found, w)
+	}
+	switch len(found) {
+	case 0:
+		return windowInfo{}, errors.New("no matching window")
+	case 1:
+		return found[0], nil
+	}
+	titles := make([]string, len(found))
+	for i, w := range found {
+		titles[i] = fmt.Sprintf("%q", w.Title)
+	}
+	return windowInfo{}, fmt.Errorf("%d windows match, be more specific: %s", len(found), strings.Join(titles, ", "))
+}
+
+func (h *automationHandler) Progress() (ipc.Progress, error) {
+	return ipc.Progress{}, errNoProgress
+}
+
+func (h *automationHandler) Stop() (ipc.Progress, error) {
+	return ipc.Progress{}, errNoStop
+}
@@ -0,0 +1,290 @@
+//go:build windows || linux
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"goclip/config"
+	"goclip/keyplan"
+)
+
+// Exit codes of goclip type.
+const (
+	exitCompleted    = 0
+	exitFailed       = 1
+	exitUsage        = 2
+	exitFocusChanged = 3
+	exitInterrupted  = 130
+)
+
+// typeCommandOptions are the flags of goclip type.
+type typeCommandOptions struct {
+	layout     string
+	speed      string
+	delayMs    int
+	compat     string
+	markup     bool
+	window     string
+	process    string
+	countdown  int
+	file       string
+	focusAbort bool
+}
+
+// runTypeCommand runs "goclip type" without the GUI and returns the exit
+// code: typing completed, failed, or was aborted by a focus change or an
+// interrupt.
+func runTypeCommand(args []string) int {
+	attachParentConsole()
+	if err := config.Load(); err != nil {
+		fmt.Fprintf(os.Stderr, "goclip: config: %v (using defaults)\n", err)
+	}
+	if err := loadUserLayouts(); err != nil {
+		fmt.Fprintf(os.Stderr, "goclip: %v\n", err)
+	}
+	cfg := config.Get()
+
+	var o typeCommandOptions
+	fs := flag.NewFlagSet("goclip type", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.StringVar(&o.layout, "layout", cfg.KeyboardLayout, `keyboard layout of the target, e.g. "German (DE)"; "auto" for the host's`)
+	fs.StringVar(&o.speed, "speed", string(cfg.DefaultSpeedOption), "typing speed: default, medium, slow or superSlow")
+	fs.IntVar(&o.delayMs, "delay", 0, "delay per character in milliseconds (0 to 10000), overrides -speed")
+	fs.StringVar(&o.compat, "compat", string(cfg.CompatibilityMode), "modifier compatibility mode: auto, forceOn or forceOff")
+	fs.BoolVar(&o.markup, "markup", false, "type {KEY} markup such as {TAB} or {ENTER} as key presses")
+	fs.StringVar(&o.window, "window", "", "target the window whose title matches this regular expression")
+	fs.StringVar(&o.process, "process", "", "target the window of this process, e.g. wfica32.exe")
+	fs.IntVar(&o.countdown, "countdown", 0, "seconds to wait before typing")
+	fs.StringVar(&o.file, "file", "", `read the text from this file ("-" for stdin)`)
+	fs.BoolVar(&o.focusAbort, "abort-on-focus-change", cfg.AbortOnFocusChange, "stop when another window gets the focus")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: goclip type [options] [text]\n\n"+
+			"Types text into a window without opening the GUI. The text comes from the\n"+
+			"arguments, from -file, or from stdin if neither is given. Without -window\n"+
+			"or -process, goclip types into the window that has the focus once the\n"+
+			"countdown ends.\n\nOptions (defaults from the saved settings):\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(fs.Output(), "\nExit codes: %d completed, %d failed, %d usage error, %d aborted by a focus\n"+
+			"change, %d interrupted.\n", exitCompleted, exitFailed, exitUsage, exitFocusChanged, exitInterrupted)
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitCompleted
+		}
+		return exitUsage
+	}
+	usage := func(format string, args ...any) int {
+		fmt.Fprintf(os.Stderr, "goclip type: "+format+"\n", args...)
+		return exitUsage
+	}
+	fail := func(err error) int {
+		fmt.Fprintf(os.Stderr, "goclip type: %v\n", err)
+		return exitFailed
+	}
+
+	layoutName, err := cliLayout(o.layout)
+	if err != nil {
+		return usage("%v", err)
+	}
+	delayFor, err := parseTypingDelay(o.speed, o.delayMs)
+	if err != nil {
+		return usage("%v", err)
+	}
+	compat, err := parseCompatibility(o.compat)
+	if err != nil {
+		return usage("%v", err)
+	}
+	if o.countdown < 0 {
+		return usage("-countdown must not be negative")
+	}
+	var titleRe *regexp.Regexp
+	if o.window != "" {
+		if titleRe, err = regexp.Compile(o.window); err != nil {
+			return usage("-window: %v", err)
+		}
+	}
+
+	text, err := cliText(o.file, fs.Args())
+	if err != nil {
+		if errors.Is(err, errCLIUsage) {
+			return usage("%v", err)
+		}
+		return fail(err)
+	}
+	if text == "" {
+		return fail(errors.New("nothing to type"))
+	}
+
+	// Ctrl+C stops typing between keys
+	var interrupted atomic.Bool
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	defer signal.Stop(sig)
+	go func() {
+		for range sig {
+			interrupted.Store(true)
+		}
+	}()
+
+	for i := o.countdown; i > 0; i-- {
+		fmt.Fprintf(os.Stderr, "Typing in %d…\n", i)
+		time.Sleep(time.Second)
+		if interrupted.Load() {
+			return exitInterrupted
+		}
+	}
+
+	var hwnd windowHandle
+	if titleRe != nil || o.process != "" {
+		if hwnd, err = findTargetWindow(titleRe, o.process); err != nil {
+			return fail(err)
+		}
+		setForegroundWindow(hwnd)
+		time.Sleep(150 * time.Millisecond)
+	} else if hwnd = getForegroundWindow(); hwnd == 0 {
+		return fail(errors.New("no window has the focus"))
+	}
+
+	opts := keyplan.Options{
+		PerCharDelay: delayFor(text),
+		Fallback:     planFallback(config.GetTargetFallback(fallbackTarget(hwnd))),
+		Unmappable:   planUnmappable(config.GetUnmappablePolicy()),
+		Markup:       o.markup,
+	}
+
+	var focusLost atomic.Bool
+	shouldStop := func() bool {
+		if interrupted.Load() {
+			return true
+		}
+		if o.focusAbort {
+			if current := getForegroundWindow(); current != 0 && current != hwnd {
+				focusLost.Store(true)
+				return true
+			}
+		}
+		return false
+	}
+	err = sendText(text, layoutName, opts, resolveModifierCompatibility(hwnd, compat), shouldStop)
+	switch {
+	case interrupted.Load():
+		fmt.Fprintln(os.Stderr, "goclip type: interrupted")
+		return exitInterrupted
+	case focusLost.Load():
+		fmt.Fprintln(os.Stderr, "goclip type: stopped, the focus moved to another window")
+		return exitFocusChanged
+	case err != nil:
+		return fail(err)
+	}
+	return exitCompleted
+}
+
+// parseTypingDelay returns the per-character delay of a speed option given
+// by name, or of delayMs if that is set. The custom speed without delayMs
+// uses the saved custom delay.
+func parseTypingDelay(speed string, delayMs int) (func(text string) time.Duration, error) {
+	option := speedOptionID(speed)
+	switch option {
+	case "":
+		option = speedOptionDefault
+	case speedOptionDefault, speedOptionMedium, speedOptionSlow, speedOptionSuperSlow:
+	case speedOptionCustom:
+		if delayMs == 0 {
+			delayMs = config.GetCustomSpeedMs()
+		}
+	default:
+		return nil, fmt.Errorf("unknown speed %q", speed)
+	}
+	if delayMs < 0 || delayMs > 10000 {
+		return nil, errors.New("the delay must be from 0 to 10000 milliseconds")
+	}
+	return func(text string) time.Duration {
+		if delayMs > 0 {
+			return time.Duration(delayMs) * time.Millisecond
+		}
+		return presetSpeedDelay(option, text)
+	}, nil
+}
+
+// parseCompatibility returns the modifier compatibility mode given by name;
+// "" is auto.
+func parseCompatibility(mode string) (compatibilityModeSetting, error) {
+	switch setting := compatibilityModeSetting(mode); setting {
+	case "":
+		return compatibilityModeAuto, nil
+	case compatibilityModeAuto, compatibilityModeForceOn, compatibilityModeForceOff:
+		return setting, nil
+	}
+	return "", fmt.Errorf("unknown compatibility mode %q", mode)
+}
+
+// errCLIUsage marks text source errors that are usage errors.
+var errCLIUsage = errors.New("give the text as arguments or with -file, not both")
+
+// cliText returns the text to type from -file, the arguments or stdin.
+// Windows line endings become newlines, as when text is pasted into the
+// GUI.
+func cliText(file string, args []string) (string, error) {
+	var (
+		data []byte
+		err  error
+	)
+	switch {
+	case file != "" && len(args) > 0:
+		return "", errCLIUsage
+	case len(args) > 0:
+		return strings.Join(args, " "), nil
+	case file != "" && file != "-":
+		data, err = os.ReadFile(file)
+	default:
+		data, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(string(data), "\r\n", "\n"), nil
+}
+
+// findTargetWindow returns the one window whose title matches titleRe
+// and/or whose process is named process (".exe" may be left out).
+func findTargetWindow(titleRe *regexp.Regexp, process string) (windowHandle, error) {
+	selfPath, _ := os.Executable()
+	selfExeLower := strings.ToLower(filepath.Base(selfPath))
+	process = strings.ToLower(process)
+
+	var found []windowInfo
+	for _, w := range enumWindows(selfExeLower) {
+		if titleRe != nil && !titleRe.MatchString(w.Title) {
+			continue
+		}
+		if process != "" {
+			exe := getWindowProcessName(w.Hwnd)
+			if exe != process && strings.TrimSuffix(exe, ".exe") != process {
+				continue
+			}
+		}
+		found = append(found, w)
+	}
+	switch len(found) {
+	case 0:
+		return 0, errors.New("no matching window")
+	case 1:
+		return found[0].Hwnd, nil
+	}
+	titles := make([]string, len(found))
+	for i, w := range found {
+		titles[i] = fmt.Sprintf("%q", w.Title)
+	}
+	return 0, fmt.Errorf("%d windows match, be more specific: %s", len(found), strings.Join(titles, ", "))
+}
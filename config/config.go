@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 )
 
 // SpeedOption represents the typing speed setting
@@ -27,6 +28,37 @@ const (
 	CompatibilityForceOff CompatibilityMode = "forceOff"
 )
 
+// InputBackend selects how keystrokes are injected on Linux
+type InputBackend string
+
+const (
+	InputBackendAuto    InputBackend = "auto"
+	InputBackendX11     InputBackend = "x11"
+	InputBackendUinput  InputBackend = "uinput"
+	InputBackendWayland InputBackend = "wayland"
+)
+
+// FallbackStrategy selects how characters the target layout cannot type
+// are sent
+type FallbackStrategy string
+
+const (
+	FallbackUnicode   FallbackStrategy = "unicode"
+	FallbackAltNumpad FallbackStrategy = "altNumpad"
+	FallbackHexInput  FallbackStrategy = "ctrlShiftU"
+)
+
+// UnmappablePolicy selects what happens to characters the target layout
+// cannot type
+type UnmappablePolicy string
+
+const (
+	UnmappableFallback      UnmappablePolicy = "fallback"
+	UnmappableFail          UnmappablePolicy = "fail"
+	UnmappableSkip          UnmappablePolicy = "skip"
+	UnmappableTransliterate UnmappablePolicy = "transliterate"
+)
+
 // Config holds all persistent application settings
 type Config struct {
 	// Typing speed settings
@@ -47,8 +79,73 @@ type Config struct {
 
 	// Always on top window setting
 	AlwaysOnTop bool `json:"alwaysOnTop"`
+
+	// XKB layouts to offer in the layout list, as symbols specs such as
+	// "de(nodeadkeys)"; files are read from xkb/symbols in the config
+	// directory, then from the system's xkeyboard-config
+	XKBLayouts []string `json:"xkbLayouts,omitempty"`
+
+	// Fallback strategy per target window, keyed by lower-case process
+	// name (or window title when the process is unknown); targets not
+	// listed use FallbackUnicode
+	TargetFallbacks map[string]FallbackStrategy `json:"targetFallbacks,omitempty"`
+
+	// Policy for characters the target layout cannot type (fallback =
+	// use the target's fallback strategy, fail = refuse before typing,
+	// skip, or transliterate to ASCII)
+	UnmappablePolicy UnmappablePolicy `json:"unmappablePolicy"`
+
+	// Interpret {KEY} markup such as {TAB} or {CTRL+ALT+DEL} in the text
+	// box
+	KeyMarkup bool `json:"keyMarkup"`
+
+	// Minutes of inactivity after which the secret vault locks itself
+	// (0 = DefaultVaultAutoLockMinutes)
+	VaultAutoLockMinutes int `json:"vaultAutoLockMinutes,omitempty"`
+
+	// Linux input backend (auto = X11 on X sessions, the Wayland virtual
+	// keyboard where the compositor offers it, uinput otherwise)
+	InputBackend InputBackend `json:"inputBackend"`
+
+	// Serve the local automation API on a socket in the config directory
+	AutomationAPI bool `json:"automationApi,omitempty"`
+
+	// Global hotkey that types the clipboard into the focused window, e.g.
+	// "Ctrl+Alt+V" ("" = off)
+	HotkeyTypeClipboard string `json:"hotkeyTypeClipboard"`
+
+	// Key that stops typing when held down, e.g. "Esc" ("" = off)
+	HotkeyStop string `json:"hotkeyStop"`
+
+	// Show an icon in the system tray and hide the window there when it
+	// is closed
+	TrayMode bool `json:"trayMode,omitempty"`
+
+	// Favourite snippets, offered in the main window and the tray menu
+	Snippets []Snippet `json:"snippets,omitempty"`
+}
+
+// Snippet is a favourite text, typed from the tray menu by name
+type Snippet struct {
+	Name   string `json:"name"`
+	Text   string `json:"text"`
+	Markup bool   `json:"markup,omitempty"` // interpret {KEY} markup
 }
 
+// Default hotkeys, also used for config files written before hotkeys
+// existed. Typing the clipboard is opt-in: a hotkey that types into
+// whatever window has the focus should not appear with an update.
+const (
+	DefaultHotkeyTypeClipboard = ""
+	DefaultHotkeyStop          = "Esc"
+)
+
+// Limits of the vault auto-lock timeout in minutes
+const (
+	DefaultVaultAutoLockMinutes = 5
+	MaxVaultAutoLockMinutes     = 24 * 60
+)
+
 // DefaultConfig returns the default configuration
 func DefaultConfig() Config {
 	return Config{
@@ -59,6 +156,11 @@ func DefaultConfig() Config {
 		AbortOnFocusChange: true,
 		Language:           "",
 		AlwaysOnTop:        false,
+		UnmappablePolicy:   UnmappableFallback,
+		InputBackend:       InputBackendAuto,
+
+		HotkeyTypeClipboard: DefaultHotkeyTypeClipboard,
+		HotkeyStop:          DefaultHotkeyStop,
 	}
 }
 
@@ -86,6 +188,12 @@ func GetConfigPath() string {
 	return configPath
 }
 
+// GetConfigDir returns the directory holding the config file and user data
+// such as imported keyboard layouts
+func GetConfigDir() string {
+	return filepath.Dir(configPath)
+}
+
 // Load reads the configuration from disk
 func Load() error {
 	configMu.Lock()
@@ -122,6 +230,26 @@ func Load() error {
 	if cfg.CompatibilityMode == "" {
 		cfg.CompatibilityMode = CompatibilityAuto
 	}
+	if cfg.UnmappablePolicy == "" {
+		cfg.UnmappablePolicy = UnmappableFallback
+	}
+	if cfg.InputBackend == "" {
+		cfg.InputBackend = InputBackendAuto
+	}
+	if cfg.VaultAutoLockMinutes < 0 {
+		cfg.VaultAutoLockMinutes = 0
+	}
+	if cfg.VaultAutoLockMinutes > MaxVaultAutoLockMinutes {
+		cfg.VaultAutoLockMinutes = MaxVaultAutoLockMinutes
+	}
+	// "" turns a hotkey off, so only a missing key gets the default; the
+	// clipboard hotkey stays off unless it was set
+	var keys map[string]json.RawMessage
+	if json.Unmarshal(data, &keys) == nil {
+		if _, ok := keys["hotkeyStop"]; !ok {
+			cfg.HotkeyStop = DefaultHotkeyStop
+		}
+	}
 
 	current = cfg
 	return nil
@@ -228,3 +356,154 @@ func GetAlwaysOnTop() bool {
 	defer configMu.RUnlock()
 	return current.AlwaysOnTop
 }
+
+// GetInputBackend returns the configured Linux input backend
+func GetInputBackend() InputBackend {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return current.InputBackend
+}
+
+// GetUnmappablePolicy returns the configured policy for characters the
+// target layout cannot type
+func GetUnmappablePolicy() UnmappablePolicy {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return current.UnmappablePolicy
+}
+
+// GetKeyMarkup returns whether {KEY} markup in the text box is interpreted
+func GetKeyMarkup() bool {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return current.KeyMarkup
+}
+
+// SetKeyMarkup turns {KEY} markup on or off and saves the configuration
+func SetKeyMarkup(on bool) error {
+	return Update(func(cfg *Config) {
+		cfg.KeyMarkup = on
+	})
+}
+
+// GetVaultAutoLock returns how long the secret vault stays unlocked
+// without being used
+func GetVaultAutoLock() time.Duration {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	m := current.VaultAutoLockMinutes
+	if m <= 0 {
+		m = DefaultVaultAutoLockMinutes
+	}
+	return time.Duration(m) * time.Minute
+}
+
+// GetVaultPath returns the file of the secret vault
+func GetVaultPath() string {
+	return filepath.Join(GetConfigDir(), "vault.json")
+}
+
+// GetAutomationAPI returns whether the local automation API is enabled
+func GetAutomationAPI() bool {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return current.AutomationAPI
+}
+
+// GetAutomationSocketPath returns the socket of the automation API
+func GetAutomationSocketPath() string {
+	return filepath.Join(GetConfigDir(), "automation.sock")
+}
+
+// GetAutomationTokenPath returns the file holding the token that requests
+// to the automation API must carry
+func GetAutomationTokenPath() string {
+	return filepath.Join(GetConfigDir(), "automation-token")
+}
+
+// GetInstanceLockPath returns the lock file held by the running goclip
+func GetInstanceLockPath() string {
+	return filepath.Join(GetConfigDir(), "instance.lock")
+}
+
+// GetInstanceSocketPath returns the socket on which the running goclip
+// receives the arguments of later launches
+func GetInstanceSocketPath() string {
+	return filepath.Join(GetConfigDir(), "instance.sock")
+}
+
+// GetTargetFallback returns the fallback strategy chosen for a target
+func GetTargetFallback(target string) FallbackStrategy {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	if f, ok := current.TargetFallbacks[target]; ok {
+		return f
+	}
+	return FallbackUnicode
+}
+
+// SetTargetFallback stores the fallback strategy for a target and saves
+// the configuration
+func SetTargetFallback(target string, f FallbackStrategy) error {
+	return Update(func(cfg *Config) {
+		// copy, so configs handed out by Get stay unchanged
+		m := make(map[string]FallbackStrategy, len(cfg.TargetFallbacks)+1)
+		for k, v := range cfg.TargetFallbacks {
+			m[k] = v
+		}
+		if f == FallbackUnicode {
+			delete(m, target)
+		} else {
+			m[target] = f
+		}
+		cfg.TargetFallbacks = m
+	})
+}
+
+// GetTrayMode returns whether goclip shows a tray icon and hides there
+func GetTrayMode() bool {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return current.TrayMode
+}
+
+// GetSnippets returns the favourite snippets
+func GetSnippets() []Snippet {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return append([]Snippet(nil), current.Snippets...)
+}
+
+// SetSnippet stores a favourite snippet, replacing the one of the same
+// name, and saves the configuration
+func SetSnippet(sn Snippet) error {
+	return Update(func(cfg *Config) {
+		// copy, so configs handed out by Get stay unchanged
+		list := make([]Snippet, 0, len(cfg.Snippets)+1)
+		replaced := false
+		for _, old := range cfg.Snippets {
+			if old.Name == sn.Name {
+				old, replaced = sn, true
+			}
+			list = append(list, old)
+		}
+		if !replaced {
+			list = append(list, sn)
+		}
+		cfg.Snippets = list
+	})
+}
+
+// DeleteSnippet removes the favourite snippet with the given name and
+// saves the configuration
+func DeleteSnippet(name string) error {
+	return Update(func(cfg *Config) {
+		list := make([]Snippet, 0, len(cfg.Snippets))
+		for _, sn := range cfg.Snippets {
+			if sn.Name != name {
+				list = append(list, sn)
+			}
+		}
+		cfg.Snippets = list
+	})
+}
@@ -0,0 +1,3358 @@
+//go:build windows || linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"goclip/config"
+	"goclip/instance"
+	"goclip/ipc"
+	"goclip/kdbx"
+	"goclip/keyplan"
+	"goclip/localization"
+	"goclip/runbook"
+	"goclip/totp"
+	"goclip/transfer"
+	"goclip/vault"
+
+	_ "embed"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/app"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/data/binding"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+//go:embed assets/logo/app.ico
+var embeddedAppIco []byte
+
+//go:embed assets/logo/app.png
+var embeddedAppPng []byte
+
+type statusKey string
+
+const (
+	statusKeyReady                statusKey = "ready"
+	statusKeySelectionCleared     statusKey = "selectionCleared"
+	statusKeyFoundWindows         statusKey = "foundWindows"
+	statusKeyWatcherWarning       statusKey = "watcherWarning"
+	statusKeyLayoutImportWarning  statusKey = "layoutImportWarning"
+	statusKeyAutomationError      statusKey = "automationError"
+	statusKeyInstanceWarning      statusKey = "instanceWarning"
+	statusKeyLaunchError          statusKey = "launchError"
+	statusKeyHotkeyWarning        statusKey = "hotkeyWarning"
+	statusKeyWindowUnavailable    statusKey = "windowUnavailable"
+	statusKeyNoWindow             statusKey = "noWindow"
+	statusKeyNothingToType        statusKey = "nothingToType"
+	statusKeyTyping               statusKey = "typing"
+	statusKeyStopping             statusKey = "stopping"
+	statusKeyTypingStopped        statusKey = "typingStopped"
+	statusKeyTypingError          statusKey = "typingError"
+	statusKeyTypedTo              statusKey = "typedTo"
+	statusKeyClipboardEmpty       statusKey = "clipboardEmpty"
+	statusKeyTypingClipboard      statusKey = "typingClipboard"
+	statusKeyTypingClipboardError statusKey = "typingClipboardError"
+	statusKeyTypedClipboard       statusKey = "typedClipboard"
+	statusKeyRunbookFinished      statusKey = "runbookFinished"
+	statusKeyTypingStep           statusKey = "typingStep"
+	statusKeyTypedStep            statusKey = "typedStep"
+	statusKeyTransferring         statusKey = "transferring"
+	statusKeyTransferredChunk     statusKey = "transferredChunk"
+	statusKeyTransferDone         statusKey = "transferDone"
+	statusKeyVaultLocked          statusKey = "vaultLocked"
+	statusKeyTypingSecret         statusKey = "typingSecret"
+	statusKeyTypingSecretError    statusKey = "typingSecretError"
+	statusKeyTypedSecret          statusKey = "typedSecret"
+	statusKeyKeePassEmpty         statusKey = "keepassEmpty"
+	statusKeyWaitingTOTP          statusKey = "waitingTOTP"
+	statusKeyTypingTOTP           statusKey = "typingTOTP"
+	statusKeyTypedTOTP            statusKey = "typedTOTP"
+)
+
+// typingStatus are the status messages of one kind of typing run: nothing
+// to type, typing, failed and done
+type typingStatus struct {
+	empty, start, failed, done statusKey
+}
+
+var (
+	typingStatusText      = typingStatus{statusKeyNothingToType, statusKeyTyping, statusKeyTypingError, statusKeyTypedTo}
+	typingStatusClipboard = typingStatus{statusKeyClipboardEmpty, statusKeyTypingClipboard, statusKeyTypingClipboardError, statusKeyTypedClipboard}
+	typingStatusStep      = typingStatus{statusKeyRunbookFinished, statusKeyTypingStep, statusKeyTypingError, statusKeyTypedStep}
+	typingStatusTransfer  = typingStatus{statusKeyNothingToType, statusKeyTransferring, statusKeyTypingError, statusKeyTransferredChunk}
+	typingStatusSecret    = typingStatus{statusKeyVaultLocked, statusKeyTypingSecret, statusKeyTypingSecretError, statusKeyTypedSecret}
+	typingStatusKeePass   = typingStatus{statusKeyKeePassEmpty, statusKeyTypingSecret, statusKeyTypingSecretError, statusKeyTypedSecret}
+	typingStatusTOTP      = typingStatus{statusKeyVaultLocked, statusKeyTypingTOTP, statusKeyTypingSecretError, statusKeyTypedTOTP}
+)
+
+// totpConfirmTime is how long a TOTP code must stay valid after it is
+// typed, for the user to submit it on the target.
+const totpConfirmTime = 5 * time.Second
+
+// typabilityDelay is how long the pre-flight check waits for typing in the
+// text box to pause, since analysing a long text on every keystroke makes
+// the box lag.
+const typabilityDelay = 300 * time.Millisecond
+
+const (
+	// stopHotkeyHold is how long the stop hotkey must be held, so a key
+	// typed into the target does not stop the run.
+	stopHotkeyHold = 500 * time.Millisecond
+	// hotkeyReleaseWait is how long a hotkey's modifiers may stay held
+	// before the press is dropped; typing under them would send shortcuts.
+	hotkeyReleaseWait = 2 * time.Second
+)
+
+// parseStopHotkey reads the key held to stop typing, which has no
+// modifiers.
+func parseStopHotkey(spec string) (keyplan.Hotkey, error) {
+	h, err := keyplan.ParseHotkey(spec)
+	if err == nil && h.Mods != 0 {
+		err = fmt.Errorf("stop hotkey %q must be a single key without modifiers", spec)
+	}
+	return h, err
+}
+
+// typingSettings are the settings of one typing run: the window's, or those
+// of a step from a procedure file.
+type typingSettings struct {
+	layout string
+	compat compatibilityModeSetting
+	markup bool
+	delay  func(text string) time.Duration
+}
+
+type statusMessage struct {
+	key  statusKey
+	args []any
+}
+
+type statusController struct {
+	label    *widget.Label
+	mu       sync.Mutex
+	last     statusMessage
+	onChange func() // runs on the UI thread after the text changed
+}
+
+func newStatusController(label *widget.Label) *statusController {
+	return &statusController{
+		label: label,
+		last:  statusMessage{key: statusKeyReady},
+	}
+}
+
+func (sc *statusController) Set(key statusKey, args ...any) {
+	sc.mu.Lock()
+	sc.last = statusMessage{key: key, args: args}
+	sc.mu.Unlock()
+	sc.renderAsync()
+}
+
+func (sc *statusController) Refresh() {
+	sc.mu.Lock()
+	msg := sc.last
+	sc.mu.Unlock()
+	sc.label.SetText(renderStatusText(msg, getCurrentLabelSet()))
+	if sc.onChange != nil {
+		sc.onChange()
+	}
+}
+
+func (sc *statusController) renderAsync() {
+	sc.mu.Lock()
+	msg := sc.last
+	sc.mu.Unlock()
+	labels := getCurrentLabelSet()
+	text := renderStatusText(msg, labels)
+	fyne.Do(func() {
+		sc.label.SetText(text)
+		if sc.onChange != nil {
+			sc.onChange()
+		}
+	})
+}
+
+func renderStatusText(msg statusMessage, labels localization.LabelSet) string {
+	switch msg.key {
+	case statusKeyReady:
+		return labels.StatusReady
+	case statusKeySelectionCleared:
+		return labels.StatusSelectionCleared
+	case statusKeyFoundWindows:
+		return fmt.Sprintf(labels.FoundWindowsFormat, statusArgInt(msg.args))
+	case statusKeyWatcherWarning:
+		return fmt.Sprintf(labels.StatusWatcherWarningFormat, statusArgString(msg.args))
+	case statusKeyLayoutImportWarning:
+		return fmt.Sprintf(labels.StatusLayoutImportWarningFormat, statusArgString(msg.args))
+	case statusKeyAutomationError:
+		return fmt.Sprintf(labels.StatusAutomationErrorFormat, statusArgString(msg.args))
+	case statusKeyInstanceWarning:
+		return fmt.Sprintf(labels.StatusInstanceWarningFormat, statusArgString(msg.args))
+	case statusKeyLaunchError:
+		return fmt.Sprintf(labels.StatusLaunchErrorFormat, statusArgString(msg.args))
+	case statusKeyHotkeyWarning:
+		return fmt.Sprintf(labels.StatusHotkeyWarningFormat, statusArgString(msg.args))
+	case statusKeyWindowUnavailable:
+		return labels.StatusWindowUnavailable
+	case statusKeyNoWindow:
+		return labels.StatusNoWindow
+	case statusKeyNothingToType:
+		return labels.StatusNothingToType
+	case statusKeyTyping:
+		return labels.StatusTyping
+	case statusKeyStopping:
+		return labels.StatusStopping
+	case statusKeyTypingStopped:
+		return labels.StatusTypingStopped
+	case statusKeyTypingError:
+		return fmt.Sprintf(labels.StatusTypingErrorFormat, statusArgString(msg.args))
+	case statusKeyTypedTo:
+		return fmt.Sprintf(labels.StatusTypedToFormat, statusArgString(msg.args))
+	case statusKeyClipboardEmpty:
+		return labels.StatusClipboardEmpty
+	case statusKeyTypingClipboard:
+		return labels.StatusTypingClipboard
+	case statusKeyTypingClipboardError:
+		return fmt.Sprintf(labels.StatusTypingClipboardErrorFormat, statusArgString(msg.args))
+	case statusKeyTypedClipboard:
+		return fmt.Sprintf(labels.StatusTypedClipboardFormat, statusArgString(msg.args))
+	case statusKeyRunbookFinished:
+		return labels.StatusRunbookFinished
+	case statusKeyTypingStep:
+		return labels.StatusTypingStep
+	case statusKeyTypedStep:
+		return fmt.Sprintf(labels.StatusTypedStepFormat, statusArgString(msg.args))
+	case statusKeyTransferring:
+		return labels.StatusTransferring
+	case statusKeyTransferredChunk:
+		return fmt.Sprintf(labels.StatusTransferredChunkFormat, statusArgString(msg.args))
+	case statusKeyTransferDone:
+		return fmt.Sprintf(labels.StatusTransferDoneFormat, statusArgString(msg.args))
+	case statusKeyVaultLocked:
+		return labels.StatusVaultLocked
+	case statusKeyTypingSecret:
+		return labels.StatusTypingSecret
+	case statusKeyTypingSecretError:
+		// the error may quote a character of the secret
+		return labels.StatusTypingSecretError
+	case statusKeyTypedSecret:
+		return fmt.Sprintf(labels.StatusTypedSecretFormat, statusArgString(msg.args))
+	case statusKeyKeePassEmpty:
+		return labels.StatusKeePassEmpty
+	case statusKeyWaitingTOTP:
+		return fmt.Sprintf(labels.StatusWaitingTOTPFormat, statusArgInt(msg.args))
+	case statusKeyTypingTOTP:
+		return labels.StatusTypingTOTP
+	case statusKeyTypedTOTP:
+		return fmt.Sprintf(labels.StatusTypedTOTPFormat, statusArgString(msg.args))
+	default:
+		return labels.StatusReady
+	}
+}
+
+func statusArgInt(args []any) int {
+	if len(args) == 0 {
+		return 0
+	}
+	switch v := args[0].(type) {
+	case int:
+		return v
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	default:
+		return 0
+	}
+}
+
+func statusArgString(args []any) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return fmt.Sprint(args[0])
+}
+
+type speedOptionID string
+
+const (
+	speedOptionDefault   speedOptionID = "default"
+	speedOptionMedium    speedOptionID = "medium"
+	speedOptionSlow      speedOptionID = "slow"
+	speedOptionSuperSlow speedOptionID = "superSlow"
+	speedOptionCustom    speedOptionID = "custom"
+)
+
+var speedOptionOrder = []speedOptionID{
+	speedOptionDefault,
+	speedOptionMedium,
+	speedOptionSlow,
+	speedOptionSuperSlow,
+	speedOptionCustom,
+}
+
+type compatibilityModeSetting string
+
+const (
+	compatibilityModeAuto     compatibilityModeSetting = "auto"
+	compatibilityModeForceOn  compatibilityModeSetting = "forceOn"
+	compatibilityModeForceOff compatibilityModeSetting = "forceOff"
+)
+
+var compatibilityModeOrder = []compatibilityModeSetting{
+	compatibilityModeAuto,
+	compatibilityModeForceOn,
+	compatibilityModeForceOff,
+}
+
+var fallbackStrategyOrder = []config.FallbackStrategy{
+	config.FallbackUnicode,
+	config.FallbackAltNumpad,
+	config.FallbackHexInput,
+}
+
+// fallbackTarget returns the key a target window's fallback strategy is
+// stored under: its process name, or its title if that is unknown.
+func fallbackTarget(hwnd windowHandle) string {
+	if exe := getWindowProcessName(hwnd); exe != "" {
+		return exe
+	}
+	return strings.ToLower(strings.TrimSpace(getWindowText(hwnd)))
+}
+
+// planFallback returns the keystroke plan fallback for a strategy.
+func planFallback(f config.FallbackStrategy) keyplan.Fallback {
+	switch f {
+	case config.FallbackAltNumpad:
+		return keyplan.FallbackAltNumpad
+	case config.FallbackHexInput:
+		return keyplan.FallbackHexInput
+	default:
+		return keyplan.FallbackUnicode
+	}
+}
+
+var unmappablePolicyOrder = []config.UnmappablePolicy{
+	config.UnmappableFallback,
+	config.UnmappableFail,
+	config.UnmappableSkip,
+	config.UnmappableTransliterate,
+}
+
+// planUnmappable returns the keystroke plan handling for a policy.
+func planUnmappable(p config.UnmappablePolicy) keyplan.Unmappable {
+	switch p {
+	case config.UnmappableFail:
+		return keyplan.UnmappableFail
+	case config.UnmappableSkip:
+		return keyplan.UnmappableSkip
+	case config.UnmappableTransliterate:
+		return keyplan.UnmappableTransliterate
+	default:
+		return keyplan.UnmappableFallback
+	}
+}
+
+// presetSpeedDelay returns the per-character delay of a speed option other
+// than custom. The default speed depends on the length of the text.
+func presetSpeedDelay(option speedOptionID, text string) time.Duration {
+	switch option {
+	case speedOptionDefault:
+		runeCount := 0
+		lines := 1
+		for _, ch := range text {
+			runeCount++
+			if ch == '\n' {
+				lines++
+			}
+		}
+
+		if runeCount <= 200 && lines <= 5 {
+			return 0
+		}
+
+		msByLines := lines
+		msByChars := runeCount / 200
+		ms := msByLines
+		if msByChars > ms {
+			ms = msByChars
+		}
+		if ms < 10 {
+			ms = 10
+		}
+		if ms > 50 {
+			ms = 50
+		}
+		return time.Duration(ms) * time.Millisecond
+	case speedOptionMedium:
+		return 50 * time.Millisecond
+	case speedOptionSlow:
+		return 100 * time.Millisecond
+	case speedOptionSuperSlow:
+		return 250 * time.Millisecond
+	default:
+		return 0
+	}
+}
+
+// resolveProcedureLayouts checks the layouts named in a procedure file.
+// "auto" stands for the host's layout.
+func resolveProcedureLayouts(p *runbook.Procedure) error {
+	for i := range p.Steps {
+		switch name := p.Steps[i].Layout; {
+		case name == "":
+		case strings.EqualFold(name, "auto"):
+			p.Steps[i].Layout = autoLayout
+		case name != autoLayout && targetLayout(name) == nil:
+			return fmt.Errorf("step %d: unknown layout %q", i+1, name)
+		}
+	}
+	return nil
+}
+
+// Version is set at build time via ldflags
+var Version = "dev"
+
+type appCompatibilityRule struct {
+	Name            string
+	ProcessNames    []string
+	TitleSubstrings []string
+}
+
+var modifierCompatibilityRules = []appCompatibilityRule{
+	{
+		Name: "Citrix Workspace / Viewer",
+		ProcessNames: []string{
+			"wfica32.exe",
+			"wfcrun32.exe",
+			"selfservice.exe",
+			"citrixworkspace.exe",
+			"cdviewer.exe",
+			"receiver.exe",
+			// Citrix Workspace app for Linux
+			"wfica",
+			"selfservice",
+		},
+		TitleSubstrings: []string{
+			"citrix workspace",
+			"citrix viewer",
+			"cdviewer",
+			"virtual apps and desktops",
+		},
+	},
+	{
+		Name: "HPE iLO Integrated Remote Console",
+		ProcessNames: []string{
+			"integratedremoteconsole.exe",
+			"hpilo-integrated-rc.exe",
+			"hpilo-integrated-remote-console.exe",
+			"hpremoteconsole.exe",
+			"hpiloremoteconsole.exe",
+		},
+		TitleSubstrings: []string{
+			"integrated remote console",
+			"hpe ilo",
+			"hp ilo",
+			"ilo remote console",
+			"ilo:",
+		},
+	},
+}
+
+// ------------------------------------------------
+
+func (r appCompatibilityRule) matches(title string, exe string) bool {
+	if exe != "" {
+		for _, proc := range r.ProcessNames {
+			if exe == proc {
+				return true
+			}
+		}
+	}
+	if title != "" {
+		for _, sub := range r.TitleSubstrings {
+			if sub == "" {
+				continue
+			}
+			if strings.Contains(title, sub) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func resolveModifierCompatibility(hwnd windowHandle, setting compatibilityModeSetting) bool {
+	switch setting {
+	case compatibilityModeForceOn:
+		return true
+	case compatibilityModeForceOff:
+		return false
+	default:
+		if hwnd == 0 {
+			return false
+		}
+		return matchesModifierCompatibilityWindow(hwnd)
+	}
+}
+
+// truncateRunes limits to n runes, appends "..." if truncated.
+func truncateRunes(s string, n int) string {
+	r := []rune(strings.TrimSpace(s))
+	if len(r) <= n {
+		return s
+	}
+	if n <= 3 {
+		return string(r[:n])
+	}
+	return string(r[:n]) + "..."
+}
+
+// load ICO from embedded bytes, with a dev-time disk fallback
+func loadAppIcon() fyne.Resource {
+	if len(embeddedAppIco) > 0 {
+		return fyne.NewStaticResource("app.ico", embeddedAppIco)
+	}
+	// fallback for `go run` from source
+	data, err := os.ReadFile("assets/logo/app.ico")
+	if err == nil {
+		return fyne.NewStaticResource("app.ico", data)
+	}
+	return nil
+}
+
+func main() {
+	// goclip type runs without the GUI
+	if len(os.Args) > 1 && os.Args[1] == "type" {
+		os.Exit(runTypeCommand(os.Args[2:]))
+	}
+
+	// a later launch hands its arguments to the running goclip and exits
+	instanceLock, launch, instanceErr := claimInstance(os.Args[1:])
+	if instanceLock != nil {
+		defer instanceLock.Release()
+	}
+
+	// Load configuration from disk
+	if err := config.Load(); err != nil {
+		// Config load failed, continue with defaults
+		_ = err
+	}
+	cfg := config.Get()
+	layoutErr := loadUserLayouts()
+
+	systemLanguageCode := localization.DetectSystemLanguage()
+	// Use saved language preference if set
+	selectedLanguageCode := cfg.Language
+	effectiveLanguage := selectedLanguageCode
+	if effectiveLanguage == "" {
+		effectiveLanguage = systemLanguageCode
+	}
+	setCurrentLabelSet(localization.Labels(effectiveLanguage))
+	languageMetas := localization.SupportedLanguages()
+
+	var applyLocalization func(localization.LabelSet)
+	var applyLanguageSelection func()
+
+	myApp := app.New()
+	myApp.Settings().SetTheme(theme.DarkTheme())
+
+	// set runtime icon (taskbar/window) from embedded resource
+	if res := loadAppIcon(); res != nil {
+		myApp.SetIcon(res)
+	}
+
+	// our own exe base name (lower) to avoid listing ourselves
+	selfPath, _ := os.Executable()
+	selfExeLower := strings.ToLower(filepath.Base(selfPath))
+
+	w := myApp.NewWindow("goclip")
+	w.Resize(fyne.NewSize(800, 460))
+
+	// also set it on the window explicitly
+	if res := loadAppIcon(); res != nil {
+		w.SetIcon(res)
+	}
+
+	// --- Input field with Hide/Show (eye) toggle ---
+	inputEntry := widget.NewMultiLineEntry()
+	inputEntry.Wrapping = fyne.TextWrapWord
+
+	masked := false
+	var updateTypability func()
+	var refreshTrayMenu func()
+	var rebuildRunbook func(keepPos bool)
+	var eyeBtn *widget.Button
+	eyeBtn = widget.NewButtonWithIcon("", theme.VisibilityIcon(), func() {
+		masked = !masked
+		inputEntry.Password = masked
+		if masked {
+			eyeBtn.SetIcon(theme.VisibilityOffIcon())
+		} else {
+			eyeBtn.SetIcon(theme.VisibilityIcon())
+		}
+		inputEntry.Refresh()
+		updateTypability()
+		rebuildRunbook(true)
+	})
+	eyeBtn.Importance = widget.LowImportance
+
+	inputRow := container.NewBorder(nil, nil, nil, eyeBtn, inputEntry)
+
+	// pre-flight report: how each character will be typed, with the
+	// problem characters highlighted in a copy of the text
+	typabilityLabel := widget.NewLabel("")
+	typabilityLabel.Wrapping = fyne.TextWrapWord
+	typabilityText := widget.NewRichText()
+	typabilityText.Wrapping = fyne.TextWrapWord
+	typabilityScroll := container.NewVScroll(typabilityText)
+	typabilityScroll.SetMinSize(fyne.NewSize(0, 60))
+	typabilityScroll.Hide()
+
+	statusLabel := widget.NewLabel("")
+	statusLabel.Wrapping = fyne.TextWrapWord
+	statusCtrl := newStatusController(statusLabel)
+
+	layoutSelect := widget.NewSelect(keyboardLayoutOptions, nil)
+	layoutSelect.Selected = cfg.KeyboardLayout
+	if layoutSelect.Selected == "" {
+		layoutSelect.Selected = autoLayout
+	}
+	if layoutErr != nil {
+		statusCtrl.Set(statusKeyLayoutImportWarning, layoutErr.Error())
+	}
+
+	languageSelect := widget.NewSelect([]string{}, nil)
+	languageLabelToCode := make(map[string]string)
+	languageSelectUpdating := false
+	languageNativeName := func(code string) string {
+		for _, meta := range languageMetas {
+			if meta.Code == code {
+				return meta.NativeName
+			}
+		}
+		return ""
+	}
+
+	languageSelect.OnChanged = func(label string) {
+		if languageSelectUpdating {
+			return
+		}
+		code, ok := languageLabelToCode[label]
+		if !ok {
+			return
+		}
+		if code == selectedLanguageCode {
+			return
+		}
+		selectedLanguageCode = code
+		applyLanguageSelection()
+	}
+
+	refreshLanguageSelectOptions := func(labels localization.LabelSet) {
+		languageSelectUpdating = true
+		autoLabel := labels.LanguageAutoOption
+		options := make([]string, 0, len(languageMetas)+1)
+		languageLabelToCode = map[string]string{autoLabel: ""}
+		options = append(options, autoLabel)
+		for _, meta := range languageMetas {
+			options = append(options, meta.NativeName)
+			languageLabelToCode[meta.NativeName] = meta.Code
+		}
+		selectedLabel := autoLabel
+		if selectedLanguageCode != "" {
+			if native := languageNativeName(selectedLanguageCode); native != "" {
+				selectedLabel = native
+			}
+		}
+		languageSelect.Options = options
+		languageSelect.SetSelected(selectedLabel)
+		languageSelectUpdating = false
+	}
+
+	// --- Typing speed controls (dropdown + optional custom ms field) ---
+	speedSelect := widget.NewSelect([]string{}, nil)
+	// Initialize speed from config
+	currentSpeedOption := speedOptionID(cfg.DefaultSpeedOption)
+	if currentSpeedOption == "" {
+		currentSpeedOption = speedOptionDefault
+	}
+	speedLabelToID := make(map[string]speedOptionID)
+	speedIDToLabel := make(map[speedOptionID]string)
+	speedSelectUpdating := false
+
+	customMsEntry := widget.NewEntry()
+	// Initialize custom ms entry with saved value if custom speed is set
+	if cfg.CustomSpeedMs > 0 {
+		customMsEntry.SetText(strconv.Itoa(cfg.CustomSpeedMs))
+	}
+	if currentSpeedOption != speedOptionCustom {
+		customMsEntry.Hide()
+	}
+
+	// Dynamic per-character delay selection
+	speedDelay := func(option speedOptionID, text string) time.Duration {
+		switch option {
+		case speedOptionCustom:
+			v := strings.TrimSpace(customMsEntry.Text)
+			if v == "" {
+				return 0
+			}
+			var acc int64
+			for _, ch := range v {
+				if ch < '0' || ch > '9' {
+					return 0
+				}
+				acc = acc*10 + int64(ch-'0')
+				if acc > 10000 {
+					acc = 10000
+					break
+				}
+			}
+			return time.Duration(acc) * time.Millisecond
+		default:
+			return presetSpeedDelay(option, text)
+		}
+	}
+	getPerCharDelay := func(text string) time.Duration {
+		return speedDelay(currentSpeedOption, text)
+	}
+
+	delayLabel := widget.NewLabel("")
+
+	updateDelayLabel := func() {
+		if currentSpeedOption != speedOptionDefault {
+			delayLabel.Hide()
+			return
+		}
+		delayLabel.Show()
+		d := getPerCharDelay(inputEntry.Text)
+		labels := getCurrentLabelSet()
+		delayLabel.SetText(fmt.Sprintf(labels.DelayLabelFormat, d.Milliseconds()))
+	}
+
+	speedSelect.OnChanged = func(label string) {
+		if speedSelectUpdating {
+			return
+		}
+		id, ok := speedLabelToID[label]
+		if !ok {
+			id = speedOptionDefault
+		}
+		if currentSpeedOption == id {
+			return
+		}
+		currentSpeedOption = id
+		if id == speedOptionCustom {
+			customMsEntry.Show()
+		} else {
+			customMsEntry.Hide()
+		}
+		updateDelayLabel()
+		if refreshTrayMenu != nil {
+			refreshTrayMenu()
+		}
+	}
+
+	customMsEntry.OnChanged = func(string) {
+		updateDelayLabel()
+	}
+
+	var typabilityTimer *time.Timer
+	inputEntry.OnChanged = func(string) {
+		updateDelayLabel()
+		if typabilityTimer != nil {
+			typabilityTimer.Stop()
+		}
+		var t *time.Timer
+		t = time.AfterFunc(typabilityDelay, func() {
+			fyne.Do(func() {
+				if typabilityTimer == t {
+					updateTypability()
+				}
+			})
+		})
+		typabilityTimer = t
+		rebuildRunbook(true)
+	}
+
+	compatibilityModeSelect := widget.NewSelect([]string{}, nil)
+	// Initialize compatibility mode from config
+	currentCompatibilitySetting := compatibilityModeSetting(cfg.CompatibilityMode)
+	if currentCompatibilitySetting == "" {
+		currentCompatibilitySetting = compatibilityModeAuto
+	}
+	compatibilityLabelToSetting := make(map[string]compatibilityModeSetting)
+	compatibilitySettingToLabel := make(map[compatibilityModeSetting]string)
+	compatibilitySelectUpdating := false
+	compatibilityStatusLabel := widget.NewLabel("")
+
+	// Create a small tappable icon for help
+	helpSize := fyne.NewSize(18, 18)
+
+	helpImg := canvas.NewImageFromResource(theme.QuestionIcon())
+	helpImg.FillMode = canvas.ImageFillContain
+	helpImg.SetMinSize(helpSize)
+
+	compatibilityHelpBtn := widget.NewButton("", func() {
+		labels := getCurrentLabelSet()
+		dialog.NewInformation(labels.CompatibilityHelpTitle, labels.CompatibilityHelpMessage, w).Show()
+	})
+	compatibilityHelpBtn.Importance = widget.LowImportance
+
+	// Force the clickable area and the icon to the same fixed size
+	compatibilityHelpContainer := container.NewGridWrap(
+		helpSize,
+		container.NewMax(compatibilityHelpBtn, helpImg),
+	)
+
+	var updateCompatibilityStatus func()
+
+	// fallback for characters the layout cannot type, remembered per target
+	fallbackSelect := widget.NewSelect([]string{}, nil)
+	fallbackLabelToStrategy := make(map[string]config.FallbackStrategy)
+	fallbackStrategyToLabel := make(map[config.FallbackStrategy]string)
+	fallbackSelectUpdating := false
+
+	refreshSpeedSelectOptions := func(labels localization.LabelSet) {
+		speedSelectUpdating = true
+		speedIDToLabel = map[speedOptionID]string{
+			speedOptionDefault:   labels.SpeedDefault,
+			speedOptionMedium:    labels.SpeedMedium,
+			speedOptionSlow:      labels.SpeedSlow,
+			speedOptionSuperSlow: labels.SpeedSuperSlow,
+			speedOptionCustom:    labels.SpeedCustom,
+		}
+		speedLabelToID = make(map[string]speedOptionID, len(speedIDToLabel))
+		options := make([]string, 0, len(speedOptionOrder))
+		for _, id := range speedOptionOrder {
+			label := speedIDToLabel[id]
+			options = append(options, label)
+			speedLabelToID[label] = id
+		}
+		speedSelect.Options = options
+		targetID := currentSpeedOption
+		if _, ok := speedIDToLabel[targetID]; !ok {
+			targetID = speedOptionDefault
+			currentSpeedOption = targetID
+		}
+		if label, ok := speedIDToLabel[targetID]; ok {
+			speedSelect.SetSelected(label)
+		}
+		if currentSpeedOption == speedOptionCustom {
+			customMsEntry.Show()
+		} else {
+			customMsEntry.Hide()
+		}
+		speedSelectUpdating = false
+	}
+
+	refreshCompatibilitySelectOptions := func(labels localization.LabelSet) {
+		compatibilitySelectUpdating = true
+		compatibilitySettingToLabel = map[compatibilityModeSetting]string{
+			compatibilityModeAuto:     labels.CompatibilityModeAuto,
+			compatibilityModeForceOn:  labels.CompatibilityModeOn,
+			compatibilityModeForceOff: labels.CompatibilityModeOff,
+		}
+		compatibilityLabelToSetting = make(map[string]compatibilityModeSetting, len(compatibilityModeOrder))
+		options := make([]string, 0, len(compatibilityModeOrder))
+		for _, setting := range compatibilityModeOrder {
+			label := compatibilitySettingToLabel[setting]
+			options = append(options, label)
+			compatibilityLabelToSetting[label] = setting
+		}
+		compatibilityModeSelect.Options = options
+		target := currentCompatibilitySetting
+		if _, ok := compatibilitySettingToLabel[target]; !ok {
+			target = compatibilityModeAuto
+			currentCompatibilitySetting = target
+		}
+		if label, ok := compatibilitySettingToLabel[target]; ok {
+			compatibilityModeSelect.SetSelected(label)
+		}
+		compatibilitySelectUpdating = false
+		updateCompatibilityStatus()
+	}
+
+	refreshFallbackSelectOptions := func(labels localization.LabelSet) {
+		fallbackSelectUpdating = true
+		fallbackStrategyToLabel = map[config.FallbackStrategy]string{
+			config.FallbackUnicode:   labels.FallbackUnicode,
+			config.FallbackAltNumpad: labels.FallbackAltNumpad,
+			config.FallbackHexInput:  labels.FallbackHexInput,
+		}
+		fallbackLabelToStrategy = make(map[string]config.FallbackStrategy, len(fallbackStrategyOrder))
+		options := make([]string, 0, len(fallbackStrategyOrder))
+		for _, strategy := range fallbackStrategyOrder {
+			label := fallbackStrategyToLabel[strategy]
+			options = append(options, label)
+			fallbackLabelToStrategy[label] = strategy
+		}
+		fallbackSelect.Options = options
+		fallbackSelectUpdating = false
+		updateCompatibilityStatus()
+	}
+
+	winOptions := []string{}
+	winMap := map[string]windowHandle{}
+
+	var laMu sync.RWMutex
+	lastActiveHandle := windowHandle(0)
+	lastActiveTitle := ""
+	lastActiveText := binding.NewString()
+	updateLastActiveLabel := func() {
+		labels := getCurrentLabelSet()
+		laMu.RLock()
+		title := strings.TrimSpace(lastActiveTitle)
+		laMu.RUnlock()
+		if title == "" {
+			title = labels.LastActiveNone
+		}
+		_ = lastActiveText.Set(fmt.Sprintf(labels.LastActiveFormat, title))
+	}
+	updateLastActiveLabel()
+	lastActiveLabel := widget.NewLabelWithData(lastActiveText)
+
+	windowSelect := widget.NewSelect(winOptions, nil)
+
+	// currentTarget returns the selected window, or the last active one
+	currentTarget := func() windowHandle {
+		selected := windowSelect.Selected
+		if selected == "" {
+			laMu.RLock()
+			defer laMu.RUnlock()
+			return lastActiveHandle
+		}
+		return winMap[selected]
+	}
+
+	updateTypability = func() {
+		labels := getCurrentLabelSet()
+		txt := inputEntry.Text
+		if txt == "" {
+			typabilityLabel.SetText("")
+			typabilityScroll.Hide()
+			return
+		}
+
+		opts := keyplan.Options{
+			Unmappable: planUnmappable(config.GetUnmappablePolicy()),
+			Markup:     config.GetKeyMarkup(),
+		}
+		if hwnd := currentTarget(); hwnd != 0 {
+			opts.Fallback = planFallback(config.GetTargetFallback(fallbackTarget(hwnd)))
+		}
+		report, err := analyzeText(txt, layoutSelect.Selected, opts)
+		if err != nil {
+			typabilityLabel.SetText(fmt.Sprintf(labels.TypabilityErrorFormat, err.Error()))
+			typabilityScroll.Hide()
+			return
+		}
+		if len(report.Problems()) == 0 {
+			typabilityLabel.SetText(labels.TypabilityAllKeys)
+			typabilityScroll.Hide()
+			return
+		}
+		typabilityLabel.SetText(fmt.Sprintf(labels.TypabilityReportFormat,
+			report.Count(keyplan.MethodKey),
+			report.Count(keyplan.MethodDeadKey)+report.Count(keyplan.MethodTransliterated),
+			report.Count(keyplan.MethodFallback),
+			report.Count(keyplan.MethodSkipped)+report.Count(keyplan.MethodUntypable),
+		))
+		// never echo hidden text
+		if masked {
+			typabilityScroll.Hide()
+			return
+		}
+		typabilityText.Segments = typabilitySegments(report)
+		typabilityText.Refresh()
+		typabilityScroll.Show()
+	}
+
+	layoutSelect.OnChanged = func(string) {
+		updateTypability()
+		if refreshTrayMenu != nil {
+			refreshTrayMenu()
+		}
+	}
+
+	updateCompatibilityStatus = func() {
+		labels := getCurrentLabelSet()
+		text := labels.CompatibilityStatusUnknown
+
+		hwnd := currentTarget()
+		fallback := config.FallbackUnicode
+		if hwnd != 0 {
+			fallback = config.GetTargetFallback(fallbackTarget(hwnd))
+		}
+
+		switch currentCompatibilitySetting {
+		case compatibilityModeForceOn:
+			text = fmt.Sprintf(labels.CompatibilityStatusFormat, labels.CompatibilityStatusActive)
+		case compatibilityModeForceOff:
+			text = fmt.Sprintf(labels.CompatibilityStatusFormat, labels.CompatibilityStatusInactive)
+		default:
+			if hwnd == 0 {
+				text = labels.CompatibilityStatusUnknown
+			} else if matchesModifierCompatibilityWindow(hwnd) {
+				text = fmt.Sprintf(labels.CompatibilityStatusFormat, labels.CompatibilityStatusActive)
+			} else {
+				text = fmt.Sprintf(labels.CompatibilityStatusFormat, labels.CompatibilityStatusInactive)
+			}
+		}
+
+		fyne.Do(func() {
+			compatibilityStatusLabel.SetText(text)
+			fallbackSelectUpdating = true
+			fallbackSelect.SetSelected(fallbackStrategyToLabel[fallback])
+			fallbackSelectUpdating = false
+			updateTypability()
+		})
+	}
+
+	fallbackSelect.OnChanged = func(label string) {
+		if fallbackSelectUpdating {
+			return
+		}
+		strategy, ok := fallbackLabelToStrategy[label]
+		hwnd := currentTarget()
+		if !ok || hwnd == 0 {
+			return
+		}
+		if err := config.SetTargetFallback(fallbackTarget(hwnd), strategy); err != nil {
+			dialog.ShowError(err, w)
+		}
+		updateTypability()
+	}
+
+	windowSelect.OnChanged = func(string) {
+		updateCompatibilityStatus()
+	}
+
+	updateCompatibilityStatus()
+
+	compatibilityModeSelect.OnChanged = func(label string) {
+		if compatibilitySelectUpdating {
+			return
+		}
+		setting, ok := compatibilityLabelToSetting[label]
+		if !ok {
+			setting = compatibilityModeAuto
+		}
+		if currentCompatibilitySetting == setting {
+			return
+		}
+		currentCompatibilitySetting = setting
+		updateCompatibilityStatus()
+	}
+
+	clearBtn := widget.NewButton("", func() {
+		windowSelect.Selected = ""
+		windowSelect.Refresh()
+		statusCtrl.Set(statusKeySelectionCleared)
+		updateCompatibilityStatus()
+	})
+
+	refreshWindows := func() {
+		wins := enumWindows(selfExeLower)
+		winOptions = []string{}
+		winMap = map[string]windowHandle{}
+		for _, wi := range wins {
+			short := truncateRunes(wi.Title, 30) // limit to 30 chars in list
+			label := fmt.Sprintf("%s (0x%X)", short, wi.Hwnd)
+			winOptions = append(winOptions, label)
+			winMap[label] = wi.Hwnd
+		}
+		windowSelect.Options = winOptions
+		windowSelect.Refresh()
+		statusCtrl.Set(statusKeyFoundWindows, len(wins))
+		updateCompatibilityStatus()
+	}
+
+	refreshBtn := widget.NewButton("", refreshWindows)
+
+	// Start event-driven watcher of foreground windows
+	err := startForegroundWatcher(selfExeLower, func(hwnd windowHandle, title string) {
+		t := truncateRunes(title, 30)
+
+		laMu.Lock()
+		lastActiveHandle = hwnd
+		lastActiveTitle = t
+		laMu.Unlock()
+
+		updateLastActiveLabel()
+		updateCompatibilityStatus()
+	})
+	if err != nil {
+		statusCtrl.Set(statusKeyWatcherWarning, err.Error())
+	}
+
+	// Ensure cleanup when main exits
+	defer stopForegroundWatcher()
+
+	// --- Typing state / stop handling ---
+	var typingMu sync.Mutex
+	typingStopRequested := false
+
+	setStopRequested := func(v bool) {
+		typingMu.Lock()
+		typingStopRequested = v
+		typingMu.Unlock()
+	}
+
+	shouldStop := func() bool {
+		typingMu.Lock()
+		v := typingStopRequested
+		typingMu.Unlock()
+		return v
+	}
+
+	// focus-change abort flag and checkbox
+	abortOnFocusChange := cfg.AbortOnFocusChange
+	abortFocusCheck := widget.NewCheck("", func(b bool) {
+		abortOnFocusChange = b
+	})
+	abortFocusCheck.SetChecked(cfg.AbortOnFocusChange)
+
+	// always on top flag and checkbox
+	var applyAlwaysOnTop func(bool)
+	alwaysOnTopCheck := widget.NewCheck("", nil)
+	applyAlwaysOnTop = func(topmost bool) {
+		// Find our own window by title and apply always on top setting
+		// We need to do this on the main thread after the window is created
+		go func() {
+			// Small delay to ensure the window title is set
+			time.Sleep(50 * time.Millisecond)
+			hwnd := findWindowByTitle(w.Title())
+			if hwnd != 0 {
+				setWindowAlwaysOnTop(hwnd, topmost)
+			}
+		}()
+	}
+	alwaysOnTopCheck.OnChanged = func(b bool) {
+		applyAlwaysOnTop(b)
+	}
+	alwaysOnTopCheck.SetChecked(cfg.AlwaysOnTop)
+	// Apply initial always on top setting
+	if cfg.AlwaysOnTop {
+		// Set window to always on top on startup
+	}
+
+	var typeBtn *widget.Button
+	var typeClipboardBtn *widget.Button
+	var sendFileBtn *widget.Button
+	var stopBtn *widget.Button
+	var actionContainer *fyne.Container
+
+	// runbook controls are disabled while typing
+	typingActive := false
+	var updateRunbookView func()
+
+	setTypingUI := func(active bool) {
+		typingActive = active
+		if updateRunbookView != nil {
+			updateRunbookView()
+		}
+		if refreshTrayMenu != nil {
+			refreshTrayMenu()
+		}
+		if actionContainer == nil {
+			return
+		}
+		if active {
+			if stopBtn != nil {
+				actionContainer.Objects = []fyne.CanvasObject{stopBtn}
+				actionContainer.Refresh()
+			}
+		} else {
+			if typeBtn != nil && typeClipboardBtn != nil && sendFileBtn != nil {
+				actionContainer.Objects = []fyne.CanvasObject{typeBtn, typeClipboardBtn, sendFileBtn}
+				actionContainer.Refresh()
+			}
+		}
+	}
+
+	// Stop button (shown while typing)
+	stopBtn = widget.NewButton("", func() {
+		setStopRequested(true)
+		statusCtrl.Set(statusKeyStopping)
+	})
+	stopBtn.Importance = widget.DangerImportance
+
+	// windowSettings returns the typing settings chosen in the window
+	windowSettings := func(markup bool) typingSettings {
+		return typingSettings{
+			layout: layoutSelect.Selected,
+			compat: currentCompatibilitySetting,
+			markup: markup,
+			delay:  getPerCharDelay,
+		}
+	}
+
+	// progress of the current or last typing run, for the automation API
+	progress := newTypingProgress()
+
+	// key held to stop typing, Name "" if off; set by applyHotkeys
+	var stopKey keyplan.Hotkey
+
+	// watchStopHotkey stops the typing run once the stop hotkey has been
+	// held for stopHotkeyHold, until the returned function is called.
+	watchStopHotkey := func() (end func()) {
+		key := stopKey
+		if key.Name == "" {
+			return func() {}
+		}
+		done := make(chan struct{})
+		go func() {
+			tick := time.NewTicker(50 * time.Millisecond)
+			defer tick.Stop()
+			var since time.Time
+			for {
+				select {
+				case <-done:
+					return
+				case <-tick.C:
+				}
+				if !hotkeyDown(key) {
+					since = time.Time{}
+					continue
+				}
+				if since.IsZero() {
+					since = time.Now()
+				} else if time.Since(since) >= stopHotkeyHold {
+					fyne.Do(func() {
+						if typingActive {
+							stopBtn.OnTapped()
+						}
+					})
+					return
+				}
+			}
+		}()
+		return func() { close(done) }
+	}
+
+	// typeIntoTarget types the text returned by getText into target, or
+	// into the selected (or last active) window if target is 0. The text
+	// is read after the window is focused. done, if set, runs on the UI
+	// thread after a complete run, once the typing UI is reset. It reports
+	// whether typing started.
+	typeIntoTarget := func(target windowHandle, getText func() string, ts typingSettings, keys typingStatus, done func()) bool {
+		selected := windowSelect.Selected
+
+		laMu.RLock()
+		curH := lastActiveHandle
+		curTitle := lastActiveTitle
+		laMu.RUnlock()
+
+		var hwnd windowHandle
+		if target != 0 {
+			hwnd = target
+			curTitle = getWindowText(target)
+		} else if selected == "" {
+			hwnd = curH
+		} else {
+			var ok bool
+			hwnd, ok = winMap[selected]
+			if !ok || hwnd == 0 {
+				statusCtrl.Set(statusKeyWindowUnavailable)
+				return false
+			}
+		}
+
+		if hwnd == 0 {
+			statusCtrl.Set(statusKeyNoWindow)
+			return false
+		}
+
+		setForegroundWindow(hwnd)
+		time.Sleep(150 * time.Millisecond)
+
+		txt := getText()
+		if txt == "" {
+			statusCtrl.Set(keys.empty)
+			return false
+		}
+
+		useModifierCompat := resolveModifierCompatibility(hwnd, ts.compat)
+		opts := keyplan.Options{
+			PerCharDelay: ts.delay(txt),
+			Fallback:     planFallback(config.GetTargetFallback(fallbackTarget(hwnd))),
+			Unmappable:   planUnmappable(config.GetUnmappablePolicy()),
+			Markup:       ts.markup,
+		}
+		setStopRequested(false)
+		setTypingUI(true)
+		statusCtrl.Set(keys.start)
+		progress.start(utf8.RuneCountInString(txt))
+		endStopWatch := watchStopHotkey()
+
+		go func(hwnd windowHandle, curTitle string, txt string, opts keyplan.Options, modifierCompat bool) {
+			// stop on user cancel or focus change (if enabled)
+			shouldStopWithFocus := func() bool {
+				if shouldStop() {
+					return true
+				}
+				if abortOnFocusChange {
+					current := getForegroundWindow()
+					if current != 0 && current != hwnd {
+						return true
+					}
+				}
+				return false
+			}
+
+			err := sendText(txt, ts.layout, opts, modifierCompat, func() bool {
+				if shouldStopWithFocus() {
+					return true
+				}
+				progress.step()
+				return false
+			})
+			endStopWatch()
+			canceled := shouldStopWithFocus()
+			progress.finish(canceled, err, keys.failed == statusKeyTypingSecretError)
+
+			title := strings.TrimSpace(getWindowText(hwnd))
+			if title == "" {
+				title = curTitle
+			}
+			title = truncateRunes(title, 30)
+
+			fyne.Do(func() {
+				complete := !canceled && err == nil
+				if canceled {
+					statusCtrl.Set(statusKeyTypingStopped)
+				} else if err != nil {
+					statusCtrl.Set(keys.failed, err.Error())
+				} else {
+					statusCtrl.Set(keys.done, title)
+				}
+				setTypingUI(false)
+				setStopRequested(false)
+				// last, so done may start the next run
+				if complete && done != nil {
+					done()
+				}
+			})
+		}(hwnd, curTitle, txt, opts, useModifierCompat)
+		return true
+	}
+
+	// typeInto types into the selected (or last active) window, see
+	// typeIntoTarget.
+	typeInto := func(getText func() string, ts typingSettings, keys typingStatus, done func()) {
+		typeIntoTarget(0, getText, ts, keys, done)
+	}
+
+	// --- Type Button ---
+	typeBtn = widget.NewButton("", func() {
+		typeInto(func() string { return inputEntry.Text }, windowSettings(config.GetKeyMarkup()), typingStatusText, nil)
+	})
+
+	// --- Type Clipboard Button ---
+	typeClipboardBtn = widget.NewButton("", func() {
+		typeInto(w.Clipboard().Content, windowSettings(false), typingStatusClipboard, nil)
+	})
+
+	// --- Automation API: local tools type through the same session ---
+	automation := &automationHandler{
+		selfExeLower: selfExeLower,
+		progress:     progress,
+		start: func(target windowHandle, text string, override func(*typingSettings)) error {
+			var err error
+			fyne.DoAndWait(func() {
+				if typingActive {
+					err = errAlreadyTyping
+					return
+				}
+				ts := windowSettings(config.GetKeyMarkup())
+				override(&ts)
+				if !typeIntoTarget(target, func() string { return text }, ts, typingStatusText, nil) {
+					err = errNoTarget
+				}
+			})
+			return err
+		},
+		stop: func() {
+			fyne.DoAndWait(func() {
+				if typingActive {
+					stopBtn.OnTapped()
+				}
+			})
+		},
+	}
+	var automationServer *ipc.Server
+	// applyAutomationAPI starts or stops the automation API server
+	applyAutomationAPI := func(on bool) error {
+		if !on {
+			if automationServer != nil {
+				automationServer.Close()
+				automationServer = nil
+			}
+			return nil
+		}
+		if automationServer != nil {
+			return nil
+		}
+		srv, err := ipc.Listen(config.GetAutomationSocketPath(), config.GetAutomationTokenPath(), automation)
+		if err != nil {
+			return err
+		}
+		automationServer = srv
+		return nil
+	}
+	defer applyAutomationAPI(false)
+
+	// --- Global hotkeys: type the clipboard into the focused window ---
+	hotkeyPending := make(chan struct{}, 1)
+	// typeClipboardFocused types the clipboard into the focused window
+	// once the hotkey's modifiers are released. A press while one waits or
+	// while typing is ignored.
+	typeClipboardFocused := func() {
+		select {
+		case hotkeyPending <- struct{}{}:
+		default:
+			return
+		}
+		go func() {
+			defer func() { <-hotkeyPending }()
+			deadline := time.Now().Add(hotkeyReleaseWait)
+			for modifiersDown() {
+				if time.Now().After(deadline) {
+					return
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+			hwnd := getForegroundWindow()
+			fyne.DoAndWait(func() {
+				if typingActive {
+					return
+				}
+				if hwnd == 0 || shouldIgnoreWindow(hwnd, getWindowText(hwnd), selfExeLower) {
+					statusCtrl.Set(statusKeyNoWindow)
+					return
+				}
+				typeIntoTarget(hwnd, w.Clipboard().Content, windowSettings(false), typingStatusClipboard, nil)
+			})
+		}()
+	}
+	var stopHotkeys func()
+	// applyHotkeys registers the hotkeys of cfg in place of those before.
+	// The stop hotkey is not registered, only watched while typing, so the
+	// key keeps working in other programs.
+	applyHotkeys := func(cfg config.Config) error {
+		if stopHotkeys != nil {
+			stopHotkeys()
+			stopHotkeys = nil
+		}
+		var errs []error
+		stopKey = keyplan.Hotkey{}
+		if cfg.HotkeyStop != "" {
+			h, err := parseStopHotkey(cfg.HotkeyStop)
+			if err != nil {
+				errs = append(errs, err)
+			} else {
+				stopKey = h
+			}
+		}
+		if cfg.HotkeyTypeClipboard != "" {
+			h, err := keyplan.ParseHotkey(cfg.HotkeyTypeClipboard)
+			if err != nil {
+				errs = append(errs, err)
+			} else {
+				stop, err := startHotkeys([]keyplan.Hotkey{h}, func(int) { typeClipboardFocused() })
+				if stop != nil {
+					stopHotkeys = stop
+				}
+				if err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+		return errors.Join(errs...)
+	}
+	defer func() {
+		if stopHotkeys != nil {
+			stopHotkeys()
+		}
+	}()
+
+	// --- System tray: quick actions while the window is hidden ---
+	trayApp, hasTray := myApp.(desktop.App)
+	trayMenu := fyne.NewMenu("goclip")
+	trayInstalled := false
+	// typeIntoLastActive types into the last active window, which the tray
+	// menu does not take the focus from
+	typeIntoLastActive := func(getText func() string, ts typingSettings, keys typingStatus) {
+		if typingActive {
+			return
+		}
+		laMu.RLock()
+		hwnd := lastActiveHandle
+		laMu.RUnlock()
+		if hwnd == 0 {
+			statusCtrl.Set(statusKeyNoWindow)
+			return
+		}
+		typeIntoTarget(hwnd, getText, ts, keys, nil)
+	}
+	// refreshTrayMenu rebuilds the tray menu from the window's state
+	refreshTrayMenu = func() {
+		if !trayInstalled {
+			return
+		}
+		labels := getCurrentLabelSet()
+
+		// progress while typing, the status line otherwise
+		statusText := truncateRunes(statusLabel.Text, 60)
+		if p := progress.get(); typingActive && p.Total > 0 {
+			statusText = fmt.Sprintf(labels.TrayProgressFormat, p.Typed, p.Total, p.Typed*100/p.Total)
+		}
+		statusItem := fyne.NewMenuItem(statusText, nil)
+		statusItem.Disabled = true
+
+		typeClipboardItem := fyne.NewMenuItem(labels.TrayTypeClipboard, func() {
+			typeIntoLastActive(w.Clipboard().Content, windowSettings(false), typingStatusClipboard)
+		})
+		typeClipboardItem.Disabled = typingActive
+
+		favouritesMenu := fyne.NewMenu(labels.TrayFavourites)
+		for _, sn := range config.GetSnippets() {
+			favouritesMenu.Items = append(favouritesMenu.Items, fyne.NewMenuItem(sn.Name, func() {
+				typeIntoLastActive(func() string { return sn.Text }, windowSettings(sn.Markup), typingStatusText)
+			}))
+		}
+		if len(favouritesMenu.Items) == 0 {
+			none := fyne.NewMenuItem(labels.TrayNoFavourites, nil)
+			none.Disabled = true
+			favouritesMenu.Items = append(favouritesMenu.Items, none)
+		}
+		favouritesItem := fyne.NewMenuItem(labels.TrayFavourites, nil)
+		favouritesItem.ChildMenu = favouritesMenu
+		favouritesItem.Disabled = typingActive
+
+		stopItem := fyne.NewMenuItem(labels.StopButton, func() {
+			if typingActive {
+				stopBtn.OnTapped()
+			}
+		})
+		stopItem.Disabled = !typingActive
+
+		// choiceMenu offers the options of sel, with the selected one checked
+		choiceMenu := func(label string, sel *widget.Select) *fyne.MenuItem {
+			menu := fyne.NewMenu(label)
+			for _, option := range sel.Options {
+				item := fyne.NewMenuItem(option, func() {
+					sel.SetSelected(option)
+				})
+				item.Checked = option == sel.Selected
+				menu.Items = append(menu.Items, item)
+			}
+			item := fyne.NewMenuItem(label, nil)
+			item.ChildMenu = menu
+			return item
+		}
+
+		quitItem := fyne.NewMenuItem(labels.TrayQuit, nil)
+		quitItem.IsQuit = true
+
+		trayMenu.Items = []*fyne.MenuItem{
+			fyne.NewMenuItem(labels.TrayShowWindow, func() {
+				w.Show()
+				w.RequestFocus()
+			}),
+			fyne.NewMenuItemSeparator(),
+			statusItem,
+			typeClipboardItem,
+			favouritesItem,
+			stopItem,
+			fyne.NewMenuItemSeparator(),
+			choiceMenu(labels.KeyboardLayoutHeading, layoutSelect),
+			choiceMenu(labels.TypingSpeedHeading, speedSelect),
+			fyne.NewMenuItemSeparator(),
+			quitItem,
+		}
+		trayMenu.Refresh()
+	}
+	statusCtrl.onChange = refreshTrayMenu
+	// keep the progress in the menu current while typing
+	progress.onStep = func() { fyne.Do(refreshTrayMenu) }
+	// applyTrayMode shows the tray icon and makes closing the window hide
+	// it there, or makes closing quit again. The icon stays until goclip
+	// quits, as the tray offers no way to remove it.
+	applyTrayMode := func(on bool) {
+		if !hasTray {
+			return
+		}
+		if !on {
+			w.SetCloseIntercept(nil)
+			return
+		}
+		if !trayInstalled {
+			trayInstalled = true
+			trayApp.SetSystemTrayIcon(fyne.NewStaticResource("app.png", embeddedAppPng))
+			refreshTrayMenu()
+			trayApp.SetSystemTrayMenu(trayMenu)
+		}
+		// also hides the window when it is closed
+		trayApp.SetSystemTrayWindow(w)
+	}
+
+	// --- File transfer: a local file typed as base64 in shell commands ---
+	var (
+		xferData []byte
+		xferFile string // local name of the file
+		xferOpts = transfer.Options{Shell: transfer.Bash}
+		xfer     *transfer.Transfer
+		xferNext int // next chunk to type
+	)
+	transferProgress := widget.NewProgressBar()
+	transferProgress.TextFormatter = func() string {
+		if xfer == nil {
+			return ""
+		}
+		return fmt.Sprintf("%d / %d", xferNext, len(xfer.Chunks))
+	}
+	transferProgress.Hide()
+
+	// typeChunk types chunk i and, once it is complete, the ones after it.
+	// A stopped transfer keeps its place in xferNext.
+	var typeChunk func(i int)
+	typeChunk = func(i int) {
+		cur := xfer
+		transferProgress.Show()
+		transferProgress.SetValue(float64(i) / float64(len(cur.Chunks)))
+		typeInto(func() string { return cur.Chunks[i] }, windowSettings(false), typingStatusTransfer, func() {
+			if cur != xfer {
+				return
+			}
+			xferNext = i + 1
+			transferProgress.SetValue(float64(xferNext) / float64(len(cur.Chunks)))
+			if xferNext < len(cur.Chunks) {
+				typeChunk(xferNext)
+				return
+			}
+			statusCtrl.Set(statusKeyTransferDone, xferFile)
+		})
+	}
+
+	showTransferDialog := func() {
+		labels := getCurrentLabelSet()
+		data, file := xferData, xferFile
+
+		fileLabel := widget.NewLabel(labels.TransferNoFile)
+		if file != "" {
+			fileLabel.SetText(file)
+		}
+		shellSelect := widget.NewSelect(nil, nil)
+		for _, sh := range transfer.Shells {
+			shellSelect.Options = append(shellSelect.Options, sh.String())
+		}
+		shellSelect.Selected = xferOpts.Shell.String()
+		gzipCheck := widget.NewCheck(labels.TransferGzipCheck, nil)
+		gzipCheck.SetChecked(xferOpts.Gzip)
+		nameEntry := widget.NewEntry()
+		nameEntry.SetText(xferOpts.Name)
+		startEntry := widget.NewEntry()
+		startEntry.SetText(strconv.Itoa(xferNext + 1))
+		infoLabel := widget.NewLabel("")
+		infoLabel.Wrapping = fyne.TextWrapWord
+
+		options := func() transfer.Options {
+			opts := transfer.Options{Name: strings.TrimSpace(nameEntry.Text), Gzip: gzipCheck.Checked}
+			for _, sh := range transfer.Shells {
+				if sh.String() == shellSelect.Selected {
+					opts.Shell = sh
+				}
+			}
+			return opts
+		}
+		updateInfo := func() {
+			opts := options()
+			if opts.Shell == transfer.Cmd {
+				gzipCheck.Disable()
+				opts.Gzip = false
+			} else {
+				gzipCheck.Enable()
+			}
+			if data == nil {
+				infoLabel.SetText("")
+				return
+			}
+			t, err := transfer.New(data, opts)
+			if err != nil {
+				infoLabel.SetText(err.Error())
+				return
+			}
+			infoLabel.SetText(fmt.Sprintf(labels.TransferInfoFormat, t.Size, t.Encoded, len(t.Chunks)))
+		}
+		// another file or other options make a new transfer
+		changed := func() {
+			startEntry.SetText("1")
+			updateInfo()
+		}
+		shellSelect.OnChanged = func(string) { changed() }
+		gzipCheck.OnChanged = func(bool) { changed() }
+		nameEntry.OnChanged = func(string) { changed() }
+		updateInfo()
+
+		chooseBtn := widget.NewButtonWithIcon(labels.TransferChooseButton, theme.FolderOpenIcon(), func() {
+			dialog.NewFileOpen(func(rc fyne.URIReadCloser, err error) {
+				if err != nil {
+					dialog.ShowError(err, w)
+					return
+				}
+				if rc == nil {
+					return
+				}
+				defer rc.Close()
+				b, err := io.ReadAll(rc)
+				if err != nil {
+					dialog.ShowError(err, w)
+					return
+				}
+				data, file = b, rc.URI().Name()
+				fileLabel.SetText(file)
+				nameEntry.SetText(file)
+				changed()
+			}, w).Show()
+		})
+
+		items := []*widget.FormItem{
+			widget.NewFormItem(labels.TransferFileLabel, container.NewBorder(nil, nil, nil, chooseBtn, fileLabel)),
+			widget.NewFormItem(labels.TransferShellLabel, shellSelect),
+			widget.NewFormItem("", gzipCheck),
+			widget.NewFormItem(labels.TransferNameLabel, nameEntry),
+			widget.NewFormItem(labels.TransferStartLabel, startEntry),
+			widget.NewFormItem("", infoLabel),
+		}
+		dialog.ShowForm(labels.TransferTitle, labels.TransferSendButton, labels.SettingsCancelButton, items, func(ok bool) {
+			if !ok {
+				return
+			}
+			if data == nil {
+				dialog.ShowError(errors.New(labels.TransferNoFile), w)
+				return
+			}
+			opts := options()
+			t, err := transfer.New(data, opts)
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			start, err := strconv.Atoi(strings.TrimSpace(startEntry.Text))
+			if err != nil || start < 1 || start > len(t.Chunks) {
+				dialog.ShowError(fmt.Errorf(labels.TransferStartErrorFormat, len(t.Chunks)), w)
+				return
+			}
+			xferData, xferFile, xferOpts, xfer, xferNext = data, file, opts, t, start-1
+			typeChunk(xferNext)
+		}, w)
+	}
+
+	// --- Send File Button ---
+	sendFileBtn = widget.NewButtonWithIcon("", theme.UploadIcon(), showTransferDialog)
+
+	// --- Runbook mode: the text split into steps typed one at a time ---
+	runbookMode := false
+	runbookSplitMode := runbook.ByLine
+	rb := runbook.New(nil)
+	runbookSplitSelect := widget.NewSelect([]string{}, nil)
+	runbookSplitLabelToMode := make(map[string]runbook.SplitMode)
+	runbookSplitModeToLabel := make(map[runbook.SplitMode]string)
+	runbookStepLabel := widget.NewLabel("")
+	runbookListUpdating := false
+	// a procedure file replaces the text as the source of the steps
+	var runbookProc *runbook.Procedure
+	runbookTitleLabel := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	runbookTitleLabel.Wrapping = fyne.TextWrapWord
+	runbookTitleLabel.Hide()
+
+	runbookList := widget.NewList(
+		func() int { return rb.Len() },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			step := rb.Step(i)
+			var text string
+			switch {
+			case step.Name != "":
+				// file steps show their name or unexpanded text, never
+				// the values filled in
+				text = truncateRunes(step.Name, 60)
+			case masked:
+				// never echo hidden text
+				text = strings.Repeat("•", 8)
+			default:
+				lines := strings.Split(step.Text, "\n")
+				text = truncateRunes(strings.TrimSpace(lines[0]), 60)
+				if len(lines) > 1 {
+					text += fmt.Sprintf(" (+%d)", len(lines)-1)
+				}
+			}
+			mark := "   "
+			if rb.Done(i) {
+				mark = "✓ "
+			}
+			label := o.(*widget.Label)
+			label.TextStyle.Bold = i == rb.Pos()
+			label.SetText(fmt.Sprintf("%s%d. %s", mark, i+1, text))
+		},
+	)
+
+	var runbookNextBtn, runbookSkipBtn, runbookRepeatBtn, runbookBackBtn *widget.Button
+
+	updateRunbookView = func() {
+		labels := getCurrentLabelSet()
+		switch {
+		case rb.Len() == 0:
+			runbookStepLabel.SetText(labels.RunbookEmpty)
+		case rb.Pos() >= rb.Len():
+			runbookStepLabel.SetText(fmt.Sprintf(labels.RunbookFinishedFormat, rb.Len()))
+		default:
+			runbookStepLabel.SetText(fmt.Sprintf(labels.RunbookStepFormat, rb.Pos()+1, rb.Len()))
+		}
+
+		runbookListUpdating = true
+		if rb.Pos() < rb.Len() {
+			runbookList.Select(rb.Pos())
+			runbookList.ScrollTo(rb.Pos())
+		} else {
+			runbookList.UnselectAll()
+		}
+		runbookListUpdating = false
+		runbookList.Refresh()
+
+		enable := func(b *widget.Button, on bool) {
+			if on && !typingActive {
+				b.Enable()
+			} else {
+				b.Disable()
+			}
+		}
+		enable(runbookNextBtn, rb.Pos() < rb.Len())
+		enable(runbookSkipBtn, rb.Pos() < rb.Len())
+		enable(runbookRepeatBtn, rb.Last() >= 0)
+		enable(runbookBackBtn, rb.Pos() > 0)
+	}
+
+	rebuildRunbook = func(keepPos bool) {
+		if !runbookMode || runbookProc != nil {
+			return
+		}
+		pos := rb.Pos()
+		rb = runbook.New(runbook.Split(inputEntry.Text, runbookSplitMode))
+		if keepPos {
+			rb.Seek(min(pos, rb.Len()))
+		}
+		updateRunbookView()
+	}
+
+	runbookList.OnSelected = func(id widget.ListItemID) {
+		if runbookListUpdating {
+			return
+		}
+		rb.Seek(id)
+		updateRunbookView()
+	}
+
+	// typeStep types step i followed by Enter and moves past it. Settings
+	// from a procedure file win over the window's.
+	typeStep := func(i int) {
+		cur := rb
+		step := cur.Step(i)
+		ts := windowSettings(config.GetKeyMarkup())
+		if step.Layout != "" {
+			ts.layout = step.Layout
+		}
+		if step.DelayMs > 0 {
+			d := time.Duration(step.DelayMs) * time.Millisecond
+			ts.delay = func(string) time.Duration { return d }
+		} else if step.Speed != "" {
+			ts.delay = func(text string) time.Duration { return speedDelay(speedOptionID(step.Speed), text) }
+		}
+		if step.Compat != "" {
+			ts.compat = compatibilityModeSetting(step.Compat)
+		}
+		if step.Markup != nil {
+			ts.markup = *step.Markup
+		}
+		text := step.Text
+		if !step.NoEnter {
+			text += "\n"
+		}
+		typeInto(func() string { return text }, ts, typingStatusStep, func() {
+			// the steps are rebuilt when the text changes during typing
+			if cur == rb {
+				rb.Typed(i)
+				updateRunbookView()
+			}
+		})
+	}
+
+	runbookNextBtn = widget.NewButtonWithIcon("", theme.MediaPlayIcon(), func() {
+		if rb.Pos() >= rb.Len() {
+			statusCtrl.Set(statusKeyRunbookFinished)
+			return
+		}
+		typeStep(rb.Pos())
+	})
+	runbookNextBtn.Importance = widget.HighImportance
+	runbookSkipBtn = widget.NewButtonWithIcon("", theme.MediaSkipNextIcon(), func() {
+		rb.Skip()
+		updateRunbookView()
+	})
+	runbookRepeatBtn = widget.NewButtonWithIcon("", theme.MediaReplayIcon(), func() {
+		if i := rb.Last(); i >= 0 {
+			typeStep(i)
+		}
+	})
+	runbookBackBtn = widget.NewButtonWithIcon("", theme.MediaSkipPreviousIcon(), func() {
+		rb.Back()
+		updateRunbookView()
+	})
+
+	refreshRunbookSplitOptions := func(labels localization.LabelSet) {
+		runbookSplitModeToLabel = map[runbook.SplitMode]string{
+			runbook.ByLine:  labels.RunbookSplitLines,
+			runbook.ByBlock: labels.RunbookSplitBlocks,
+		}
+		runbookSplitLabelToMode = make(map[string]runbook.SplitMode, len(runbookSplitModeToLabel))
+		options := make([]string, 0, len(runbookSplitModeToLabel))
+		for _, mode := range []runbook.SplitMode{runbook.ByLine, runbook.ByBlock} {
+			label := runbookSplitModeToLabel[mode]
+			options = append(options, label)
+			runbookSplitLabelToMode[label] = mode
+		}
+		runbookSplitSelect.Options = options
+		runbookSplitSelect.Selected = runbookSplitModeToLabel[runbookSplitMode]
+		runbookSplitSelect.Refresh()
+	}
+
+	runbookSplitSelect.OnChanged = func(label string) {
+		mode, ok := runbookSplitLabelToMode[label]
+		if !ok || mode == runbookSplitMode {
+			return
+		}
+		runbookSplitMode = mode
+		rebuildRunbook(false)
+	}
+
+	// startProcedure asks for the values of the procedure's variables and
+	// then replaces the steps with the procedure's
+	var runbookCloseBtn *widget.Button
+	startProcedure := func(p *runbook.Procedure) {
+		labels := getCurrentLabelSet()
+		start := func(values map[string]string) {
+			steps, err := p.Expand(values, config.GetKeyMarkup())
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			runbookProc = p
+			rb = runbook.New(steps)
+			runbookTitleLabel.SetText(p.Title)
+			runbookTitleLabel.Show()
+			runbookSplitSelect.Hide()
+			runbookCloseBtn.Show()
+			updateRunbookView()
+		}
+		if len(p.Vars) == 0 {
+			start(nil)
+			return
+		}
+		entries := make([]*widget.Entry, len(p.Vars))
+		items := make([]*widget.FormItem, len(p.Vars))
+		for i, v := range p.Vars {
+			if v.Secret {
+				entries[i] = widget.NewPasswordEntry()
+			} else {
+				entries[i] = widget.NewEntry()
+			}
+			entries[i].SetText(v.Default)
+			items[i] = widget.NewFormItem(v.Prompt, entries[i])
+		}
+		dialog.ShowForm(p.Title, labels.RunbookStartButton, labels.SettingsCancelButton, items, func(ok bool) {
+			if !ok {
+				return
+			}
+			values := make(map[string]string, len(p.Vars))
+			for i, v := range p.Vars {
+				values[v.Name] = entries[i].Text
+			}
+			start(values)
+		}, w)
+	}
+
+	// openProcedure starts the procedure file name read as data (or
+	// the error of reading it)
+	openProcedure := func(name string, data []byte, err error) {
+		var p *runbook.Procedure
+		if err == nil {
+			p, err = runbook.ParseFile(data)
+		}
+		if err == nil {
+			err = resolveProcedureLayouts(p)
+		}
+		if err != nil {
+			dialog.ShowError(fmt.Errorf(getCurrentLabelSet().RunbookFileErrorFormat, name, err), w)
+			return
+		}
+		if p.Title == "" {
+			p.Title = strings.TrimSuffix(name, filepath.Ext(name))
+		}
+		startProcedure(p)
+	}
+
+	runbookOpenBtn := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
+		fd := dialog.NewFileOpen(func(rc fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			if rc == nil {
+				return
+			}
+			defer rc.Close()
+			data, err := io.ReadAll(rc)
+			openProcedure(rc.URI().Name(), data, err)
+		}, w)
+		fd.SetFilter(storage.NewExtensionFileFilter([]string{".toml"}))
+		fd.Show()
+	})
+	runbookCloseBtn = widget.NewButtonWithIcon("", theme.CancelIcon(), func() {
+		runbookProc = nil
+		runbookTitleLabel.Hide()
+		runbookCloseBtn.Hide()
+		runbookSplitSelect.Show()
+		rebuildRunbook(false)
+	})
+	runbookCloseBtn.Hide()
+
+	runbookPanel := container.NewBorder(
+		container.NewVBox(
+			runbookTitleLabel,
+			container.NewBorder(nil, nil, nil, container.NewHBox(runbookOpenBtn, runbookCloseBtn), runbookSplitSelect),
+			runbookStepLabel,
+		),
+		container.NewGridWithColumns(4, runbookBackBtn, runbookSkipBtn, runbookRepeatBtn, runbookNextBtn),
+		nil,
+		nil,
+		runbookList,
+	)
+
+	// Action container that switches between [Type, Type Clipboard] and [Stop]
+	actionContainer = container.NewHBox(typeBtn, typeClipboardBtn, sendFileBtn)
+
+	// Left side: window selector + buttons
+	targetWindowLabel := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	keyboardLayoutLabel := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	typingSpeedLabel := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	compatibilityModeLabel := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	fallbackLabel := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	textToTypeLabel := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+
+	// {KEY} markup in the text box, saved as soon as it is toggled
+	markupCheck := widget.NewCheck("", nil)
+	markupCheck.SetChecked(cfg.KeyMarkup)
+	markupCheck.OnChanged = func(on bool) {
+		if err := config.SetKeyMarkup(on); err != nil {
+			dialog.ShowError(err, w)
+		}
+		updateTypability()
+	}
+
+	// Version label + languageselector in bottom right
+	versionLabel := widget.NewLabel(Version)
+	versionLabel.TextStyle = fyne.TextStyle{Italic: true}
+	versionLabel.Alignment = fyne.TextAlignTrailing
+	languageHeadingLabel := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+
+	// top/header section
+	// left side: window selector + buttons + last active
+	left := container.NewVBox(
+		targetWindowLabel,
+		container.NewHBox(windowSelect, clearBtn),
+		refreshBtn,
+		lastActiveLabel,
+	)
+	// right side: split into two columns
+	compatibilityHeader := container.NewHBox(
+		compatibilityModeLabel,
+		container.NewCenter(compatibilityHelpContainer),
+	)
+
+	// left column on the right side: layout + speed
+	rightRight := container.NewVBox(
+		keyboardLayoutLabel,
+		layoutSelect,
+		widget.NewSeparator(),
+		typingSpeedLabel,
+		speedSelect,
+		customMsEntry,
+	)
+
+	// right column on the right side: compatibility controls
+	rightLeft := container.NewVBox(
+		compatibilityHeader,
+		compatibilityModeSelect,
+		compatibilityStatusLabel,
+		widget.NewSeparator(),
+		fallbackLabel,
+		fallbackSelect,
+	)
+
+	// combine into a two-column container
+	right := container.NewHBox(
+		rightLeft,
+		rightRight,
+	)
+	// assemble header
+	header := container.NewBorder(nil, nil, left, right, nil)
+
+	// body/center section
+	// center: text to type + input area
+	// in runbook mode the step list sits next to the text
+	inputArea := container.NewStack(inputRow)
+	runbookSplit := container.NewHSplit(inputRow, runbookPanel)
+	runbookSplit.Offset = 0.6
+	runbookCheck := widget.NewCheck("", func(on bool) {
+		runbookMode = on
+		if on {
+			rebuildRunbook(false)
+			inputArea.Objects = []fyne.CanvasObject{runbookSplit}
+		} else {
+			inputArea.Objects = []fyne.CanvasObject{inputRow}
+		}
+		inputArea.Refresh()
+	})
+
+	// --- Favourite snippets: saved texts, also typed from the tray menu ---
+	favouritesSelect := widget.NewSelect(nil, nil)
+	// refreshFavourites lists the saved favourites
+	refreshFavourites := func() {
+		var names []string
+		for _, sn := range config.GetSnippets() {
+			names = append(names, sn.Name)
+		}
+		favouritesSelect.Options = names
+		favouritesSelect.Refresh()
+		if refreshTrayMenu != nil {
+			refreshTrayMenu()
+		}
+	}
+	// picking a favourite loads it into the text box for editing
+	favouritesSelect.OnChanged = func(name string) {
+		for _, sn := range config.GetSnippets() {
+			if sn.Name == name {
+				inputEntry.SetText(sn.Text)
+				markupCheck.SetChecked(sn.Markup)
+				return
+			}
+		}
+	}
+	refreshFavourites()
+
+	favouriteSaveBtn := widget.NewButtonWithIcon("", theme.ContentAddIcon(), func() {
+		labels := getCurrentLabelSet()
+		if inputEntry.Text == "" {
+			dialog.ShowError(errors.New(labels.StatusNothingToType), w)
+			return
+		}
+		name := widget.NewEntry()
+		name.SetText(favouritesSelect.Selected)
+		items := []*widget.FormItem{
+			widget.NewFormItem(labels.FavouriteNameLabel, name),
+		}
+		dialog.ShowForm(labels.FavouriteSaveButton, labels.SettingsSaveButton, labels.SettingsCancelButton, items, func(ok bool) {
+			snippetName := strings.TrimSpace(name.Text)
+			if !ok || snippetName == "" {
+				return
+			}
+			sn := config.Snippet{Name: snippetName, Text: inputEntry.Text, Markup: markupCheck.Checked}
+			if err := config.SetSnippet(sn); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			refreshFavourites()
+			favouritesSelect.SetSelected(snippetName)
+		}, w)
+	})
+	favouriteSaveBtn.Importance = widget.LowImportance
+
+	favouriteDeleteBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+		name := favouritesSelect.Selected
+		if name == "" {
+			return
+		}
+		labels := getCurrentLabelSet()
+		dialog.ShowConfirm(labels.FavouriteDeleteTitle, fmt.Sprintf(labels.FavouriteDeleteConfirmFormat, name), func(ok bool) {
+			if !ok {
+				return
+			}
+			if err := config.DeleteSnippet(name); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			favouritesSelect.ClearSelected()
+			refreshFavourites()
+		}, w)
+	})
+	favouriteDeleteBtn.Importance = widget.LowImportance
+
+	body_center := container.NewBorder(
+		container.NewHBox(textToTypeLabel, markupCheck, runbookCheck, favouritesSelect, favouriteSaveBtn, favouriteDeleteBtn),
+		container.NewVBox(typabilityLabel, typabilityScroll),
+		nil,
+		nil,
+		inputArea,
+	)
+	// assemble body
+	body := container.NewBorder(
+		nil,
+		nil,
+		nil,
+		nil,
+		body_center,
+	)
+
+	//bottom/footer section
+	//bottom left: delay label + checkbox + action buttons + status
+	bottom_left := container.NewVBox(
+		delayLabel,
+		actionContainer,
+		transferProgress,
+		statusLabel,
+	)
+
+	// --- Secret vault: credentials typed without being shown ---
+	secretVault := vault.New(config.GetVaultPath())
+	var vaultWindow fyne.Window
+	var refreshVaultWindow func()
+	vaultAutoLocked := func() {
+		fyne.Do(func() {
+			if refreshVaultWindow != nil {
+				refreshVaultWindow()
+			}
+		})
+	}
+	secretVault.SetAutoLock(config.GetVaultAutoLock(), vaultAutoLocked)
+
+	// typeSecret types a secret into the target. The value is read only
+	// once the target has the focus and never reaches a widget.
+	typeSecret := func(name string) {
+		typeInto(func() string {
+			value, err := secretVault.Get(name)
+			if err != nil {
+				return ""
+			}
+			return value
+		}, windowSettings(false), typingStatusSecret, nil)
+	}
+
+	// typeTOTP types the current code of a TOTP seed from the vault. If the
+	// code expires before it is typed and confirmed on the target, it waits
+	// for the next one; Stop cancels the wait.
+	typeTOTP := func(name string) error {
+		seed, err := secretVault.Get(name)
+		if err != nil {
+			return err
+		}
+		key, err := totp.Parse(seed)
+		if err != nil {
+			return err
+		}
+		code := strings.Repeat("0", key.Digits)
+		need := totpConfirmTime + time.Duration(key.Digits)*getPerCharDelay(code)
+		typeCode := func() {
+			typeInto(func() string { return key.Code(time.Now()) }, windowSettings(false), typingStatusTOTP, nil)
+		}
+		wait := key.Wait(time.Now(), need)
+		if wait == 0 {
+			typeCode()
+			return nil
+		}
+		setStopRequested(false)
+		setTypingUI(true)
+		statusCtrl.Set(statusKeyWaitingTOTP, int((wait+time.Second-1)/time.Second))
+		time.AfterFunc(wait, func() {
+			fyne.Do(func() {
+				stopped := shouldStop()
+				setTypingUI(false)
+				setStopRequested(false)
+				if stopped {
+					statusCtrl.Set(statusKeyTypingStopped)
+					return
+				}
+				typeCode()
+			})
+		})
+		return nil
+	}
+
+	showVaultWindow := func() {
+		if vaultWindow != nil {
+			vaultWindow.RequestFocus()
+			return
+		}
+		labels := getCurrentLabelSet()
+		win := myApp.NewWindow(labels.VaultTitle)
+		win.Resize(fyne.NewSize(420, 400))
+		vaultWindow = win
+		win.SetOnClosed(func() {
+			vaultWindow = nil
+			refreshVaultWindow = nil
+		})
+
+		refreshVaultWindow = func() {
+			labels := getCurrentLabelSet()
+			heading := func(text string) *widget.Label {
+				l := widget.NewLabel(text)
+				l.Wrapping = fyne.TextWrapWord
+				return l
+			}
+
+			switch {
+			case !secretVault.Exists():
+				pass := widget.NewPasswordEntry()
+				repeat := widget.NewPasswordEntry()
+				createBtn := widget.NewButtonWithIcon(labels.VaultCreateButton, theme.ContentAddIcon(), func() {
+					if pass.Text != repeat.Text {
+						dialog.ShowError(errors.New(labels.VaultPassphraseMismatch), win)
+						return
+					}
+					if err := secretVault.Create(pass.Text); err != nil {
+						dialog.ShowError(err, win)
+						return
+					}
+					refreshVaultWindow()
+				})
+				createBtn.Importance = widget.HighImportance
+				win.SetContent(container.NewVBox(
+					heading(fmt.Sprintf(labels.VaultCreateHintFormat, secretVault.Path())),
+					widget.NewForm(
+						widget.NewFormItem(labels.VaultPassphraseLabel, pass),
+						widget.NewFormItem(labels.VaultRepeatLabel, repeat),
+					),
+					createBtn,
+				))
+
+			case secretVault.Locked():
+				pass := widget.NewPasswordEntry()
+				var unlockBtn *widget.Button
+				unlock := func() {
+					unlockBtn.Disable()
+					// the key derivation takes a moment
+					go func(passphrase string) {
+						err := secretVault.Unlock(passphrase)
+						fyne.Do(func() {
+							if vaultWindow != win {
+								return
+							}
+							if err != nil {
+								unlockBtn.Enable()
+								dialog.ShowError(err, win)
+								return
+							}
+							refreshVaultWindow()
+						})
+					}(pass.Text)
+				}
+				unlockBtn = widget.NewButtonWithIcon(labels.VaultUnlockButton, theme.LoginIcon(), unlock)
+				unlockBtn.Importance = widget.HighImportance
+				pass.OnSubmitted = func(string) { unlock() }
+				win.SetContent(container.NewVBox(
+					heading(labels.VaultLockedHint),
+					widget.NewForm(widget.NewFormItem(labels.VaultPassphraseLabel, pass)),
+					unlockBtn,
+				))
+				win.Canvas().Focus(pass)
+
+			default:
+				names, _ := secretVault.Names()
+				isTOTP := map[string]bool{}
+				for _, name := range names {
+					if value, err := secretVault.Get(name); err == nil && totp.IsURI(value) {
+						isTOTP[name] = true
+					}
+				}
+				selected := ""
+				var typeSecretBtn, typeCodeBtn, deleteBtn *widget.Button
+				list := widget.NewList(
+					func() int { return len(names) },
+					func() fyne.CanvasObject { return widget.NewLabel("") },
+					func(i widget.ListItemID, o fyne.CanvasObject) {
+						text := names[i]
+						if isTOTP[text] {
+							text += " (TOTP)"
+						}
+						o.(*widget.Label).SetText(text)
+					},
+				)
+				list.OnSelected = func(id widget.ListItemID) {
+					selected = names[id]
+					// a seed is never typed itself
+					if isTOTP[selected] {
+						typeSecretBtn.Disable()
+						typeCodeBtn.Enable()
+					} else {
+						typeSecretBtn.Enable()
+						typeCodeBtn.Disable()
+					}
+					deleteBtn.Enable()
+				}
+
+				typeSecretBtn = widget.NewButtonWithIcon(labels.VaultTypeButton, theme.MediaPlayIcon(), func() {
+					if selected != "" {
+						typeSecret(selected)
+					}
+				})
+				typeSecretBtn.Importance = widget.HighImportance
+				typeSecretBtn.Disable()
+
+				typeCodeBtn = widget.NewButtonWithIcon(labels.VaultTypeCodeButton, theme.HistoryIcon(), func() {
+					if selected == "" {
+						return
+					}
+					if err := typeTOTP(selected); err != nil {
+						dialog.ShowError(err, win)
+					}
+				})
+				typeCodeBtn.Importance = widget.HighImportance
+				typeCodeBtn.Disable()
+
+				deleteBtn = widget.NewButtonWithIcon(labels.VaultDeleteButton, theme.DeleteIcon(), func() {
+					name := selected
+					dialog.ShowConfirm(labels.VaultDeleteButton, fmt.Sprintf(labels.VaultDeleteConfirmFormat, name), func(ok bool) {
+						if !ok {
+							return
+						}
+						if err := secretVault.Delete(name); err != nil {
+							dialog.ShowError(err, win)
+						}
+						refreshVaultWindow()
+					}, win)
+				})
+				deleteBtn.Disable()
+
+				addBtn := widget.NewButtonWithIcon(labels.VaultAddButton, theme.ContentAddIcon(), func() {
+					name := widget.NewEntry()
+					name.SetText(selected)
+					value := widget.NewPasswordEntry()
+					totpCheck := widget.NewCheck(labels.VaultTOTPCheck, nil)
+					totpCheck.SetChecked(isTOTP[selected])
+					items := []*widget.FormItem{
+						widget.NewFormItem(labels.VaultNameLabel, name),
+						widget.NewFormItem(labels.VaultSecretLabel, value),
+						widget.NewFormItem("", totpCheck),
+					}
+					dialog.ShowForm(labels.VaultAddTitle, labels.SettingsSaveButton, labels.SettingsCancelButton, items, func(ok bool) {
+						if !ok {
+							return
+						}
+						secretName, secret := strings.TrimSpace(name.Text), value.Text
+						if totpCheck.Checked || totp.IsURI(secret) {
+							// stored as a URI, so the seed is recognised later
+							key, err := totp.Parse(secret)
+							if err != nil {
+								dialog.ShowError(err, win)
+								return
+							}
+							secret = key.URI(secretName)
+						}
+						if err := secretVault.Set(secretName, secret); err != nil {
+							dialog.ShowError(err, win)
+						}
+						refreshVaultWindow()
+					}, win)
+				})
+
+				passphraseBtn := widget.NewButton(labels.VaultChangePassphraseButton, func() {
+					pass := widget.NewPasswordEntry()
+					repeat := widget.NewPasswordEntry()
+					items := []*widget.FormItem{
+						widget.NewFormItem(labels.VaultPassphraseLabel, pass),
+						widget.NewFormItem(labels.VaultRepeatLabel, repeat),
+					}
+					dialog.ShowForm(labels.VaultChangePassphraseButton, labels.SettingsSaveButton, labels.SettingsCancelButton, items, func(ok bool) {
+						if !ok {
+							return
+						}
+						if pass.Text != repeat.Text {
+							dialog.ShowError(errors.New(labels.VaultPassphraseMismatch), win)
+							return
+						}
+						if err := secretVault.ChangePassphrase(pass.Text); err != nil {
+							dialog.ShowError(err, win)
+						}
+						refreshVaultWindow()
+					}, win)
+				})
+
+				lockBtn := widget.NewButtonWithIcon(labels.VaultLockButton, theme.LogoutIcon(), func() {
+					secretVault.Lock()
+					refreshVaultWindow()
+				})
+
+				hint := labels.VaultUnlockedHint
+				if len(names) == 0 {
+					hint = labels.VaultEmpty
+				}
+				win.SetContent(container.NewBorder(
+					heading(hint),
+					container.NewVBox(
+						container.NewGridWithColumns(2, typeSecretBtn, typeCodeBtn),
+						container.NewGridWithColumns(2, addBtn, deleteBtn),
+						container.NewGridWithColumns(2, passphraseBtn, lockBtn),
+					),
+					nil,
+					nil,
+					list,
+				))
+			}
+		}
+		refreshVaultWindow()
+		win.Show()
+	}
+
+	vaultBtn := widget.NewButtonWithIcon("", theme.AccountIcon(), showVaultWindow)
+	vaultBtn.Importance = widget.LowImportance
+
+	// --- KeePass: entries of a KDBX 4 database typed like vault secrets ---
+	var (
+		keepassData    []byte // the database file, kept to reopen it
+		keepassName    string
+		keepassKeyData []byte // the key file, nil if none
+		keepassKeyName string
+		keepassDB      *kdbx.Database // nil while closed
+		keepassTimer   *time.Timer
+		keepassWindow  fyne.Window
+	)
+	var refreshKeePassWindow func()
+	closeKeePass := func() {
+		keepassDB = nil
+		if keepassTimer != nil {
+			keepassTimer.Stop()
+			keepassTimer = nil
+		}
+	}
+	// touchKeePass closes the database once it has not been used for the
+	// vault's auto-lock time.
+	touchKeePass := func() {
+		if keepassTimer != nil {
+			keepassTimer.Stop()
+		}
+		var t *time.Timer
+		t = time.AfterFunc(config.GetVaultAutoLock(), func() {
+			fyne.Do(func() {
+				if keepassTimer != t {
+					return
+				}
+				closeKeePass()
+				if refreshKeePassWindow != nil {
+					refreshKeePassWindow()
+				}
+			})
+		})
+		keepassTimer = t
+	}
+
+	// typeKeePass types a value of entry i. value runs once the target has
+	// the focus; like vault secrets, the text never reaches a widget.
+	typeKeePass := func(i int, markup bool, value func(e kdbx.Entry) string) {
+		db := keepassDB
+		if db == nil {
+			return
+		}
+		touchKeePass()
+		typeInto(func() string {
+			if keepassDB != db {
+				return ""
+			}
+			return value(db.Entries[i])
+		}, windowSettings(markup), typingStatusKeePass, nil)
+	}
+
+	showKeePassWindow := func() {
+		if keepassWindow != nil {
+			keepassWindow.RequestFocus()
+			return
+		}
+		labels := getCurrentLabelSet()
+		win := myApp.NewWindow(labels.KeePassTitle)
+		win.Resize(fyne.NewSize(480, 460))
+		keepassWindow = win
+		win.SetOnClosed(func() {
+			keepassWindow = nil
+			refreshKeePassWindow = nil
+		})
+
+		refreshKeePassWindow = func() {
+			labels := getCurrentLabelSet()
+			heading := func(text string) *widget.Label {
+				l := widget.NewLabel(text)
+				l.Wrapping = fyne.TextWrapWord
+				return l
+			}
+
+			if keepassDB == nil {
+				fileLabel := widget.NewLabel(labels.TransferNoFile)
+				if keepassName != "" {
+					fileLabel.SetText(keepassName)
+				}
+				keyLabel := widget.NewLabel(labels.KeePassNoKeyFile)
+				if keepassKeyData != nil {
+					keyLabel.SetText(keepassKeyName)
+				}
+				// choose reads a file picked by the user
+				choose := func(filter []string, picked func(data []byte, name string)) {
+					fd := dialog.NewFileOpen(func(rc fyne.URIReadCloser, err error) {
+						if err != nil {
+							dialog.ShowError(err, win)
+							return
+						}
+						if rc == nil {
+							return
+						}
+						defer rc.Close()
+						data, err := io.ReadAll(rc)
+						if err != nil {
+							dialog.ShowError(err, win)
+							return
+						}
+						picked(data, rc.URI().Name())
+					}, win)
+					if filter != nil {
+						fd.SetFilter(storage.NewExtensionFileFilter(filter))
+					}
+					fd.Show()
+				}
+				chooseDBBtn := widget.NewButton(labels.TransferChooseButton, func() {
+					choose([]string{".kdbx"}, func(data []byte, name string) {
+						keepassData, keepassName = data, name
+						fileLabel.SetText(name)
+					})
+				})
+				chooseKeyBtn := widget.NewButton(labels.TransferChooseButton, func() {
+					choose(nil, func(data []byte, name string) {
+						keepassKeyData, keepassKeyName = data, name
+						keyLabel.SetText(name)
+					})
+				})
+				clearKeyBtn := widget.NewButtonWithIcon("", theme.CancelIcon(), func() {
+					keepassKeyData, keepassKeyName = nil, ""
+					keyLabel.SetText(labels.KeePassNoKeyFile)
+				})
+
+				pass := widget.NewPasswordEntry()
+				var openBtn *widget.Button
+				open := func() {
+					if keepassData == nil {
+						dialog.ShowError(errors.New(labels.KeePassNoDatabase), win)
+						return
+					}
+					openBtn.Disable()
+					// the key derivation takes a moment
+					go func(data []byte, password string, keyFile []byte) {
+						db, err := kdbx.Open(data, password, keyFile)
+						fyne.Do(func() {
+							if keepassWindow != win {
+								return
+							}
+							if err != nil {
+								openBtn.Enable()
+								dialog.ShowError(err, win)
+								return
+							}
+							keepassDB = db
+							touchKeePass()
+							refreshKeePassWindow()
+						})
+					}(keepassData, pass.Text, keepassKeyData)
+				}
+				openBtn = widget.NewButtonWithIcon(labels.KeePassOpenButton, theme.LoginIcon(), open)
+				openBtn.Importance = widget.HighImportance
+				pass.OnSubmitted = func(string) { open() }
+
+				win.SetContent(container.NewVBox(
+					heading(labels.KeePassClosedHint),
+					widget.NewForm(
+						widget.NewFormItem(labels.KeePassDatabaseLabel, container.NewBorder(nil, nil, nil, chooseDBBtn, fileLabel)),
+						widget.NewFormItem(labels.KeePassKeyFileLabel, container.NewBorder(nil, nil, nil, container.NewHBox(chooseKeyBtn, clearKeyBtn), keyLabel)),
+						widget.NewFormItem(labels.KeePassPasswordLabel, pass),
+					),
+					openBtn,
+				))
+				if keepassData != nil {
+					win.Canvas().Focus(pass)
+				}
+				return
+			}
+
+			db := keepassDB
+			shown := db.Search("")
+			selected := -1
+			var typeUserBtn, typePassBtn, autoTypeBtn *widget.Button
+			list := widget.NewList(
+				func() int { return len(shown) },
+				func() fyne.CanvasObject { return widget.NewLabel("") },
+				func(i widget.ListItemID, o fyne.CanvasObject) {
+					e := db.Entries[shown[i]]
+					text := e.Title
+					if e.UserName != "" {
+						text += " · " + e.UserName
+					}
+					if e.Group != "" {
+						text += " (" + e.Group + ")"
+					}
+					o.(*widget.Label).SetText(text)
+				},
+			)
+			list.OnSelected = func(id widget.ListItemID) {
+				selected = shown[id]
+				typeUserBtn.Enable()
+				typePassBtn.Enable()
+				if db.Entries[selected].AutoType != "" {
+					autoTypeBtn.Enable()
+				} else {
+					autoTypeBtn.Disable()
+				}
+			}
+			unselect := func() {
+				selected = -1
+				typeUserBtn.Disable()
+				typePassBtn.Disable()
+				autoTypeBtn.Disable()
+			}
+
+			search := widget.NewEntry()
+			search.SetPlaceHolder(labels.KeePassSearchPlaceholder)
+			search.OnChanged = func(q string) {
+				shown = db.Search(q)
+				list.UnselectAll()
+				unselect()
+				list.Refresh()
+			}
+
+			typeUserBtn = widget.NewButtonWithIcon(labels.KeePassTypeUserButton, theme.AccountIcon(), func() {
+				if selected >= 0 {
+					typeKeePass(selected, false, func(e kdbx.Entry) string { return e.UserName })
+				}
+			})
+			typePassBtn = widget.NewButtonWithIcon(labels.KeePassTypePasswordButton, theme.MediaPlayIcon(), func() {
+				if selected >= 0 {
+					typeKeePass(selected, false, func(e kdbx.Entry) string { return e.Password })
+				}
+			})
+			autoTypeBtn = widget.NewButtonWithIcon(labels.KeePassAutoTypeButton, theme.MediaPlayIcon(), func() {
+				if selected < 0 {
+					return
+				}
+				e := db.Entries[selected]
+				// check the sequence before the target gets the focus; the
+				// error names the sequence, never a value
+				if _, err := e.AutoTypeMarkup(e.AutoType); err != nil {
+					dialog.ShowError(fmt.Errorf(labels.KeePassAutoTypeErrorFormat, err), win)
+					return
+				}
+				typeKeePass(selected, true, func(e kdbx.Entry) string {
+					text, _ := e.AutoTypeMarkup(e.AutoType)
+					return text
+				})
+			})
+			autoTypeBtn.Importance = widget.HighImportance
+			unselect()
+
+			closeBtn := widget.NewButtonWithIcon(labels.KeePassCloseButton, theme.LogoutIcon(), func() {
+				closeKeePass()
+				refreshKeePassWindow()
+			})
+
+			name := db.Name
+			if name == "" {
+				name = keepassName
+			}
+			hint := fmt.Sprintf(labels.KeePassOpenHintFormat, name, len(db.Entries))
+			win.SetContent(container.NewBorder(
+				container.NewVBox(heading(hint), search),
+				container.NewVBox(
+					container.NewGridWithColumns(3, typeUserBtn, typePassBtn, autoTypeBtn),
+					closeBtn,
+				),
+				nil,
+				nil,
+				list,
+			))
+			win.Canvas().Focus(search)
+		}
+		refreshKeePassWindow()
+		win.Show()
+	}
+
+	keepassBtn := widget.NewButtonWithIcon("", theme.StorageIcon(), showKeePassWindow)
+	keepassBtn.Importance = widget.LowImportance
+
+	// Settings button (gear icon)
+	var settingsBtn *widget.Button
+	showSettingsDialog := func() {
+		labels := getCurrentLabelSet()
+
+		// Create settings dialog
+		settingsWindow := myApp.NewWindow(labels.SettingsTitle)
+		settingsWindow.Resize(fyne.NewSize(600, 500))
+
+		// Copy current config
+		currentCfg := config.Get()
+
+		// Speed option selector
+		settingsSpeedSelect := widget.NewSelect([]string{}, nil)
+		settingsSpeedLabelToID := make(map[string]speedOptionID)
+		settingsSpeedIDToLabel := make(map[speedOptionID]string)
+		settingsCurrentSpeedOption := speedOptionID(currentCfg.DefaultSpeedOption)
+		if settingsCurrentSpeedOption == "" {
+			settingsCurrentSpeedOption = speedOptionDefault
+		}
+
+		// Custom ms entry for settings
+		settingsCustomMsEntry := widget.NewEntry()
+		if currentCfg.CustomSpeedMs > 0 {
+			settingsCustomMsEntry.SetText(strconv.Itoa(currentCfg.CustomSpeedMs))
+		}
+		settingsCustomMsEntry.SetPlaceHolder(labels.SettingsCustomSpeedMs)
+		if settingsCurrentSpeedOption != speedOptionCustom {
+			settingsCustomMsEntry.Hide()
+		}
+
+		settingsSpeedSelect.OnChanged = func(label string) {
+			if id, ok := settingsSpeedLabelToID[label]; ok {
+				settingsCurrentSpeedOption = id
+				if id == speedOptionCustom {
+					settingsCustomMsEntry.Show()
+				} else {
+					settingsCustomMsEntry.Hide()
+				}
+			}
+		}
+
+		// Populate speed options
+		settingsSpeedIDToLabel = map[speedOptionID]string{
+			speedOptionDefault:   labels.SpeedDefault,
+			speedOptionMedium:    labels.SpeedMedium,
+			speedOptionSlow:      labels.SpeedSlow,
+			speedOptionSuperSlow: labels.SpeedSuperSlow,
+			speedOptionCustom:    labels.SpeedCustom,
+		}
+		speedOptions := make([]string, 0, len(speedOptionOrder))
+		for _, id := range speedOptionOrder {
+			label := settingsSpeedIDToLabel[id]
+			speedOptions = append(speedOptions, label)
+			settingsSpeedLabelToID[label] = id
+		}
+		settingsSpeedSelect.Options = speedOptions
+		if label, ok := settingsSpeedIDToLabel[settingsCurrentSpeedOption]; ok {
+			settingsSpeedSelect.SetSelected(label)
+		}
+
+		// Keyboard layout selector
+		settingsLayoutSelect := widget.NewSelect(layoutSelect.Options, nil)
+		settingsLayoutSelect.SetSelected(currentCfg.KeyboardLayout)
+
+		// Compatibility mode selector
+		settingsCompatSelect := widget.NewSelect([]string{}, nil)
+		settingsCompatLabelToSetting := make(map[string]compatibilityModeSetting)
+		settingsCompatSettingToLabel := map[compatibilityModeSetting]string{
+			compatibilityModeAuto:     labels.CompatibilityModeAuto,
+			compatibilityModeForceOn:  labels.CompatibilityModeOn,
+			compatibilityModeForceOff: labels.CompatibilityModeOff,
+		}
+		compatOptions := make([]string, 0, len(compatibilityModeOrder))
+		for _, setting := range compatibilityModeOrder {
+			label := settingsCompatSettingToLabel[setting]
+			compatOptions = append(compatOptions, label)
+			settingsCompatLabelToSetting[label] = setting
+		}
+		settingsCompatSelect.Options = compatOptions
+		settingsCurrentCompatMode := compatibilityModeSetting(currentCfg.CompatibilityMode)
+		if label, ok := settingsCompatSettingToLabel[settingsCurrentCompatMode]; ok {
+			settingsCompatSelect.SetSelected(label)
+		}
+
+		// Policy for characters the layout cannot type
+		settingsUnmappableSelect := widget.NewSelect([]string{}, nil)
+		settingsUnmappableLabelToPolicy := make(map[string]config.UnmappablePolicy)
+		settingsUnmappablePolicyToLabel := map[config.UnmappablePolicy]string{
+			config.UnmappableFallback:      labels.UnmappableFallback,
+			config.UnmappableFail:          labels.UnmappableFail,
+			config.UnmappableSkip:          labels.UnmappableSkip,
+			config.UnmappableTransliterate: labels.UnmappableTransliterate,
+		}
+		unmappableOptions := make([]string, 0, len(unmappablePolicyOrder))
+		for _, policy := range unmappablePolicyOrder {
+			label := settingsUnmappablePolicyToLabel[policy]
+			unmappableOptions = append(unmappableOptions, label)
+			settingsUnmappableLabelToPolicy[label] = policy
+		}
+		settingsUnmappableSelect.Options = unmappableOptions
+		if label, ok := settingsUnmappablePolicyToLabel[currentCfg.UnmappablePolicy]; ok {
+			settingsUnmappableSelect.SetSelected(label)
+		}
+
+		// Abort on focus change checkbox
+		settingsAbortFocusCheck := widget.NewCheck(labels.SettingsAbortFocusLabel, nil)
+		settingsAbortFocusCheck.SetChecked(currentCfg.AbortOnFocusChange)
+
+		// Always on top checkbox
+		settingsAlwaysOnTopCheck := widget.NewCheck(labels.SettingsAlwaysOnTopLabel, nil)
+		settingsAlwaysOnTopCheck.SetChecked(currentCfg.AlwaysOnTop)
+
+		// Vault auto-lock timeout
+		settingsVaultLockEntry := widget.NewEntry()
+		settingsVaultLockEntry.SetText(strconv.Itoa(int(config.GetVaultAutoLock() / time.Minute)))
+
+		// Automation API
+		settingsAutomationCheck := widget.NewCheck(labels.SettingsAutomationCheck, nil)
+		settingsAutomationCheck.SetChecked(currentCfg.AutomationAPI)
+		settingsAutomationHint := widget.NewLabel(fmt.Sprintf(labels.SettingsAutomationHintFormat,
+			config.GetAutomationSocketPath(), config.GetAutomationTokenPath()))
+		settingsAutomationHint.Wrapping = fyne.TextWrapBreak
+
+		settingsTrayCheck := widget.NewCheck(labels.SettingsTrayCheck, nil)
+		settingsTrayCheck.SetChecked(currentCfg.TrayMode)
+
+		// Global hotkeys, empty to turn one off
+		settingsHotkeyTypeEntry := widget.NewEntry()
+		settingsHotkeyTypeEntry.SetPlaceHolder(labels.SettingsHotkeyPlaceholder)
+		settingsHotkeyTypeEntry.SetText(currentCfg.HotkeyTypeClipboard)
+		settingsHotkeyStopEntry := widget.NewEntry()
+		settingsHotkeyStopEntry.SetPlaceHolder(labels.SettingsHotkeyPlaceholder)
+		settingsHotkeyStopEntry.SetText(currentCfg.HotkeyStop)
+
+		// Language selector
+		settingsLanguageSelect := widget.NewSelect(languageSelect.Options, nil)
+		settingsLanguageLabelToCode := make(map[string]string)
+		autoLabel := labels.LanguageAutoOption
+		settingsLanguageLabelToCode[autoLabel] = ""
+		for _, meta := range languageMetas {
+			settingsLanguageLabelToCode[meta.NativeName] = meta.Code
+		}
+
+		selectedLabel := autoLabel
+		if currentCfg.Language != "" {
+			for _, meta := range languageMetas {
+				if meta.Code == currentCfg.Language {
+					selectedLabel = meta.NativeName
+					break
+				}
+			}
+		}
+		settingsLanguageSelect.SetSelected(selectedLabel)
+
+		// Status label for save confirmation
+		settingsStatusLabel := widget.NewLabel("")
+
+		// Save button
+		saveBtn := widget.NewButton(labels.SettingsSaveButton, func() {
+			// Build new config from form, keeping settings the form doesn't show
+			newCfg := config.Get()
+			newCfg.DefaultSpeedOption = config.SpeedOption(settingsCurrentSpeedOption)
+			newCfg.CustomSpeedMs = 0
+			newCfg.KeyboardLayout = settingsLayoutSelect.Selected
+			newCfg.CompatibilityMode = config.CompatibilityMode(settingsCurrentCompatMode)
+			newCfg.AbortOnFocusChange = settingsAbortFocusCheck.Checked
+			newCfg.Language = settingsLanguageLabelToCode[settingsLanguageSelect.Selected]
+			newCfg.AlwaysOnTop = settingsAlwaysOnTopCheck.Checked
+			newCfg.AutomationAPI = settingsAutomationCheck.Checked
+			newCfg.TrayMode = settingsTrayCheck.Checked
+			newCfg.HotkeyTypeClipboard = strings.TrimSpace(settingsHotkeyTypeEntry.Text)
+			newCfg.HotkeyStop = strings.TrimSpace(settingsHotkeyStopEntry.Text)
+			if newCfg.HotkeyTypeClipboard != "" {
+				if _, err := keyplan.ParseHotkey(newCfg.HotkeyTypeClipboard); err != nil {
+					dialog.ShowError(err, settingsWindow)
+					return
+				}
+			}
+			if newCfg.HotkeyStop != "" {
+				if _, err := parseStopHotkey(newCfg.HotkeyStop); err != nil {
+					dialog.ShowError(err, settingsWindow)
+					return
+				}
+			}
+			if policy, ok := settingsUnmappableLabelToPolicy[settingsUnmappableSelect.Selected]; ok {
+				newCfg.UnmappablePolicy = policy
+			}
+			if m, err := strconv.Atoi(strings.TrimSpace(settingsVaultLockEntry.Text)); err == nil && m > 0 && m <= config.MaxVaultAutoLockMinutes {
+				newCfg.VaultAutoLockMinutes = m
+			}
+
+			// Parse custom speed if custom is selected
+			if settingsCurrentSpeedOption == speedOptionCustom {
+				if val := strings.TrimSpace(settingsCustomMsEntry.Text); val != "" {
+					if ms, err := strconv.Atoi(val); err == nil && ms >= 0 && ms <= 10000 {
+						newCfg.CustomSpeedMs = ms
+					}
+				}
+			}
+
+			// Handle compatibility mode selection change
+			if label := settingsCompatSelect.Selected; label != "" {
+				if setting, ok := settingsCompatLabelToSetting[label]; ok {
+					newCfg.CompatibilityMode = config.CompatibilityMode(setting)
+				}
+			}
+
+			// Save config
+			if err := config.SaveConfig(newCfg); err != nil {
+				dialog.ShowError(err, settingsWindow)
+				return
+			}
+
+			// Apply settings to main window
+			currentSpeedOption = speedOptionID(newCfg.DefaultSpeedOption)
+			if currentSpeedOption == speedOptionCustom && newCfg.CustomSpeedMs > 0 {
+				customMsEntry.SetText(strconv.Itoa(newCfg.CustomSpeedMs))
+				customMsEntry.Show()
+			} else if currentSpeedOption != speedOptionCustom {
+				customMsEntry.Hide()
+			}
+			if label, ok := speedIDToLabel[currentSpeedOption]; ok {
+				speedSelect.SetSelected(label)
+			}
+
+			layoutSelect.SetSelected(newCfg.KeyboardLayout)
+
+			currentCompatibilitySetting = compatibilityModeSetting(newCfg.CompatibilityMode)
+			if label, ok := compatibilitySettingToLabel[currentCompatibilitySetting]; ok {
+				compatibilityModeSelect.SetSelected(label)
+			}
+
+			abortOnFocusChange = newCfg.AbortOnFocusChange
+			abortFocusCheck.SetChecked(newCfg.AbortOnFocusChange)
+			secretVault.SetAutoLock(config.GetVaultAutoLock(), vaultAutoLocked)
+			if err := applyAutomationAPI(newCfg.AutomationAPI); err != nil {
+				dialog.ShowError(err, w)
+			}
+			if err := applyHotkeys(newCfg); err != nil {
+				dialog.ShowError(err, w)
+			}
+			applyTrayMode(newCfg.TrayMode)
+
+			// Apply always on top setting
+			alwaysOnTopCheck.SetChecked(newCfg.AlwaysOnTop)
+			applyAlwaysOnTop(newCfg.AlwaysOnTop)
+
+			// Apply language change
+			selectedLanguageCode = newCfg.Language
+			applyLanguageSelection()
+
+			updateDelayLabel()
+			updateCompatibilityStatus()
+
+			settingsStatusLabel.SetText(labels.SettingsSavedStatus)
+			settingsStatusLabel.Refresh()
+
+			// Close the settings window after saving
+			settingsWindow.Close()
+		})
+		saveBtn.Importance = widget.HighImportance
+
+		// Cancel button
+		cancelBtn := widget.NewButton(labels.SettingsCancelButton, func() {
+			settingsWindow.Close()
+		})
+
+		// Reset button
+		resetBtn := widget.NewButton(labels.SettingsResetButton, func() {
+			dialog.ShowConfirm(
+				labels.SettingsResetConfirmTitle,
+				labels.SettingsResetConfirmMessage,
+				func(confirmed bool) {
+					if confirmed {
+						defaultCfg := config.DefaultConfig()
+						if err := config.SaveConfig(defaultCfg); err != nil {
+							dialog.ShowError(err, settingsWindow)
+							return
+						}
+						settingsWindow.Close()
+
+						// Reload and apply defaults
+						_ = config.Load()
+						cfg := config.Get()
+
+						currentSpeedOption = speedOptionID(cfg.DefaultSpeedOption)
+						if label, ok := speedIDToLabel[currentSpeedOption]; ok {
+							speedSelect.SetSelected(label)
+						}
+						customMsEntry.SetText("")
+						customMsEntry.Hide()
+
+						layoutSelect.SetSelected(cfg.KeyboardLayout)
+
+						currentCompatibilitySetting = compatibilityModeSetting(cfg.CompatibilityMode)
+						if label, ok := compatibilitySettingToLabel[currentCompatibilitySetting]; ok {
+							compatibilityModeSelect.SetSelected(label)
+						}
+
+						abortOnFocusChange = cfg.AbortOnFocusChange
+						abortFocusCheck.SetChecked(cfg.AbortOnFocusChange)
+						secretVault.SetAutoLock(config.GetVaultAutoLock(), vaultAutoLocked)
+						if err := applyAutomationAPI(cfg.AutomationAPI); err != nil {
+							dialog.ShowError(err, w)
+						}
+						if err := applyHotkeys(cfg); err != nil {
+							dialog.ShowError(err, w)
+						}
+						applyTrayMode(cfg.TrayMode)
+
+						// Reset always on top
+						alwaysOnTopCheck.SetChecked(cfg.AlwaysOnTop)
+						markupCheck.SetChecked(cfg.KeyMarkup)
+						applyAlwaysOnTop(cfg.AlwaysOnTop)
+
+						selectedLanguageCode = cfg.Language
+						applyLanguageSelection()
+
+						updateDelayLabel()
+						updateCompatibilityStatus()
+					}
+				},
+				settingsWindow,
+			)
+		})
+		resetBtn.Importance = widget.WarningImportance
+
+		// Create settings form
+		settingsContent := container.NewVBox(
+			widget.NewLabelWithStyle(labels.SettingsDefaultSpeedHeading, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			settingsSpeedSelect,
+			settingsCustomMsEntry,
+			widget.NewSeparator(),
+
+			widget.NewLabelWithStyle(labels.SettingsKeyboardLayoutLabel, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			settingsLayoutSelect,
+			widget.NewSeparator(),
+
+			widget.NewLabelWithStyle(labels.SettingsCompatibilityLabel, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			settingsCompatSelect,
+			widget.NewSeparator(),
+
+			widget.NewLabelWithStyle(labels.SettingsUnmappableLabel, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			settingsUnmappableSelect,
+			widget.NewSeparator(),
+
+			settingsAbortFocusCheck,
+			settingsAlwaysOnTopCheck,
+			widget.NewSeparator(),
+
+			widget.NewLabelWithStyle(labels.SettingsVaultAutoLockLabel, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			settingsVaultLockEntry,
+			widget.NewSeparator(),
+
+			widget.NewLabelWithStyle(labels.SettingsAutomationLabel, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			settingsAutomationCheck,
+			settingsAutomationHint,
+			widget.NewSeparator(),
+
+			widget.NewLabelWithStyle(labels.SettingsHotkeysLabel, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			widget.NewLabel(labels.SettingsHotkeyTypeClipboardLabel),
+			settingsHotkeyTypeEntry,
+			widget.NewLabel(labels.SettingsHotkeyStopLabel),
+			settingsHotkeyStopEntry,
+			widget.NewSeparator(),
+
+			widget.NewLabelWithStyle(labels.SettingsTrayLabel, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			settingsTrayCheck,
+			widget.NewSeparator(),
+
+			widget.NewLabelWithStyle(labels.SettingsLanguageLabel, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			settingsLanguageSelect,
+			widget.NewSeparator(),
+
+			container.NewHBox(saveBtn, cancelBtn, resetBtn),
+			settingsStatusLabel,
+		)
+
+		scrollContainer := container.NewVScroll(settingsContent)
+		settingsWindow.SetContent(scrollContainer)
+		settingsWindow.Show()
+	}
+
+	settingsBtn = widget.NewButtonWithIcon("", theme.SettingsIcon(), showSettingsDialog)
+	settingsBtn.Importance = widget.LowImportance
+
+	// bottom right: language selector + version + settings button
+	bottom_right := container.NewVBox(
+		abortFocusCheck,
+		alwaysOnTopCheck,
+		languageHeadingLabel,
+		languageSelect,
+		container.NewHBox(keepassBtn, vaultBtn, settingsBtn),
+		versionLabel,
+	)
+	// assemble footer
+	footer := container.NewBorder(
+		nil,
+		nil,
+		bottom_left,
+		bottom_right,
+		nil,
+	)
+
+	content := container.NewBorder(header, footer, nil, nil, body)
+	w.SetContent(content)
+
+	applyLocalization = func(labels localization.LabelSet) {
+		w.SetTitle(labels.AppTitle)
+		inputEntry.SetPlaceHolder(labels.InputPlaceholder)
+		targetWindowLabel.SetText(labels.TargetWindowHeading)
+		keyboardLayoutLabel.SetText(labels.KeyboardLayoutHeading)
+		typingSpeedLabel.SetText(labels.TypingSpeedHeading)
+		compatibilityModeLabel.SetText(labels.CompatibilityModeHeading)
+		fallbackLabel.SetText(labels.FallbackHeading)
+		textToTypeLabel.SetText(labels.TextToTypeHeading)
+		markupCheck.SetText(labels.KeyMarkupCheck)
+		runbookCheck.SetText(labels.RunbookModeCheck)
+		runbookNextBtn.SetText(labels.RunbookNextButton)
+		runbookSkipBtn.SetText(labels.RunbookSkipButton)
+		runbookRepeatBtn.SetText(labels.RunbookRepeatButton)
+		runbookBackBtn.SetText(labels.RunbookBackButton)
+		runbookOpenBtn.SetText(labels.RunbookOpenButton)
+		runbookCloseBtn.SetText(labels.RunbookCloseButton)
+		favouritesSelect.PlaceHolder = labels.FavouritesPlaceholder
+		favouritesSelect.Refresh()
+		favouriteSaveBtn.SetText(labels.FavouriteSaveButton)
+		refreshRunbookSplitOptions(labels)
+		updateRunbookView()
+		languageHeadingLabel.SetText(labels.LanguageHeading)
+		clearBtn.SetText(labels.ClearButton)
+		refreshBtn.SetText(labels.RefreshWindowsButton)
+		typeBtn.SetText(labels.TypeButton)
+		typeClipboardBtn.SetText(labels.TypeClipboardButton)
+		sendFileBtn.SetText(labels.SendFileButton)
+		stopBtn.SetText(labels.StopButton)
+		settingsBtn.SetText(labels.SettingsButton)
+		vaultBtn.SetText(labels.VaultButton)
+		keepassBtn.SetText(labels.KeePassButton)
+		abortFocusCheck.SetText(labels.AbortOnFocusChange)
+		alwaysOnTopCheck.SetText(labels.AlwaysOnTop)
+		customMsEntry.SetPlaceHolder(labels.CustomMsPlaceholder)
+		windowSelect.PlaceHolder = labels.WindowPlaceholder
+		windowSelect.Refresh()
+		refreshSpeedSelectOptions(labels)
+		refreshCompatibilitySelectOptions(labels)
+		refreshFallbackSelectOptions(labels)
+		refreshLanguageSelectOptions(labels)
+		updateLastActiveLabel()
+		updateDelayLabel()
+		statusCtrl.Refresh()
+		updateCompatibilityStatus()
+		refreshTrayMenu()
+	}
+
+	applyLanguageSelection = func() {
+		effectiveCode := selectedLanguageCode
+		if effectiveCode == "" {
+			effectiveCode = systemLanguageCode
+		}
+		effectiveCode = localization.ResolveCode(effectiveCode)
+		labels := localization.Labels(effectiveCode)
+		setCurrentLabelSet(labels)
+		applyLocalization(labels)
+	}
+
+	applyLanguageSelection()
+
+	updateDelayLabel()
+	refreshWindows()
+
+	if err := applyAutomationAPI(cfg.AutomationAPI); err != nil {
+		statusCtrl.Set(statusKeyAutomationError, err.Error())
+	}
+	if err := applyHotkeys(cfg); err != nil {
+		statusCtrl.Set(statusKeyHotkeyWarning, err.Error())
+	}
+	applyTrayMode(cfg.TrayMode)
+
+	// handleLaunch carries out the arguments of this or a later launch
+	handleLaunch := func(r launchRequest) error {
+		switch {
+		case r.typeFile != "":
+			data, err := os.ReadFile(r.typeFile)
+			if err != nil {
+				return err
+			}
+			if typingActive {
+				return errAlreadyTyping
+			}
+			text := strings.ReplaceAll(string(data), "\r\n", "\n")
+			if !typeIntoTarget(0, func() string { return text }, windowSettings(config.GetKeyMarkup()), typingStatusText, nil) {
+				return errors.New("typing did not start: select a target window in goclip")
+			}
+		case r.openFile != "":
+			data, err := os.ReadFile(r.openFile)
+			name := filepath.Base(r.openFile)
+			if strings.EqualFold(filepath.Ext(name), ".toml") {
+				runbookCheck.SetChecked(true)
+				openProcedure(name, data, err)
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			inputEntry.SetText(strings.ReplaceAll(string(data), "\r\n", "\n"))
+		}
+		return nil
+	}
+	if instanceLock != nil {
+		srv, err := instance.Listen(config.GetInstanceSocketPath(), func(req instance.Request) error {
+			r, err := parseLaunchArgs(req.Args, req.Dir)
+			if err != nil {
+				return err
+			}
+			fyne.DoAndWait(func() {
+				err = handleLaunch(r)
+				// bring goclip forward, unless it types into another window
+				if err == nil && r.typeFile == "" {
+					w.Show()
+					w.RequestFocus()
+				}
+			})
+			return err
+		})
+		if err != nil {
+			instanceErr = err
+		} else {
+			defer srv.Close()
+		}
+	}
+	if instanceErr != nil {
+		statusCtrl.Set(statusKeyInstanceWarning, instanceErr.Error())
+	}
+	myApp.Lifecycle().SetOnStarted(func() {
+		if err := handleLaunch(launch); err != nil {
+			statusCtrl.Set(statusKeyLaunchError, err.Error())
+		}
+	})
+
+	// Apply initial always on top setting after window is shown
+	if cfg.AlwaysOnTop {
+		applyAlwaysOnTop(true)
+	}
+
+	w.ShowAndRun()
+}
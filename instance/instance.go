@@ -0,0 +1,160 @@
+// Package instance keeps goclip to one running instance per user. The
+// first instance holds a lock file and listens on a socket next to it; a
+// later launch finds the lock taken and forwards its arguments over the
+// socket instead of starting a second GUI.
+package instance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrRunning is returned by Acquire when another goclip holds the lock.
+var ErrRunning = errors.New("another goclip is running")
+
+// errLocked is returned by lockFile when the file is locked.
+var errLocked = errors.New("locked")
+
+// maxRequestSize limits a forwarded request.
+const maxRequestSize = 1 << 20
+
+// forwardTimeout is how long Forward waits for the running instance,
+// which may still be starting.
+const forwardTimeout = 5 * time.Second
+
+// Lock is held by the running instance until it exits or releases it.
+type Lock struct {
+	f *os.File
+}
+
+// Acquire takes the lock file at path, creating it if needed. If another
+// process holds it, Acquire returns ErrRunning.
+func Acquire(path string) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		if errors.Is(err, errLocked) {
+			return nil, ErrRunning
+		}
+		return nil, err
+	}
+	return &Lock{f: f}, nil
+}
+
+// Release gives up the lock.
+func (l *Lock) Release() error {
+	return l.f.Close()
+}
+
+// Request is what a later launch forwards to the running instance.
+type Request struct {
+	Args []string `json:"args"`
+	Dir  string   `json:"dir"` // working directory of the launch, for relative paths
+}
+
+type response struct {
+	Error string `json:"error,omitempty"`
+}
+
+// Server receives the requests of later launches.
+type Server struct {
+	ln net.Listener
+}
+
+// Listen receives requests on a Unix domain socket at socketPath and
+// passes each to handle; an error handle returns is reported to the
+// launch. Only the holder of the lock may listen, so a socket already at
+// socketPath is left over from an instance that ended and is replaced.
+func Listen(socketPath string, handle func(Request) error) (*Server, error) {
+	if err := os.Remove(socketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	ln, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+	// only the user may connect
+	if err := os.Chmod(socketPath, 0o600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	s := &Server{ln: ln}
+	go s.accept(handle)
+	return s, nil
+}
+
+// Close stops receiving requests and removes the socket.
+func (s *Server) Close() error {
+	return s.ln.Close()
+}
+
+func (s *Server) accept(handle func(Request) error) {
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go serve(c, handle)
+	}
+}
+
+func serve(c net.Conn, handle func(Request) error) {
+	defer c.Close()
+	c.SetReadDeadline(time.Now().Add(forwardTimeout))
+	var req Request
+	var resp response
+	if err := json.NewDecoder(io.LimitReader(c, maxRequestSize)).Decode(&req); err != nil {
+		resp.Error = "bad request: " + err.Error()
+	} else if err := handle(req); err != nil {
+		resp.Error = err.Error()
+	}
+	json.NewEncoder(c).Encode(resp)
+}
+
+// Forward sends args and the working directory to the instance listening
+// on socketPath and returns the error it reports. It retries for a few
+// seconds while the instance is starting.
+func Forward(socketPath string, args []string) error {
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	var c net.Conn
+	deadline := time.Now().Add(forwardTimeout)
+	for {
+		c, err = net.DialTimeout("unix", socketPath, time.Second)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w but does not answer: %v", ErrRunning, err)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	defer c.Close()
+
+	c.SetDeadline(time.Now().Add(forwardTimeout))
+	if err := json.NewEncoder(c).Encode(Request{Args: args, Dir: dir}); err != nil {
+		return err
+	}
+	var resp response
+	if err := json.NewDecoder(c).Decode(&resp); err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package instance
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAcquire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "goclip", "instance.lock")
+	first, err := Acquire(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Acquire(path); !errors.Is(err, ErrRunning) {
+		t.Fatalf("second Acquire while the first holds the lock: got %v, want ErrRunning", err)
+	}
+	if err := first.Release(); err != nil {
+		t.Fatal(err)
+	}
+	second, err := Acquire(path)
+	if err != nil {
+		t.Fatalf("Acquire after Release: %v", err)
+	}
+	second.Release()
+}
+
+// socketDir returns a new directory for sockets, short enough for their
+// path limit of about 100 bytes.
+func socketDir(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "instance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestForward(t *testing.T) {
+	socketPath := filepath.Join(socketDir(t), "s")
+	got := make(chan Request, 2)
+	s, err := Listen(socketPath, func(req Request) error {
+		got <- req
+		if len(req.Args) > 0 && req.Args[0] == "-fail" {
+			return errors.New("unknown layout \"xx\"")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	args := []string{"-layout", "German (DE)", "-text", "hello world"}
+	if err := Forward(socketPath, args); err != nil {
+		t.Fatal(err)
+	}
+	wd, _ := os.Getwd()
+	if req := <-got; !reflect.DeepEqual(req, Request{Args: args, Dir: wd}) {
+		t.Errorf("handler got %+v, want the args and %s", req, wd)
+	}
+
+	// the handler's error comes back to the launch
+	err = Forward(socketPath, []string{"-fail"})
+	if err == nil || err.Error() != `unknown layout "xx"` {
+		t.Errorf("got %v, want the handler's error", err)
+	}
+	<-got
+}
+
+func TestListenReplacesStaleSocket(t *testing.T) {
+	socketPath := filepath.Join(socketDir(t), "s")
+	if err := os.WriteFile(socketPath, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Listen(socketPath, func(Request) error { return nil })
+	if err != nil {
+		t.Fatalf("stale socket: %v", err)
+	}
+	if err := Forward(socketPath, nil); err != nil {
+		t.Errorf("Forward: %v", err)
+	}
+	s.Close()
+	if _, err := os.Lstat(socketPath); !os.IsNotExist(err) {
+		t.Errorf("Close left the socket behind: %v", err)
+	}
+}
+
+func TestForwardWithoutInstance(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for the forward timeout")
+	}
+	err := Forward(filepath.Join(socketDir(t), "s"), nil)
+	if !errors.Is(err, ErrRunning) || !strings.Contains(err.Error(), "does not answer") {
+		t.Errorf("got %v, want ErrRunning that does not answer", err)
+	}
+}
@@ -0,0 +1,324 @@
+// Package ipc is goclip's local automation API: a Unix domain socket that
+// takes one JSON request per line and answers each with one JSON response
+// line. Every request carries a token that is kept in a file only the user
+// can read, so only the user's own tooling can drive goclip.
+package ipc
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Commands of a request.
+const (
+	CommandWindows  = "windows"  // list the windows goclip can type into
+	CommandType     = "type"     // start typing text into a window
+	CommandProgress = "progress" // report the current or last typing run
+	CommandStop     = "stop"     // stop the typing run
+)
+
+// States of a typing run.
+const (
+	StateIdle      = "idle" // nothing typed since goclip started
+	StateTyping    = "typing"
+	StateCompleted = "completed"
+	StateStopped   = "stopped" // by Stop, a stop request or a focus change
+	StateFailed    = "failed"
+)
+
+// MaxRequestSize limits a request line, text included.
+const MaxRequestSize = 4 << 20
+
+var (
+	// ErrBadToken is the error of requests without the right token. The
+	// server closes the connection after answering it.
+	ErrBadToken = errors.New("bad token")
+	// ErrInUse is returned by Listen when another process serves the
+	// socket.
+	ErrInUse = errors.New("the socket is in use by another process")
+)
+
+// Request is one request line.
+type Request struct {
+	Token   string `json:"token"`
+	Command string `json:"command"`
+
+	// Parameters of "type". The target is the window with Handle (from
+	// "windows"), or the one window whose title matches the regular
+	// expression Window and/or whose process is Process; with none of
+	// them, the window selected in goclip or else the last active one.
+	// Unset settings are the ones chosen in the goclip window.
+	Text    string `json:"text,omitempty"`
+	Handle  uint64 `json:"handle,omitempty"`
+	Window  string `json:"window,omitempty"`
+	Process string `json:"process,omitempty"`
+	Layout  string `json:"layout,omitempty"`
+	Speed   string `json:"speed,omitempty"`
+	DelayMs int    `json:"delayMs,omitempty"`
+	Compat  string `json:"compat,omitempty"`
+	Markup  *bool  `json:"markup,omitempty"`
+}
+
+// Response is the answer to one request.
+type Response struct {
+	OK       bool      `json:"ok"`
+	Error    string    `json:"error,omitempty"`
+	Windows  []Window  `json:"windows,omitempty"`
+	Progress *Progress `json:"progress,omitempty"`
+}
+
+// Window is a window goclip can type into.
+type Window struct {
+	Handle  uint64 `json:"handle"`
+	Title   string `json:"title"`
+	Process string `json:"process,omitempty"`
+}
+
+// Progress describes the current or last typing run of goclip, whether it
+// was started by the API or in the window.
+type Progress struct {
+	Run   int    `json:"run"` // counts the runs since goclip started
+	State string `json:"state"`
+	Typed int    `json:"typed"` // characters typed so far
+	Total int    `json:"total"`
+	Error string `json:"error,omitempty"`
+}
+
+// Handler carries out the requests. Its methods are called from the
+// connections' goroutines.
+type Handler interface {
+	Windows() ([]Window, error)
+	// Type starts typing and returns the progress of the new run.
+	Type(req Request) (Progress, error)
+	Progress() (Progress, error)
+	// Stop asks the running typing run to stop.
+	Stop() (Progress, error)
+}
+
+// Server serves the API on a socket until it is closed.
+type Server struct {
+	ln      net.Listener
+	token   string
+	handler Handler
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+}
+
+// Listen serves h on a Unix domain socket at socketPath, accepting the
+// token stored at tokenPath (created by Token if missing). A socket left
+// behind by a process that ended is replaced.
+func Listen(socketPath, tokenPath string, h Handler) (*Server, error) {
+	token, err := Token(tokenPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := removeStale(socketPath); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+	// only the user may connect
+	if err := os.Chmod(socketPath, 0o600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	s := &Server{ln: ln, token: token, handler: h, conns: map[net.Conn]struct{}{}}
+	go s.accept()
+	return s, nil
+}
+
+// removeStale removes the socket at path unless a process answers on it.
+func removeStale(path string) error {
+	if _, err := os.Lstat(path); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if c, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		c.Close()
+		return ErrInUse
+	}
+	return os.Remove(path)
+}
+
+// Close stops the server, closes its connections and removes the socket.
+// It does not wait for requests the handler is still carrying out.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+	return s.ln.Close()
+}
+
+func (s *Server) accept() {
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			c.Close()
+			return
+		}
+		s.conns[c] = struct{}{}
+		s.mu.Unlock()
+		go s.serve(c)
+	}
+}
+
+func (s *Server) serve(c net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		c.Close()
+	}()
+
+	sc := bufio.NewScanner(c)
+	sc.Buffer(make([]byte, 0, 64<<10), MaxRequestSize)
+	enc := json.NewEncoder(c)
+	for sc.Scan() {
+		line := sc.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		var req Request
+		if err := json.Unmarshal(line, &req); err != nil {
+			// the line is skipped; the next one may still be a request
+			if err := enc.Encode(Response{Error: "bad request: " + err.Error()}); err != nil {
+				return
+			}
+			continue
+		}
+		if !s.validToken(req.Token) {
+			enc.Encode(Response{Error: ErrBadToken.Error()})
+			return
+		}
+		if err := enc.Encode(s.handle(req)); err != nil {
+			return
+		}
+	}
+	if errors.Is(sc.Err(), bufio.ErrTooLong) {
+		enc.Encode(Response{Error: fmt.Sprintf("request longer than %d bytes", MaxRequestSize)})
+	}
+}
+
+// validToken reports whether token is the server's. Hashing both first
+// makes the comparison take the same time whatever the token's length.
+func (s *Server) validToken(token string) bool {
+	got, want := sha256.Sum256([]byte(token)), sha256.Sum256([]byte(s.token))
+	return subtle.ConstantTimeCompare(got[:], want[:]) == 1
+}
+
+func (s *Server) handle(req Request) Response {
+	switch req.Command {
+	case CommandWindows:
+		wins, err := s.handler.Windows()
+		if err != nil {
+			return Response{Error: err.Error()}
+		}
+		return Response{OK: true, Windows: wins}
+	case CommandType:
+		p, err := s.handler.Type(req)
+		if err != nil {
+			return Response{Error: err.Error()}
+		}
+		return Response{OK: true, Progress: &p}
+	case CommandProgress:
+		p, err := s.handler.Progress()
+		if err != nil {
+			return Response{Error: err.Error()}
+		}
+		return Response{OK: true, Progress: &p}
+	case CommandStop:
+		p, err := s.handler.Stop()
+		if err != nil {
+			return Response{Error: err.Error()}
+		}
+		return Response{OK: true, Progress: &p}
+	}
+	return Response{Error: fmt.Sprintf("unknown command %q", req.Command)}
+}
+
+// Token returns the token stored at path, creating the file with a new
+// random token, readable only by the user, if it is missing.
+func Token(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if t := strings.TrimSpace(string(data)); t != "" {
+			return t, nil
+		}
+		return "", fmt.Errorf("%s is empty; delete it to get a new token", path)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	t := hex.EncodeToString(b)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", err
+	}
+	// written aside and linked into place, so a goclip starting at the
+	// same time never reads a half-written token
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".token-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(t + "\n"); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Link(tmp.Name(), path); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return Token(path)
+		}
+		return "", err
+	}
+	return t, nil
+}
+
+// Call sends one request to the server at socketPath and returns its
+// response.
+func Call(socketPath string, req Request) (*Response, error) {
+	c, err := net.DialTimeout("unix", socketPath, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	if err := json.NewEncoder(c).Encode(req); err != nil {
+		return nil, err
+	}
+	var resp Response
+	if err := json.NewDecoder(c).Decode(&resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,313 @@
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeHandler answers requests without typing anything. With noSession
+// it has no typing session to report on, like the macOS build.
+type fakeHandler struct {
+	noSession bool
+
+	mu    sync.Mutex
+	typed []string
+}
+
+func (h *fakeHandler) Windows() ([]Window, error) {
+	return []Window{{Handle: 7, Title: "iLO console", Process: "java"}}, nil
+}
+
+func (h *fakeHandler) Type(req Request) (Progress, error) {
+	if req.Text == "" {
+		return Progress{}, errors.New("nothing to type")
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.typed = append(h.typed, req.Text)
+	return Progress{Run: len(h.typed), State: StateTyping, Total: len(req.Text)}, nil
+}
+
+var errNoSession = errors.New("not available")
+
+func (h *fakeHandler) Progress() (Progress, error) {
+	if h.noSession {
+		return Progress{}, errNoSession
+	}
+	return Progress{Run: 1, State: StateCompleted, Typed: 4, Total: 4}, nil
+}
+
+func (h *fakeHandler) Stop() (Progress, error) {
+	if h.noSession {
+		return Progress{}, errNoSession
+	}
+	return Progress{Run: 1, State: StateStopped}, nil
+}
+
+// listen starts a server in a new directory. The directory is short, as
+// socket paths are limited to about 100 bytes.
+func listen(t *testing.T, h Handler) (s *Server, socketPath, token string) {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "ipc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socketPath = filepath.Join(dir, "s")
+	tokenPath := filepath.Join(dir, "token")
+	if s, err = Listen(socketPath, tokenPath, h); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	if token, err = Token(tokenPath); err != nil {
+		t.Fatal(err)
+	}
+	return s, socketPath, token
+}
+
+// session is a connection that sends raw lines.
+type session struct {
+	t  *testing.T
+	c  net.Conn
+	rd *bufio.Reader
+}
+
+func dial(t *testing.T, socketPath string) *session {
+	t.Helper()
+	c, err := net.Dial("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	c.SetDeadline(time.Now().Add(5 * time.Second))
+	return &session{t: t, c: c, rd: bufio.NewReader(c)}
+}
+
+// send writes line and reads the response.
+func (s *session) send(line string) Response {
+	s.t.Helper()
+	if _, err := s.c.Write([]byte(line + "\n")); err != nil {
+		s.t.Fatal(err)
+	}
+	data, err := s.rd.ReadBytes('\n')
+	if err != nil {
+		s.t.Fatalf("no response to %s: %v", line, err)
+	}
+	var resp Response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		s.t.Fatalf("response %q: %v", data, err)
+	}
+	return resp
+}
+
+func (s *session) request(req Request) Response {
+	s.t.Helper()
+	line, _ := json.Marshal(req)
+	return s.send(string(line))
+}
+
+// closed reports whether the server has closed the connection.
+func (s *session) closed() bool {
+	_, err := s.rd.ReadByte()
+	return err != nil
+}
+
+func TestCommands(t *testing.T) {
+	h := &fakeHandler{}
+	_, socketPath, token := listen(t, h)
+	s := dial(t, socketPath)
+
+	resp := s.request(Request{Token: token, Command: CommandWindows})
+	if !resp.OK || len(resp.Windows) != 1 || resp.Windows[0].Handle != 7 {
+		t.Errorf("windows: %+v", resp)
+	}
+	// several requests on one connection
+	resp = s.request(Request{Token: token, Command: CommandType, Text: "root", Window: "iLO"})
+	if !resp.OK || resp.Progress == nil || resp.Progress.State != StateTyping || resp.Progress.Total != 4 {
+		t.Errorf("type: %+v", resp)
+	}
+	resp = s.request(Request{Token: token, Command: CommandType})
+	if resp.OK || resp.Error != "nothing to type" {
+		t.Errorf("type without text: %+v", resp)
+	}
+	resp = s.request(Request{Token: token, Command: CommandProgress})
+	if !resp.OK || resp.Progress == nil || resp.Progress.State != StateCompleted {
+		t.Errorf("progress: %+v", resp)
+	}
+	resp = s.request(Request{Token: token, Command: CommandStop})
+	if !resp.OK || resp.Progress == nil || resp.Progress.State != StateStopped {
+		t.Errorf("stop: %+v", resp)
+	}
+	resp = s.request(Request{Token: token, Command: "reboot"})
+	if resp.OK || !strings.Contains(resp.Error, "unknown command") {
+		t.Errorf("unknown command: %+v", resp)
+	}
+	if len(h.typed) != 1 || h.typed[0] != "root" {
+		t.Errorf("typed %q", h.typed)
+	}
+
+	// Call sends one request on a connection of its own
+	r, err := Call(socketPath, Request{Token: token, Command: CommandProgress})
+	if err != nil || !r.OK {
+		t.Errorf("Call: %+v, %v", r, err)
+	}
+}
+
+func TestCommandErrors(t *testing.T) {
+	_, socketPath, token := listen(t, &fakeHandler{noSession: true})
+	s := dial(t, socketPath)
+	for _, cmd := range []string{CommandProgress, CommandStop} {
+		resp := s.request(Request{Token: token, Command: cmd})
+		if resp.OK || resp.Error != errNoSession.Error() || resp.Progress != nil {
+			t.Errorf("%s: got %+v, want the handler's error", cmd, resp)
+		}
+	}
+}
+
+func TestBadToken(t *testing.T) {
+	h := &fakeHandler{}
+	_, socketPath, token := listen(t, h)
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"missing", ""},
+		{"wrong", strings.Repeat("0", len(token))},
+		{"prefix", token[:len(token)-1]},
+		{"longer", token + "0"},
+		{"upper case", strings.ToUpper(token)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := dial(t, socketPath)
+			resp := s.request(Request{Token: tt.token, Command: CommandType, Text: "rm -rf /"})
+			if resp.OK || resp.Error != ErrBadToken.Error() {
+				t.Errorf("got %+v, want %v", resp, ErrBadToken)
+			}
+			if !s.closed() {
+				t.Error("the connection stays open after a bad token")
+			}
+		})
+	}
+	if len(h.typed) != 0 {
+		t.Errorf("typed %q with a bad token", h.typed)
+	}
+}
+
+func TestValidToken(t *testing.T) {
+	s := &Server{token: "secret"}
+	for token, want := range map[string]bool{
+		"secret": true, "": false, "secre": false, "secret ": false, "Secret": false, "other!": false,
+	} {
+		if got := s.validToken(token); got != want {
+			t.Errorf("validToken(%q) = %v, want %v", token, got, want)
+		}
+	}
+}
+
+func TestMalformedRequest(t *testing.T) {
+	_, socketPath, token := listen(t, &fakeHandler{})
+	s := dial(t, socketPath)
+	for _, line := range []string{`{"token":`, `not json`, `["array"]`} {
+		resp := s.send(line)
+		if resp.OK || !strings.HasPrefix(resp.Error, "bad request: ") {
+			t.Errorf("%s: got %+v, want a bad request error", line, resp)
+		}
+	}
+	// the connection is still usable
+	if resp := s.request(Request{Token: token, Command: CommandProgress}); !resp.OK {
+		t.Errorf("after malformed lines: %+v", resp)
+	}
+}
+
+func TestSocketMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix socket permissions are not used on Windows")
+	}
+	_, socketPath, _ := listen(t, &fakeHandler{})
+	fi, err := os.Stat(socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := fi.Mode().Perm(); mode != 0o600 {
+		t.Errorf("socket mode %v, want 0600", mode)
+	}
+	fi, err = os.Stat(filepath.Join(filepath.Dir(socketPath), "token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := fi.Mode().Perm(); mode&0o077 != 0 {
+		t.Errorf("token mode %v, want only the user to read it", mode)
+	}
+}
+
+func TestStaleSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "ipc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socketPath := filepath.Join(dir, "s")
+	tokenPath := filepath.Join(dir, "token")
+
+	// a process that ended leaves its socket file behind
+	ln, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	ln.Close()
+	if _, err := os.Lstat(socketPath); err != nil {
+		t.Fatal("the stale socket is gone:", err)
+	}
+	s, err := Listen(socketPath, tokenPath, &fakeHandler{})
+	if err != nil {
+		t.Fatalf("stale socket: %v", err)
+	}
+	token, _ := Token(tokenPath)
+	if r, err := Call(socketPath, Request{Token: token, Command: CommandProgress}); err != nil || !r.OK {
+		t.Errorf("the new server does not answer: %+v, %v", r, err)
+	}
+
+	// a live one is left alone
+	if _, err := Listen(socketPath, tokenPath, &fakeHandler{}); !errors.Is(err, ErrInUse) {
+		t.Errorf("live socket: got %v, want ErrInUse", err)
+	}
+	if r, err := Call(socketPath, Request{Token: token, Command: CommandProgress}); err != nil || !r.OK {
+		t.Errorf("the first server stopped answering: %+v, %v", r, err)
+	}
+
+	s.Close()
+	if _, err := os.Lstat(socketPath); !os.IsNotExist(err) {
+		t.Errorf("Close left the socket behind: %v", err)
+	}
+}
+
+func TestToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dir", "token")
+	first, err := Token(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 64 {
+		t.Errorf("token %q, want 32 random bytes in hex", first)
+	}
+	if again, err := Token(path); err != nil || again != first {
+		t.Errorf("second Token = %q, %v, want the stored %q", again, err, first)
+	}
+	if err := os.WriteFile(path, []byte("\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Token(path); err == nil {
+		t.Error("an empty token file is accepted")
+	}
+}
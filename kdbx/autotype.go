@@ -0,0 +1,211 @@
+package kdbx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// autoTypeKeys maps KeePass key names to the key names of goclip's {KEY}
+// markup.
+var autoTypeKeys = map[string]string{
+	"TAB":       "TAB",
+	"ENTER":     "ENTER",
+	"SPACE":     "SPACE",
+	"ESC":       "ESC",
+	"BACKSPACE": "BACKSPACE",
+	"BS":        "BACKSPACE",
+	"BKSP":      "BACKSPACE",
+	"DEL":       "DELETE",
+	"DELETE":    "DELETE",
+	"INS":       "INSERT",
+	"INSERT":    "INSERT",
+	"HOME":      "HOME",
+	"END":       "END",
+	"PGUP":      "PGUP",
+	"PGDN":      "PGDN",
+	"UP":        "UP",
+	"DOWN":      "DOWN",
+	"LEFT":      "LEFT",
+	"RIGHT":     "RIGHT",
+	"WIN":       "WIN",
+	"LWIN":      "WIN",
+	"RWIN":      "WIN",
+	"APPS":      "MENU",
+}
+
+// autoTypeChars are the KeePass key names that type a character.
+var autoTypeChars = map[string]rune{
+	"ADD": '+', "SUBTRACT": '-', "MULTIPLY": '*', "DIVIDE": '/',
+	"+": '+', "^": '^', "%": '%', "~": '~', "@": '@',
+	"(": '(', ")": ')', "[": '[', "]": ']', "{": '{', "}": '}',
+}
+
+// autoTypeModifiers are the KeePass modifier prefixes.
+var autoTypeModifiers = map[rune]string{'+': "SHIFT", '^': "CTRL", '%': "ALT", '@': "WIN"}
+
+// AutoTypeMarkup turns a KeePass auto-type sequence such as
+// {USERNAME}{TAB}{PASSWORD}{ENTER} into text with goclip's {KEY} markup.
+// Field placeholders ({TITLE}, {USERNAME}, {PASSWORD}, {URL}, {NOTES} and
+// {S:name}) are replaced by the entry's values, key names and the +, ^, %,
+// @ and ~ shortcuts by markup keys, and {DELAY n} by {SLEEP n}. Features
+// that need KeePass itself, such as field references, fail with an error.
+func (e Entry) AutoTypeMarkup(seq string) (string, error) {
+	var b strings.Builder
+	rs := []rune(seq)
+	for i := 0; i < len(rs); {
+		var mods []string
+		for i < len(rs) && autoTypeModifiers[rs[i]] != "" {
+			mods = append(mods, autoTypeModifiers[rs[i]])
+			i++
+		}
+		if i == len(rs) {
+			return "", fmt.Errorf("%s without a key at the end of the sequence", strings.Join(mods, "+"))
+		}
+
+		switch r := rs[i]; r {
+		case '(', ')':
+			return "", fmt.Errorf("groups in parentheses are not supported")
+		case '~':
+			i++
+			writeKey(&b, mods, "ENTER", "")
+		case '{':
+			end := i + 1
+			if end+1 < len(rs) && rs[end] == '}' && rs[end+1] == '}' {
+				end++ // {}} types a brace
+			}
+			for end < len(rs) && rs[end] != '}' {
+				end++
+			}
+			if end == len(rs) {
+				return "", fmt.Errorf("%s: missing }", string(rs[i:]))
+			}
+			token := string(rs[i : end+1])
+			i = end + 1
+			if err := e.writePlaceholder(&b, mods, token); err != nil {
+				return "", fmt.Errorf("%s: %w", token, err)
+			}
+		default:
+			i++
+			if len(mods) > 0 {
+				if err := writeChord(&b, mods, r); err != nil {
+					return "", err
+				}
+				continue
+			}
+			b.WriteString(escapeMarkup(string(r)))
+		}
+	}
+	return b.String(), nil
+}
+
+func (e Entry) writePlaceholder(b *strings.Builder, mods []string, token string) error {
+	inner := token[1 : len(token)-1]
+	name, arg, _ := strings.Cut(inner, " ")
+	upper := strings.ToUpper(name)
+
+	if r, ok := autoTypeChars[upper]; ok && arg == "" {
+		if len(mods) > 0 {
+			return writeChord(b, mods, r)
+		}
+		b.WriteString(escapeMarkup(string(r)))
+		return nil
+	}
+
+	value, isField := "", true
+	switch {
+	case upper == "TITLE":
+		value = e.Title
+	case upper == "USERNAME":
+		value = e.UserName
+	case upper == "PASSWORD":
+		value = e.Password
+	case upper == "URL":
+		value = e.URL
+	case upper == "NOTES":
+		value = e.Notes
+	case strings.HasPrefix(upper, "S:"):
+		v, ok := e.Fields[inner[2:]]
+		if !ok {
+			return fmt.Errorf("the entry has no field %q", inner[2:])
+		}
+		value = v
+	default:
+		isField = false
+	}
+	if isField {
+		if len(mods) > 0 {
+			return fmt.Errorf("modifiers cannot be applied to a field")
+		}
+		b.WriteString(escapeMarkup(value))
+		return nil
+	}
+
+	count := ""
+	if arg != "" {
+		n, err := strconv.Atoi(strings.TrimSpace(arg))
+		if err != nil || n < 1 {
+			return fmt.Errorf("bad count %q", arg)
+		}
+		count = strconv.Itoa(n)
+	}
+	if upper == "DELAY" {
+		if count == "" || len(mods) > 0 {
+			return fmt.Errorf("use {DELAY milliseconds}")
+		}
+		fmt.Fprintf(b, "{SLEEP %s}", count)
+		return nil
+	}
+	if key, ok := autoTypeKeys[upper]; ok {
+		writeKey(b, mods, key, count)
+		return nil
+	}
+	if f, ok := strings.CutPrefix(upper, "F"); ok {
+		if n, err := strconv.Atoi(f); err == nil && n >= 1 && n <= 12 {
+			writeKey(b, mods, upper, count)
+			return nil
+		}
+	}
+	if d, ok := strings.CutPrefix(upper, "NUMPAD"); ok && len(d) == 1 && d[0] >= '0' && d[0] <= '9' {
+		if len(mods) > 0 {
+			return writeChord(b, mods, rune(d[0]))
+		}
+		n := 1
+		if count != "" {
+			n, _ = strconv.Atoi(count)
+		}
+		b.WriteString(strings.Repeat(d, n))
+		return nil
+	}
+	return fmt.Errorf("not supported")
+}
+
+func writeKey(b *strings.Builder, mods []string, key, count string) {
+	b.WriteByte('{')
+	for _, m := range mods {
+		b.WriteString(m + "+")
+	}
+	b.WriteString(key)
+	if count != "" {
+		b.WriteString(" " + count)
+	}
+	b.WriteByte('}')
+}
+
+// writeChord writes a character pressed with modifiers, e.g. ^v.
+func writeChord(b *strings.Builder, mods []string, r rune) error {
+	switch r {
+	case ' ':
+		writeKey(b, mods, "SPACE", "")
+		return nil
+	case '{', '}', '+':
+		return fmt.Errorf("%s+%c is not supported", strings.Join(mods, "+"), r)
+	}
+	writeKey(b, mods, string(r), "")
+	return nil
+}
+
+// escapeMarkup doubles the braces of s so the markup types them literally.
+func escapeMarkup(s string) string {
+	return strings.NewReplacer("{", "{{", "}", "}}").Replace(s)
+}
@@ -0,0 +1,220 @@
+package kdbx
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+// Entry is one KeePass entry.
+type Entry struct {
+	Title    string
+	UserName string
+	Password string
+	URL      string
+	Notes    string
+	Group    string            // path of the group, e.g. "Root/Servers"
+	Fields   map[string]string // every string field by name, including the standard ones
+
+	// AutoType is the entry's auto-type sequence, inherited from its
+	// groups if it sets none; empty if auto-type is off.
+	AutoType string
+}
+
+// DefaultAutoType is the sequence of entries and groups that set none.
+const DefaultAutoType = "{USERNAME}{TAB}{PASSWORD}{ENTER}"
+
+// Database is an opened KeePass database.
+type Database struct {
+	Name    string
+	Entries []Entry // in the order of the groups, without the recycle bin
+}
+
+// Search returns the indexes of the entries whose title, user name, URL,
+// notes or group contain every word of query, ignoring case. An empty
+// query matches everything.
+func (db *Database) Search(query string) []int {
+	words := strings.Fields(strings.ToLower(query))
+	var found []int
+	for i, e := range db.Entries {
+		text := strings.ToLower(strings.Join([]string{e.Title, e.UserName, e.URL, e.Notes, e.Group}, "\n"))
+		match := true
+		for _, w := range words {
+			if !strings.Contains(text, w) {
+				match = false
+				break
+			}
+		}
+		if match {
+			found = append(found, i)
+		}
+	}
+	return found
+}
+
+// node is an element of the XML document.
+type node struct {
+	name      string
+	text      string
+	protected bool
+	children  []*node
+}
+
+func (n *node) child(name string) *node {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func (n *node) childText(name string) string {
+	if c := n.child(name); c != nil {
+		return c.text
+	}
+	return ""
+}
+
+// parseXML reads the XML document of the database. Protected values are
+// decrypted while reading, in document order.
+func parseXML(data []byte, stream *protectedStream) (*Database, error) {
+	root, err := readTree(data, stream)
+	if err != nil {
+		return nil, err
+	}
+	if root.name != "KeePassFile" {
+		return nil, errors.New("not a KeePass XML document")
+	}
+	db := &Database{}
+	var recycleBin string
+	if meta := root.child("Meta"); meta != nil {
+		db.Name = meta.childText("DatabaseName")
+		if !strings.EqualFold(meta.childText("RecycleBinEnabled"), "False") {
+			recycleBin = meta.childText("RecycleBinUUID")
+		}
+	}
+	r := root.child("Root")
+	if r == nil {
+		return nil, errors.New("the database has no root group")
+	}
+	for _, g := range r.children {
+		if g.name == "Group" {
+			db.addGroup(g, "", DefaultAutoType, true, recycleBin)
+		}
+	}
+	return db, nil
+}
+
+func readTree(data []byte, stream *protectedStream) (*node, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var (
+		stack []*node
+		root  *node
+	)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &node{name: t.Name.Local}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			} else if root == nil {
+				root = n
+			}
+			stack = append(stack, n)
+			for _, a := range t.Attr {
+				if a.Name.Local == "Protected" && strings.EqualFold(a.Value, "True") {
+					n.protected = true
+				}
+			}
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		case xml.EndElement:
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if n.protected {
+				raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(n.text))
+				if err != nil {
+					return nil, errors.New("bad protected value")
+				}
+				stream.xor(raw, raw)
+				n.text = string(raw)
+			}
+		}
+	}
+	if root == nil {
+		return nil, errors.New("empty XML document")
+	}
+	return root, nil
+}
+
+// addGroup adds the entries of group g and its subgroups. seq is the
+// auto-type sequence inherited from the parent, enabled whether auto-type
+// is on there.
+func (db *Database) addGroup(g *node, parent, seq string, enabled bool, recycleBin string) {
+	if recycleBin != "" && g.childText("UUID") == recycleBin {
+		return
+	}
+	path := g.childText("Name")
+	if parent != "" {
+		path = parent + "/" + path
+	}
+	if s := g.childText("DefaultAutoTypeSequence"); s != "" {
+		seq = s
+	}
+	switch strings.ToLower(g.childText("EnableAutoType")) {
+	case "true":
+		enabled = true
+	case "false":
+		enabled = false
+	}
+
+	for _, c := range g.children {
+		switch c.name {
+		case "Entry":
+			db.Entries = append(db.Entries, readEntry(c, path, seq, enabled))
+		case "Group":
+			db.addGroup(c, path, seq, enabled, recycleBin)
+		}
+	}
+}
+
+func readEntry(n *node, group, seq string, enabled bool) Entry {
+	e := Entry{Group: group, Fields: map[string]string{}}
+	for _, c := range n.children {
+		if c.name == "String" {
+			e.Fields[c.childText("Key")] = c.childText("Value")
+		}
+	}
+	e.Title = e.Fields["Title"]
+	e.UserName = e.Fields["UserName"]
+	e.Password = e.Fields["Password"]
+	e.URL = e.Fields["URL"]
+	e.Notes = e.Fields["Notes"]
+
+	if at := n.child("AutoType"); at != nil {
+		if strings.EqualFold(at.childText("Enabled"), "False") {
+			enabled = false
+		}
+		if s := at.childText("DefaultSequence"); s != "" {
+			seq = s
+		}
+	}
+	if enabled {
+		e.AutoType = seq
+	}
+	return e
+}
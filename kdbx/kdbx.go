@@ -0,0 +1,479 @@
+// Package kdbx reads KeePass databases in the KDBX 4 format. It only reads:
+// a database is opened with its password and/or key file, and its entries
+// can be listed, searched and turned into text to type.
+//
+// Supported are the AES-KDF, Argon2d and Argon2id key derivations, the
+// AES-256 and ChaCha20 ciphers and the Salsa20 and ChaCha20 inner streams
+// that protect passwords inside the file.
+package kdbx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20"
+)
+
+var (
+	// ErrWrongKey is returned when the password or key file does not open
+	// the database (or the file was modified).
+	ErrWrongKey = errors.New("wrong password or key file")
+	// ErrNoKey is returned when neither a password nor a key file is given.
+	ErrNoKey = errors.New("no password or key file")
+)
+
+const (
+	signature1 = 0x9AA2D903
+	signature2 = 0xB54BFB67
+)
+
+// Outer header fields.
+const (
+	hdrEnd         = 0
+	hdrCipherID    = 2
+	hdrCompression = 3
+	hdrMasterSeed  = 4
+	hdrEncryption  = 7
+	hdrKdf         = 11
+)
+
+// Inner header fields.
+const (
+	innerEnd       = 0
+	innerStreamID  = 1
+	innerStreamKey = 2
+)
+
+var (
+	cipherAES256   = uuid("31c1f2e6bf714350be5805216afc5aff")
+	cipherChaCha20 = uuid("d6038a2b8b6f4cb5a524339a31dbb59a")
+
+	kdfAES      = uuid("c9d9f39a628a4460bf740d08c18a4fea")
+	kdfArgon2d  = uuid("ef636ddf8c29444b91f7a9a403e30a0c")
+	kdfArgon2id = uuid("9e298b1956db4773b23dfc3ec6f0a1e6")
+)
+
+// maxArgon2Memory keeps a damaged or hostile file from asking for more
+// memory than a desktop has.
+const maxArgon2Memory = 4 << 30
+
+// Open decrypts a KDBX 4 database. An empty password counts as no password
+// when a key file is given; keyFile is the contents of the key file, or
+// nil.
+func Open(data []byte, password string, keyFile []byte) (*Database, error) {
+	if password == "" && keyFile == nil {
+		return nil, ErrNoKey
+	}
+	h, err := readHeader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	composite, err := compositeKey(password, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	transformed, err := h.kdf.transform(composite)
+	if err != nil {
+		return nil, err
+	}
+	encKey, hmacKey := h.keys(transformed)
+
+	rest := data[len(h.raw):]
+	if len(rest) < 64 {
+		return nil, errors.New("truncated header")
+	}
+	sum := sha256.Sum256(h.raw)
+	if !bytes.Equal(sum[:], rest[:32]) {
+		return nil, errors.New("the header is damaged")
+	}
+	if !hmac.Equal(blockHMAC(hmacKey, ^uint64(0), h.raw), rest[32:64]) {
+		return nil, ErrWrongKey
+	}
+	encrypted, err := readBlocks(rest[64:], hmacKey)
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := h.decrypt(encKey, encrypted)
+	if err != nil {
+		return nil, err
+	}
+	if h.gzip {
+		zr, err := gzip.NewReader(bytes.NewReader(plain))
+		if err != nil {
+			return nil, err
+		}
+		if plain, err = io.ReadAll(zr); err != nil {
+			return nil, err
+		}
+	}
+
+	stream, xmlData, err := readInnerHeader(plain)
+	if err != nil {
+		return nil, err
+	}
+	return parseXML(xmlData, stream)
+}
+
+type header struct {
+	raw        []byte // the header bytes up to the end field
+	cipher     []byte
+	gzip       bool
+	masterSeed []byte
+	iv         []byte
+	kdf        kdfParams
+}
+
+func readHeader(data []byte) (*header, error) {
+	if len(data) < 12 ||
+		binary.LittleEndian.Uint32(data[0:]) != signature1 ||
+		binary.LittleEndian.Uint32(data[4:]) != signature2 {
+		return nil, errors.New("not a KeePass database")
+	}
+	if major := binary.LittleEndian.Uint32(data[8:]) >> 16; major != 4 {
+		return nil, fmt.Errorf("KDBX version %d is not supported; save the database as KDBX 4", major)
+	}
+
+	h := &header{}
+	pos := 12
+	for {
+		if len(data) < pos+5 {
+			return nil, errors.New("truncated header")
+		}
+		id := data[pos]
+		size := int(binary.LittleEndian.Uint32(data[pos+1:]))
+		pos += 5
+		if size < 0 || len(data)-pos < size {
+			return nil, errors.New("truncated header")
+		}
+		value := data[pos : pos+size]
+		pos += size
+
+		switch id {
+		case hdrEnd:
+			h.raw = data[:pos]
+			return h, h.check()
+		case hdrCipherID:
+			h.cipher = value
+		case hdrCompression:
+			if len(value) != 4 {
+				return nil, errors.New("bad compression field")
+			}
+			h.gzip = binary.LittleEndian.Uint32(value) == 1
+		case hdrMasterSeed:
+			h.masterSeed = value
+		case hdrEncryption:
+			h.iv = value
+		case hdrKdf:
+			p, err := readVariantDictionary(value)
+			if err != nil {
+				return nil, fmt.Errorf("KDF parameters: %w", err)
+			}
+			h.kdf = p
+		}
+	}
+}
+
+func (h *header) check() error {
+	switch {
+	case bytes.Equal(h.cipher, cipherAES256):
+		if len(h.iv) != aes.BlockSize {
+			return errors.New("bad AES IV")
+		}
+	case bytes.Equal(h.cipher, cipherChaCha20):
+		if len(h.iv) != chacha20.NonceSize {
+			return errors.New("bad ChaCha20 nonce")
+		}
+	case h.cipher == nil:
+		return errors.New("no cipher in the header")
+	default:
+		return errors.New("unsupported cipher (use AES-256 or ChaCha20)")
+	}
+	if len(h.masterSeed) != 32 {
+		return errors.New("bad master seed")
+	}
+	if h.kdf == nil {
+		return errors.New("no KDF parameters in the header")
+	}
+	return nil
+}
+
+// keys returns the cipher key and the base key of the block HMACs.
+func (h *header) keys(transformed []byte) (encKey, hmacKey []byte) {
+	e := sha256.New()
+	e.Write(h.masterSeed)
+	e.Write(transformed)
+	m := sha512.New()
+	m.Write(h.masterSeed)
+	m.Write(transformed)
+	m.Write([]byte{1})
+	return e.Sum(nil), m.Sum(nil)
+}
+
+func (h *header) decrypt(key, data []byte) ([]byte, error) {
+	if bytes.Equal(h.cipher, cipherChaCha20) {
+		c, err := chacha20.NewUnauthenticatedCipher(key, h.iv)
+		if err != nil {
+			return nil, err
+		}
+		c.XORKeyStream(data, data)
+		return data, nil
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, errors.New("bad ciphertext length")
+	}
+	cipher.NewCBCDecrypter(block, h.iv).CryptBlocks(data, data)
+	pad := int(data[len(data)-1])
+	if pad == 0 || pad > aes.BlockSize {
+		return nil, errors.New("bad padding")
+	}
+	return data[:len(data)-pad], nil
+}
+
+// readBlocks verifies and joins the HMAC blocks that follow the header.
+func readBlocks(data, hmacKey []byte) ([]byte, error) {
+	var out []byte
+	for i := uint64(0); ; i++ {
+		if len(data) < 36 {
+			return nil, errors.New("truncated data")
+		}
+		mac := data[:32]
+		size := int(int32(binary.LittleEndian.Uint32(data[32:])))
+		if size < 0 || len(data)-36 < size {
+			return nil, errors.New("truncated data")
+		}
+		if !hmac.Equal(blockHMAC(hmacKey, i, data[32:36+size]), mac) {
+			return nil, fmt.Errorf("block %d is damaged", i)
+		}
+		if size == 0 {
+			return out, nil
+		}
+		out = append(out, data[36:36+size]...)
+		data = data[36+size:]
+	}
+}
+
+// blockHMAC authenticates block i, which starts with its size.
+func blockHMAC(hmacKey []byte, i uint64, block []byte) []byte {
+	var index [8]byte
+	binary.LittleEndian.PutUint64(index[:], i)
+	k := sha512.New()
+	k.Write(index[:])
+	k.Write(hmacKey)
+	mac := hmac.New(sha256.New, k.Sum(nil))
+	mac.Write(index[:])
+	mac.Write(block)
+	return mac.Sum(nil)
+}
+
+// readInnerHeader returns the protected value stream and the XML document.
+func readInnerHeader(data []byte) (*protectedStream, []byte, error) {
+	var (
+		id  uint32
+		key []byte
+	)
+	for {
+		if len(data) < 5 {
+			return nil, nil, errors.New("truncated inner header")
+		}
+		field := data[0]
+		size := int(binary.LittleEndian.Uint32(data[1:]))
+		data = data[5:]
+		if size < 0 || len(data) < size {
+			return nil, nil, errors.New("truncated inner header")
+		}
+		value := data[:size]
+		data = data[size:]
+
+		switch field {
+		case innerEnd:
+			s, err := newProtectedStream(id, key)
+			return s, data, err
+		case innerStreamID:
+			if len(value) != 4 {
+				return nil, nil, errors.New("bad inner stream ID")
+			}
+			id = binary.LittleEndian.Uint32(value)
+		case innerStreamKey:
+			key = value
+		}
+		// attachments (field 3) are not needed for typing
+	}
+}
+
+// compositeKey combines the password and the key file as KeePass does.
+func compositeKey(password string, keyFile []byte) ([]byte, error) {
+	c := sha256.New()
+	if password != "" {
+		p := sha256.Sum256([]byte(password))
+		c.Write(p[:])
+	}
+	if keyFile != nil {
+		k, err := keyFileKey(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("key file: %w", err)
+		}
+		c.Write(k)
+	}
+	return c.Sum(nil), nil
+}
+
+// kdfParams are the decoded KDF parameters, a KeePass VariantDictionary.
+type kdfParams map[string][]byte
+
+// Value types of a VariantDictionary.
+const (
+	vdEnd       = 0x00
+	vdUInt32    = 0x04
+	vdUInt64    = 0x05
+	vdBool      = 0x08
+	vdInt32     = 0x0C
+	vdInt64     = 0x0D
+	vdString    = 0x18
+	vdByteArray = 0x42
+)
+
+func readVariantDictionary(data []byte) (kdfParams, error) {
+	if len(data) < 2 || data[1] > 1 {
+		return nil, errors.New("unsupported version")
+	}
+	data = data[2:]
+	p := kdfParams{}
+	for {
+		if len(data) < 1 {
+			return nil, errors.New("truncated")
+		}
+		typ := data[0]
+		if typ == vdEnd {
+			return p, nil
+		}
+		if len(data) < 5 {
+			return nil, errors.New("truncated")
+		}
+		n := int(int32(binary.LittleEndian.Uint32(data[1:])))
+		data = data[5:]
+		if n < 0 || len(data) < n+4 {
+			return nil, errors.New("truncated")
+		}
+		name := string(data[:n])
+		data = data[n:]
+		m := int(int32(binary.LittleEndian.Uint32(data)))
+		data = data[4:]
+		if m < 0 || len(data) < m {
+			return nil, errors.New("truncated")
+		}
+		switch typ {
+		case vdUInt32, vdInt32:
+			if m != 4 {
+				return nil, fmt.Errorf("%s: bad size", name)
+			}
+		case vdUInt64, vdInt64:
+			if m != 8 {
+				return nil, fmt.Errorf("%s: bad size", name)
+			}
+		case vdBool, vdString, vdByteArray:
+		default:
+			return nil, fmt.Errorf("%s: unknown type %#x", name, typ)
+		}
+		p[name] = data[:m]
+		data = data[m:]
+	}
+}
+
+func (p kdfParams) uint(name string) (uint64, error) {
+	v, ok := p[name]
+	switch {
+	case !ok:
+		return 0, fmt.Errorf("KDF parameter %s is missing", name)
+	case len(v) == 4:
+		return uint64(binary.LittleEndian.Uint32(v)), nil
+	case len(v) == 8:
+		return binary.LittleEndian.Uint64(v), nil
+	}
+	return 0, fmt.Errorf("KDF parameter %s is not a number", name)
+}
+
+// transform derives the transformed key from the composite key.
+func (p kdfParams) transform(composite []byte) ([]byte, error) {
+	id := p["$UUID"]
+	salt := p["S"]
+	switch {
+	case bytes.Equal(id, kdfAES):
+		rounds, err := p.uint("R")
+		if err != nil {
+			return nil, err
+		}
+		block, err := aes.NewCipher(salt)
+		if err != nil {
+			return nil, fmt.Errorf("AES-KDF seed: %w", err)
+		}
+		key := bytes.Clone(composite)
+		for ; rounds > 0; rounds-- {
+			block.Encrypt(key[0:16], key[0:16])
+			block.Encrypt(key[16:32], key[16:32])
+		}
+		sum := sha256.Sum256(key)
+		return sum[:], nil
+
+	case bytes.Equal(id, kdfArgon2d), bytes.Equal(id, kdfArgon2id):
+		iterations, err := p.uint("I")
+		if err != nil {
+			return nil, err
+		}
+		memory, err := p.uint("M")
+		if err != nil {
+			return nil, err
+		}
+		parallelism, err := p.uint("P")
+		if err != nil {
+			return nil, err
+		}
+		version, err := p.uint("V")
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case version != argon2Version:
+			return nil, fmt.Errorf("Argon2 version %#x is not supported", version)
+		case iterations < 1 || iterations > 1<<32-1:
+			return nil, fmt.Errorf("bad Argon2 iterations %d", iterations)
+		case memory < 8<<10 || memory > maxArgon2Memory:
+			return nil, fmt.Errorf("Argon2 memory of %d bytes is not supported", memory)
+		case parallelism < 1 || parallelism > 255:
+			return nil, fmt.Errorf("Argon2 parallelism %d is not supported", parallelism)
+		}
+		if bytes.Equal(id, kdfArgon2d) {
+			return argon2dKey(composite, salt, p["K"], p["A"], uint32(iterations), uint32(memory/1024), uint8(parallelism), 32), nil
+		}
+		if len(p["K"]) > 0 || len(p["A"]) > 0 {
+			return nil, errors.New("Argon2id with a secret or associated data is not supported")
+		}
+		return argon2.IDKey(composite, salt, uint32(iterations), uint32(memory/1024), uint8(parallelism), 32), nil
+	}
+	return nil, errors.New("unsupported key derivation (use AES-KDF or Argon2)")
+}
+
+func uuid(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
@@ -0,0 +1,290 @@
+package kdbx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/salsa20"
+)
+
+// fixture describes a small KDBX 4 database written by writeKDBX.
+type fixture struct {
+	cipher  []byte // cipherAES256 or cipherChaCha20
+	kdf     []byte // kdfAES, kdfArgon2d or kdfArgon2id
+	stream  uint32 // streamSalsa20 or streamChaCha20
+	gzip    bool
+	keyFile []byte
+}
+
+// fixtureXML is the document of every fixture. The %s are the protected
+// passwords, base64 encoded after the inner stream.
+const fixtureXML = `<?xml version="1.0" encoding="utf-8" standalone="yes"?>
+<KeePassFile>
+	<Meta>
+		<DatabaseName>Fixture</DatabaseName>
+		<RecycleBinEnabled>True</RecycleBinEnabled>
+		<RecycleBinUUID>YmluYmluYmluYmluYmluYg==</RecycleBinUUID>
+	</Meta>
+	<Root>
+		<Group>
+			<UUID>cm9vdHJvb3Ryb290cm9vdA==</UUID>
+			<Name>Root</Name>
+			<Entry>
+				<String><Key>Title</Key><Value>Mail</Value></String>
+				<String><Key>UserName</Key><Value>alice</Value></String>
+				<String><Key>Password</Key><Value Protected="True">%s</Value></String>
+			</Entry>
+			<Group>
+				<UUID>c2VydmVyc3NlcnZlcnNzZQ==</UUID>
+				<Name>Servers</Name>
+				<Entry>
+					<String><Key>Title</Key><Value>db</Value></String>
+					<String><Key>Password</Key><Value Protected="True">%s</Value></String>
+					<AutoType><Enabled>True</Enabled><DefaultSequence>{PASSWORD}{ENTER}</DefaultSequence></AutoType>
+				</Entry>
+			</Group>
+			<Group>
+				<UUID>YmluYmluYmluYmluYmluYg==</UUID>
+				<Name>Recycle Bin</Name>
+				<Entry>
+					<String><Key>Title</Key><Value>old</Value></String>
+					<String><Key>Password</Key><Value Protected="True">%s</Value></String>
+				</Entry>
+			</Group>
+		</Group>
+	</Root>
+</KeePassFile>`
+
+var fixturePasswords = []string{"p@ss{1}", "ünïcode ✓ and a longer password to cross a Salsa20 block", "gone"}
+
+// writeKDBX writes a database as KeePass does, independently of the reader:
+// the inner stream is applied to all protected values as one message, and
+// the payload is split into small HMAC blocks.
+func writeKDBX(t *testing.T, f fixture, password string) []byte {
+	t.Helper()
+	seed := bytes.Repeat([]byte{0x5e}, 32)
+	salt := bytes.Repeat([]byte{0x5a}, 32)
+
+	// KDF parameters, a VariantDictionary
+	var vd bytes.Buffer
+	vd.Write([]byte{0x00, 0x01})
+	item := func(typ byte, name string, value []byte) {
+		vd.WriteByte(typ)
+		binary.Write(&vd, binary.LittleEndian, uint32(len(name)))
+		vd.WriteString(name)
+		binary.Write(&vd, binary.LittleEndian, uint32(len(value)))
+		vd.Write(value)
+	}
+	u32 := func(v uint32) []byte { return binary.LittleEndian.AppendUint32(nil, v) }
+	u64 := func(v uint64) []byte { return binary.LittleEndian.AppendUint64(nil, v) }
+	item(vdByteArray, "$UUID", f.kdf)
+	item(vdByteArray, "S", salt)
+	if bytes.Equal(f.kdf, kdfAES) {
+		item(vdUInt64, "R", u64(1000))
+	} else {
+		item(vdUInt64, "I", u64(2))
+		item(vdUInt64, "M", u64(64<<10))
+		item(vdUInt32, "P", u32(2))
+		item(vdUInt32, "V", u32(argon2Version))
+	}
+	vd.WriteByte(vdEnd)
+
+	iv := bytes.Repeat([]byte{0x1f}, aes.BlockSize)
+	if bytes.Equal(f.cipher, cipherChaCha20) {
+		iv = iv[:chacha20.NonceSize]
+	}
+	var compression uint32
+	if f.gzip {
+		compression = 1
+	}
+
+	var hdr bytes.Buffer
+	binary.Write(&hdr, binary.LittleEndian, []uint32{signature1, signature2, 4 << 16})
+	field := func(id byte, value []byte) {
+		hdr.WriteByte(id)
+		binary.Write(&hdr, binary.LittleEndian, uint32(len(value)))
+		hdr.Write(value)
+	}
+	field(hdrCipherID, f.cipher)
+	field(hdrCompression, u32(compression))
+	field(hdrMasterSeed, seed)
+	field(hdrEncryption, iv)
+	field(hdrKdf, vd.Bytes())
+	field(hdrEnd, []byte("\r\n\r\n"))
+
+	// the keys
+	c := sha256.New()
+	if password != "" {
+		p := sha256.Sum256([]byte(password))
+		c.Write(p[:])
+	}
+	if f.keyFile != nil {
+		c.Write(f.keyFile) // 32 raw bytes
+	}
+	composite := c.Sum(nil)
+	var transformed []byte
+	switch {
+	case bytes.Equal(f.kdf, kdfAES):
+		block, _ := aes.NewCipher(salt)
+		key := bytes.Clone(composite)
+		for i := 0; i < 1000; i++ {
+			block.Encrypt(key[:16], key[:16])
+			block.Encrypt(key[16:], key[16:])
+		}
+		sum := sha256.Sum256(key)
+		transformed = sum[:]
+	case bytes.Equal(f.kdf, kdfArgon2d):
+		transformed = argon2dKey(composite, salt, nil, nil, 2, 64, 2, 32)
+	default:
+		transformed = argon2.IDKey(composite, salt, 2, 64, 2, 32)
+	}
+	encKey := sha256.Sum256(append(bytes.Clone(seed), transformed...))
+	hmacKey := sha512.Sum512(append(append(bytes.Clone(seed), transformed...), 1))
+	mac := func(i uint64, data []byte) []byte {
+		index := u64(i)
+		k := sha512.Sum512(append(index, hmacKey[:]...))
+		m := hmac.New(sha256.New, k[:])
+		m.Write(index)
+		m.Write(data)
+		return m.Sum(nil)
+	}
+
+	// the protected values as one message
+	streamKey := bytes.Repeat([]byte{0x33}, 64)
+	plain := []byte(strings.Join(fixturePasswords, ""))
+	enc := make([]byte, len(plain))
+	switch f.stream {
+	case streamSalsa20:
+		key := sha256.Sum256(streamKey)
+		salsa20.XORKeyStream(enc, plain, salsaNonce[:], &key)
+	case streamChaCha20:
+		h := sha512.Sum512(streamKey)
+		s, _ := chacha20.NewUnauthenticatedCipher(h[:32], h[32:44])
+		s.XORKeyStream(enc, plain)
+	}
+	var values []any
+	for _, p := range fixturePasswords {
+		values = append(values, base64.StdEncoding.EncodeToString(enc[:len(p)]))
+		enc = enc[len(p):]
+	}
+
+	var payload bytes.Buffer
+	payload.WriteByte(innerStreamID)
+	binary.Write(&payload, binary.LittleEndian, uint32(4))
+	binary.Write(&payload, binary.LittleEndian, f.stream)
+	payload.WriteByte(innerStreamKey)
+	binary.Write(&payload, binary.LittleEndian, uint32(len(streamKey)))
+	payload.Write(streamKey)
+	payload.Write([]byte{innerEnd, 0, 0, 0, 0})
+	fmt.Fprintf(&payload, fixtureXML, values...)
+
+	data := payload.Bytes()
+	if f.gzip {
+		var z bytes.Buffer
+		zw := gzip.NewWriter(&z)
+		zw.Write(data)
+		zw.Close()
+		data = z.Bytes()
+	}
+	if bytes.Equal(f.cipher, cipherChaCha20) {
+		s, _ := chacha20.NewUnauthenticatedCipher(encKey[:], iv)
+		s.XORKeyStream(data, data)
+	} else {
+		pad := aes.BlockSize - len(data)%aes.BlockSize
+		data = append(data, bytes.Repeat([]byte{byte(pad)}, pad)...)
+		block, _ := aes.NewCipher(encKey[:])
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, data)
+	}
+
+	out := bytes.Clone(hdr.Bytes())
+	sum := sha256.Sum256(hdr.Bytes())
+	out = append(out, sum[:]...)
+	out = append(out, mac(^uint64(0), hdr.Bytes())...)
+	for i := uint64(0); ; i++ {
+		n := min(len(data), 200)
+		block := append(u32(uint32(n)), data[:n]...)
+		out = append(out, mac(i, block)...)
+		out = append(out, block...)
+		if n == 0 {
+			return out
+		}
+		data = data[n:]
+	}
+}
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name string
+		f    fixture
+	}{
+		{"aes-kdf aes salsa20 gzip", fixture{cipher: cipherAES256, kdf: kdfAES, stream: streamSalsa20, gzip: true}},
+		{"argon2d chacha20 chacha20", fixture{cipher: cipherChaCha20, kdf: kdfArgon2d, stream: streamChaCha20}},
+		{"argon2id aes chacha20 gzip", fixture{cipher: cipherAES256, kdf: kdfArgon2id, stream: streamChaCha20, gzip: true}},
+		{"argon2d aes salsa20", fixture{cipher: cipherAES256, kdf: kdfArgon2d, stream: streamSalsa20}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := writeKDBX(t, tt.f, "master")
+			db, err := Open(data, "master", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkFixture(t, db)
+
+			if _, err := Open(data, "wrong", nil); !errors.Is(err, ErrWrongKey) {
+				t.Errorf("wrong password: got %v, want ErrWrongKey", err)
+			}
+			damaged := bytes.Clone(data)
+			damaged[len(damaged)-40] ^= 1
+			if _, err := Open(damaged, "master", nil); err == nil || !strings.Contains(err.Error(), "damaged") {
+				t.Errorf("damaged block: got %v", err)
+			}
+		})
+	}
+}
+
+func TestOpenKeyFile(t *testing.T) {
+	keyFile := bytes.Repeat([]byte{0x42}, 32)
+	data := writeKDBX(t, fixture{cipher: cipherAES256, kdf: kdfArgon2id, stream: streamChaCha20, keyFile: keyFile}, "")
+	db, err := Open(data, "", keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkFixture(t, db)
+	if _, err := Open(data, "", nil); !errors.Is(err, ErrNoKey) {
+		t.Errorf("no key: got %v, want ErrNoKey", err)
+	}
+}
+
+func checkFixture(t *testing.T, db *Database) {
+	t.Helper()
+	if db.Name != "Fixture" {
+		t.Errorf("name %q", db.Name)
+	}
+	want := []Entry{
+		{Title: "Mail", UserName: "alice", Password: fixturePasswords[0], Group: "Root", AutoType: DefaultAutoType},
+		{Title: "db", Password: fixturePasswords[1], Group: "Root/Servers", AutoType: "{PASSWORD}{ENTER}"},
+	}
+	if len(db.Entries) != len(want) {
+		t.Fatalf("got %d entries, want %d (the recycle bin is left out)", len(db.Entries), len(want))
+	}
+	for i, w := range want {
+		e := db.Entries[i]
+		if e.Title != w.Title || e.UserName != w.UserName || e.Password != w.Password || e.Group != w.Group || e.AutoType != w.AutoType {
+			t.Errorf("entry %d = %+v, want %+v", i, e, w)
+		}
+	}
+}
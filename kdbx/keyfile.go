@@ -0,0 +1,65 @@
+package kdbx
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"strings"
+)
+
+// keyFile is the XML key file format of KeePass 2 (versions 1.0 and 2.0).
+type keyFile struct {
+	XMLName xml.Name `xml:"KeyFile"`
+	Version string   `xml:"Meta>Version"`
+	Data    struct {
+		Hash  string `xml:"Hash,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"Key>Data"`
+}
+
+// keyFileKey returns the 32 bytes a key file adds to the composite key.
+// Like KeePass, it accepts XML key files, 32 raw bytes and 64 hex digits;
+// any other file counts by its SHA-256.
+func keyFileKey(data []byte) ([]byte, error) {
+	if bytes.Contains(data[:min(len(data), 512)], []byte("<KeyFile")) {
+		return xmlKeyFileKey(data)
+	}
+	if len(data) == 32 {
+		return data, nil
+	}
+	if len(data) == 64 {
+		if k, err := hex.DecodeString(string(data)); err == nil {
+			return k, nil
+		}
+	}
+	sum := sha256.Sum256(data)
+	return sum[:], nil
+}
+
+func xmlKeyFileKey(data []byte) ([]byte, error) {
+	var f keyFile
+	if err := xml.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	value := strings.Join(strings.Fields(f.Data.Value), "")
+	switch strings.TrimSpace(f.Version) {
+	case "1.0", "1.00":
+		return base64.StdEncoding.DecodeString(value)
+	case "2.0", "2.00":
+		k, err := hex.DecodeString(value)
+		if err != nil {
+			return nil, err
+		}
+		if f.Data.Hash != "" {
+			sum := sha256.Sum256(k)
+			if !strings.EqualFold(hex.EncodeToString(sum[:4]), f.Data.Hash) {
+				return nil, errors.New("the key does not match its checksum")
+			}
+		}
+		return k, nil
+	}
+	return nil, errors.New("unsupported key file version " + f.Version)
+}
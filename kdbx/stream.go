@@ -0,0 +1,66 @@
+package kdbx
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/salsa20/salsa"
+)
+
+// Inner stream IDs.
+const (
+	streamSalsa20  = 2
+	streamChaCha20 = 3
+)
+
+// salsaNonce is the fixed nonce of the Salsa20 inner stream.
+var salsaNonce = [8]byte{0xE8, 0x30, 0x09, 0x4B, 0x97, 0x20, 0x5D, 0x2A}
+
+// protectedStream decrypts the protected values of the XML document. It is
+// one key stream across the whole document, so values must be decrypted
+// in the order they appear.
+type protectedStream struct {
+	xor func(dst, src []byte)
+}
+
+func newProtectedStream(id uint32, key []byte) (*protectedStream, error) {
+	switch id {
+	case streamChaCha20:
+		h := sha512.Sum512(key)
+		c, err := chacha20.NewUnauthenticatedCipher(h[:32], h[32:44])
+		if err != nil {
+			return nil, err
+		}
+		return &protectedStream{xor: c.XORKeyStream}, nil
+	case streamSalsa20:
+		s := &salsaStream{key: sha256.Sum256(key)}
+		copy(s.counter[:8], salsaNonce[:])
+		return &protectedStream{xor: s.xor}, nil
+	}
+	return nil, fmt.Errorf("unsupported inner stream %d", id)
+}
+
+// salsaStream is Salsa20 as a continuous stream; the salsa package only
+// encrypts whole messages.
+type salsaStream struct {
+	key     [32]byte
+	counter [16]byte // nonce, then the little-endian block counter
+	block   [64]byte
+	used    int // bytes of block already used
+}
+
+func (s *salsaStream) xor(dst, src []byte) {
+	for i := range src {
+		if s.used == 0 || s.used == len(s.block) {
+			var zero [64]byte
+			salsa.XORKeyStream(s.block[:], zero[:], &s.counter, &s.key)
+			binary.LittleEndian.PutUint64(s.counter[8:], binary.LittleEndian.Uint64(s.counter[8:])+1)
+			s.used = 0
+		}
+		dst[i] = src[i] ^ s.block[s.used]
+		s.used++
+	}
+}
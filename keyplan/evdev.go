@@ -0,0 +1,85 @@
+package keyplan
+
+// Linux evdev (KEY_*) codes for scan codes that do not map one to one.
+// For plain set-1 scan codes 0x01-0x58 the evdev code equals the scan code.
+var evdevExtended = map[uint16]uint16{
+	0x1C: 96,  // KEY_KPENTER
+	0x1D: 97,  // KEY_RIGHTCTRL
+	0x20: 113, // KEY_MUTE
+	0x2E: 114, // KEY_VOLUMEDOWN
+	0x30: 115, // KEY_VOLUMEUP
+	0x35: 98,  // KEY_KPSLASH
+	0x37: 99,  // KEY_SYSRQ
+	0x38: 100, // KEY_RIGHTALT
+	0x47: 102, // KEY_HOME
+	0x48: 103, // KEY_UP
+	0x49: 104, // KEY_PAGEUP
+	0x4B: 105, // KEY_LEFT
+	0x4D: 106, // KEY_RIGHT
+	0x4F: 107, // KEY_END
+	0x50: 108, // KEY_DOWN
+	0x51: 109, // KEY_PAGEDOWN
+	0x52: 110, // KEY_INSERT
+	0x53: 111, // KEY_DELETE
+	0x5B: 125, // KEY_LEFTMETA
+	0x5C: 126, // KEY_RIGHTMETA
+	0x5D: 127, // KEY_COMPOSE
+}
+
+var evdevPlain = map[uint16]uint16{
+	0x70: 93,  // KEY_KATAKANAHIRAGANA
+	0x73: 89,  // KEY_RO
+	0x79: 92,  // KEY_HENKAN
+	0x7B: 94,  // KEY_MUHENKAN
+	0x7D: 124, // KEY_YEN
+	0xF1: 123, // KEY_HANJA
+	0xF2: 122, // KEY_HANGEUL
+}
+
+var evdevReverse = func() map[uint16]Key {
+	m := make(map[uint16]Key, len(evdevExtended)+len(evdevPlain))
+	for sc, code := range evdevExtended {
+		m[code] = Key{Code: sc, Extended: true}
+	}
+	for sc, code := range evdevPlain {
+		m[code] = Key{Code: sc}
+	}
+	return m
+}()
+
+// EvdevCode returns the Linux input event code (KEY_*) for k.
+func EvdevCode(k Key) (uint16, bool) {
+	if k.Extended {
+		code, ok := evdevExtended[k.Code]
+		return code, ok
+	}
+	if k.Code >= 0x01 && k.Code <= 0x58 && k.Code != 0x54 && k.Code != 0x55 {
+		return k.Code, true
+	}
+	code, ok := evdevPlain[k.Code]
+	return code, ok
+}
+
+// KeyFromEvdev is the inverse of EvdevCode.
+func KeyFromEvdev(code uint16) (Key, bool) {
+	if code >= 0x01 && code <= 0x58 && code != 0x54 && code != 0x55 {
+		return Key{Code: code}, true
+	}
+	k, ok := evdevReverse[code]
+	return k, ok
+}
+
+// evdev codes for the modifier keys.
+var evdevModifiers = map[Modifier]uint16{
+	ModShift: 42,  // KEY_LEFTSHIFT
+	ModCtrl:  29,  // KEY_LEFTCTRL
+	ModAlt:   56,  // KEY_LEFTALT
+	ModAltGr: 100, // KEY_RIGHTALT
+	ModMeta:  125, // KEY_LEFTMETA
+}
+
+// EvdevModifier returns the evdev code of the key that produces mod.
+func EvdevModifier(mod Modifier) (uint16, bool) {
+	code, ok := evdevModifiers[mod]
+	return code, ok
+}
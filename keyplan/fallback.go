@@ -0,0 +1,106 @@
+package keyplan
+
+import (
+	"strconv"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Fallback selects how Build types characters the layout cannot produce.
+type Fallback uint8
+
+const (
+	// FallbackUnicode injects the character itself (KEYEVENTF_UNICODE on
+	// Windows). It is used when Options.Fallback is zero.
+	FallbackUnicode Fallback = iota + 1
+	// FallbackAltNumpad holds Alt and types the character's code page
+	// number on the numeric keypad, e.g. Alt+0169 for ©. It only uses scan
+	// codes, but needs a Windows target with Num Lock on.
+	FallbackAltNumpad
+	// FallbackHexInput types Ctrl+Shift+U, the hex code point and Space,
+	// the Unicode entry of GTK and IBus on Linux targets. The keys come
+	// from the layout's mapper.
+	FallbackHexInput
+)
+
+// String returns the name used in plan listings.
+func (f Fallback) String() string {
+	switch f {
+	case FallbackUnicode:
+		return "unicode"
+	case FallbackAltNumpad:
+		return "alt+numpad"
+	case FallbackHexInput:
+		return "ctrl+shift+u"
+	default:
+		return "none"
+	}
+}
+
+// numpadKeys are the keypad digit keys 0-9 (Num Lock on).
+var numpadKeys = [10]Key{
+	{Code: 0x52}, {Code: 0x4F}, {Code: 0x50}, {Code: 0x51}, {Code: 0x4B},
+	{Code: 0x4C}, {Code: 0x4D}, {Code: 0x47}, {Code: 0x48}, {Code: 0x49},
+}
+
+// altNumpadCode returns the digits Windows expects while Alt is held: a
+// leading 0 and the Windows-1252 code, or else the code page 437 code.
+// Characters in neither code page cannot be typed this way.
+func altNumpadCode(r rune) (string, bool) {
+	if c, ok := charmap.Windows1252.EncodeRune(r); ok {
+		return "0" + strconv.Itoa(int(c)), true
+	}
+	if c, ok := charmap.CodePage437.EncodeRune(r); ok {
+		return strconv.Itoa(int(c)), true
+	}
+	return "", false
+}
+
+// hexInputStrokes returns the strokes for Ctrl+Shift+U, the hex digits of
+// r and Space, or false if the layout lacks one of those keys.
+func hexInputStrokes(m Mapper, r rune) ([]Stroke, bool) {
+	u, ok := m.Lookup('u')
+	if !ok {
+		return nil, false
+	}
+	u.Mods |= ModCtrl | ModShift
+	strokes := []Stroke{u}
+	for _, d := range strconv.FormatInt(int64(r), 16) + " " {
+		s, ok := m.Lookup(d)
+		if !ok {
+			return nil, false
+		}
+		strokes = append(strokes, s)
+	}
+	return strokes, true
+}
+
+// fallback types r, which the layout has no key for, with the strategy of
+// the options and returns the strategy used. Strategies that cannot type r
+// fall back to Unicode.
+func (b *builder) fallback(r rune) Fallback {
+	switch b.opts.Fallback {
+	case FallbackAltNumpad:
+		if digits, ok := altNumpadCode(r); ok {
+			b.via = FallbackAltNumpad
+			b.add(Event{Kind: ModifierDown, Mod: ModAlt})
+			for _, d := range digits {
+				b.tap(Stroke{Key: numpadKeys[d-'0']})
+			}
+			b.add(Event{Kind: ModifierUp, Mod: ModAlt})
+			b.via = 0
+			return FallbackAltNumpad
+		}
+	case FallbackHexInput:
+		if strokes, ok := hexInputStrokes(b.m, r); ok {
+			b.via = FallbackHexInput
+			for _, s := range strokes {
+				b.tap(s)
+			}
+			b.via = 0
+			return FallbackHexInput
+		}
+	}
+	b.add(Event{Kind: Unicode, Rune: r, Fallback: FallbackUnicode})
+	return FallbackUnicode
+}
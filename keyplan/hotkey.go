@@ -0,0 +1,72 @@
+package keyplan
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Hotkey is a key combination such as Ctrl+Alt+V for a global hotkey. The
+// key is a named key from the markup (ESC, F5, ...) or a letter or digit,
+// which the host's own layout resolves.
+type Hotkey struct {
+	Mods Modifier // ModShift, ModCtrl, ModAlt and ModMeta
+	Name string   // key name as parsed, upper case
+	Key  Key      // scan code of a named key, zero for letters and digits
+	Char rune     // upper-case letter or digit, 0 for named keys
+}
+
+// hotkeyModifierOrder is the order of the modifiers in Hotkey.String.
+var hotkeyModifierOrder = []struct {
+	mod  Modifier
+	name string
+}{
+	{ModCtrl, "Ctrl"},
+	{ModAlt, "Alt"},
+	{ModShift, "Shift"},
+	{ModMeta, "Win"},
+}
+
+// ParseHotkey reads a key combination written like a markup chord, e.g.
+// "Ctrl+Alt+V", "Win+F12" or "Esc". Case does not matter; each modifier
+// may be given once. AltGr is not accepted, since it types characters
+// rather than selecting shortcuts.
+func ParseHotkey(spec string) (Hotkey, error) {
+	parts := strings.Split(strings.TrimSpace(spec), "+")
+	var h Hotkey
+	for _, p := range parts[:len(parts)-1] {
+		mod, ok := markupModifiers[strings.ToUpper(strings.TrimSpace(p))]
+		if !ok || mod == ModAltGr {
+			return Hotkey{}, fmt.Errorf("unknown modifier %q in hotkey %q", p, spec)
+		}
+		if h.Mods&mod != 0 {
+			return Hotkey{}, fmt.Errorf("modifier %q given twice in hotkey %q", strings.TrimSpace(p), spec)
+		}
+		h.Mods |= mod
+	}
+
+	h.Name = strings.ToUpper(strings.TrimSpace(parts[len(parts)-1]))
+	if k, ok := markupKeys[h.Name]; ok {
+		h.Key = k
+		return h, nil
+	}
+	if len(h.Name) == 1 && (h.Name[0] >= 'A' && h.Name[0] <= 'Z' || h.Name[0] >= '0' && h.Name[0] <= '9') {
+		h.Char = rune(h.Name[0])
+		return h, nil
+	}
+	if _, isMod := markupModifiers[h.Name]; isMod || h.Name == "" {
+		return Hotkey{}, fmt.Errorf("hotkey %q has no key", spec)
+	}
+	return Hotkey{}, fmt.Errorf("unknown key %q in hotkey %q", h.Name, spec)
+}
+
+// String returns the hotkey as ParseHotkey reads it, e.g. "Ctrl+Alt+V".
+func (h Hotkey) String() string {
+	var b strings.Builder
+	for _, m := range hotkeyModifierOrder {
+		if h.Mods&m.mod != 0 {
+			b.WriteString(m.name + "+")
+		}
+	}
+	b.WriteString(h.Name)
+	return b.String()
+}
@@ -0,0 +1,70 @@
+package keyplan_test
+
+import (
+	"strings"
+	"testing"
+
+	"goclip/keyplan"
+)
+
+func TestParseHotkey(t *testing.T) {
+	tests := []struct {
+		spec string
+		want keyplan.Hotkey
+		str  string
+	}{
+		{"Ctrl+Alt+V", keyplan.Hotkey{Mods: keyplan.ModCtrl | keyplan.ModAlt, Name: "V", Char: 'V'}, "Ctrl+Alt+V"},
+		{"ctrl+alt+v", keyplan.Hotkey{Mods: keyplan.ModCtrl | keyplan.ModAlt, Name: "V", Char: 'V'}, "Ctrl+Alt+V"},
+		{" Alt + CTRL + v ", keyplan.Hotkey{Mods: keyplan.ModCtrl | keyplan.ModAlt, Name: "V", Char: 'V'}, "Ctrl+Alt+V"},
+		{"Control+Shift+1", keyplan.Hotkey{Mods: keyplan.ModCtrl | keyplan.ModShift, Name: "1", Char: '1'}, "Ctrl+Shift+1"},
+		{"Esc", keyplan.Hotkey{Name: "ESC", Key: keyplan.Key{Code: 0x01}}, "ESC"},
+		{"win+f12", keyplan.Hotkey{Mods: keyplan.ModMeta, Name: "F12", Key: keyplan.Key{Code: 0x58}}, "Win+F12"},
+		{"Cmd+Space", keyplan.Hotkey{Mods: keyplan.ModMeta, Name: "SPACE", Key: keyplan.Key{Code: 0x39}}, "Win+SPACE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			h, err := keyplan.ParseHotkey(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h != tt.want {
+				t.Errorf("got %+v, want %+v", h, tt.want)
+			}
+			if h.String() != tt.str {
+				t.Errorf("String() = %q, want %q", h.String(), tt.str)
+			}
+			// String reads back as the same hotkey
+			if again, err := keyplan.ParseHotkey(h.String()); err != nil || again != h {
+				t.Errorf("ParseHotkey(%q) = %+v, %v", h.String(), again, err)
+			}
+		})
+	}
+}
+
+func TestParseHotkeyErrors(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"", "has no key"},
+		{"Ctrl+", "has no key"},
+		{"Ctrl+Alt+ ", "has no key"},
+		{"Ctrl", "has no key"},
+		{"Ctrl+Shift", "has no key"},
+		{"Ctrl+Ctrl+V", "given twice"},
+		{"Win+Meta+V", "given twice"},
+		{"Hyper+V", `unknown modifier "Hyper"`},
+		{"AltGr+V", `unknown modifier "AltGr"`},
+		{"Ctrl+Alt+Bogus", `unknown key "BOGUS"`},
+		{"Ctrl+ä", "unknown key"},
+		{"Ctrl+VV", `unknown key "VV"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			h, err := keyplan.ParseHotkey(tt.spec)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %+v, %v; want an error containing %q", h, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package keyplan
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Injector delivers plan events to an OS input API (SendInput, CGEvent,
+// XTEST, ...) or to an in-memory recorder.
+type Injector interface {
+	// PressKey sends a physical key down or up event.
+	PressKey(k Key, down bool) error
+	// PressModifier sends a modifier key down or up event.
+	PressModifier(m Modifier, down bool) error
+	// TypeUnicode injects a character without a physical key.
+	TypeUnicode(r rune) error
+	// Sleep pauses between events.
+	Sleep(d time.Duration)
+}
+
+// stopSlice is how often shouldStop is polled during a long delay, such as
+// {SLEEP 60000} in markup, so a stop request does not wait for it to end.
+const stopSlice = 50 * time.Millisecond
+
+// Replay sends every event of p to inj in order. shouldStop is polled
+// between characters (never while a modifier is held) and every stopSlice
+// of a longer delay; a stop request ends the replay without an error. If an event fails, modifiers that are still
+// held are released before the error is returned.
+func Replay(p Plan, inj Injector, shouldStop func() bool) error {
+	var held []Modifier
+	releaseHeld := func() {
+		for i := len(held) - 1; i >= 0; i-- {
+			_ = inj.PressModifier(held[i], false)
+		}
+		held = held[:0]
+	}
+
+	lastPos := -1
+	for _, ev := range p.Events {
+		if ev.Pos != lastPos && len(held) == 0 {
+			lastPos = ev.Pos
+			if shouldStop != nil && shouldStop() {
+				return nil
+			}
+		}
+
+		var err error
+		switch ev.Kind {
+		case ModifierDown:
+			err = inj.PressModifier(ev.Mod, true)
+			if err == nil {
+				held = append(held, ev.Mod)
+			}
+		case ModifierUp:
+			err = inj.PressModifier(ev.Mod, false)
+			for i := len(held) - 1; i >= 0; i-- {
+				if held[i] == ev.Mod {
+					held = append(held[:i], held[i+1:]...)
+					break
+				}
+			}
+		case KeyDown:
+			err = inj.PressKey(ev.Key, true)
+		case KeyUp:
+			err = inj.PressKey(ev.Key, false)
+		case Unicode:
+			err = inj.TypeUnicode(ev.Rune)
+		case Delay:
+			d := ev.Delay
+			if shouldStop != nil && len(held) == 0 {
+				for ; d > stopSlice; d -= stopSlice {
+					inj.Sleep(stopSlice)
+					if shouldStop() {
+						return nil
+					}
+				}
+			}
+			inj.Sleep(d)
+		}
+		if err != nil {
+			releaseHeld()
+			return err
+		}
+	}
+	return nil
+}
+
+var modifierNames = map[Modifier]string{
+	ModShift: "Shift",
+	ModCtrl:  "Ctrl",
+	ModAlt:   "Alt",
+	ModAltGr: "AltGr",
+	ModMeta:  "Meta",
+}
+
+// String returns the modifier names joined with "+", e.g. "Shift+AltGr".
+func (m Modifier) String() string {
+	var parts []string
+	for _, mod := range modifierOrder {
+		if m&mod != 0 {
+			parts = append(parts, modifierNames[mod])
+		}
+	}
+	if len(parts) == 0 {
+		return "None"
+	}
+	return strings.Join(parts, "+")
+}
+
+// String returns the scan code in the usual "E0 1D" / "1E" notation.
+func (k Key) String() string {
+	if k.Extended {
+		return fmt.Sprintf("E0 %02X", k.Code)
+	}
+	return fmt.Sprintf("%02X", k.Code)
+}
+
+// String returns a compact description such as "+Shift", "-1E",
+// "U+20AC" or "sleep 10ms".
+func (e Event) String() string {
+	switch e.Kind {
+	case KeyDown:
+		return "+" + e.Key.String()
+	case KeyUp:
+		return "-" + e.Key.String()
+	case ModifierDown:
+		return "+" + e.Mod.String()
+	case ModifierUp:
+		return "-" + e.Mod.String()
+	case Unicode:
+		return fmt.Sprintf("U+%04X", e.Rune)
+	case Delay:
+		return "sleep " + e.Delay.String()
+	default:
+		return "?"
+	}
+}
@@ -0,0 +1,146 @@
+package keyplan_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"goclip/keyplan"
+	"goclip/layout"
+)
+
+func TestReplay(t *testing.T) {
+	p, err := keyplan.Build("Ab", layout.US, keyplan.Options{PerCharDelay: 5 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rec keyplan.Recorder
+	if err := keyplan.Replay(p, &rec, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rec.String(), "+Shift +1E -1E -Shift +30 -30"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	// the clock only advances through Sleep
+	records := rec.Records()
+	if last := records[len(records)-1]; last.Event.Kind != keyplan.Delay || last.At != 5*time.Millisecond {
+		t.Errorf("last record %s at %v, want the second delay at 5ms", last.Event, last.At)
+	}
+}
+
+func TestReplayStop(t *testing.T) {
+	p, err := keyplan.Build("AbC", layout.US, keyplan.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rec keyplan.Recorder
+	polls := 0
+	// stop before the third character
+	stop := func() bool {
+		polls++
+		return polls == 3
+	}
+	if err := keyplan.Replay(p, &rec, stop); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rec.String(), "+Shift +1E -1E -Shift +30 -30"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if polls != 3 {
+		t.Errorf("shouldStop polled %d times, want once per character", polls)
+	}
+}
+
+func TestReplayStopNotPolledWhileModifierHeld(t *testing.T) {
+	// Alt+0169: the digits belong to one character, typed with Alt held
+	p, err := keyplan.Build("©", layout.US, keyplan.Options{Fallback: keyplan.FallbackAltNumpad})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rec keyplan.Recorder
+	polls := 0
+	if err := keyplan.Replay(p, &rec, func() bool { polls++; return false }); err != nil {
+		t.Fatal(err)
+	}
+	if polls != 1 {
+		t.Errorf("shouldStop polled %d times, want 1", polls)
+	}
+}
+
+func TestReplayReleasesModifiersOnError(t *testing.T) {
+	// Ä is Shift+AltGr+Q on US International
+	p, err := keyplan.Build("aÄ", mustLayout(t, "US International"), keyplan.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	errRejected := errors.New("rejected")
+	rec := keyplan.Recorder{Fail: func(e keyplan.Event) error {
+		if e.Kind == keyplan.KeyDown && e.Key == (keyplan.Key{Code: 0x10}) {
+			return errRejected
+		}
+		return nil
+	}}
+	if err := keyplan.Replay(p, &rec, nil); !errors.Is(err, errRejected) {
+		t.Fatalf("got %v, want the injector's error", err)
+	}
+	if got, want := rec.String(), "+1E -1E +Shift +AltGr -AltGr -Shift"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+// sleepingRecorder records like Recorder but also sleeps for real.
+type sleepingRecorder struct {
+	keyplan.Recorder
+}
+
+func (r *sleepingRecorder) Sleep(d time.Duration) {
+	time.Sleep(d)
+	r.Recorder.Sleep(d)
+}
+
+func TestReplayStopDuringSleep(t *testing.T) {
+	p, err := keyplan.Build("a{SLEEP 60000}b", layout.US, keyplan.Options{Markup: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rec sleepingRecorder
+	var stopped atomic.Bool
+	time.AfterFunc(100*time.Millisecond, func() { stopped.Store(true) })
+
+	start := time.Now()
+	if err := keyplan.Replay(p, &rec, stopped.Load); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Replay returned after %v, want it to stop during the sleep", elapsed)
+	}
+	if got, want := rec.String(), "+1E -1E"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestReplaySplitsLongDelays(t *testing.T) {
+	p, err := keyplan.Build("{SLEEP 120}a", layout.US, keyplan.Options{Markup: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rec keyplan.Recorder
+	if err := keyplan.Replay(p, &rec, func() bool { return false }); err != nil {
+		t.Fatal(err)
+	}
+	var slept []time.Duration
+	var total time.Duration
+	for _, e := range rec.Events() {
+		if e.Kind == keyplan.Delay {
+			slept = append(slept, e.Delay)
+			total += e.Delay
+		}
+	}
+	if len(slept) != 3 || total != 120*time.Millisecond {
+		t.Errorf("slept %v, want 120ms in slices of at most 50ms", slept)
+	}
+	if got, want := rec.String(), "+1E -1E"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,237 @@
+// Package keyplan turns text into an ordered, platform-neutral list of key
+// events. The OS backends only replay a plan; all decisions about which
+// physical keys and modifiers to press live here.
+package keyplan
+
+import (
+	"strings"
+	"time"
+)
+
+// Key identifies a physical key by its PC/AT set-1 scan code. Extended keys
+// (arrows, right Alt, navigation block, ...) carry the E0 prefix flag.
+// Backends that use another code space translate from this position.
+type Key struct {
+	Code     uint16
+	Extended bool
+}
+
+// Modifier is a bit set of modifier keys held while a key is tapped.
+type Modifier uint8
+
+const (
+	ModShift Modifier = 1 << iota
+	ModCtrl
+	ModAlt
+	ModAltGr
+	ModMeta
+)
+
+// modifierOrder is the press order; releases happen in reverse.
+var modifierOrder = []Modifier{ModShift, ModCtrl, ModAlt, ModAltGr, ModMeta}
+
+// Stroke is a key together with the modifiers it needs.
+type Stroke struct {
+	Key  Key
+	Mods Modifier
+}
+
+// Mapper resolves a character to a stroke on one target layout.
+// ok is false when the layout cannot produce the character.
+type Mapper interface {
+	Lookup(r rune) (s Stroke, ok bool)
+}
+
+// DeadKeyMapper is a Mapper that also knows the layout's dead keys. Build
+// uses it for characters Lookup cannot type directly.
+type DeadKeyMapper interface {
+	Mapper
+	// LookupDead returns the dead key and the base key that together type
+	// r, e.g. ^ then e for ê, or ^ then space for a lone ^.
+	LookupDead(r rune) (dead, base Stroke, ok bool)
+}
+
+// MapperFunc adapts a plain function to the Mapper interface.
+type MapperFunc func(r rune) (Stroke, bool)
+
+// Lookup calls f(r).
+func (f MapperFunc) Lookup(r rune) (Stroke, bool) {
+	return f(r)
+}
+
+// EventKind tells a backend what to do with an Event.
+type EventKind uint8
+
+const (
+	KeyDown EventKind = iota + 1
+	KeyUp
+	ModifierDown
+	ModifierUp
+	Unicode
+	Delay
+)
+
+// Event is a single step of a plan.
+type Event struct {
+	Kind  EventKind
+	Key   Key           // KeyDown, KeyUp
+	Mod   Modifier      // ModifierDown, ModifierUp (exactly one bit)
+	Rune  rune          // Unicode
+	Delay time.Duration // Delay
+	Pos   int           // index of the source rune this event belongs to
+	// Fallback is the strategy that produced the event for a character the
+	// layout cannot type, zero for ordinary key strokes.
+	Fallback Fallback
+}
+
+// Plan is the ordered list of events for a piece of text.
+type Plan struct {
+	Events []Event
+	Runes  int // number of source runes covered by the plan
+}
+
+// Options tune how a plan is built.
+type Options struct {
+	// PerCharDelay is inserted after every typed character.
+	PerCharDelay time.Duration
+	// Fallback types characters the layout cannot produce, by default
+	// FallbackUnicode.
+	Fallback Fallback
+	// Unmappable decides whether such characters are typed with Fallback
+	// at all.
+	Unmappable Unmappable
+	// UnicodeKeys is set when the backend types FallbackUnicode characters
+	// with a real key it maps on the fly (X11's spare keycode, an uploaded
+	// Wayland keymap). Every character is typable then, so Unmappable does
+	// not apply with that fallback.
+	UnicodeKeys bool
+	// Markup turns {KEY} tokens into key presses, see markup.go.
+	Markup bool
+}
+
+// Well-known keys used by the planner itself.
+var (
+	KeyEnter = Key{Code: 0x1C}
+)
+
+// Build creates the plan for text using m for character lookups.
+// CRLF is normalized to a single Enter press. Characters the mapper
+// cannot resolve are composed with a dead key if m is a DeadKeyMapper,
+// and otherwise handled as opts.Unmappable says. The error is an
+// *UnmappableError, only returned for UnmappableFail, or a *MarkupError.
+func Build(text string, m Mapper, opts Options) (Plan, error) {
+	b := newBuilder(m, opts)
+	if err := b.run(text, true); err != nil {
+		return Plan{}, err
+	}
+	return Plan{Events: b.events, Runes: len(b.report)}, nil
+}
+
+type builder struct {
+	opts   Options
+	m      Mapper
+	dm     DeadKeyMapper // m, if it knows dead keys
+	pos    int
+	via    Fallback // marks the events of a fallback sequence
+	events []Event
+	report Report
+}
+
+func newBuilder(m Mapper, opts Options) *builder {
+	dm, _ := m.(DeadKeyMapper)
+	return &builder{opts: opts, m: m, dm: dm}
+}
+
+// run plans text. With stop set it returns the first UnmappableError or
+// MarkupError, otherwise it reports the characters as MethodUntypable and
+// goes on.
+func (b *builder) run(text string, stop bool) error {
+	rs := []rune(strings.ReplaceAll(text, "\r\n", "\n"))
+	for i := 0; i < len(rs); i++ {
+		b.pos = i
+		n, err := 0, error(nil)
+		if b.opts.Markup {
+			n, err = b.markup(rs[i:])
+		}
+		if n == 0 {
+			n = 1
+			if err = b.char(rs[i]); err == nil {
+				b.delay()
+			}
+		}
+		if err != nil {
+			if stop {
+				return err
+			}
+			for j := i; j < i+n; j++ {
+				b.pos = j
+				b.note(rs[j], MethodUntypable, 0)
+			}
+		}
+		i += n - 1
+	}
+	return nil
+}
+
+// char plans a single character and reports how it is typed.
+func (b *builder) char(r rune) error {
+	if r == '\n' {
+		b.tap(Stroke{Key: KeyEnter})
+		b.note(r, MethodKey, 0)
+	} else if strokes, ok := b.strokes(r); ok {
+		for _, s := range strokes {
+			b.tap(s)
+		}
+		if len(strokes) == 1 {
+			b.note(r, MethodKey, 0)
+		} else {
+			b.note(r, MethodDeadKey, 0)
+		}
+	} else {
+		return b.unmappable(r)
+	}
+	return nil
+}
+
+func (b *builder) add(e Event) {
+	e.Pos = b.pos
+	if e.Fallback == 0 {
+		e.Fallback = b.via
+	}
+	b.events = append(b.events, e)
+}
+
+// strokes returns the key strokes typing r on the layout: a single key, or
+// a dead key followed by its base.
+func (b *builder) strokes(r rune) ([]Stroke, bool) {
+	if s, ok := b.m.Lookup(r); ok {
+		return []Stroke{s}, true
+	}
+	if b.dm != nil {
+		if dead, base, ok := b.dm.LookupDead(r); ok {
+			return []Stroke{dead, base}, true
+		}
+	}
+	return nil, false
+}
+
+func (b *builder) tap(s Stroke) {
+	for _, mod := range modifierOrder {
+		if s.Mods&mod != 0 {
+			b.add(Event{Kind: ModifierDown, Mod: mod})
+		}
+	}
+	b.add(Event{Kind: KeyDown, Key: s.Key})
+	b.add(Event{Kind: KeyUp, Key: s.Key})
+	for i := len(modifierOrder) - 1; i >= 0; i-- {
+		if mod := modifierOrder[i]; s.Mods&mod != 0 {
+			b.add(Event{Kind: ModifierUp, Mod: mod})
+		}
+	}
+}
+
+func (b *builder) delay() {
+	if b.opts.PerCharDelay > 0 {
+		b.add(Event{Kind: Delay, Delay: b.opts.PerCharDelay})
+	}
+}
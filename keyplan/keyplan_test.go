@@ -0,0 +1,175 @@
+package keyplan_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"goclip/keyplan"
+	"goclip/layout"
+)
+
+// planString lists the key and modifier events of p like Recorder.String.
+func planString(p keyplan.Plan) string {
+	var parts []string
+	for _, e := range p.Events {
+		if e.Kind != keyplan.Delay {
+			parts = append(parts, e.String())
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func mustLayout(t *testing.T, name string) *layout.Layout {
+	t.Helper()
+	l, ok := layout.Get(name)
+	if !ok {
+		t.Fatalf("no built-in layout %q", name)
+	}
+	return l
+}
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name   string
+		layout string
+		text   string
+		opts   keyplan.Options
+		want   string
+	}{
+		{
+			name:   "shift and altgr",
+			layout: "German (DE)",
+			text:   "Ä@€",
+			want:   "+Shift +28 -28 -Shift +AltGr +10 -10 -AltGr +AltGr +12 -12 -AltGr",
+		},
+		{
+			name:   "crlf is one enter",
+			layout: "English (US)",
+			text:   "a\r\nb\nc",
+			want:   "+1E -1E +1C -1C +30 -30 +1C -1C +2E -2E",
+		},
+		{
+			name:   "dead key and base",
+			layout: "German (DE)",
+			text:   "ê",
+			want:   "+29 -29 +12 -12",
+		},
+		{
+			name:   "shifted dead key alone is dead key then space",
+			layout: "German (DE)",
+			text:   "`",
+			want:   "+Shift +0D -0D -Shift +39 -39",
+		},
+		{
+			name:   "unmappable falls back to unicode",
+			layout: "English (US)",
+			text:   "a€",
+			want:   "+1E -1E U+20AC",
+		},
+		{
+			name:   "unmappable skipped",
+			layout: "English (US)",
+			text:   "a€b",
+			opts:   keyplan.Options{Unmappable: keyplan.UnmappableSkip},
+			want:   "+1E -1E +30 -30",
+		},
+		{
+			name:   "transliterated",
+			layout: "English (US)",
+			text:   "ß",
+			opts:   keyplan.Options{Unmappable: keyplan.UnmappableTransliterate},
+			want:   "+1F -1F +1F -1F",
+		},
+		{
+			name:   "unicode keys type what fail would refuse",
+			layout: "English (US)",
+			text:   "a€",
+			opts:   keyplan.Options{Unmappable: keyplan.UnmappableFail, UnicodeKeys: true},
+			want:   "+1E -1E U+20AC",
+		},
+		{
+			name:   "unicode keys type what would be transliterated",
+			layout: "English (US)",
+			text:   "ß",
+			opts:   keyplan.Options{Unmappable: keyplan.UnmappableTransliterate, UnicodeKeys: true},
+			want:   "U+00DF",
+		},
+		{
+			name:   "unicode keys do not cover other fallbacks",
+			layout: "English (US)",
+			text:   "ß",
+			opts:   keyplan.Options{Unmappable: keyplan.UnmappableTransliterate, Fallback: keyplan.FallbackAltNumpad, UnicodeKeys: true},
+			want:   "+1F -1F +1F -1F",
+		},
+		{
+			name:   "alt numpad fallback is alt+0169",
+			layout: "English (US)",
+			text:   "©",
+			opts:   keyplan.Options{Fallback: keyplan.FallbackAltNumpad},
+			want:   "+Alt +52 -52 +4F -4F +4D -4D +49 -49 -Alt",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := keyplan.Build(tt.text, mustLayout(t, tt.layout), tt.opts)
+			if err != nil {
+				t.Fatalf("Build(%q): %v", tt.text, err)
+			}
+			if got := planString(p); got != tt.want {
+				t.Errorf("Build(%q)\n got %s\nwant %s", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildRunesAndPositions(t *testing.T) {
+	p, err := keyplan.Build("A\r\nê", mustLayout(t, "German (DE)"), keyplan.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Runes != 3 {
+		t.Errorf("Runes = %d, want 3", p.Runes)
+	}
+	// A is 4 events, Enter 2, the dead key pair 4
+	wantPos := []int{0, 0, 0, 0, 1, 1, 2, 2, 2, 2}
+	if len(p.Events) != len(wantPos) {
+		t.Fatalf("got %d events, want %d: %s", len(p.Events), len(wantPos), planString(p))
+	}
+	for i, e := range p.Events {
+		if e.Pos != wantPos[i] {
+			t.Errorf("event %d (%s) has Pos %d, want %d", i, e, e.Pos, wantPos[i])
+		}
+	}
+}
+
+func TestBuildPerCharDelay(t *testing.T) {
+	p, err := keyplan.Build("ab", layout.US, keyplan.Options{PerCharDelay: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var delays int
+	for _, e := range p.Events {
+		if e.Kind == keyplan.Delay {
+			delays++
+			if e.Delay != 10*time.Millisecond {
+				t.Errorf("delay %v, want 10ms", e.Delay)
+			}
+		}
+	}
+	if delays != 2 {
+		t.Errorf("got %d delays, want one per character", delays)
+	}
+}
+
+func TestBuildUnmappableFail(t *testing.T) {
+	_, err := keyplan.Build("ab€", layout.US, keyplan.Options{Unmappable: keyplan.UnmappableFail})
+	var ue *keyplan.UnmappableError
+	if !errors.As(err, &ue) {
+		t.Fatalf("got %v, want an *UnmappableError", err)
+	}
+	if ue.Rune != '€' || ue.Pos != 2 {
+		t.Errorf("got %q at %d, want '€' at 2", ue.Rune, ue.Pos)
+	}
+}
@@ -0,0 +1,186 @@
+package keyplan
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Limits for the number in a markup token: the count of {UP 3} and the
+// milliseconds of {SLEEP 500}.
+const (
+	maxRepeat = 1000
+	maxSleep  = 60000
+)
+
+// markupKeys are the key names understood in markup, upper case.
+var markupKeys = map[string]Key{
+	"ENTER":     KeyEnter,
+	"RETURN":    KeyEnter,
+	"TAB":       {Code: 0x0F},
+	"ESC":       {Code: 0x01},
+	"ESCAPE":    {Code: 0x01},
+	"SPACE":     {Code: 0x39},
+	"BACKSPACE": {Code: 0x0E},
+	"BS":        {Code: 0x0E},
+	"INSERT":    {Code: 0x52, Extended: true},
+	"INS":       {Code: 0x52, Extended: true},
+	"DELETE":    {Code: 0x53, Extended: true},
+	"DEL":       {Code: 0x53, Extended: true},
+	"HOME":      {Code: 0x47, Extended: true},
+	"END":       {Code: 0x4F, Extended: true},
+	"PGUP":      {Code: 0x49, Extended: true},
+	"PAGEUP":    {Code: 0x49, Extended: true},
+	"PGDN":      {Code: 0x51, Extended: true},
+	"PAGEDOWN":  {Code: 0x51, Extended: true},
+	"UP":        {Code: 0x48, Extended: true},
+	"DOWN":      {Code: 0x50, Extended: true},
+	"LEFT":      {Code: 0x4B, Extended: true},
+	"RIGHT":     {Code: 0x4D, Extended: true},
+	"MENU":      {Code: 0x5D, Extended: true},
+	"F1":        {Code: 0x3B},
+	"F2":        {Code: 0x3C},
+	"F3":        {Code: 0x3D},
+	"F4":        {Code: 0x3E},
+	"F5":        {Code: 0x3F},
+	"F6":        {Code: 0x40},
+	"F7":        {Code: 0x41},
+	"F8":        {Code: 0x42},
+	"F9":        {Code: 0x43},
+	"F10":       {Code: 0x44},
+	"F11":       {Code: 0x57},
+	"F12":       {Code: 0x58},
+}
+
+// markupModifiers are the modifier names usable in chords, upper case.
+var markupModifiers = map[string]Modifier{
+	"SHIFT":   ModShift,
+	"CTRL":    ModCtrl,
+	"CONTROL": ModCtrl,
+	"ALT":     ModAlt,
+	"ALTGR":   ModAltGr,
+	"WIN":     ModMeta,
+	"META":    ModMeta,
+	"CMD":     ModMeta,
+}
+
+// MarkupError reports a markup token Build cannot plan.
+type MarkupError struct {
+	Token string
+	Pos   int // index of the token's opening brace in the text
+	Err   string
+}
+
+func (e *MarkupError) Error() string {
+	return fmt.Sprintf("%s at position %d: %s", e.Token, e.Pos+1, e.Err)
+}
+
+// markup plans the markup token at the start of rs, if there is one, and
+// returns the number of runes it spans. {{ and }} type a literal brace.
+func (b *builder) markup(rs []rune) (int, error) {
+	if len(rs) > 1 && (rs[0] == '{' || rs[0] == '}') && rs[1] == rs[0] {
+		if err := b.char(rs[0]); err != nil {
+			return 2, err
+		}
+		c := b.report[len(b.report)-1]
+		c.Pos++
+		b.report = append(b.report, c)
+		b.delay()
+		return 2, nil
+	}
+	if rs[0] != '{' {
+		return 0, nil
+	}
+
+	end := -1
+	for i, r := range rs {
+		if r == '}' {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return len(rs), &MarkupError{Token: "{", Pos: b.pos, Err: "missing } (type {{ for a literal brace)"}
+	}
+	token := string(rs[:end+1])
+	fail := func(format string, args ...any) (int, error) {
+		return end + 1, &MarkupError{Token: token, Pos: b.pos, Err: fmt.Sprintf(format, args...)}
+	}
+
+	fields := strings.Fields(string(rs[1:end]))
+	if len(fields) == 0 || len(fields) > 2 {
+		return fail("expected {KEY}, {KEY count} or {SLEEP ms}")
+	}
+	name := strings.ToUpper(fields[0])
+	n, limit := 1, maxRepeat
+	if name == "SLEEP" {
+		if len(fields) != 2 {
+			return fail("SLEEP needs a duration in milliseconds")
+		}
+		limit = maxSleep
+	}
+	if len(fields) == 2 {
+		var err error
+		n, err = strconv.Atoi(fields[1])
+		if err != nil || n < 1 || n > limit {
+			return fail("%s must be followed by a number from 1 to %d", name, limit)
+		}
+	}
+
+	if name == "SLEEP" {
+		b.add(Event{Kind: Delay, Delay: time.Duration(n) * time.Millisecond})
+	} else if mod, ok := markupModifiers[name]; ok {
+		// a modifier on its own, e.g. {WIN} to open the start menu
+		for i := 0; i < n; i++ {
+			b.add(Event{Kind: ModifierDown, Mod: mod})
+			b.add(Event{Kind: ModifierUp, Mod: mod})
+			b.delay()
+		}
+	} else {
+		s, err := b.chord(fields[0])
+		if err != nil {
+			return fail("%v", err)
+		}
+		for i := 0; i < n; i++ {
+			b.tap(s)
+			b.delay()
+		}
+	}
+	for i := 0; i <= end; i++ {
+		b.report = append(b.report, CharReport{Rune: rs[i], Pos: b.pos + i, Method: MethodKey})
+	}
+	return end + 1, nil
+}
+
+// chord resolves a key name with optional modifiers, such as CTRL+ALT+DEL,
+// WIN+R or SHIFT+TAB. The key itself may be a plus, as in CTRL++.
+func (b *builder) chord(spec string) (Stroke, error) {
+	var mods Modifier
+	last := spec
+	if i := strings.LastIndex(spec[:len(spec)-1], "+"); i >= 0 {
+		for _, p := range strings.Split(spec[:i], "+") {
+			mod, ok := markupModifiers[strings.ToUpper(p)]
+			if !ok {
+				return Stroke{}, fmt.Errorf("unknown modifier %q", p)
+			}
+			mods |= mod
+		}
+		last = spec[i+1:]
+	}
+
+	if k, ok := markupKeys[strings.ToUpper(last)]; ok {
+		return Stroke{Key: k, Mods: mods}, nil
+	}
+	if rs := []rune(last); len(rs) == 1 {
+		// letters name the key, so WIN+R does not add Shift
+		s, ok := b.m.Lookup(unicode.ToLower(rs[0]))
+		if !ok {
+			return Stroke{}, fmt.Errorf("no key for %q on the target layout", rs[0])
+		}
+		s.Mods |= mods
+		return s, nil
+	}
+	return Stroke{}, fmt.Errorf("unknown key %q", last)
+}
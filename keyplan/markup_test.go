@@ -0,0 +1,86 @@
+package keyplan_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"goclip/keyplan"
+	"goclip/layout"
+)
+
+func TestMarkup(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"{{", "+Shift +1A -1A -Shift"},
+		{"}}", "+Shift +1B -1B -Shift"},
+		{"a{{b}}", "+1E -1E +Shift +1A -1A -Shift +30 -30 +Shift +1B -1B -Shift"},
+		{"{ENTER}", "+1C -1C"},
+		{"{tab 3}", "+0F -0F +0F -0F +0F -0F"},
+		{"{SLEEP 250}", "sleep 250ms"},
+		{"{CTRL+ALT+DEL}", "+Ctrl +Alt +E0 53 -E0 53 -Alt -Ctrl"},
+		// letters name the key, so no Shift
+		{"{WIN+R}", "+Meta +13 -13 -Meta"},
+		// a plus as the key
+		{"{CTRL++}", "+Shift +Ctrl +0D -0D -Ctrl -Shift"},
+		{"{+}", "+Shift +0D -0D -Shift"},
+		{"{WIN}", "+Meta -Meta"},
+	}
+	for _, tt := range tests {
+		p, err := keyplan.Build(tt.text, layout.US, keyplan.Options{Markup: true})
+		if err != nil {
+			t.Errorf("Build(%q): %v", tt.text, err)
+			continue
+		}
+		var parts []string
+		for _, e := range p.Events {
+			parts = append(parts, e.String())
+		}
+		if got := strings.Join(parts, " "); got != tt.want {
+			t.Errorf("Build(%q)\n got %s\nwant %s", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMarkupErrors(t *testing.T) {
+	tests := []struct {
+		text  string
+		token string
+		pos   int
+	}{
+		{"ab{ENTER", "{", 2},
+		{"{", "{", 0},
+		{"{SLEEP}", "{SLEEP}", 0},
+		{"{SLEEP 0}", "{SLEEP 0}", 0},
+		{"{SLEEP 60001}", "{SLEEP 60001}", 0},
+		{"{TAB 1001}", "{TAB 1001}", 0},
+		{"{TAB x}", "{TAB x}", 0},
+		{"{}", "{}", 0},
+		{"x{NOPE}", "{NOPE}", 1},
+		{"{HYPER+A}", "{HYPER+A}", 0},
+		{"{CTRL+}", "{CTRL+}", 0},
+	}
+	for _, tt := range tests {
+		_, err := keyplan.Build(tt.text, layout.US, keyplan.Options{Markup: true})
+		var me *keyplan.MarkupError
+		if !errors.As(err, &me) {
+			t.Errorf("Build(%q): got %v, want a *MarkupError", tt.text, err)
+			continue
+		}
+		if me.Token != tt.token || me.Pos != tt.pos {
+			t.Errorf("Build(%q): got %s at %d, want %s at %d", tt.text, me.Token, me.Pos, tt.token, tt.pos)
+		}
+	}
+}
+
+func TestMarkupOff(t *testing.T) {
+	p, err := keyplan.Build("{{", layout.US, keyplan.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := planString(p), "+Shift +1A -1A -Shift +Shift +1A -1A -Shift"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,111 @@
+package keyplan
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Record is one event captured by a Recorder.
+type Record struct {
+	// At is the virtual time since the recorder started; it only advances
+	// through Sleep, so recordings are deterministic.
+	At    time.Duration
+	Event Event
+}
+
+// Recorder is an in-memory Injector that captures every event instead of
+// sending it to the OS. It never sleeps.
+type Recorder struct {
+	// Fail, if set, is called before an event is recorded. A non-nil error
+	// is returned to the caller as if the OS had rejected the event.
+	Fail func(Event) error
+
+	mu      sync.Mutex
+	elapsed time.Duration
+	records []Record
+}
+
+func (r *Recorder) record(e Event) error {
+	if r.Fail != nil {
+		if err := r.Fail(e); err != nil {
+			return err
+		}
+	}
+	r.mu.Lock()
+	r.records = append(r.records, Record{At: r.elapsed, Event: e})
+	r.mu.Unlock()
+	return nil
+}
+
+// PressKey records a KeyDown or KeyUp event.
+func (r *Recorder) PressKey(k Key, down bool) error {
+	kind := KeyUp
+	if down {
+		kind = KeyDown
+	}
+	return r.record(Event{Kind: kind, Key: k})
+}
+
+// PressModifier records a ModifierDown or ModifierUp event.
+func (r *Recorder) PressModifier(m Modifier, down bool) error {
+	kind := ModifierUp
+	if down {
+		kind = ModifierDown
+	}
+	return r.record(Event{Kind: kind, Mod: m})
+}
+
+// TypeUnicode records a Unicode event.
+func (r *Recorder) TypeUnicode(c rune) error {
+	return r.record(Event{Kind: Unicode, Rune: c})
+}
+
+// Sleep records a Delay event and advances the virtual clock.
+func (r *Recorder) Sleep(d time.Duration) {
+	_ = r.record(Event{Kind: Delay, Delay: d})
+	r.mu.Lock()
+	r.elapsed += d
+	r.mu.Unlock()
+}
+
+// Records returns a copy of everything captured so far.
+func (r *Recorder) Records() []Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]Record, len(r.records))
+	copy(out, r.records)
+	return out
+}
+
+// Events returns the captured events without timestamps.
+func (r *Recorder) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]Event, 0, len(r.records))
+	for _, rec := range r.records {
+		out = append(out, rec.Event)
+	}
+	return out
+}
+
+// String returns the captured key and modifier events separated by spaces,
+// e.g. "+Shift +1E -1E -Shift". Delays are omitted.
+func (r *Recorder) String() string {
+	var parts []string
+	for _, e := range r.Events() {
+		if e.Kind == Delay {
+			continue
+		}
+		parts = append(parts, e.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+// Reset discards all captured events and rewinds the virtual clock.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	r.records = nil
+	r.elapsed = 0
+	r.mu.Unlock()
+}
@@ -0,0 +1,84 @@
+package keyplan
+
+// Method tells how a character of a text is typed.
+type Method uint8
+
+const (
+	// MethodKey is a single key stroke of the layout.
+	MethodKey Method = iota
+	// MethodDeadKey is a dead key followed by a base key.
+	MethodDeadKey
+	// MethodTransliterated is an ASCII replacement typed with keys.
+	MethodTransliterated
+	// MethodFallback uses Options.Fallback; CharReport.Fallback tells
+	// which strategy could type the character.
+	MethodFallback
+	// MethodSkipped is left out (UnmappableSkip).
+	MethodSkipped
+	// MethodUntypable makes Build fail (UnmappableFail).
+	MethodUntypable
+)
+
+// String returns the name used in reports.
+func (m Method) String() string {
+	switch m {
+	case MethodKey:
+		return "key"
+	case MethodDeadKey:
+		return "dead key"
+	case MethodTransliterated:
+		return "transliterated"
+	case MethodFallback:
+		return "fallback"
+	case MethodSkipped:
+		return "skipped"
+	default:
+		return "untypable"
+	}
+}
+
+// CharReport is the analysis of one character.
+type CharReport struct {
+	Rune     rune
+	Pos      int // index of the rune in the text, after CRLF normalization
+	Method   Method
+	Fallback Fallback // MethodFallback only
+}
+
+// Report has one entry per character of an analyzed text.
+type Report []CharReport
+
+// Analyze reports how Build would type each character of text, without
+// stopping at characters UnmappableFail refuses.
+func Analyze(text string, m Mapper, opts Options) Report {
+	b := newBuilder(m, opts)
+	b.run(text, false)
+	return b.report
+}
+
+// Count returns the number of characters typed with method m.
+func (r Report) Count(m Method) int {
+	n := 0
+	for _, c := range r {
+		if c.Method == m {
+			n++
+		}
+	}
+	return n
+}
+
+// Problems returns the characters that are not typed with a key of their
+// own.
+func (r Report) Problems() Report {
+	var p Report
+	for _, c := range r {
+		if c.Method != MethodKey {
+			p = append(p, c)
+		}
+	}
+	return p
+}
+
+func (b *builder) note(r rune, m Method, f Fallback) {
+	b.report = append(b.report, CharReport{Rune: r, Pos: b.pos, Method: m, Fallback: f})
+}
@@ -0,0 +1,121 @@
+package keyplan
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Unmappable selects what Build does with a character the layout cannot
+// type, not even with a dead key.
+type Unmappable uint8
+
+const (
+	// UnmappableFallback types the character with Options.Fallback.
+	UnmappableFallback Unmappable = iota
+	// UnmappableFail makes Build return an *UnmappableError, so nothing is
+	// typed at all.
+	UnmappableFail
+	// UnmappableSkip leaves the character out.
+	UnmappableSkip
+	// UnmappableTransliterate types an ASCII replacement such as ss for ß
+	// or e for é. Characters without one use Options.Fallback.
+	UnmappableTransliterate
+)
+
+// UnmappableError reports the first character of a text the layout cannot
+// type when Options.Unmappable is UnmappableFail.
+type UnmappableError struct {
+	Rune rune
+	Pos  int // index of the rune in the text
+}
+
+func (e *UnmappableError) Error() string {
+	return fmt.Sprintf("character %q (U+%04X) at position %d cannot be typed on the target layout", e.Rune, e.Rune, e.Pos+1)
+}
+
+// transliterations are ASCII replacements that decomposition does not
+// provide.
+var transliterations = map[rune]string{
+	'ß': "ss", 'ẞ': "SS",
+	'æ': "ae", 'Æ': "AE",
+	'œ': "oe", 'Œ': "OE",
+	'ø': "o", 'Ø': "O",
+	'đ': "d", 'Đ': "D",
+	'ð': "d", 'Ð': "D",
+	'þ': "th", 'Þ': "Th",
+	'ł': "l", 'Ł': "L",
+	'ħ': "h", 'Ħ': "H",
+	'ı': "i",
+	'‘': "'", '’': "'", '‚': "'", '‛': "'", '′': "'",
+	'“': `"`, '”': `"`, '„': `"`, '‟': `"`, '″': `"`,
+	'«': "<<", '»': ">>", '‹': "<", '›': ">",
+	'‐': "-", '‑': "-", '‒': "-", '–': "-", '—': "-", '―': "-", '−': "-",
+	'⁄': "/", '•': "*", '·': ".",
+	'×': "x", '÷': "/",
+	'©': "(C)", '®': "(R)",
+	'€': "EUR", '£': "GBP", '¥': "JPY",
+	'¡': "!", '¿': "?",
+}
+
+// transliterate returns an ASCII replacement for r: an entry of the table
+// above, or the compatibility decomposition without its accents.
+func transliterate(r rune) (string, bool) {
+	if s, ok := transliterations[r]; ok {
+		return s, true
+	}
+	var b strings.Builder
+	for _, d := range norm.NFKD.String(string(r)) {
+		switch {
+		case unicode.Is(unicode.Mn, d):
+		case d <= unicode.MaxASCII:
+			b.WriteRune(d)
+		default:
+			s, ok := transliterations[d]
+			if !ok {
+				return "", false
+			}
+			b.WriteString(s)
+		}
+	}
+	return b.String(), b.Len() > 0
+}
+
+// unmappable handles r, which the layout cannot type, according to the
+// options.
+func (b *builder) unmappable(r rune) error {
+	policy := b.opts.Unmappable
+	if f := b.opts.Fallback; b.opts.UnicodeKeys && (f == 0 || f == FallbackUnicode) {
+		policy = UnmappableFallback
+	}
+	switch policy {
+	case UnmappableFail:
+		return &UnmappableError{Rune: r, Pos: b.pos}
+	case UnmappableSkip:
+		b.note(r, MethodSkipped, 0)
+		return nil
+	case UnmappableTransliterate:
+		if s, ok := transliterate(r); ok {
+			var strokes []Stroke
+			for _, c := range s {
+				ss, ok := b.strokes(c)
+				if !ok {
+					strokes = nil
+					break
+				}
+				strokes = append(strokes, ss...)
+			}
+			if strokes != nil {
+				for _, s := range strokes {
+					b.tap(s)
+				}
+				b.note(r, MethodTransliterated, 0)
+				return nil
+			}
+		}
+	}
+	b.note(r, MethodFallback, b.fallback(r))
+	return nil
+}
@@ -0,0 +1,99 @@
+//go:build ignore
+
+// gen.go generates table.go from the X11 keysymdef.h header.
+//
+//	go run gen.go [path/to/keysymdef.h]
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+var defineRe = regexp.MustCompile(`^#define XK_([a-zA-Z_0-9]+)\s+0x([0-9a-fA-F]+)\s*(?:/\*\s*U\+([0-9A-Fa-f]{4,6})\s)?`)
+
+type entry struct {
+	value uint32
+	r     rune
+}
+
+func main() {
+	path := "/usr/include/X11/keysymdef.h"
+	if len(os.Args) > 1 {
+		path = os.Args[1]
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	legacy := map[uint32]rune{}
+	names := map[string]uint32{}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		m := defineRe.FindStringSubmatch(sc.Text())
+		if m == nil {
+			continue
+		}
+		v, _ := strconv.ParseUint(m[2], 16, 32)
+		names[m[1]] = uint32(v)
+		if m[3] == "" {
+			continue
+		}
+		r, _ := strconv.ParseUint(m[3], 16, 32)
+		value := uint32(v)
+		// Latin-1 and the 0x01xxxxxx range are computed, not tabled.
+		if value < 0x100 || value&0xff000000 == 0x01000000 {
+			continue
+		}
+		if _, dup := legacy[value]; !dup {
+			legacy[value] = rune(r)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	entries := make([]entry, 0, len(legacy))
+	for v, r := range legacy {
+		entries = append(entries, entry{v, r})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].value < entries[j].value })
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by gen.go from keysymdef.h; DO NOT EDIT.\n\n")
+	buf.WriteString("package keysym\n\n")
+	buf.WriteString("// legacyRunes maps pre-Unicode keysyms to the characters they produce.\n")
+	buf.WriteString("var legacyRunes = map[Keysym]rune{\n")
+	for _, e := range entries {
+		fmt.Fprintf(&buf, "\t0x%04x: 0x%04x,\n", e.value, e.r)
+	}
+	buf.WriteString("}\n\n")
+	buf.WriteString("// names maps keysym names (without the XK_ prefix) to their values.\n")
+	buf.WriteString("var names = map[string]Keysym{\n")
+	for _, name := range sorted {
+		fmt.Fprintf(&buf, "\t%q: 0x%04x,\n", name, names[name])
+	}
+	buf.WriteString("}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("table.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
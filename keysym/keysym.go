@@ -0,0 +1,112 @@
+// Package keysym converts between X11 keysyms and Unicode characters.
+package keysym
+
+import (
+	"strconv"
+	"strings"
+)
+
+//go:generate go run gen.go
+
+// Keysym is an X11 keysym value as used by the core protocol and XKB.
+type Keysym uint32
+
+// Frequently used non-character keysyms.
+const (
+	NoSymbol        Keysym = 0
+	ISOLevel3Shift  Keysym = 0xfe03
+	ModeSwitch      Keysym = 0xff7e
+	ShiftL          Keysym = 0xffe1
+	ControlL        Keysym = 0xffe3
+	AltL            Keysym = 0xffe9
+	SuperL          Keysym = 0xffeb
+	KeypadFirst     Keysym = 0xff80
+	KeypadLast      Keysym = 0xffbd
+	unicodeKeysyms  Keysym = 0x01000000
+	unicodeKeysymHi Keysym = 0x0110ffff
+)
+
+// IsKeypad reports whether k is on the numeric keypad.
+func IsKeypad(k Keysym) bool {
+	return k >= KeypadFirst && k <= KeypadLast
+}
+
+// ToRune returns the character produced by k, if any.
+func ToRune(k Keysym) (rune, bool) {
+	switch {
+	case k == NoSymbol:
+		return 0, false
+	case (k >= 0x20 && k <= 0x7e) || (k >= 0xa0 && k <= 0xff):
+		// Latin-1 keysyms equal their code point
+		return rune(k), true
+	case k >= unicodeKeysyms+0x100 && k <= unicodeKeysymHi:
+		return rune(k - unicodeKeysyms), true
+	}
+	if r, ok := legacyRunes[k]; ok {
+		return r, true
+	}
+	return 0, false
+}
+
+// FromRune returns the keysym for r. Latin-1 characters use their legacy
+// value, everything else the Unicode keysym range understood by all
+// current X servers and xkbcommon.
+func FromRune(r rune) Keysym {
+	switch {
+	case (r >= 0x20 && r <= 0x7e) || (r >= 0xa0 && r <= 0xff):
+		return Keysym(r)
+	case r == '\n' || r == '\r':
+		return 0xff0d // Return
+	case r == '\t':
+		return 0xff09 // Tab
+	case r == '\b':
+		return 0xff08 // BackSpace
+	default:
+		return unicodeKeysyms + Keysym(r)
+	}
+}
+
+// deadRunes maps dead key keysyms to the spacing form of their accent.
+var deadRunes = map[Keysym]rune{
+	0xfe50: '`', // dead_grave
+	0xfe51: '´', // dead_acute
+	0xfe52: '^', // dead_circumflex
+	0xfe53: '~', // dead_tilde
+	0xfe54: '¯', // dead_macron
+	0xfe55: '˘', // dead_breve
+	0xfe56: '˙', // dead_abovedot
+	0xfe57: '¨', // dead_diaeresis
+	0xfe58: '°', // dead_abovering
+	0xfe59: '˝', // dead_doubleacute
+	0xfe5a: 'ˇ', // dead_caron
+	0xfe5b: '¸', // dead_cedilla
+	0xfe5c: '˛', // dead_ogonek
+	0xfe5d: 'ͺ', // dead_iota
+}
+
+// DeadRune returns the spacing form of the accent of dead key k, e.g. '^'
+// for dead_circumflex.
+func DeadRune(k Keysym) (rune, bool) {
+	r, ok := deadRunes[k]
+	return r, ok
+}
+
+// FromName returns the keysym for a name as written in XKB files: a
+// keysymdef.h name without the XK_ prefix, "U" and a hex code point, or a
+// hex value starting with "0x".
+func FromName(name string) (Keysym, bool) {
+	if k, ok := names[name]; ok {
+		return k, true
+	}
+	switch {
+	case len(name) > 1 && name[0] == 'U':
+		if v, err := strconv.ParseUint(name[1:], 16, 32); err == nil && v <= 0x10ffff {
+			return FromRune(rune(v)), true
+		}
+	case strings.HasPrefix(name, "0x"):
+		if v, err := strconv.ParseUint(name[2:], 16, 32); err == nil {
+			return Keysym(v), true
+		}
+	}
+	return NoSymbol, false
+}
@@ -0,0 +1,26 @@
+//go:build windows || darwin || linux
+
+package main
+
+import (
+	"sync"
+
+	"goclip/localization"
+)
+
+var (
+	labelSetMu      sync.RWMutex
+	currentLabelSet localization.LabelSet
+)
+
+func setCurrentLabelSet(ls localization.LabelSet) {
+	labelSetMu.Lock()
+	currentLabelSet = ls
+	labelSetMu.Unlock()
+}
+
+func getCurrentLabelSet() localization.LabelSet {
+	labelSetMu.RLock()
+	defer labelSetMu.RUnlock()
+	return currentLabelSet
+}
@@ -0,0 +1,97 @@
+//go:build windows || linux
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"goclip/config"
+	"goclip/instance"
+)
+
+// launchRequest is what goclip is asked to do by its arguments, either
+// when it starts or when a later launch forwards them.
+type launchRequest struct {
+	typeFile string // type this file into the selected or last active window
+	openFile string // open this file: a procedure file (.toml) as procedure, anything else as the text to type
+}
+
+// parseLaunchArgs reads the arguments of the GUI. Relative paths are
+// resolved against dir, the working directory of the launch.
+func parseLaunchArgs(args []string, dir string) (launchRequest, error) {
+	var r launchRequest
+	fs := flag.NewFlagSet("goclip", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&r.typeFile, "type-file", "", "")
+	if err := fs.Parse(args); err != nil {
+		return r, err
+	}
+	switch {
+	case fs.NArg() > 1:
+		return r, errors.New("give only one file to open")
+	case fs.NArg() == 1 && r.typeFile != "":
+		return r, errors.New("give either --type-file or a file to open, not both")
+	case fs.NArg() == 1:
+		r.openFile = fs.Arg(0)
+	}
+	for _, p := range []*string{&r.typeFile, &r.openFile} {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(dir, *p)
+		}
+	}
+	return r, nil
+}
+
+// printLaunchUsage describes the arguments of the GUI.
+func printLaunchUsage(w io.Writer) {
+	fmt.Fprint(w, `Usage: goclip [--type-file file | file]
+       goclip type [options] [text]
+
+  --type-file file  type the file into the window selected in goclip, or
+                    else the last active one
+  file              open a procedure file (.toml), or load any other file
+                    as the text to type
+
+If goclip is already running, the arguments are handed to it and this
+launch exits. See goclip type -h for typing without the GUI.
+`)
+}
+
+// exitLaunchError reports an error of the launch arguments or of handing
+// them to the running goclip and exits.
+func exitLaunchError(err error, code int) {
+	attachParentConsole()
+	fmt.Fprintf(os.Stderr, "goclip: %v\n", err)
+	os.Exit(code)
+}
+
+// claimInstance makes this launch the running goclip and returns its lock
+// and arguments. If another goclip is running, the arguments are handed to
+// it and the process exits. If the lock cannot be taken for another
+// reason, goclip runs without it and the error is returned.
+func claimInstance(args []string) (*instance.Lock, launchRequest, error) {
+	dir, _ := os.Getwd()
+	launch, err := parseLaunchArgs(args, dir)
+	if errors.Is(err, flag.ErrHelp) {
+		attachParentConsole()
+		printLaunchUsage(os.Stderr)
+		os.Exit(exitCompleted)
+	}
+	if err != nil {
+		exitLaunchError(err, exitUsage)
+	}
+
+	lock, err := instance.Acquire(config.GetInstanceLockPath())
+	if errors.Is(err, instance.ErrRunning) {
+		if err := instance.Forward(config.GetInstanceSocketPath(), args); err != nil {
+			exitLaunchError(err, exitFailed)
+		}
+		os.Exit(exitCompleted)
+	}
+	return lock, launch, err
+}
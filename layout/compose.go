@@ -0,0 +1,114 @@
+package layout
+
+import (
+	"sort"
+
+	"goclip/keyplan"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// accents maps the spacing form of a dead key to the combining mark it puts
+// on the base character.
+var accents = map[rune]rune{
+	'`':  0x0300,
+	'´':  0x0301,
+	'\'': 0x0301, // US International
+	'^':  0x0302,
+	'~':  0x0303,
+	'¯':  0x0304,
+	'˘':  0x0306,
+	'˙':  0x0307,
+	'¨':  0x0308,
+	'"':  0x0308, // US International
+	'°':  0x030A,
+	'˝':  0x030B,
+	'ˇ':  0x030C,
+	'¸':  0x0327,
+	'˛':  0x0328,
+}
+
+// accentDeadKeys composes every dead key in entries with each base
+// character that has a precomposed form with its accent, the way X's
+// Compose tables do for XKB dead keys.
+func accentDeadKeys(entries []Entry) DeadKeys {
+	out := DeadKeys{}
+	for _, e := range entries {
+		mark, ok := accents[e.Rune]
+		if !e.Dead || !ok || out[e.Rune] != nil {
+			continue
+		}
+		table := map[rune]rune{}
+		for _, b := range entries {
+			if b.Dead {
+				continue
+			}
+			c := []rune(norm.NFC.String(string([]rune{b.Rune, mark})))
+			if len(c) == 1 && c[0] != b.Rune {
+				table[b.Rune] = c[0]
+			}
+		}
+		out[e.Rune] = table
+	}
+	return out
+}
+
+// deadPair is a dead key and the base key typed after it.
+type deadPair struct {
+	dead, base keyplan.Stroke
+}
+
+// LookupDead returns the dead key and base key that type r on l. It
+// implements keyplan.DeadKeyMapper.
+func (l *Layout) LookupDead(r rune) (dead, base keyplan.Stroke, ok bool) {
+	p, ok := l.composed[r]
+	return p.dead, p.base, ok
+}
+
+// composeIndex lists every character l can type with one dead key: the
+// compositions the layout defines, and each dead key's spacing form. Dead
+// keys and base characters are tried in table order; the first pair wins.
+func (l *Layout) composeIndex() map[rune]deadPair {
+	out := map[rune]deadPair{}
+	add := func(r rune, dead keyplan.Stroke, base rune) {
+		bs, ok := l.strokes[base]
+		if _, dup := out[r]; ok && !dup {
+			out[r] = deadPair{dead: dead, base: bs}
+		}
+	}
+	for _, e := range l.entries {
+		if !e.Dead {
+			continue
+		}
+		// the spacing form itself is dead key, then space
+		add(e.Rune, e.Stroke, ' ')
+
+		table := l.deadKeys[e.Rune]
+		bases := make([]rune, 0, len(table))
+		for b := range table {
+			bases = append(bases, b)
+		}
+		sort.Slice(bases, func(i, j int) bool { return bases[i] < bases[j] })
+		for _, b := range bases {
+			add(table[b], e.Stroke, b)
+		}
+	}
+	return out
+}
+
+// DeadKeysFor splits r into its base character and accent, and returns the
+// spacing forms of the dead keys that put that accent on a base character.
+// ok is false if r is not a base character with a single known accent.
+func DeadKeysFor(r rune) (base rune, deads []rune, ok bool) {
+	d := []rune(norm.NFD.String(string(r)))
+	if len(d) != 2 {
+		return 0, nil, false
+	}
+	for dead, mark := range accents {
+		if mark == d[1] {
+			deads = append(deads, dead)
+		}
+	}
+	sort.Slice(deads, func(i, j int) bool { return deads[i] < deads[j] })
+	return d[0], deads, len(deads) > 0
+}
@@ -0,0 +1,56 @@
+package layout_test
+
+import (
+	"testing"
+
+	"goclip/keyplan"
+	"goclip/layout"
+)
+
+func TestLookupDead(t *testing.T) {
+	key := func(code uint16, mods keyplan.Modifier) keyplan.Stroke {
+		return keyplan.Stroke{Key: keyplan.Key{Code: code}, Mods: mods}
+	}
+	tests := []struct {
+		layout     string
+		r          rune
+		dead, base keyplan.Stroke
+		ok         bool
+	}{
+		{"German (DE)", 'ê', key(0x29, 0), key(0x12, 0), true},
+		{"German (DE)", 'Ý', key(0x0D, 0), key(0x2C, keyplan.ModShift), true},
+		{"German (DE)", '^', key(0x29, 0), key(0x39, 0), true},
+		// German has no tilde dead key, and ´ does not go on consonants
+		{"German (DE)", 'ñ', keyplan.Stroke{}, keyplan.Stroke{}, false},
+		{"German (DE)", 'ć', keyplan.Stroke{}, keyplan.Stroke{}, false},
+		// ' and c is ç on US International, so there is no ć
+		{"US International", 'ç', key(0x28, 0), key(0x2E, 0), true},
+		{"US International", 'ć', keyplan.Stroke{}, keyplan.Stroke{}, false},
+		{"Polish (Programmers)", 'ł', key(0x29, keyplan.ModShift), key(0x26, 0), true},
+		{"Polish (Programmers)", 'ź', key(0x29, keyplan.ModShift), key(0x2D, 0), true},
+		{"Czech (CS)", 'ř', key(0x0D, keyplan.ModShift), key(0x13, 0), true},
+	}
+	for _, tt := range tests {
+		l, ok := layout.Get(tt.layout)
+		if !ok {
+			t.Fatalf("no built-in layout %q", tt.layout)
+		}
+		dead, base, ok := l.LookupDead(tt.r)
+		if ok != tt.ok || dead != tt.dead || base != tt.base {
+			t.Errorf("%s: LookupDead(%q) = %v, %v, %v, want %v, %v, %v", tt.layout, tt.r, dead, base, ok, tt.dead, tt.base, tt.ok)
+		}
+	}
+}
+
+func TestLookupDeadXKB(t *testing.T) {
+	l, err := layout.ParseXKB("de", "testdata/xkb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// X composes a dead key with any base that has a precomposed form
+	for _, r := range "êẑŷ" {
+		if _, _, ok := l.LookupDead(r); !ok {
+			t.Errorf("LookupDead(%q) failed", r)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package layout
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"goclip/keyplan"
+)
+
+// klcShiftStates maps the shift states of a KLC SHIFTSTATE section
+// (bit 1 Shift, bit 2 Ctrl, bit 4 Alt) to modifiers and the order their
+// entries are listed in. Ctrl+Alt is AltGr.
+var klcShiftStates = map[int]struct {
+	mods  keyplan.Modifier
+	order int
+}{
+	0: {0, 0},
+	1: {keyplan.ModShift, 1},
+	6: {keyplan.ModAltGr, 2},
+	7: {keyplan.ModShift | keyplan.ModAltGr, 3},
+	2: {keyplan.ModCtrl, 4},
+	3: {keyplan.ModShift | keyplan.ModCtrl, 5},
+}
+
+// ParseKLC reads a Microsoft Keyboard Layout Creator source file. The file
+// may be UTF-16 (as MSKLC saves it) or UTF-8. The layout is named after the
+// description on the KBD line.
+func ParseKLC(data []byte) (*Layout, error) {
+	text, err := decodeKLC(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		name     string
+		states   []int
+		levels   [6][]Entry
+		deadKeys = DeadKeys{}
+		section  string
+		dead     rune
+	)
+	for n, line := range strings.Split(text, "\n") {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], ";") {
+			continue
+		}
+		fail := func(format string, args ...any) error {
+			return fmt.Errorf("line %d: %s", n+1, fmt.Sprintf(format, args...))
+		}
+
+		switch kw := strings.ToUpper(fields[0]); kw {
+		case "KBD":
+			if len(fields) < 3 {
+				return nil, fail("KBD needs a name and a description")
+			}
+			name = strings.Trim(strings.Join(fields[2:], " "), `"`)
+			section = ""
+			continue
+		case "DEADKEY":
+			if len(fields) < 2 {
+				return nil, fail("DEADKEY without a character")
+			}
+			r, _, ok := klcChar(fields[1])
+			if !ok {
+				return nil, fail("bad dead key %q", fields[1])
+			}
+			dead = r
+			deadKeys[dead] = map[rune]rune{}
+			section = kw
+			continue
+		case "SHIFTSTATE", "LAYOUT", "LIGATURE", "KEYNAME", "KEYNAME_EXT", "KEYNAME_DEAD",
+			"DESCRIPTIONS", "LANGUAGENAMES", "ATTRIBUTES", "COPYRIGHT", "COMPANY",
+			"LOCALENAME", "LOCALEID", "VERSION":
+			section = kw
+			continue
+		case "ENDKBD":
+			section = kw
+		}
+
+		switch section {
+		case "SHIFTSTATE":
+			state, err := strconv.Atoi(fields[0])
+			if err != nil {
+				return nil, fail("bad shift state %q", fields[0])
+			}
+			states = append(states, state)
+		case "LAYOUT":
+			// SC VK Cap value per shift state; SGCap rows start with -1
+			if fields[0] == "-1" {
+				continue
+			}
+			if len(fields) < 3 {
+				return nil, fail("incomplete LAYOUT row")
+			}
+			key, err := klcScanCode(fields[0])
+			if err != nil {
+				return nil, fail("%v", err)
+			}
+			for i, tok := range fields[3:] {
+				if i >= len(states) {
+					break
+				}
+				ss, ok := klcShiftStates[states[i]]
+				if !ok {
+					continue
+				}
+				r, isDead, ok := klcChar(tok)
+				if !ok || unicode.IsControl(r) {
+					continue
+				}
+				levels[ss.order] = append(levels[ss.order], Entry{
+					Stroke: keyplan.Stroke{Key: key, Mods: ss.mods},
+					Rune:   r,
+					Dead:   isDead,
+				})
+			}
+		case "DEADKEY":
+			if len(fields) < 2 {
+				return nil, fail("incomplete DEADKEY row")
+			}
+			base, _, ok1 := klcChar(fields[0])
+			composed, _, ok2 := klcChar(fields[1])
+			if !ok1 || !ok2 {
+				return nil, fail("bad DEADKEY row")
+			}
+			deadKeys[dead][base] = composed
+		}
+	}
+
+	if name == "" {
+		return nil, errors.New("missing KBD line")
+	}
+	if len(states) == 0 {
+		return nil, errors.New("missing SHIFTSTATE section")
+	}
+	var entries []Entry
+	for _, level := range levels {
+		entries = append(entries, level...)
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("missing LAYOUT section")
+	}
+	return New(name, "", entries, deadKeys), nil
+}
+
+// decodeKLC converts the file to a string, honoring a UTF-16 byte order mark.
+func decodeKLC(data []byte) (string, error) {
+	var order func([]byte) uint16
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		order = func(b []byte) uint16 { return uint16(b[0]) | uint16(b[1])<<8 }
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		order = func(b []byte) uint16 { return uint16(b[1]) | uint16(b[0])<<8 }
+	default:
+		data = bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})
+		if !utf8.Valid(data) {
+			return "", errors.New("file is neither UTF-16 nor UTF-8")
+		}
+		return strings.ReplaceAll(string(data), "\r", ""), nil
+	}
+	data = data[2:]
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		units = append(units, order(data[i:]))
+	}
+	return strings.ReplaceAll(string(utf16.Decode(units)), "\r", ""), nil
+}
+
+// klcScanCode parses a LAYOUT scan code such as "1e" or "e01c".
+func klcScanCode(s string) (keyplan.Key, error) {
+	v, err := strconv.ParseUint(s, 16, 16)
+	if err != nil {
+		return keyplan.Key{}, fmt.Errorf("bad scan code %q", s)
+	}
+	switch {
+	case v <= 0x7F:
+		return keyplan.Key{Code: uint16(v)}, nil
+	case v&0xFF00 == 0xE000 && v&0xFF <= 0x7F:
+		return keyplan.Key{Code: uint16(v & 0xFF), Extended: true}, nil
+	}
+	return keyplan.Key{}, fmt.Errorf("bad scan code %q", s)
+}
+
+// klcChar parses a character cell: four hex digits or a literal character,
+// followed by "@" for a dead key. "-1" and "%%" (a ligature) yield false.
+func klcChar(s string) (r rune, dead bool, ok bool) {
+	if strings.HasSuffix(s, "@") && len(s) > 1 {
+		s, dead = s[:len(s)-1], true
+	}
+	if utf8.RuneCountInString(s) == 1 {
+		r, _ := utf8.DecodeRuneInString(s)
+		return r, dead, true
+	}
+	if len(s) == 4 {
+		if v, err := strconv.ParseUint(s, 16, 32); err == nil {
+			return rune(v), dead, true
+		}
+	}
+	return 0, false, false
+}
+
+// LoadDir parses every .klc file in dir, in name order. A missing directory
+// is not an error; files that fail to parse are reported together and the
+// others are still returned.
+func LoadDir(dir string) ([]*Layout, error) {
+	ents, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, e := range ents {
+		if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), ".klc") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var (
+		layouts []*Layout
+		errs    []error
+	)
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err == nil {
+			var l *Layout
+			if l, err = ParseKLC(data); err == nil {
+				layouts = append(layouts, l)
+				continue
+			}
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", name, err))
+	}
+	return layouts, errors.Join(errs...)
+}
@@ -0,0 +1,143 @@
+package layout_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"goclip/keyplan"
+	"goclip/layout"
+)
+
+// utf16File encodes text as UTF-16 with a byte order mark, as MSKLC saves
+// its files.
+func utf16File(text string, bigEndian bool) []byte {
+	units := utf16.Encode([]rune("\uFEFF" + text))
+	out := make([]byte, 0, 2*len(units))
+	for _, u := range units {
+		if bigEndian {
+			out = append(out, byte(u>>8), byte(u))
+		} else {
+			out = append(out, byte(u), byte(u>>8))
+		}
+	}
+	return out
+}
+
+func TestParseKLC(t *testing.T) {
+	data, err := os.ReadFile("testdata/klc/demini.klc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stroke := func(code uint16, mods keyplan.Modifier) keyplan.Stroke {
+		return keyplan.Stroke{Key: keyplan.Key{Code: code}, Mods: mods}
+	}
+	want := map[rune]keyplan.Stroke{
+		'1': stroke(0x02, 0),
+		'!': stroke(0x02, keyplan.ModShift),
+		'²': stroke(0x02, keyplan.ModAltGr),
+		'q': stroke(0x10, 0),
+		'Q': stroke(0x10, keyplan.ModShift),
+		'@': stroke(0x10, keyplan.ModAltGr), // a literal @, not a dead key marker
+		'€': stroke(0x12, keyplan.ModAltGr),
+		'z': stroke(0x15, 0),
+		'°': stroke(0x29, keyplan.ModShift),
+		' ': stroke(0x39, 0),
+		',': stroke(0x53, 0),
+		'/': {Key: keyplan.Key{Code: 0x35, Extended: true}},
+	}
+	dead := map[rune]keyplan.Stroke{
+		'^': stroke(0x29, 0),
+		'´': stroke(0x0D, 0),
+		'`': stroke(0x0D, keyplan.ModShift),
+	}
+	compose := []struct{ dead, base, want rune }{
+		{'^', 'a', 'â'},
+		{'´', 'e', 'é'},
+		{'`', 'a', 'à'},
+		{'´', ' ', '´'},
+	}
+
+	encodings := []struct {
+		name string
+		data []byte
+	}{
+		{"utf-8", data},
+		{"utf-8 bom", append([]byte{0xEF, 0xBB, 0xBF}, data...)},
+		{"utf-16le", utf16File(string(data), false)},
+		{"utf-16be", utf16File(string(data), true)},
+	}
+	for _, enc := range encodings {
+		t.Run(enc.name, func(t *testing.T) {
+			l, err := layout.ParseKLC(enc.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if l.Name != "German Mini" {
+				t.Errorf("name %q", l.Name)
+			}
+			for r, w := range want {
+				if got, ok := l.Lookup(r); !ok || got != w {
+					t.Errorf("Lookup(%q) = %v, %v, want %v", r, got, ok, w)
+				}
+			}
+			for r, w := range dead {
+				if got, ok := l.DeadKey(r); !ok || got != w {
+					t.Errorf("DeadKey(%q) = %v, %v, want %v", r, got, ok, w)
+				}
+				if got, ok := l.Lookup(r); ok {
+					t.Errorf("Lookup(%q) = %v, want only the dead key", r, got)
+				}
+			}
+			for _, c := range compose {
+				if got, ok := l.Compose(c.dead, c.base); !ok || got != c.want {
+					t.Errorf("Compose(%q, %q) = %q, %v, want %q", c.dead, c.base, got, ok, c.want)
+				}
+			}
+			// Ctrl columns hold control characters, -1 cells nothing
+			for _, r := range []rune{0x01, 0x11, 0x1A, 0x1E} {
+				if got, ok := l.Lookup(r); ok {
+					t.Errorf("Lookup(%q) = %v, want no key", r, got)
+				}
+			}
+		})
+	}
+}
+
+func TestParseKLCErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"no KBD", "SHIFTSTATE\n0\nLAYOUT\n1e A 1 a\n", "missing KBD line"},
+		{"no SHIFTSTATE", "KBD x \"X\"\nLAYOUT\n1e A 1 a\n", "missing SHIFTSTATE"},
+		{"no LAYOUT", "KBD x \"X\"\nSHIFTSTATE\n0\n", "missing LAYOUT"},
+		{"bad scan code", "KBD x \"X\"\nSHIFTSTATE\n0\nLAYOUT\nzz A 1 a\n", "line 5: bad scan code"},
+		{"bad shift state", "KBD x \"X\"\nSHIFTSTATE\nshift\n", "line 3: bad shift state"},
+		{"bad dead key", "KBD x \"X\"\nDEADKEY 12345\n", "line 2: bad dead key"},
+		{"not text", "KBD x \"X\"\xff\xfe\xfd", "neither UTF-16 nor UTF-8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := layout.ParseKLC([]byte(tt.text))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	layouts, err := layout.LoadDir("testdata/klc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layouts) != 1 || layouts[0].Name != "German Mini" {
+		t.Errorf("got %d layouts, want German Mini", len(layouts))
+	}
+	if layouts, err := layout.LoadDir("testdata/missing"); err != nil || layouts != nil {
+		t.Errorf("missing directory: got %v, %v", layouts, err)
+	}
+}
@@ -0,0 +1,212 @@
+// Package layout carries goclip's own keyboard layout tables. A layout maps
+// each character to the physical key (a keyplan.Key scan code position) and
+// modifiers that produce it on the target system, so any backend on any host
+// can type for any target layout without asking the OS.
+package layout
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"goclip/keyplan"
+)
+
+// Entry is one character or dead key produced by a key and modifier combination.
+type Entry struct {
+	Stroke keyplan.Stroke
+	Rune   rune
+	// Dead marks a dead key; Rune is then its spacing form (e.g. '^').
+	Dead bool
+}
+
+// Layout is an immutable keyboard layout table. It implements
+// keyplan.DeadKeyMapper.
+type Layout struct {
+	Name string
+	// KLID is the Windows keyboard layout identifier the table mirrors,
+	// empty if there is none.
+	KLID string
+
+	entries  []Entry
+	strokes  map[rune]keyplan.Stroke
+	dead     map[rune]keyplan.Stroke
+	deadKeys DeadKeys
+	composed map[rune]deadPair
+}
+
+// DeadKeys maps the spacing form of each dead key to the characters it
+// composes, by base character.
+type DeadKeys map[rune]map[rune]rune
+
+// commonKeys exist on every layout.
+var commonKeys = []Entry{
+	{Stroke: keyplan.Stroke{Key: keyplan.Key{Code: 0x39}}, Rune: ' '},
+	{Stroke: keyplan.Stroke{Key: keyplan.Key{Code: 0x0F}}, Rune: '\t'},
+}
+
+// New builds a layout from its entries and dead key compositions (which may
+// be nil). When several entries produce the same character, the first one
+// wins, so list plain keys before shifted and AltGr ones.
+func New(name, klid string, entries []Entry, deadKeys DeadKeys) *Layout {
+	l := &Layout{
+		Name:     name,
+		KLID:     klid,
+		entries:  append(append([]Entry(nil), entries...), commonKeys...),
+		strokes:  map[rune]keyplan.Stroke{},
+		dead:     map[rune]keyplan.Stroke{},
+		deadKeys: deadKeys,
+	}
+	for _, e := range l.entries {
+		index := l.strokes
+		if e.Dead {
+			index = l.dead
+		}
+		if _, ok := index[e.Rune]; !ok {
+			index[e.Rune] = e.Stroke
+		}
+	}
+	l.composed = l.composeIndex()
+	return l
+}
+
+// Lookup returns the stroke typing r directly. Dead keys are not returned,
+// since pressing one alone types nothing.
+func (l *Layout) Lookup(r rune) (keyplan.Stroke, bool) {
+	s, ok := l.strokes[r]
+	return s, ok
+}
+
+// DeadKey returns the stroke of the dead key whose spacing form is r.
+func (l *Layout) DeadKey(r rune) (keyplan.Stroke, bool) {
+	s, ok := l.dead[r]
+	return s, ok
+}
+
+// Compose returns the character the dead key dead produces before base.
+// It reports false if the layout defines no such composition.
+func (l *Layout) Compose(dead, base rune) (rune, bool) {
+	r, ok := l.deadKeys[dead][base]
+	return r, ok
+}
+
+// Renamed returns a copy of l with another name.
+func (l *Layout) Renamed(name string) *Layout {
+	c := *l
+	c.Name = name
+	return &c
+}
+
+// Entries returns every key of the layout in table order.
+func (l *Layout) Entries() []Entry {
+	return append([]Entry(nil), l.entries...)
+}
+
+// rowKeys are the scan codes of the character keys, row by row in physical
+// order: E (TLDE, 1..=, JIS yen), D (Q..]), C (A..', ISO #/\), B (ISO <,
+// Z../, JIS ro).
+var rowKeys = [4][]uint16{
+	{0x29, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0A, 0x0B, 0x0C, 0x0D, 0x7D},
+	{0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1A, 0x1B},
+	{0x1E, 0x1F, 0x20, 0x21, 0x22, 0x23, 0x24, 0x25, 0x26, 0x27, 0x28, 0x2B},
+	{0x56, 0x2C, 0x2D, 0x2E, 0x2F, 0x30, 0x31, 0x32, 0x33, 0x34, 0x35, 0x73},
+}
+
+// levelMods are the modifiers of the four table levels.
+var levelMods = [4]keyplan.Modifier{
+	0,
+	keyplan.ModShift,
+	keyplan.ModAltGr,
+	keyplan.ModShift | keyplan.ModAltGr,
+}
+
+// def is a layout in table form. Each level has one line per key row with
+// space-separated tokens: a single character, "--" for nothing, or a
+// character followed by "*" for a dead key. Lines may stop early. dead
+// lists what each dead key composes as space-separated base and result
+// pairs ("aá eé").
+type def struct {
+	name   string
+	klid   string
+	levels [4][4]string
+	dead   map[rune]string
+}
+
+func (d def) build() *Layout {
+	var entries []Entry
+	for level, rows := range d.levels {
+		for row, line := range rows {
+			for i, tok := range strings.Fields(line) {
+				if i >= len(rowKeys[row]) {
+					panic(fmt.Sprintf("layout %s: too many keys in row %d", d.name, row))
+				}
+				if tok == "--" {
+					continue
+				}
+				r, size := utf8.DecodeRuneInString(tok)
+				dead := tok[size:] == "*"
+				if size != len(tok) && !dead {
+					panic(fmt.Sprintf("layout %s: bad token %q", d.name, tok))
+				}
+				if _, ok := d.dead[r]; dead && !ok {
+					panic(fmt.Sprintf("layout %s: no compositions for dead key %q", d.name, r))
+				}
+				entries = append(entries, Entry{
+					Stroke: keyplan.Stroke{Key: keyplan.Key{Code: rowKeys[row][i]}, Mods: levelMods[level]},
+					Rune:   r,
+					Dead:   dead,
+				})
+			}
+		}
+	}
+	var deadKeys DeadKeys
+	if len(d.dead) > 0 {
+		deadKeys = DeadKeys{}
+	}
+	for dead, pairs := range d.dead {
+		deadKeys[dead] = map[rune]rune{}
+		for _, pair := range strings.Fields(pairs) {
+			p := []rune(pair)
+			if len(p) != 2 {
+				panic(fmt.Sprintf("layout %s: bad composition %q", d.name, pair))
+			}
+			deadKeys[dead][p[0]] = p[1]
+		}
+	}
+	return New(d.name, d.klid, entries, deadKeys)
+}
+
+var (
+	builtins = func() []*Layout {
+		out := make([]*Layout, len(tables))
+		for i, d := range tables {
+			out[i] = d.build()
+		}
+		return out
+	}()
+	byName = func() map[string]*Layout {
+		m := make(map[string]*Layout, len(builtins))
+		for _, l := range builtins {
+			m[l.Name] = l
+		}
+		return m
+	}()
+)
+
+// US is the English (US) layout, the fallback when nothing better is known.
+var US = byName["English (US)"]
+
+// Get returns the built-in layout with the given name.
+func Get(name string) (*Layout, bool) {
+	l, ok := byName[name]
+	return l, ok
+}
+
+// Names returns the names of the built-in layouts in menu order.
+func Names() []string {
+	names := make([]string, len(builtins))
+	for i, l := range builtins {
+		names[i] = l.Name
+	}
+	return names
+}
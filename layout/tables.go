@@ -0,0 +1,665 @@
+package layout
+
+// tables lists the built-in layouts in menu order. Levels are normal,
+// Shift, AltGr and Shift+AltGr; see def for the token syntax.
+var tables = []def{
+	{
+		name: "English (US)", klid: "00000409",
+		levels: [4][4]string{
+			{
+				"` 1 2 3 4 5 6 7 8 9 0 - =",
+				"q w e r t y u i o p [ ]",
+				"a s d f g h j k l ; ' \\",
+				"\\ z x c v b n m , . /",
+			},
+			{
+				"~ ! @ # $ % ^ & * ( ) _ +",
+				"Q W E R T Y U I O P { }",
+				"A S D F G H J K L : \" |",
+				"| Z X C V B N M < > ?",
+			},
+		},
+	},
+	{
+		name: "US International", klid: "00020409",
+		dead: usIntlDead,
+		levels: [4][4]string{
+			{
+				"`* 1 2 3 4 5 6 7 8 9 0 - =",
+				"q w e r t y u i o p [ ]",
+				"a s d f g h j k l ; '* \\",
+				"\\ z x c v b n m , . /",
+			},
+			{
+				"~* ! @ # $ % ^* & * ( ) _ +",
+				"Q W E R T Y U I O P { }",
+				"A S D F G H J K L : \"* |",
+				"| Z X C V B N M < > ?",
+			},
+			{
+				"-- ¡ ² ³ ¤ € ¼ ½ ¾ ‘ ’ ¥ ×",
+				"ä å é ® þ ü ú í ó ö « »",
+				"á ß ð -- -- -- -- -- ø ¶ ´ ¬",
+				"-- æ -- © -- -- ñ µ ç -- ¿",
+			},
+			{
+				"-- ¹ -- -- £ -- -- -- -- -- -- -- ÷",
+				"Ä Å É -- Þ Ü Ú Í Ó Ö -- --",
+				"Á § Ð -- -- -- -- -- Ø ° ¨ ¦",
+				"-- Æ -- ¢ -- -- Ñ -- Ç",
+			},
+		},
+	},
+	{
+		name: "English (UK)", klid: "00000809",
+		levels: [4][4]string{
+			{
+				"` 1 2 3 4 5 6 7 8 9 0 - =",
+				"q w e r t y u i o p [ ]",
+				"a s d f g h j k l ; ' #",
+				"\\ z x c v b n m , . /",
+			},
+			{
+				"¬ ! \" £ $ % ^ & * ( ) _ +",
+				"Q W E R T Y U I O P { }",
+				"A S D F G H J K L : @ ~",
+				"| Z X C V B N M < > ?",
+			},
+			{
+				"¦ -- -- -- €",
+				"-- -- é -- -- -- ú í ó",
+				"á",
+			},
+			{
+				"",
+				"-- -- É -- -- -- Ú Í Ó",
+				"Á",
+			},
+		},
+	},
+	{
+		name: "German (DE)", klid: "00000407",
+		dead: westernDead,
+		levels: [4][4]string{
+			{
+				"^* 1 2 3 4 5 6 7 8 9 0 ß ´*",
+				"q w e r t z u i o p ü +",
+				"a s d f g h j k l ö ä #",
+				"< y x c v b n m , . -",
+			},
+			{
+				"° ! \" § $ % & / ( ) = ? `*",
+				"Q W E R T Z U I O P Ü *",
+				"A S D F G H J K L Ö Ä '",
+				"> Y X C V B N M ; : _",
+			},
+			{
+				"-- -- ² ³ -- -- -- { [ ] } \\",
+				"@ -- € -- -- -- -- -- -- -- -- ~",
+				"",
+				"| -- -- -- -- -- -- µ",
+			},
+		},
+	},
+	{
+		name: "French (FR)", klid: "0000040C",
+		dead: westernDead,
+		levels: [4][4]string{
+			{
+				"² & é \" ' ( - è _ ç à ) =",
+				"a z e r t y u i o p ^* $",
+				"q s d f g h j k l m ù *",
+				"< w x c v b n , ; : !",
+			},
+			{
+				"-- 1 2 3 4 5 6 7 8 9 0 ° +",
+				"A Z E R T Y U I O P ¨* £",
+				"Q S D F G H J K L M % µ",
+				"> W X C V B N ? . / §",
+			},
+			{
+				"-- -- ~* # { [ | `* \\ ^ @ ] }",
+				"-- -- € -- -- -- -- -- -- -- -- ¤",
+			},
+		},
+	},
+	{
+		name: "Spanish (ES)", klid: "0000040A",
+		dead: westernDead,
+		levels: [4][4]string{
+			{
+				"º 1 2 3 4 5 6 7 8 9 0 ' ¡",
+				"q w e r t y u i o p `* +",
+				"a s d f g h j k l ñ ´* ç",
+				"< z x c v b n m , . -",
+			},
+			{
+				"ª ! \" · $ % & / ( ) = ? ¿",
+				"Q W E R T Y U I O P ^* *",
+				"A S D F G H J K L Ñ ¨* Ç",
+				"> Z X C V B N M ; : _",
+			},
+			{
+				"\\ | @ # ~* -- ¬",
+				"-- -- € -- -- -- -- -- -- -- [ ]",
+				"-- -- -- -- -- -- -- -- -- -- { }",
+			},
+		},
+	},
+	{
+		name: "Italian (IT)", klid: "00000410",
+		levels: [4][4]string{
+			{
+				"\\ 1 2 3 4 5 6 7 8 9 0 ' ì",
+				"q w e r t y u i o p è +",
+				"a s d f g h j k l ò à ù",
+				"< z x c v b n m , . -",
+			},
+			{
+				"| ! \" £ $ % & / ( ) = ? ^",
+				"Q W E R T Y U I O P é *",
+				"A S D F G H J K L ç ° §",
+				"> Z X C V B N M ; : _",
+			},
+			{
+				"",
+				"-- -- € -- -- -- -- -- -- -- [ ]",
+				"-- -- -- -- -- -- -- -- -- @ #",
+			},
+			{
+				"",
+				"-- -- -- -- -- -- -- -- -- -- { }",
+			},
+		},
+	},
+	{
+		name: "Dutch (NL)", klid: "00000413",
+		dead: dutchDead,
+		levels: [4][4]string{
+			{
+				"@ 1 2 3 4 5 6 7 8 9 0 / °",
+				"q w e r t y u i o p ¨* *",
+				"a s d f g h j k l + ´* <",
+				"] z x c v b n m , . -",
+			},
+			{
+				"§ ! \" # $ % & _ ( ) ' ? ~*",
+				"Q W E R T Y U I O P ^* |",
+				"A S D F G H J K L ± `* >",
+				"[ Z X C V B N M ; : =",
+			},
+			{
+				"¬ ¹ ² ³ ¼ ½ ¾ £ { } -- \\ ¸*",
+				"-- -- € ¶",
+				"-- ß",
+				"¦ « » ¢ -- -- -- µ",
+			},
+		},
+	},
+	{
+		name: "Portuguese (BR - ABNT2)", klid: "00010416",
+		dead: westernDead,
+		levels: [4][4]string{
+			{
+				"' 1 2 3 4 5 6 7 8 9 0 - =",
+				"q w e r t y u i o p ´* [",
+				"a s d f g h j k l ç ~* ]",
+				"\\ z x c v b n m , . ; /",
+			},
+			{
+				"\" ! @ # $ % ¨* & * ( ) _ +",
+				"Q W E R T Y U I O P `* {",
+				"A S D F G H J K L Ç ^* }",
+				"| Z X C V B N M < > : ?",
+			},
+			{
+				"-- ¹ ² ³ £ ¢ ¬ -- -- -- -- -- §",
+				"/ ? ° -- -- -- -- -- -- -- -- ª",
+				"-- -- -- -- -- -- -- -- -- -- -- º",
+				"-- -- -- ₢ -- -- -- -- -- -- -- °",
+			},
+		},
+	},
+	{
+		name: "Portuguese (PT)", klid: "00000816",
+		dead: westernDead,
+		levels: [4][4]string{
+			{
+				"\\ 1 2 3 4 5 6 7 8 9 0 ' «",
+				"q w e r t y u i o p + ´*",
+				"a s d f g h j k l ç º ~*",
+				"< z x c v b n m , . -",
+			},
+			{
+				"| ! \" # $ % & / ( ) = ? »",
+				"Q W E R T Y U I O P * `*",
+				"A S D F G H J K L Ç ª ^*",
+				"> Z X C V B N M ; : _",
+			},
+			{
+				"-- -- @ £ § -- -- { [ ] }",
+				"-- -- € -- -- -- -- -- -- -- ¨*",
+			},
+		},
+	},
+	{
+		name: "Danish (DA)", klid: "00000406",
+		dead: westernDead,
+		levels: [4][4]string{
+			{
+				"½ 1 2 3 4 5 6 7 8 9 0 + ´*",
+				"q w e r t y u i o p å ¨*",
+				"a s d f g h j k l æ ø '",
+				"< z x c v b n m , . -",
+			},
+			{
+				"§ ! \" # ¤ % & / ( ) = ? `*",
+				"Q W E R T Y U I O P Å ^*",
+				"A S D F G H J K L Æ Ø *",
+				"> Z X C V B N M ; : _",
+			},
+			{
+				"-- -- @ £ $ € -- { [ ] } -- |",
+				"-- -- € -- -- -- -- -- -- -- -- ~*",
+				"",
+				"\\ -- -- -- -- -- -- µ",
+			},
+		},
+	},
+	{
+		name: "Swedish (SV)", klid: "0000041D",
+		dead:   westernDead,
+		levels: swedishFinnish,
+	},
+	{
+		name: "Finnish (FI)", klid: "0000040B",
+		dead:   westernDead,
+		levels: swedishFinnish,
+	},
+	{
+		name: "Norwegian (NO)", klid: "00000414",
+		dead: westernDead,
+		levels: [4][4]string{
+			{
+				"| 1 2 3 4 5 6 7 8 9 0 + \\",
+				"q w e r t y u i o p å ¨*",
+				"a s d f g h j k l ø æ '",
+				"< z x c v b n m , . -",
+			},
+			{
+				"§ ! \" # ¤ % & / ( ) = ? `*",
+				"Q W E R T Y U I O P Å ^*",
+				"A S D F G H J K L Ø Æ *",
+				"> Z X C V B N M ; : _",
+			},
+			{
+				"-- -- @ £ $ € -- { [ ] } -- ´*",
+				"-- -- € -- -- -- -- -- -- -- -- ~*",
+				"",
+				"-- -- -- -- -- -- -- µ",
+			},
+		},
+	},
+	{
+		name: "Swiss German (DE-CH)", klid: "00000807",
+		dead: westernDead,
+		levels: [4][4]string{
+			{
+				"§ 1 2 3 4 5 6 7 8 9 0 ' ^*",
+				"q w e r t z u i o p ü ¨*",
+				"a s d f g h j k l ö ä $",
+				"< y x c v b n m , . -",
+			},
+			{
+				"° + \" * ç % & / ( ) = ? `*",
+				"Q W E R T Z U I O P è !",
+				"A S D F G H J K L é à £",
+				"> Y X C V B N M ; : _",
+			},
+			swissAltGr,
+		},
+	},
+	{
+		name: "Swiss French (FR-CH)", klid: "0000100C",
+		dead: westernDead,
+		levels: [4][4]string{
+			{
+				"§ 1 2 3 4 5 6 7 8 9 0 ' ^*",
+				"q w e r t z u i o p è ¨*",
+				"a s d f g h j k l é à $",
+				"< y x c v b n m , . -",
+			},
+			{
+				"° + \" * ç % & / ( ) = ? `*",
+				"Q W E R T Z U I O P ü !",
+				"A S D F G H J K L ö ä £",
+				"> Y X C V B N M ; : _",
+			},
+			swissAltGr,
+		},
+	},
+	{
+		name: "Polish (Programmers)", klid: "00000415",
+		dead: polishDead,
+		levels: [4][4]string{
+			{
+				"` 1 2 3 4 5 6 7 8 9 0 - =",
+				"q w e r t y u i o p [ ]",
+				"a s d f g h j k l ; ' \\",
+				"\\ z x c v b n m , . /",
+			},
+			{
+				"~* ! @ # $ % ^ & * ( ) _ +",
+				"Q W E R T Y U I O P { }",
+				"A S D F G H J K L : \" |",
+				"| Z X C V B N M < > ?",
+			},
+			{
+				"",
+				"-- -- ę -- -- -- € -- ó",
+				"ą ś -- -- -- -- -- -- ł",
+				"-- ż ź ć -- -- ń",
+			},
+			{
+				"",
+				"-- -- Ę -- -- -- -- -- Ó",
+				"Ą Ś -- -- -- -- -- -- Ł",
+				"-- Ż Ź Ć -- -- Ń",
+			},
+		},
+	},
+	{
+		name: "Czech (CS)", klid: "00000405",
+		dead: czechSlovakDead,
+		levels: [4][4]string{
+			{
+				"; + ě š č ř ž ý á í é = ´*",
+				"q w e r t z u i o p ú )",
+				"a s d f g h j k l ů § ¨*",
+				"\\ y x c v b n m , . -",
+			},
+			{
+				"° 1 2 3 4 5 6 7 8 9 0 % ˇ*",
+				"Q W E R T Z U I O P / (",
+				"A S D F G H J K L \" ! '",
+				"| Y X C V B N M ? : _",
+			},
+			{
+				"-- ~ -- -- -- -- -- `",
+				"\\ | € -- -- -- -- -- -- -- ÷ ×",
+				"-- đ Đ [ ] -- -- ł Ł $ ß ¤",
+				"-- -- # & @ { } -- < > *",
+			},
+		},
+	},
+	{
+		name: "Slovak (SK)", klid: "0000041B",
+		dead: czechSlovakDead,
+		levels: [4][4]string{
+			{
+				"; + ľ š č ť ž ý á í é = ´*",
+				"q w e r t z u i o p ú ä",
+				"a s d f g h j k l ô § ň",
+				"& y x c v b n m , . -",
+			},
+			{
+				"° 1 2 3 4 5 6 7 8 9 0 % ˇ*",
+				"Q W E R T Z U I O P / (",
+				"A S D F G H J K L \" ! )",
+				"* Y X C V B N M ? : _",
+			},
+			{
+				"-- ~ -- -- -- -- -- `",
+				"\\ | € -- -- -- -- -- -- -- ÷ ×",
+				"-- đ Đ [ ] -- -- ł Ł $ ß ¤",
+				"-- -- # & @ { } -- < > *",
+			},
+		},
+	},
+	{
+		name: "Hungarian (HU)", klid: "0000040E",
+		dead: hungarianDead,
+		levels: [4][4]string{
+			{
+				"0 1 2 3 4 5 6 7 8 9 ö ü ó",
+				"q w e r t z u i o p ő ú",
+				"a s d f g h j k l é á ű",
+				"í y x c v b n m , . -",
+			},
+			{
+				"§ ' \" + ! % / = ( ) Ö Ü Ó",
+				"Q W E R T Z U I O P Ő Ú",
+				"A S D F G H J K L É Á Ű",
+				"Í Y X C V B N M ? : _",
+			},
+			{
+				"-- ~ ˇ* ^* ˘* °* ˛* ` ˙* ´* ˝* ¨* ¸*",
+				"\\ | Ä -- -- -- € Í -- -- ÷ ×",
+				"ä đ Đ [ ] -- í ł Ł $ ß ¤",
+				"< > # & @ { } -- ; -- *",
+			},
+		},
+	},
+	{
+		name: "Turkish (Q)", klid: "0000041F",
+		dead: westernDead,
+		levels: [4][4]string{
+			{
+				"\" 1 2 3 4 5 6 7 8 9 0 * -",
+				"q w e r t y u ı o p ğ ü",
+				"a s d f g h j k l ş i ,",
+				"< z x c v b n m ö ç .",
+			},
+			{
+				"é ! ' ^* + % & / ( ) = ? _",
+				"Q W E R T Y U I O P Ğ Ü",
+				"A S D F G H J K L Ş İ ;",
+				"> Z X C V B N M Ö Ç :",
+			},
+			{
+				"-- -- £ # $ -- -- { [ ] } \\ |",
+				"@ -- € -- ₺ -- -- -- -- -- ¨* ~*",
+				"æ ß -- -- -- -- -- -- -- ´* -- `*",
+				"|",
+			},
+		},
+	},
+	{
+		name: "Russian (RU)", klid: "00000419",
+		levels: [4][4]string{
+			{
+				"ё 1 2 3 4 5 6 7 8 9 0 - =",
+				"й ц у к е н г ш щ з х ъ",
+				"ф ы в а п р о л д ж э \\",
+				"\\ я ч с м и т ь б ю .",
+			},
+			{
+				"Ё ! \" № ; % : ? * ( ) _ +",
+				"Й Ц У К Е Н Г Ш Щ З Х Ъ",
+				"Ф Ы В А П Р О Л Д Ж Э /",
+				"/ Я Ч С М И Т Ь Б Ю ,",
+			},
+			{
+				"-- -- -- -- -- -- -- -- ₽",
+			},
+		},
+	},
+	{
+		name: "Ukrainian (UK)", klid: "00000422",
+		levels: [4][4]string{
+			{
+				"' 1 2 3 4 5 6 7 8 9 0 - =",
+				"й ц у к е н г ш щ з х ї",
+				"ф і в а п р о л д ж є ґ",
+				"/ я ч с м и т ь б ю .",
+			},
+			{
+				"~ ! \" № ; % : ? * ( ) _ +",
+				"Й Ц У К Е Н Г Ш Щ З Х Ї",
+				"Ф І В А П Р О Л Д Ж Є Ґ",
+				"| Я Ч С М И Т Ь Б Ю ,",
+			},
+		},
+	},
+	{
+		name: "Hebrew (HE)", klid: "0000040D",
+		levels: [4][4]string{
+			{
+				"; 1 2 3 4 5 6 7 8 9 0 - =",
+				"/ ' ק ר א ט ו ן ם פ ] [",
+				"ש ד ג כ ע י ח ל ך ף , \\",
+				"\\ ז ס ב ה נ מ צ ת ץ .",
+			},
+			{
+				"~ ! @ # $ % ^ & * ) ( _ +",
+				"Q W E R T Y U I O P } {",
+				"A S D F G H J K L : \" |",
+				"| Z X C V B N M > < ?",
+			},
+			{
+				"-- -- -- -- ₪",
+			},
+		},
+	},
+	{
+		name: "Arabic (AR)", klid: "00000401",
+		levels: [4][4]string{
+			{
+				"ذ 1 2 3 4 5 6 7 8 9 0 - =",
+				"ض ص ث ق ف غ ع ه خ ح ج د",
+				"ش س ي ب ل ا ت ن م ك ط \\",
+				"-- ئ ء ؤ ر -- ى ة و ز ظ",
+			},
+			{
+				"\u0651 ! @ # $ % ^ & * ) ( _ +",
+				"\u064e \u064b \u064f \u064c -- إ ` ÷ × ؛ < >",
+				"\u0650 \u064d ] [ -- أ ـ ، / : \" |",
+				"-- ~ \u0652 } { -- آ ' , . ؟",
+			},
+		},
+	},
+	{
+		name: "Japanese (JP)", klid: "00000411",
+		levels: [4][4]string{
+			{
+				"-- 1 2 3 4 5 6 7 8 9 0 - ^ \\",
+				"q w e r t y u i o p @ [",
+				"a s d f g h j k l ; : ]",
+				"-- z x c v b n m , . / \\",
+			},
+			{
+				"-- ! \" # $ % & ' ( ) -- = ~ |",
+				"Q W E R T Y U I O P ` {",
+				"A S D F G H J K L + * }",
+				"-- Z X C V B N M < > ? _",
+			},
+		},
+	},
+	{
+		name: "Korean (KO)", klid: "00000412",
+		levels: [4][4]string{
+			{
+				"` 1 2 3 4 5 6 7 8 9 0 - =",
+				"q w e r t y u i o p [ ]",
+				"a s d f g h j k l ; ' \\",
+				"\\ z x c v b n m , . /",
+			},
+			{
+				"~ ! @ # $ % ^ & * ( ) _ +",
+				"Q W E R T Y U I O P { }",
+				"A S D F G H J K L : \" |",
+				"| Z X C V B N M < > ?",
+			},
+		},
+	},
+}
+
+// Swedish and Finnish share one Windows layout.
+var swedishFinnish = [4][4]string{
+	{
+		"§ 1 2 3 4 5 6 7 8 9 0 + ´*",
+		"q w e r t y u i o p å ¨*",
+		"a s d f g h j k l ö ä '",
+		"< z x c v b n m , . -",
+	},
+	{
+		"½ ! \" # ¤ % & / ( ) = ? `*",
+		"Q W E R T Y U I O P Å ^*",
+		"A S D F G H J K L Ö Ä *",
+		"> Z X C V B N M ; : _",
+	},
+	{
+		"-- -- @ £ $ € -- { [ ] } \\",
+		"-- -- € -- -- -- -- -- -- -- -- ~*",
+		"",
+		"| -- -- -- -- -- -- µ",
+	},
+}
+
+// swissAltGr is the AltGr level shared by both Swiss layouts.
+var swissAltGr = [4]string{
+	"-- ¦ @ # ° § ¬ | ¢ -- -- ´* ~*",
+	"-- -- € -- -- -- -- -- -- -- [ ]",
+	"-- -- -- -- -- -- -- -- -- -- { }",
+	"\\",
+}
+
+// Dead key compositions, as base and result pairs (see def).
+const (
+	graveVowels      = "aà eè iì oò uù AÀ EÈ IÌ OÒ UÙ"
+	acuteVowels      = "aá eé ií oó uú yý AÁ EÉ IÍ OÓ UÚ YÝ"
+	circumflexVowels = "aâ eê iî oô uû AÂ EÊ IÎ OÔ UÛ"
+	tildeANO         = "aã nñ oõ AÃ NÑ OÕ"
+	diaeresisVowels  = "aä eë iï oö uü yÿ AÄ EË IÏ OÖ UÜ"
+)
+
+// westernDead are the dead keys of the Western European layouts.
+var westernDead = map[rune]string{
+	'`': graveVowels,
+	'´': acuteVowels,
+	'^': circumflexVowels,
+	'~': tildeANO,
+	'¨': diaeresisVowels,
+}
+
+// usIntlDead types ç with the apostrophe, not ć.
+var usIntlDead = map[rune]string{
+	'`':  graveVowels,
+	'\'': acuteVowels + " cç CÇ",
+	'^':  circumflexVowels,
+	'~':  tildeANO,
+	'"':  diaeresisVowels,
+}
+
+var dutchDead = map[rune]string{
+	'`': graveVowels,
+	'´': acuteVowels,
+	'^': circumflexVowels,
+	'~': tildeANO,
+	'¨': diaeresisVowels,
+	'¸': "cç CÇ",
+}
+
+// polishDead puts the Polish diacritics on their Latin letters; ~x is ź.
+var polishDead = map[rune]string{
+	'~': "aą cć eę lł nń oó sś xź zż AĄ CĆ EĘ LŁ NŃ OÓ SŚ XŹ ZŻ",
+}
+
+var czechSlovakDead = map[rune]string{
+	'´': acuteVowels + " cć lĺ nń rŕ sś zź CĆ LĹ NŃ RŔ SŚ ZŹ",
+	'ˇ': "cč dď eě lľ nň rř sš tť zž CČ DĎ EĚ LĽ NŇ RŘ SŠ TŤ ZŽ",
+	'¨': "aä eë oö uü AÄ EË OÖ UÜ",
+}
+
+var hungarianDead = map[rune]string{
+	'´': acuteVowels + " cć lĺ nń rŕ sś zź CĆ LĹ NŃ RŔ SŚ ZŹ",
+	'ˇ': "cč dď eě lľ nň rř sš tť zž CČ DĎ EĚ LĽ NŇ RŘ SŠ TŤ ZŽ",
+	'^': "aâ iî oô AÂ IÎ OÔ",
+	'˘': "aă AĂ",
+	'°': "uů UŮ",
+	'˛': "aą eę AĄ EĘ",
+	'˙': "zż ZŻ",
+	'˝': "oő uű OŐ UŰ",
+	'¨': "aä eë oö uü AÄ EË OÖ UÜ",
+	'¸': "cç sş CÇ SŞ",
+}
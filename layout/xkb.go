@@ -0,0 +1,503 @@
+package layout
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"goclip/keyplan"
+	"goclip/keysym"
+)
+
+// DefaultXKBDir is where xkeyboard-config installs its symbols files.
+const DefaultXKBDir = "/usr/share/X11/xkb/symbols"
+
+// maxXKBIncludes bounds include nesting, which also stops include loops.
+const maxXKBIncludes = 16
+
+// xkbKeyNames maps XKB key names to the scan codes of rowKeys.
+var xkbKeyNames = func() map[string]keyplan.Key {
+	m := map[string]keyplan.Key{
+		"TLDE": {Code: 0x29},
+		"AE13": {Code: 0x7D},
+		"BKSL": {Code: 0x2B},
+		"AC12": {Code: 0x2B},
+		"LSGT": {Code: 0x56},
+		"AB11": {Code: 0x73},
+		"SPCE": {Code: 0x39},
+	}
+	for i, code := range rowKeys[0][1:13] {
+		m[fmt.Sprintf("AE%02d", i+1)] = keyplan.Key{Code: code}
+	}
+	for i, code := range rowKeys[1] {
+		m[fmt.Sprintf("AD%02d", i+1)] = keyplan.Key{Code: code}
+	}
+	for i, code := range rowKeys[2][:11] {
+		m[fmt.Sprintf("AC%02d", i+1)] = keyplan.Key{Code: code}
+	}
+	for i, code := range rowKeys[3][1:11] {
+		m[fmt.Sprintf("AB%02d", i+1)] = keyplan.Key{Code: code}
+	}
+	return m
+}()
+
+// ParseXKB builds the layout named by an XKB symbols spec such as "de",
+// "de(nodeadkeys)" or "us(altgr-intl)", following include statements.
+// Like setxkbmap, it puts the "pc" symbols underneath, which is where keys
+// such as <LSGT> get their defaults. Symbols files are looked up in dirs in order; with no dirs, DefaultXKBDir
+// is used. Only the first group and the first four levels (plain, Shift,
+// AltGr, Shift+AltGr) are kept.
+func ParseXKB(spec string, dirs ...string) (*Layout, error) {
+	if len(dirs) == 0 {
+		dirs = []string{DefaultXKBDir}
+	}
+	p := &xkbParser{dirs: dirs, files: map[string]*xkbFile{}}
+	keys := map[string][]keysym.Keysym{}
+	name, err := p.include(keys, "pc+"+spec, xkbOverride, 0)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = spec
+	}
+
+	var levels [4][]Entry
+	for _, row := range [][]string{
+		{"TLDE", "AE01", "AE02", "AE03", "AE04", "AE05", "AE06", "AE07", "AE08", "AE09", "AE10", "AE11", "AE12", "AE13"},
+		{"AD01", "AD02", "AD03", "AD04", "AD05", "AD06", "AD07", "AD08", "AD09", "AD10", "AD11", "AD12"},
+		{"AC01", "AC02", "AC03", "AC04", "AC05", "AC06", "AC07", "AC08", "AC09", "AC10", "AC11", "BKSL"},
+		{"LSGT", "AB01", "AB02", "AB03", "AB04", "AB05", "AB06", "AB07", "AB08", "AB09", "AB10", "AB11"},
+	} {
+		for _, kn := range row {
+			for level, sym := range keys[kn] {
+				if level >= len(levels) {
+					break
+				}
+				e := Entry{Stroke: keyplan.Stroke{Key: xkbKeyNames[kn], Mods: levelMods[level]}}
+				if r, ok := keysym.DeadRune(sym); ok {
+					e.Rune, e.Dead = r, true
+				} else if r, ok := keysym.ToRune(sym); ok {
+					e.Rune = r
+				} else {
+					continue
+				}
+				levels[level] = append(levels[level], e)
+			}
+		}
+	}
+	var entries []Entry
+	for _, level := range levels {
+		entries = append(entries, level...)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("xkb %s: no character keys", spec)
+	}
+	return New(name, "", entries, accentDeadKeys(entries)), nil
+}
+
+type xkbMerge int
+
+const (
+	xkbOverride xkbMerge = iota // new levels win
+	xkbAugment                  // existing levels win
+	xkbReplace                  // new keys replace old ones entirely
+)
+
+// xkbFile is a parsed symbols file: its sections by name.
+type xkbFile struct {
+	sections map[string][]xkbToken
+	dflt     string
+}
+
+type xkbParser struct {
+	dirs  []string
+	files map[string]*xkbFile
+}
+
+// include merges the keys of an include spec ("pc+us(intl)|foo") into keys
+// and returns the last group 1 name its sections define.
+func (p *xkbParser) include(keys map[string][]keysym.Keysym, spec string, mode xkbMerge, depth int) (string, error) {
+	if depth > maxXKBIncludes {
+		return "", fmt.Errorf("xkb %s: includes nested too deeply", spec)
+	}
+	var name string
+	for spec != "" {
+		i := strings.IndexAny(spec[1:], "+|") + 1
+		part := spec
+		if i > 0 {
+			part, spec = spec[:i], spec[i:]
+		} else {
+			spec = ""
+		}
+		switch part[0] {
+		case '+':
+			part, mode = part[1:], xkbOverride
+		case '|':
+			part, mode = part[1:], xkbAugment
+		}
+		// parts for other groups ("us:2") don't touch the first group
+		if strings.Contains(part, ":") {
+			continue
+		}
+		file, variant := part, ""
+		if j := strings.IndexByte(part, '('); j >= 0 && strings.HasSuffix(part, ")") {
+			file, variant = part[:j], part[j+1:len(part)-1]
+		}
+		f, err := p.file(file)
+		if err != nil {
+			return "", err
+		}
+		if variant == "" {
+			variant = f.dflt
+		}
+		body, ok := f.sections[variant]
+		if !ok {
+			return "", fmt.Errorf("xkb %s: no variant %q", file, variant)
+		}
+		n, err := p.section(keys, file+"("+variant+")", body, mode, depth)
+		if err != nil {
+			return "", err
+		}
+		if n != "" {
+			name = n
+		}
+	}
+	return name, nil
+}
+
+// file loads and splits a symbols file into its sections.
+func (p *xkbParser) file(name string) (*xkbFile, error) {
+	if f, ok := p.files[name]; ok {
+		return f, nil
+	}
+	var (
+		data []byte
+		err  error
+	)
+	for _, dir := range p.dirs {
+		data, err = os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("xkb symbols file %q not found", name)
+	}
+	toks, err := xkbLex(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("xkb %s: %w", name, err)
+	}
+
+	f := &xkbFile{sections: map[string][]xkbToken{}}
+	for i := 0; i < len(toks); {
+		// flags... xkb_symbols "name" { body } ;
+		dflt := false
+		for i < len(toks) && toks[i].kind == xkbIdent && toks[i].text != "xkb_symbols" {
+			dflt = dflt || toks[i].text == "default"
+			i++
+		}
+		if i+3 >= len(toks) || toks[i].text != "xkb_symbols" || toks[i+1].kind != xkbString || toks[i+2].text != "{" {
+			return nil, fmt.Errorf("xkb %s: line %d: expected xkb_symbols section", name, toks[min(i, len(toks)-1)].line)
+		}
+		section := toks[i+1].text
+		end := xkbMatch(toks, i+2)
+		if end < 0 {
+			return nil, fmt.Errorf("xkb %s: section %q is not closed", name, section)
+		}
+		f.sections[section] = toks[i+3 : end]
+		if dflt || f.dflt == "" {
+			f.dflt = section
+		}
+		i = end + 1
+		if i < len(toks) && toks[i].text == ";" {
+			i++
+		}
+	}
+	if len(f.sections) == 0 {
+		return nil, fmt.Errorf("xkb %s: no xkb_symbols sections", name)
+	}
+	p.files[name] = f
+	return f, nil
+}
+
+// section applies the statements of one xkb_symbols section to keys and
+// returns its group 1 name.
+func (p *xkbParser) section(keys map[string][]keysym.Keysym, where string, toks []xkbToken, mode xkbMerge, depth int) (string, error) {
+	var name string
+	for i := 0; i < len(toks); {
+		end, next := xkbStatement(toks, i)
+		s := toks[i:end]
+		i = next
+		if len(s) == 0 {
+			continue
+		}
+
+		m := mode
+		if s[0].kind == xkbIdent && len(s) > 1 {
+			switch s[0].text {
+			case "include":
+				s = s[1:]
+			case "override":
+				m, s = xkbOverride, s[1:]
+			case "augment":
+				m, s = xkbAugment, s[1:]
+			case "replace":
+				m, s = xkbReplace, s[1:]
+			}
+		}
+
+		switch {
+		case s[0].kind == xkbString:
+			if _, err := p.include(keys, s[0].text, m, depth+1); err != nil {
+				return "", err
+			}
+		case s[0].text == "name" && len(s) >= 6 && s[len(s)-1].kind == xkbString:
+			if strings.EqualFold(s[2].text, "Group1") {
+				name = s[len(s)-1].text
+			}
+		case s[0].text == "key" && len(s) >= 3 && s[1].kind == xkbKeyName && s[2].text == "{":
+			syms, err := xkbKeySymbols(s[3:])
+			if err != nil {
+				return "", fmt.Errorf("xkb %s: line %d: %w", where, s[0].line, err)
+			}
+			if syms != nil {
+				xkbMergeKey(keys, s[1].text, syms, m)
+			}
+		}
+	}
+	return name, nil
+}
+
+// xkbKeySymbols returns the group 1 keysyms of a key body (the tokens after
+// its opening brace), or nil if the key defines none. The body is a comma
+// separated list of fields: "symbols[Group1] = [...]" names its group,
+// a bare "[...]" is the next unnamed group, and the rest (type[...],
+// actions[...], repeat, ...) are skipped.
+func xkbKeySymbols(body []xkbToken) ([]keysym.Keysym, error) {
+	unnamed := -1
+	for i := 0; i < len(body); i++ {
+		t := body[i]
+		switch {
+		case t.kind == xkbIdent && i+1 < len(body) && body[i+1].text == "[":
+			// field[GroupN] = value
+			end := xkbMatch(body, i+1)
+			if end < 0 || end+2 >= len(body) || body[end+1].text != "=" {
+				return nil, fmt.Errorf("malformed %s field", t.text)
+			}
+			group := ""
+			for _, g := range body[i+2 : end] {
+				group += g.text
+			}
+			v := end + 2
+			if t.text == "symbols" && body[v].text == "[" && xkbGroup1(group) {
+				return xkbSymbolList(body, v)
+			}
+			i = xkbSkipValue(body, v)
+		case t.kind == xkbIdent && i+1 < len(body) && body[i+1].text == "=":
+			if i+2 >= len(body) {
+				return nil, fmt.Errorf("malformed %s field", t.text)
+			}
+			i = xkbSkipValue(body, i+2)
+		case t.text == "[":
+			// the first unnamed list is group 1, unless a field names it
+			if unnamed < 0 {
+				unnamed = i
+			}
+			i = xkbSkipValue(body, i)
+		case t.text == "{" || t.text == "(":
+			i = xkbSkipValue(body, i)
+		}
+	}
+	if unnamed >= 0 {
+		return xkbSymbolList(body, unnamed)
+	}
+	return nil, nil
+}
+
+// xkbGroup1 reports whether a group index ("Group1", "group1", "1") names
+// the first group.
+func xkbGroup1(group string) bool {
+	return strings.EqualFold(group, "Group1") || group == "1"
+}
+
+// xkbSkipValue returns the index of the last token of the value starting
+// at toks[i].
+func xkbSkipValue(toks []xkbToken, i int) int {
+	switch toks[i].text {
+	case "{", "[", "(":
+		if end := xkbMatch(toks, i); end > i {
+			return end
+		}
+	}
+	return i
+}
+
+// xkbSymbolList parses "[ sym, sym, { a, b }, ... ]" starting at toks[i].
+// Levels with several keysyms count as empty.
+func xkbSymbolList(toks []xkbToken, i int) ([]keysym.Keysym, error) {
+	end := xkbMatch(toks, i)
+	if end < 0 {
+		return nil, errors.New("unclosed symbol list")
+	}
+	var syms []keysym.Keysym
+	for j := i + 1; j < end; j++ {
+		t := toks[j]
+		switch {
+		case t.text == ",":
+		case t.text == "{":
+			syms = append(syms, keysym.NoSymbol)
+			if k := xkbMatch(toks, j); k > j {
+				j = k
+			}
+		case t.kind == xkbIdent:
+			sym, _ := keysym.FromName(t.text)
+			syms = append(syms, sym)
+		default:
+			return nil, fmt.Errorf("unexpected %q in symbol list", t.text)
+		}
+	}
+	return syms, nil
+}
+
+// xkbMergeKey merges the levels of a key definition into keys.
+func xkbMergeKey(keys map[string][]keysym.Keysym, key string, syms []keysym.Keysym, mode xkbMerge) {
+	old := keys[key]
+	if mode == xkbReplace || old == nil {
+		keys[key] = syms
+		return
+	}
+	for len(old) < len(syms) {
+		old = append(old, keysym.NoSymbol)
+	}
+	for level, sym := range syms {
+		if sym == keysym.NoSymbol {
+			continue
+		}
+		if mode == xkbOverride || old[level] == keysym.NoSymbol {
+			old[level] = sym
+		}
+	}
+	keys[key] = old
+}
+
+type xkbTokenKind int
+
+const (
+	xkbPunct xkbTokenKind = iota
+	xkbIdent
+	xkbString
+	xkbKeyName
+)
+
+type xkbToken struct {
+	kind xkbTokenKind
+	text string
+	line int
+}
+
+// xkbLex splits XKB source into tokens, dropping // and # comments.
+func xkbLex(src string) ([]xkbToken, error) {
+	var toks []xkbToken
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#' || strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '"':
+			j := i + 1
+			for j < len(src) && src[j] != '"' {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("line %d: unterminated string", line)
+			}
+			toks = append(toks, xkbToken{xkbString, strings.ReplaceAll(src[i+1:j], `\"`, `"`), line})
+			i = j + 1
+		case c == '<':
+			j := strings.IndexByte(src[i:], '>')
+			if j < 0 {
+				return nil, fmt.Errorf("line %d: unterminated key name", line)
+			}
+			toks = append(toks, xkbToken{xkbKeyName, src[i+1 : i+j], line})
+			i += j + 1
+		case isXKBIdent(c):
+			j := i
+			for j < len(src) && isXKBIdent(src[j]) {
+				j++
+			}
+			toks = append(toks, xkbToken{xkbIdent, src[i:j], line})
+			i = j
+		default:
+			toks = append(toks, xkbToken{xkbPunct, string(c), line})
+			i++
+		}
+	}
+	return toks, nil
+}
+
+func isXKBIdent(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// xkbMatch returns the index of the bracket closing toks[i], or -1.
+func xkbMatch(toks []xkbToken, i int) int {
+	depth := 0
+	for j := i; j < len(toks); j++ {
+		switch toks[j].text {
+		case "{", "[", "(":
+			if toks[j].kind == xkbPunct {
+				depth++
+			}
+		case "}", "]", ")":
+			if toks[j].kind == xkbPunct {
+				depth--
+				if depth == 0 {
+					return j
+				}
+			}
+		}
+	}
+	return -1
+}
+
+// xkbStatement returns the end of the statement starting at toks[i] and
+// the start of the next one. Statements end with ";", but XKB lets include
+// statements omit it.
+func xkbStatement(toks []xkbToken, i int) (end, next int) {
+	if i+1 < len(toks) && toks[i].kind == xkbIdent && toks[i+1].kind == xkbString {
+		if i+2 < len(toks) && toks[i+2].text == ";" {
+			return i + 2, i + 3
+		}
+		return i + 2, i + 2
+	}
+	depth := 0
+	for j := i; j < len(toks); j++ {
+		if toks[j].kind != xkbPunct {
+			continue
+		}
+		switch toks[j].text {
+		case "{", "[", "(":
+			depth++
+		case "}", "]", ")":
+			depth--
+		case ";":
+			if depth == 0 {
+				return j, j + 1
+			}
+		}
+	}
+	return len(toks), len(toks)
+}
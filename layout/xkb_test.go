@@ -0,0 +1,92 @@
+package layout_test
+
+import (
+	"testing"
+
+	"goclip/keyplan"
+	"goclip/layout"
+)
+
+func TestParseXKB(t *testing.T) {
+	stroke := func(code uint16, mods keyplan.Modifier) keyplan.Stroke {
+		return keyplan.Stroke{Key: keyplan.Key{Code: code}, Mods: mods}
+	}
+	tests := []struct {
+		spec  string
+		name  string
+		want  map[rune]keyplan.Stroke
+		dead  map[rune]keyplan.Stroke
+		unset []rune
+	}{
+		{
+			spec: "de",
+			name: "German",
+			want: map[rune]keyplan.Stroke{
+				// <AE11> sets its type before symbols[Group1]
+				'ß':  stroke(0x0C, 0),
+				'?':  stroke(0x0C, keyplan.ModShift),
+				'\\': stroke(0x0C, keyplan.ModAltGr),
+				'¿':  stroke(0x0C, keyplan.ModShift|keyplan.ModAltGr),
+				'z':  stroke(0x15, 0),
+				'y':  stroke(0x2C, 0),
+				'€':  stroke(0x12, keyplan.ModAltGr),
+				'<':  stroke(0x56, 0),
+				'|':  stroke(0x56, keyplan.ModAltGr),
+				'"':  stroke(0x03, keyplan.ModShift),
+			},
+			dead: map[rune]keyplan.Stroke{
+				'^': stroke(0x29, 0),
+				'´': stroke(0x0D, 0),
+			},
+			// overridden by de
+			unset: []rune{'-', '@'},
+		},
+		{
+			spec: "de(nodeadkeys)",
+			name: "German (no dead keys)",
+			want: map[rune]keyplan.Stroke{
+				'ß': stroke(0x0C, 0),
+				'?': stroke(0x0C, keyplan.ModShift),
+				'^': stroke(0x29, 0),
+				'´': stroke(0x0D, 0),
+			},
+		},
+		{
+			// fr does not define <LSGT>, so it comes from pc
+			spec: "fr",
+			name: "French",
+			want: map[rune]keyplan.Stroke{
+				'&': stroke(0x02, 0),
+				'<': stroke(0x56, 0),
+				'>': stroke(0x56, keyplan.ModShift),
+				' ': stroke(0x39, 0),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			l, err := layout.ParseXKB(tt.spec, "testdata/xkb")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if l.Name != tt.name {
+				t.Errorf("name %q, want %q", l.Name, tt.name)
+			}
+			for r, want := range tt.want {
+				if got, ok := l.Lookup(r); !ok || got != want {
+					t.Errorf("Lookup(%q) = %v, %v, want %v", r, got, ok, want)
+				}
+			}
+			for r, want := range tt.dead {
+				if got, ok := l.DeadKey(r); !ok || got != want {
+					t.Errorf("DeadKey(%q) = %v, %v, want %v", r, got, ok, want)
+				}
+			}
+			for _, r := range tt.unset {
+				if got, ok := l.Lookup(r); ok {
+					t.Errorf("Lookup(%q) = %v, want no key", r, got)
+				}
+			}
+		})
+	}
+}
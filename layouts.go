@@ -0,0 +1,77 @@
+//go:build windows || darwin || linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"goclip/config"
+	"goclip/layout"
+)
+
+// autoLayout types with the host's own keyboard layout.
+const autoLayout = "Auto (Use System)"
+
+// keyboardLayoutOptions lists the target layouts offered in the GUI.
+var keyboardLayoutOptions = append([]string{autoLayout}, layout.Names()...)
+
+// userLayouts are the layouts imported from .klc files in the config
+// directory and the XKB layouts listed in the config. They take precedence
+// over built-in layouts of the same name.
+var userLayouts = map[string]*layout.Layout{}
+
+// loadUserLayouts imports the .klc files from the config directory and the
+// configured XKB layouts and adds them to keyboardLayoutOptions. Call it
+// once at startup, after config.Load and before the layout list is shown.
+// Layouts that fail to load are reported in the error.
+func loadUserLayouts() error {
+	dir := config.GetConfigDir()
+	ls, err := layout.LoadDir(dir)
+	errs := []error{err}
+	for _, spec := range config.Get().XKBLayouts {
+		l, err := layout.ParseXKB(spec, filepath.Join(dir, "xkb", "symbols"), layout.DefaultXKBDir)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		// the spec tells variants with similar descriptions apart
+		ls = append(ls, l.Renamed(fmt.Sprintf("%s [%s]", l.Name, spec)))
+	}
+	for _, l := range ls {
+		if _, builtin := layout.Get(l.Name); !builtin && userLayouts[l.Name] == nil {
+			keyboardLayoutOptions = append(keyboardLayoutOptions, l.Name)
+		}
+		userLayouts[l.Name] = l
+	}
+	return errors.Join(errs...)
+}
+
+// targetLayout returns goclip's table for a named target layout, or nil
+// for "Auto" and unknown names, which use the host layout.
+func targetLayout(name string) *layout.Layout {
+	if l, ok := userLayouts[name]; ok {
+		return l
+	}
+	if l, ok := layout.Get(name); ok {
+		return l
+	}
+	return nil
+}
+
+// cliLayout resolves a layout name given on the command line or in an
+// automation API request. Case does not matter; "auto" and "" stand for
+// the host's layout.
+func cliLayout(name string) (string, error) {
+	if name == "" || strings.EqualFold(name, "auto") {
+		return autoLayout, nil
+	}
+	for _, option := range keyboardLayoutOptions {
+		if strings.EqualFold(option, name) {
+			return option, nil
+		}
+	}
+	return "", fmt.Errorf("unknown layout %q; known layouts: auto, %s", name, strings.Join(keyboardLayoutOptions[1:], ", "))
+}
@@ -9,6 +9,24 @@ import (
 type LabelSet struct {
 	AppTitle                         string
 	InputPlaceholder                 string
+	TypabilityAllKeys                string
+	TypabilityReportFormat           string
+	TypabilityErrorFormat            string
+	KeyMarkupCheck                   string
+	RunbookModeCheck                 string
+	RunbookSplitLines                string
+	RunbookSplitBlocks               string
+	RunbookStepFormat                string
+	RunbookFinishedFormat            string
+	RunbookEmpty                     string
+	RunbookNextButton                string
+	RunbookSkipButton                string
+	RunbookRepeatButton              string
+	RunbookBackButton                string
+	RunbookOpenButton                string
+	RunbookCloseButton               string
+	RunbookStartButton               string
+	RunbookFileErrorFormat           string
 	StatusReady                      string
 	TargetWindowHeading              string
 	ClearButton                      string
@@ -44,6 +62,17 @@ type LabelSet struct {
 	StatusTypedClipboardFormat       string
 	StatusSelectionCleared           string
 	StatusWatcherWarningFormat       string
+	StatusLayoutImportWarningFormat  string
+	StatusAutomationErrorFormat      string
+	StatusInstanceWarningFormat      string
+	StatusHotkeyWarningFormat        string
+	StatusLaunchErrorFormat          string
+	StatusRunbookFinished            string
+	StatusTypingStep                 string
+	StatusTypedStepFormat            string
+	StatusTransferring               string
+	StatusTransferredChunkFormat     string
+	StatusTransferDoneFormat         string
 	LanguageHeading                  string
 	LanguageAutoOption               string
 	CompatibilityModeHeading         string
@@ -57,24 +86,114 @@ type LabelSet struct {
 	CompatibilityHelpTitle           string
 	CompatibilityHelpMessage         string
 	AbortOnFocusChange               string
+	FallbackHeading                  string
+	FallbackUnicode                  string
+	FallbackAltNumpad                string
+	FallbackHexInput                 string
+	SendFileButton                   string
+	TransferTitle                    string
+	TransferFileLabel                string
+	TransferNoFile                   string
+	TransferChooseButton             string
+	TransferShellLabel               string
+	TransferGzipCheck                string
+	TransferNameLabel                string
+	TransferStartLabel               string
+	TransferInfoFormat               string
+	TransferStartErrorFormat         string
+	TransferSendButton               string
+	VaultButton                      string
+	VaultTitle                       string
+	VaultCreateHintFormat            string
+	VaultPassphraseLabel             string
+	VaultRepeatLabel                 string
+	VaultPassphraseMismatch          string
+	VaultCreateButton                string
+	VaultLockedHint                  string
+	VaultUnlockButton                string
+	VaultUnlockedHint                string
+	VaultEmpty                       string
+	VaultTypeButton                  string
+	VaultAddButton                   string
+	VaultAddTitle                    string
+	VaultNameLabel                   string
+	VaultSecretLabel                 string
+	VaultDeleteButton                string
+	VaultDeleteConfirmFormat         string
+	VaultLockButton                  string
+	VaultChangePassphraseButton      string
+	StatusVaultLocked                string
+	StatusTypingSecret               string
+	StatusTypingSecretError          string
+	StatusTypedSecretFormat          string
+	KeePassButton                    string
+	KeePassTitle                     string
+	KeePassClosedHint                string
+	KeePassDatabaseLabel             string
+	KeePassKeyFileLabel              string
+	KeePassNoKeyFile                 string
+	KeePassPasswordLabel             string
+	KeePassNoDatabase                string
+	KeePassOpenButton                string
+	KeePassOpenHintFormat            string
+	KeePassSearchPlaceholder         string
+	KeePassTypeUserButton            string
+	KeePassTypePasswordButton        string
+	KeePassAutoTypeButton            string
+	KeePassAutoTypeErrorFormat       string
+	KeePassCloseButton               string
+	StatusKeePassEmpty               string
+	VaultTypeCodeButton              string
+	VaultTOTPCheck                   string
+	StatusWaitingTOTPFormat          string
+	StatusTypingTOTP                 string
+	StatusTypedTOTPFormat            string
+	FavouritesPlaceholder            string
+	FavouriteSaveButton              string
+	FavouriteNameLabel               string
+	FavouriteDeleteTitle             string
+	FavouriteDeleteConfirmFormat     string
+	TrayShowWindow                   string
+	TrayTypeClipboard                string
+	TrayFavourites                   string
+	TrayNoFavourites                 string
+	TrayProgressFormat               string
+	TrayQuit                         string
 
 	// Settings page
-	SettingsTitle               string
-	SettingsButton              string
-	SettingsDefaultSpeedHeading string
-	SettingsCustomSpeedLabel    string
-	SettingsCustomSpeedMs       string
-	SettingsKeyboardLayoutLabel string
-	SettingsCompatibilityLabel  string
-	SettingsAbortFocusLabel     string
-	SettingsLanguageLabel       string
-	SettingsSaveButton          string
-	SettingsCancelButton        string
-	SettingsSavedStatus         string
-	SettingsResetButton         string
-	SettingsResetConfirmTitle   string
-	SettingsResetConfirmMessage string
-	SettingsAlwaysOnTopLabel    string
+	SettingsTitle                    string
+	SettingsButton                   string
+	SettingsDefaultSpeedHeading      string
+	SettingsCustomSpeedLabel         string
+	SettingsCustomSpeedMs            string
+	SettingsKeyboardLayoutLabel      string
+	SettingsCompatibilityLabel       string
+	SettingsAbortFocusLabel          string
+	SettingsLanguageLabel            string
+	SettingsSaveButton               string
+	SettingsCancelButton             string
+	SettingsSavedStatus              string
+	SettingsResetButton              string
+	SettingsResetConfirmTitle        string
+	SettingsResetConfirmMessage      string
+	SettingsAlwaysOnTopLabel         string
+	SettingsUnmappableLabel          string
+	SettingsVaultAutoLockLabel       string
+	SettingsAutomationLabel          string
+	SettingsAutomationCheck          string
+	SettingsAutomationHintFormat     string
+	SettingsHotkeysLabel             string
+	SettingsHotkeyTypeClipboardLabel string
+	SettingsHotkeyStopLabel          string
+	SettingsHotkeyPlaceholder        string
+	SettingsTrayLabel                string
+	SettingsTrayCheck                string
+
+	// Unmappable character policies
+	UnmappableFallback      string
+	UnmappableFail          string
+	UnmappableSkip          string
+	UnmappableTransliterate string
 
 	// Always on top
 	AlwaysOnTop string
@@ -98,6 +217,24 @@ var (
 			labels: LabelSet{
 				AppTitle:                         "goclip",
 				InputPlaceholder:                 "Type here…",
+				TypabilityAllKeys:                "Every character has a key on this layout.",
+				TypabilityReportFormat:           "%d by key, %d by dead key or replacement, %d by fallback, %d not typed (highlighted).",
+				TypabilityErrorFormat:            "Cannot check the text: %s",
+				KeyMarkupCheck:                   "Key markup ({TAB}, {CTRL+ALT+DEL}, {{ for {)",
+				RunbookModeCheck:                 "Runbook mode",
+				RunbookSplitLines:                "One step per line",
+				RunbookSplitBlocks:               "Blocks between blank lines",
+				RunbookStepFormat:                "Step %d of %d",
+				RunbookFinishedFormat:            "All %d steps done.",
+				RunbookEmpty:                     "The text has no steps.",
+				RunbookNextButton:                "Next",
+				RunbookSkipButton:                "Skip",
+				RunbookRepeatButton:              "Repeat",
+				RunbookBackButton:                "Back",
+				RunbookOpenButton:                "Open…",
+				RunbookCloseButton:               "Close",
+				RunbookStartButton:               "Start",
+				RunbookFileErrorFormat:           "Cannot open %s: %v",
 				StatusReady:                      "Ready.",
 				TargetWindowHeading:              "Target Window",
 				ClearButton:                      "Clear",
@@ -133,6 +270,17 @@ var (
 				StatusTypedClipboardFormat:       "Typed clipboard to: %s",
 				StatusSelectionCleared:           "Selection cleared → using last active window.",
 				StatusWatcherWarningFormat:       "Warning: foreground watcher failed, falling back: %s",
+				StatusLayoutImportWarningFormat:  "Warning: could not import keyboard layout: %s",
+				StatusAutomationErrorFormat:      "Warning: automation API not started: %s",
+				StatusInstanceWarningFormat:      "Warning: cannot check for another running goclip: %s",
+				StatusHotkeyWarningFormat:        "Warning: global hotkeys not available: %s",
+				StatusLaunchErrorFormat:          "Could not carry out the command line: %s",
+				StatusRunbookFinished:            "No step left in the runbook.",
+				StatusTypingStep:                 "Typing step...",
+				StatusTypedStepFormat:            "Step typed to: %s",
+				StatusTransferring:               "Typing file…",
+				StatusTransferredChunkFormat:     "Chunk typed to: %s",
+				StatusTransferDoneFormat:         "%s typed. Check the SHA256 result on the target.",
 				LanguageHeading:                  "Interface Language",
 				LanguageAutoOption:               "Auto (System)",
 				CompatibilityModeHeading:         "Modifier Compatibility",
@@ -146,24 +294,114 @@ var (
 				CompatibilityHelpTitle:           "Modifier compatibility",
 				CompatibilityHelpMessage:         "Some apps may not detect Alt, Shift, or AltGr correctly. Auto: Applies a fix for known apps like Citrix Workspace or HPE iLO. Always on: Always apply the fix. Off: Never apply the fix.",
 				AbortOnFocusChange:               "Abort on focus change",
+				FallbackHeading:                  "Fallback for this target",
+				FallbackUnicode:                  "Unicode",
+				FallbackAltNumpad:                "Alt+Numpad code",
+				FallbackHexInput:                 "Ctrl+Shift+U hex (Linux)",
+				SendFileButton:                   "Send File…",
+				TransferTitle:                    "Send File by Typing",
+				TransferFileLabel:                "File",
+				TransferNoFile:                   "No file chosen.",
+				TransferChooseButton:             "Choose…",
+				TransferShellLabel:               "Target shell",
+				TransferGzipCheck:                "Compress with gzip",
+				TransferNameLabel:                "Name on target",
+				TransferStartLabel:               "Start at chunk",
+				TransferInfoFormat:               "%d bytes, %d base64 characters in %d chunks. To resume after a stop, clear the half-typed command (Ctrl+C) and start at the chunk shown.",
+				TransferStartErrorFormat:         "The start chunk must be between 1 and %d.",
+				TransferSendButton:               "Send",
+				VaultButton:                      "Vault",
+				VaultTitle:                       "Secret Vault",
+				VaultCreateHintFormat:            "Create a vault to keep passwords encrypted with a master passphrase in %s. Secrets can be typed into the target but are never shown or copied to the clipboard.",
+				VaultPassphraseLabel:             "Passphrase",
+				VaultRepeatLabel:                 "Repeat",
+				VaultPassphraseMismatch:          "The passphrases do not match.",
+				VaultCreateButton:                "Create Vault",
+				VaultLockedHint:                  "The vault is locked.",
+				VaultUnlockButton:                "Unlock",
+				VaultUnlockedHint:                "Select a secret and press Type to type it into the target window.",
+				VaultEmpty:                       "The vault holds no secrets yet.",
+				VaultTypeButton:                  "Type",
+				VaultAddButton:                   "Add…",
+				VaultAddTitle:                    "Add or Replace Secret",
+				VaultNameLabel:                   "Name",
+				VaultSecretLabel:                 "Secret",
+				VaultDeleteButton:                "Delete",
+				VaultDeleteConfirmFormat:         "Delete the secret %q?",
+				VaultLockButton:                  "Lock",
+				VaultChangePassphraseButton:      "Change Passphrase…",
+				StatusVaultLocked:                "The vault is locked.",
+				StatusTypingSecret:               "Typing secret…",
+				StatusTypingSecretError:          "The secret could not be typed. Check the keyboard layout and the unmappable character policy.",
+				StatusTypedSecretFormat:          "Secret typed to: %s",
+				KeePassButton:                    "KeePass",
+				KeePassTitle:                     "KeePass Database",
+				KeePassClosedHint:                "Open a KeePass database (KDBX 4) with its password, its key file or both. Entries can be typed into the target window; passwords are never shown. The database is read only and closes again after the vault's auto-lock time.",
+				KeePassDatabaseLabel:             "Database",
+				KeePassKeyFileLabel:              "Key File",
+				KeePassNoKeyFile:                 "None",
+				KeePassPasswordLabel:             "Password",
+				KeePassNoDatabase:                "Choose a database file first.",
+				KeePassOpenButton:                "Open",
+				KeePassOpenHintFormat:            "%s: %d entries. Select one and type its user name, its password or its auto-type sequence.",
+				KeePassSearchPlaceholder:         "Search title, user name, URL, notes or group…",
+				KeePassTypeUserButton:            "User Name",
+				KeePassTypePasswordButton:        "Password",
+				KeePassAutoTypeButton:            "Auto-Type",
+				KeePassAutoTypeErrorFormat:       "The auto-type sequence cannot be typed: %v",
+				KeePassCloseButton:               "Close Database",
+				StatusKeePassEmpty:               "Nothing to type: the field is empty or the database was closed.",
+				VaultTypeCodeButton:              "Type TOTP Code",
+				VaultTOTPCheck:                   "TOTP seed (otpauth:// URI or base32 secret)",
+				StatusWaitingTOTPFormat:          "The TOTP code is about to expire, waiting %d s for the next one…",
+				StatusTypingTOTP:                 "Typing TOTP code…",
+				StatusTypedTOTPFormat:            "TOTP code typed to: %s",
+				FavouritesPlaceholder:            "Favourites",
+				FavouriteSaveButton:              "Save as Favourite",
+				FavouriteNameLabel:               "Name",
+				FavouriteDeleteTitle:             "Delete Favourite",
+				FavouriteDeleteConfirmFormat:     "Delete the favourite %q?",
+				TrayShowWindow:                   "Show goclip",
+				TrayTypeClipboard:                "Type Clipboard into Last Active Window",
+				TrayFavourites:                   "Type Favourite",
+				TrayNoFavourites:                 "No favourites saved",
+				TrayProgressFormat:               "Typing: %d of %d characters (%d%%)",
+				TrayQuit:                         "Quit",
 
 				// Settings page
-				SettingsTitle:               "Settings",
-				SettingsButton:              "Settings",
-				SettingsDefaultSpeedHeading: "Default Typing Speed",
-				SettingsCustomSpeedLabel:    "Custom Speed (ms)",
-				SettingsCustomSpeedMs:       "milliseconds per character",
-				SettingsKeyboardLayoutLabel: "Default Keyboard Layout",
-				SettingsCompatibilityLabel:  "Default Modifier Compatibility",
-				SettingsAbortFocusLabel:     "Abort on focus change by default",
-				SettingsLanguageLabel:       "Interface Language",
-				SettingsSaveButton:          "Save",
-				SettingsCancelButton:        "Cancel",
-				SettingsSavedStatus:         "Settings saved.",
-				SettingsResetButton:         "Reset to Defaults",
-				SettingsResetConfirmTitle:   "Reset Settings",
-				SettingsResetConfirmMessage: "Are you sure you want to reset all settings to their default values?",
-				SettingsAlwaysOnTopLabel:    "Always on top by default",
+				SettingsTitle:                    "Settings",
+				SettingsButton:                   "Settings",
+				SettingsDefaultSpeedHeading:      "Default Typing Speed",
+				SettingsCustomSpeedLabel:         "Custom Speed (ms)",
+				SettingsCustomSpeedMs:            "milliseconds per character",
+				SettingsKeyboardLayoutLabel:      "Default Keyboard Layout",
+				SettingsCompatibilityLabel:       "Default Modifier Compatibility",
+				SettingsAbortFocusLabel:          "Abort on focus change by default",
+				SettingsLanguageLabel:            "Interface Language",
+				SettingsSaveButton:               "Save",
+				SettingsCancelButton:             "Cancel",
+				SettingsSavedStatus:              "Settings saved.",
+				SettingsResetButton:              "Reset to Defaults",
+				SettingsResetConfirmTitle:        "Reset Settings",
+				SettingsResetConfirmMessage:      "Are you sure you want to reset all settings to their default values?",
+				SettingsAlwaysOnTopLabel:         "Always on top by default",
+				SettingsUnmappableLabel:          "Characters the Layout Cannot Type",
+				SettingsVaultAutoLockLabel:       "Lock the Vault and Close KeePass After (Minutes)",
+				SettingsAutomationLabel:          "Automation API",
+				SettingsAutomationCheck:          "Accept requests from local tools",
+				SettingsAutomationHintFormat:     "Socket: %s\nToken: %s",
+				SettingsHotkeysLabel:             "Global Hotkeys",
+				SettingsHotkeyTypeClipboardLabel: "Type the clipboard into the focused window",
+				SettingsHotkeyStopLabel:          "Stop typing (hold for half a second)",
+				SettingsHotkeyPlaceholder:        "e.g. Ctrl+Alt+V, empty = off",
+				SettingsTrayLabel:                "System Tray",
+				SettingsTrayCheck:                "Show a tray icon and hide the window there when it is closed",
+
+				// Unmappable character policies
+				UnmappableFallback:      "Use the target's fallback",
+				UnmappableFail:          "Refuse to type",
+				UnmappableSkip:          "Skip them",
+				UnmappableTransliterate: "Replace with ASCII (ß → ss)",
 
 				// Always on top
 				AlwaysOnTop: "Always on top",
@@ -174,6 +412,24 @@ var (
 			labels: LabelSet{
 				AppTitle:                         "goclip",
 				InputPlaceholder:                 "Hier tippen…",
+				TypabilityAllKeys:                "Jedes Zeichen hat eine Taste in diesem Layout.",
+				TypabilityReportFormat:           "%d per Taste, %d per Tottaste oder Ersatz, %d per Ausweichmethode, %d nicht getippt (hervorgehoben).",
+				TypabilityErrorFormat:            "Text kann nicht geprüft werden: %s",
+				KeyMarkupCheck:                   "Tasten-Markup ({TAB}, {CTRL+ALT+DEL}, {{ für {)",
+				RunbookModeCheck:                 "Runbook-Modus",
+				RunbookSplitLines:                "Ein Schritt pro Zeile",
+				RunbookSplitBlocks:               "Blöcke zwischen Leerzeilen",
+				RunbookStepFormat:                "Schritt %d von %d",
+				RunbookFinishedFormat:            "Alle %d Schritte erledigt.",
+				RunbookEmpty:                     "Der Text enthält keine Schritte.",
+				RunbookNextButton:                "Weiter",
+				RunbookSkipButton:                "Überspringen",
+				RunbookRepeatButton:              "Wiederholen",
+				RunbookBackButton:                "Zurück",
+				RunbookOpenButton:                "Öffnen…",
+				RunbookCloseButton:               "Schließen",
+				RunbookStartButton:               "Starten",
+				RunbookFileErrorFormat:           "%s kann nicht geöffnet werden: %v",
 				StatusReady:                      "Bereit.",
 				TargetWindowHeading:              "Zielfenster",
 				ClearButton:                      "Auswahl aufheben",
@@ -209,6 +465,17 @@ var (
 				StatusTypedClipboardFormat:       "Zwischenablage getippt nach: %s",
 				StatusSelectionCleared:           "Auswahl entfernt → zuletzt aktives Fenster wird verwendet.",
 				StatusWatcherWarningFormat:       "Warnung: Vordergrundüberwachung fehlgeschlagen, Fallback: %s",
+				StatusLayoutImportWarningFormat:  "Warnung: Tastaturlayout konnte nicht importiert werden: %s",
+				StatusAutomationErrorFormat:      "Warnung: Automatisierungs-API nicht gestartet: %s",
+				StatusInstanceWarningFormat:      "Warnung: Prüfung auf ein laufendes goclip nicht möglich: %s",
+				StatusHotkeyWarningFormat:        "Warnung: globale Tastenkürzel nicht verfügbar: %s",
+				StatusLaunchErrorFormat:          "Befehlszeile konnte nicht ausgeführt werden: %s",
+				StatusRunbookFinished:            "Kein Schritt mehr im Runbook.",
+				StatusTypingStep:                 "Tippe Schritt...",
+				StatusTypedStepFormat:            "Schritt getippt in: %s",
+				StatusTransferring:               "Tippe Datei…",
+				StatusTransferredChunkFormat:     "Abschnitt getippt in: %s",
+				StatusTransferDoneFormat:         "%s getippt. Prüfe das SHA256-Ergebnis auf dem Ziel.",
 				LanguageHeading:                  "Anzeigesprache",
 				LanguageAutoOption:               "Automatisch (System)",
 				CompatibilityModeHeading:         "Modifikatorkompatibilität",
@@ -222,24 +489,114 @@ var (
 				CompatibilityHelpTitle:           "Modifikatorkompatibilität",
 				CompatibilityHelpMessage:         "Manche Apps erkennen Alt, Shift oder AltGr nicht richtig. Auto: Wendet eine Korrektur für bekannte Apps wie Citrix Workspace oder HPE iLO an. Immer an: Korrektur immer verwenden. Aus: Korrektur nie verwenden.",
 				AbortOnFocusChange:               "Bei Fokuswechsel abbrechen",
+				FallbackHeading:                  "Ersatz für dieses Ziel",
+				FallbackUnicode:                  "Unicode",
+				FallbackAltNumpad:                "Alt+Ziffernblock-Code",
+				FallbackHexInput:                 "Strg+Umschalt+U Hex (Linux)",
+				SendFileButton:                   "Datei senden…",
+				TransferTitle:                    "Datei per Tippen senden",
+				TransferFileLabel:                "Datei",
+				TransferNoFile:                   "Keine Datei gewählt.",
+				TransferChooseButton:             "Auswählen…",
+				TransferShellLabel:               "Ziel-Shell",
+				TransferGzipCheck:                "Mit gzip komprimieren",
+				TransferNameLabel:                "Name auf dem Ziel",
+				TransferStartLabel:               "Ab Abschnitt",
+				TransferInfoFormat:               "%d Bytes, %d Base64-Zeichen in %d Abschnitten. Zum Fortsetzen nach einem Abbruch den halb getippten Befehl löschen (Strg+C) und beim angezeigten Abschnitt starten.",
+				TransferStartErrorFormat:         "Der Startabschnitt muss zwischen 1 und %d liegen.",
+				TransferSendButton:               "Senden",
+				VaultButton:                      "Tresor",
+				VaultTitle:                       "Geheimnis-Tresor",
+				VaultCreateHintFormat:            "Lege einen Tresor an, um Passwörter mit einer Master-Passphrase verschlüsselt in %s zu speichern. Geheimnisse können ins Ziel getippt, aber nie angezeigt oder in die Zwischenablage kopiert werden.",
+				VaultPassphraseLabel:             "Passphrase",
+				VaultRepeatLabel:                 "Wiederholen",
+				VaultPassphraseMismatch:          "Die Passphrasen stimmen nicht überein.",
+				VaultCreateButton:                "Tresor anlegen",
+				VaultLockedHint:                  "Der Tresor ist gesperrt.",
+				VaultUnlockButton:                "Entsperren",
+				VaultUnlockedHint:                "Wähle ein Geheimnis und drücke Tippen, um es ins Zielfenster zu tippen.",
+				VaultEmpty:                       "Der Tresor enthält noch keine Geheimnisse.",
+				VaultTypeButton:                  "Tippen",
+				VaultAddButton:                   "Hinzufügen…",
+				VaultAddTitle:                    "Geheimnis hinzufügen oder ersetzen",
+				VaultNameLabel:                   "Name",
+				VaultSecretLabel:                 "Geheimnis",
+				VaultDeleteButton:                "Löschen",
+				VaultDeleteConfirmFormat:         "Geheimnis %q löschen?",
+				VaultLockButton:                  "Sperren",
+				VaultChangePassphraseButton:      "Passphrase ändern…",
+				StatusVaultLocked:                "Der Tresor ist gesperrt.",
+				StatusTypingSecret:               "Tippe Geheimnis…",
+				StatusTypingSecretError:          "Das Geheimnis konnte nicht getippt werden. Prüfe das Tastaturlayout und die Regel für nicht tippbare Zeichen.",
+				StatusTypedSecretFormat:          "Geheimnis getippt in: %s",
+				KeePassButton:                    "KeePass",
+				KeePassTitle:                     "KeePass-Datenbank",
+				KeePassClosedHint:                "Öffne eine KeePass-Datenbank (KDBX 4) mit ihrem Passwort, ihrer Schlüsseldatei oder beidem. Einträge können ins Zielfenster getippt werden; Passwörter werden nie angezeigt. Die Datenbank wird nur gelesen und nach der Sperrzeit des Tresors wieder geschlossen.",
+				KeePassDatabaseLabel:             "Datenbank",
+				KeePassKeyFileLabel:              "Schlüsseldatei",
+				KeePassNoKeyFile:                 "Keine",
+				KeePassPasswordLabel:             "Passwort",
+				KeePassNoDatabase:                "Wähle zuerst eine Datenbankdatei.",
+				KeePassOpenButton:                "Öffnen",
+				KeePassOpenHintFormat:            "%s: %d Einträge. Wähle einen aus und tippe seinen Benutzernamen, sein Passwort oder seine Auto-Type-Sequenz.",
+				KeePassSearchPlaceholder:         "Titel, Benutzername, URL, Notizen oder Gruppe suchen…",
+				KeePassTypeUserButton:            "Benutzername",
+				KeePassTypePasswordButton:        "Passwort",
+				KeePassAutoTypeButton:            "Auto-Type",
+				KeePassAutoTypeErrorFormat:       "Die Auto-Type-Sequenz kann nicht getippt werden: %v",
+				KeePassCloseButton:               "Datenbank schließen",
+				StatusKeePassEmpty:               "Nichts zu tippen: Das Feld ist leer oder die Datenbank wurde geschlossen.",
+				VaultTypeCodeButton:              "TOTP-Code tippen",
+				VaultTOTPCheck:                   "TOTP-Seed (otpauth://-URI oder Base32-Geheimnis)",
+				StatusWaitingTOTPFormat:          "Der TOTP-Code läuft gleich ab, warte %d s auf den nächsten…",
+				StatusTypingTOTP:                 "Tippe TOTP-Code…",
+				StatusTypedTOTPFormat:            "TOTP-Code getippt in: %s",
+				FavouritesPlaceholder:            "Favoriten",
+				FavouriteSaveButton:              "Als Favorit speichern",
+				FavouriteNameLabel:               "Name",
+				FavouriteDeleteTitle:             "Favorit löschen",
+				FavouriteDeleteConfirmFormat:     "Favorit %q löschen?",
+				TrayShowWindow:                   "goclip anzeigen",
+				TrayTypeClipboard:                "Zwischenablage ins zuletzt aktive Fenster tippen",
+				TrayFavourites:                   "Favorit tippen",
+				TrayNoFavourites:                 "Keine Favoriten gespeichert",
+				TrayProgressFormat:               "Tippe: %d von %d Zeichen (%d %%)",
+				TrayQuit:                         "Beenden",
 
 				// Settings page
-				SettingsTitle:               "Einstellungen",
-				SettingsButton:              "Einstellungen",
-				SettingsDefaultSpeedHeading: "Standard-Schreibgeschwindigkeit",
-				SettingsCustomSpeedLabel:    "Benutzerdefinierte Geschwindigkeit (ms)",
-				SettingsCustomSpeedMs:       "Millisekunden pro Zeichen",
-				SettingsKeyboardLayoutLabel: "Standard-Tastaturlayout",
-				SettingsCompatibilityLabel:  "Standard-Modifikatorkompatibilität",
-				SettingsAbortFocusLabel:     "Standardmäßig bei Fokuswechsel abbrechen",
-				SettingsLanguageLabel:       "Anzeigesprache",
-				SettingsSaveButton:          "Speichern",
-				SettingsCancelButton:        "Abbrechen",
-				SettingsSavedStatus:         "Einstellungen gespeichert.",
-				SettingsResetButton:         "Auf Standard zurücksetzen",
-				SettingsResetConfirmTitle:   "Einstellungen zurücksetzen",
-				SettingsResetConfirmMessage: "Möchten Sie wirklich alle Einstellungen auf die Standardwerte zurücksetzen?",
-				SettingsAlwaysOnTopLabel:    "Standardmäßig immer im Vordergrund",
+				SettingsTitle:                    "Einstellungen",
+				SettingsButton:                   "Einstellungen",
+				SettingsDefaultSpeedHeading:      "Standard-Schreibgeschwindigkeit",
+				SettingsCustomSpeedLabel:         "Benutzerdefinierte Geschwindigkeit (ms)",
+				SettingsCustomSpeedMs:            "Millisekunden pro Zeichen",
+				SettingsKeyboardLayoutLabel:      "Standard-Tastaturlayout",
+				SettingsCompatibilityLabel:       "Standard-Modifikatorkompatibilität",
+				SettingsAbortFocusLabel:          "Standardmäßig bei Fokuswechsel abbrechen",
+				SettingsLanguageLabel:            "Anzeigesprache",
+				SettingsSaveButton:               "Speichern",
+				SettingsCancelButton:             "Abbrechen",
+				SettingsSavedStatus:              "Einstellungen gespeichert.",
+				SettingsResetButton:              "Auf Standard zurücksetzen",
+				SettingsResetConfirmTitle:        "Einstellungen zurücksetzen",
+				SettingsResetConfirmMessage:      "Möchten Sie wirklich alle Einstellungen auf die Standardwerte zurücksetzen?",
+				SettingsAlwaysOnTopLabel:         "Standardmäßig immer im Vordergrund",
+				SettingsUnmappableLabel:          "Zeichen, die das Layout nicht tippen kann",
+				SettingsVaultAutoLockLabel:       "Tresor sperren und KeePass schließen nach (Minuten)",
+				SettingsAutomationLabel:          "Automatisierungs-API",
+				SettingsAutomationCheck:          "Anfragen lokaler Werkzeuge annehmen",
+				SettingsAutomationHintFormat:     "Socket: %s\nToken: %s",
+				SettingsHotkeysLabel:             "Globale Tastenkürzel",
+				SettingsHotkeyTypeClipboardLabel: "Zwischenablage in das fokussierte Fenster tippen",
+				SettingsHotkeyStopLabel:          "Tippen stoppen (eine halbe Sekunde halten)",
+				SettingsHotkeyPlaceholder:        "z. B. Ctrl+Alt+V, leer = aus",
+				SettingsTrayLabel:                "Infobereich",
+				SettingsTrayCheck:                "Symbol im Infobereich zeigen und das Fenster beim Schließen dorthin ausblenden",
+
+				// Unmappable character policies
+				UnmappableFallback:      "Ausweichmethode des Ziels verwenden",
+				UnmappableFail:          "Nicht tippen",
+				UnmappableSkip:          "Auslassen",
+				UnmappableTransliterate: "Durch ASCII ersetzen (ß → ss)",
 
 				// Always on top
 				AlwaysOnTop: "Immer im Vordergrund",
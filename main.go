@@ -3,11 +3,12 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -16,205 +17,17 @@ import (
 	// #include <windows.h>
 	"C"
 
-	"goclip/config"
-	"goclip/localization"
+	"goclip/keyplan"
+	"goclip/layout"
 
-	_ "embed"
-
-	"fyne.io/fyne/v2"
-	"fyne.io/fyne/v2/app"
-	"fyne.io/fyne/v2/canvas"
-	"fyne.io/fyne/v2/container"
-	"fyne.io/fyne/v2/data/binding"
-	"fyne.io/fyne/v2/dialog"
-	"fyne.io/fyne/v2/theme"
-	"fyne.io/fyne/v2/widget"
 	"golang.org/x/sys/windows"
 )
 
-//go:embed assets/logo/app.ico
-var embeddedAppIco []byte
-
-type statusKey string
-
-const (
-	statusKeyReady                statusKey = "ready"
-	statusKeySelectionCleared     statusKey = "selectionCleared"
-	statusKeyFoundWindows         statusKey = "foundWindows"
-	statusKeyWatcherWarning       statusKey = "watcherWarning"
-	statusKeyWindowUnavailable    statusKey = "windowUnavailable"
-	statusKeyNoWindow             statusKey = "noWindow"
-	statusKeyNothingToType        statusKey = "nothingToType"
-	statusKeyTyping               statusKey = "typing"
-	statusKeyStopping             statusKey = "stopping"
-	statusKeyTypingStopped        statusKey = "typingStopped"
-	statusKeyTypingError          statusKey = "typingError"
-	statusKeyTypedTo              statusKey = "typedTo"
-	statusKeyClipboardEmpty       statusKey = "clipboardEmpty"
-	statusKeyTypingClipboard      statusKey = "typingClipboard"
-	statusKeyTypingClipboardError statusKey = "typingClipboardError"
-	statusKeyTypedClipboard       statusKey = "typedClipboard"
-)
-
-type statusMessage struct {
-	key  statusKey
-	args []any
-}
-
-type statusController struct {
-	label *widget.Label
-	mu    sync.Mutex
-	last  statusMessage
-}
-
-func newStatusController(label *widget.Label) *statusController {
-	return &statusController{
-		label: label,
-		last:  statusMessage{key: statusKeyReady},
-	}
-}
-
-func (sc *statusController) Set(key statusKey, args ...any) {
-	sc.mu.Lock()
-	sc.last = statusMessage{key: key, args: args}
-	sc.mu.Unlock()
-	sc.renderAsync()
-}
-
-func (sc *statusController) Refresh() {
-	sc.mu.Lock()
-	msg := sc.last
-	sc.mu.Unlock()
-	sc.label.SetText(renderStatusText(msg, getCurrentLabelSet()))
-}
-
-func (sc *statusController) renderAsync() {
-	sc.mu.Lock()
-	msg := sc.last
-	sc.mu.Unlock()
-	labels := getCurrentLabelSet()
-	text := renderStatusText(msg, labels)
-	fyne.Do(func() {
-		sc.label.SetText(text)
-	})
-}
-
-func renderStatusText(msg statusMessage, labels localization.LabelSet) string {
-	switch msg.key {
-	case statusKeyReady:
-		return labels.StatusReady
-	case statusKeySelectionCleared:
-		return labels.StatusSelectionCleared
-	case statusKeyFoundWindows:
-		return fmt.Sprintf(labels.FoundWindowsFormat, statusArgInt(msg.args))
-	case statusKeyWatcherWarning:
-		return fmt.Sprintf(labels.StatusWatcherWarningFormat, statusArgString(msg.args))
-	case statusKeyWindowUnavailable:
-		return labels.StatusWindowUnavailable
-	case statusKeyNoWindow:
-		return labels.StatusNoWindow
-	case statusKeyNothingToType:
-		return labels.StatusNothingToType
-	case statusKeyTyping:
-		return labels.StatusTyping
-	case statusKeyStopping:
-		return labels.StatusStopping
-	case statusKeyTypingStopped:
-		return labels.StatusTypingStopped
-	case statusKeyTypingError:
-		return fmt.Sprintf(labels.StatusTypingErrorFormat, statusArgString(msg.args))
-	case statusKeyTypedTo:
-		return fmt.Sprintf(labels.StatusTypedToFormat, statusArgString(msg.args))
-	case statusKeyClipboardEmpty:
-		return labels.StatusClipboardEmpty
-	case statusKeyTypingClipboard:
-		return labels.StatusTypingClipboard
-	case statusKeyTypingClipboardError:
-		return fmt.Sprintf(labels.StatusTypingClipboardErrorFormat, statusArgString(msg.args))
-	case statusKeyTypedClipboard:
-		return fmt.Sprintf(labels.StatusTypedClipboardFormat, statusArgString(msg.args))
-	default:
-		return labels.StatusReady
-	}
-}
-
-func statusArgInt(args []any) int {
-	if len(args) == 0 {
-		return 0
-	}
-	switch v := args[0].(type) {
-	case int:
-		return v
-	case int32:
-		return int(v)
-	case int64:
-		return int(v)
-	default:
-		return 0
-	}
-}
-
-func statusArgString(args []any) string {
-	if len(args) == 0 {
-		return ""
-	}
-	return fmt.Sprint(args[0])
-}
-
-var (
-	labelSetMu      sync.RWMutex
-	currentLabelSet localization.LabelSet
-)
-
-func setCurrentLabelSet(ls localization.LabelSet) {
-	labelSetMu.Lock()
-	currentLabelSet = ls
-	labelSetMu.Unlock()
-}
-
-func getCurrentLabelSet() localization.LabelSet {
-	labelSetMu.RLock()
-	defer labelSetMu.RUnlock()
-	return currentLabelSet
-}
-
-type speedOptionID string
-
-const (
-	speedOptionDefault   speedOptionID = "default"
-	speedOptionMedium    speedOptionID = "medium"
-	speedOptionSlow      speedOptionID = "slow"
-	speedOptionSuperSlow speedOptionID = "superSlow"
-	speedOptionCustom    speedOptionID = "custom"
-)
-
-var speedOptionOrder = []speedOptionID{
-	speedOptionDefault,
-	speedOptionMedium,
-	speedOptionSlow,
-	speedOptionSuperSlow,
-	speedOptionCustom,
-}
-
-type compatibilityModeSetting string
-
-const (
-	compatibilityModeAuto     compatibilityModeSetting = "auto"
-	compatibilityModeForceOn  compatibilityModeSetting = "forceOn"
-	compatibilityModeForceOff compatibilityModeSetting = "forceOff"
-)
-
-var compatibilityModeOrder = []compatibilityModeSetting{
-	compatibilityModeAuto,
-	compatibilityModeForceOn,
-	compatibilityModeForceOff,
-}
-
-// Version is set at build time via ldflags
-var Version = "dev"
+// windowHandle identifies a top-level window for the shared GUI.
+type windowHandle = windows.Handle
 
 type windowInfo struct {
-	Hwnd  windows.Handle
+	Hwnd  windowHandle
 	Title string
 }
 
@@ -247,7 +60,7 @@ var (
 	procSendInput                = user32.NewProc("SendInput")
 	procVkKeyScanExW             = user32.NewProc("VkKeyScanExW")
 	procMapVirtualKeyExW         = user32.NewProc("MapVirtualKeyExW")
-	procLoadKeyboardLayoutW      = user32.NewProc("LoadKeyboardLayoutW")
+	procToUnicodeEx              = user32.NewProc("ToUnicodeEx")
 	procGetKeyboardLayout        = user32.NewProc("GetKeyboardLayout")
 	procGetWindowThreadProcessId = user32.NewProc("GetWindowThreadProcessId")
 	procGetForegroundWindow      = user32.NewProc("GetForegroundWindow")
@@ -255,6 +68,7 @@ var (
 	procFindWindowW              = user32.NewProc("FindWindowW")
 
 	procQueryFullProcessImageNameW = kernel32.NewProc("QueryFullProcessImageNameW")
+	procAttachConsole              = kernel32.NewProc("AttachConsole")
 )
 
 const (
@@ -271,7 +85,8 @@ const (
 	vkRMenu    = 0xA5
 	vkReturn   = 0x0D
 
-	mapvkVKToVSC = 0
+	mapvkVKToVSC  = 0
+	mapvkVKToChar = 2
 
 	processQueryLimitedInformation = 0x1000
 
@@ -295,72 +110,6 @@ var ignoredTitleSubstringsLower = []string{
 	// add more substrings if needed
 }
 
-type appCompatibilityRule struct {
-	Name            string
-	ProcessNames    []string
-	TitleSubstrings []string
-}
-
-var modifierCompatibilityRules = []appCompatibilityRule{
-	{
-		Name: "Citrix Workspace / Viewer",
-		ProcessNames: []string{
-			"wfica32.exe",
-			"wfcrun32.exe",
-			"selfservice.exe",
-			"citrixworkspace.exe",
-			"cdviewer.exe",
-			"receiver.exe",
-		},
-		TitleSubstrings: []string{
-			"citrix workspace",
-			"citrix viewer",
-			"cdviewer",
-			"virtual apps and desktops",
-		},
-	},
-	{
-		Name: "HPE iLO Integrated Remote Console",
-		ProcessNames: []string{
-			"integratedremoteconsole.exe",
-			"hpilo-integrated-rc.exe",
-			"hpilo-integrated-remote-console.exe",
-			"hpremoteconsole.exe",
-			"hpiloremoteconsole.exe",
-		},
-		TitleSubstrings: []string{
-			"integrated remote console",
-			"hpe ilo",
-			"hp ilo",
-			"ilo remote console",
-			"ilo:",
-		},
-	},
-}
-
-// ------------------------------------------------
-
-func (r appCompatibilityRule) matches(title string, exe string) bool {
-	if exe != "" {
-		for _, proc := range r.ProcessNames {
-			if exe == proc {
-				return true
-			}
-		}
-	}
-	if title != "" {
-		for _, sub := range r.TitleSubstrings {
-			if sub == "" {
-				continue
-			}
-			if strings.Contains(title, sub) {
-				return true
-			}
-		}
-	}
-	return false
-}
-
 type keyboardInput struct {
 	WVK         uint16
 	WScan       uint16
@@ -459,6 +208,158 @@ func stopForegroundWatcher() {
 	foregroundCallbackRef = 0
 }
 
+// ------------------------- Hotkeys -------------------------
+//
+// Global hotkeys registered with RegisterHotKey on a thread of their own,
+// which runs the message loop that receives WM_HOTKEY.
+
+var (
+	procRegisterHotKey     = user32.NewProc("RegisterHotKey")
+	procUnregisterHotKey   = user32.NewProc("UnregisterHotKey")
+	procGetMessageW        = user32.NewProc("GetMessageW")
+	procPeekMessageW       = user32.NewProc("PeekMessageW")
+	procPostThreadMessageW = user32.NewProc("PostThreadMessageW")
+	procGetAsyncKeyState   = user32.NewProc("GetAsyncKeyState")
+)
+
+const (
+	modAlt      = 0x0001
+	modControl  = 0x0002
+	modShift    = 0x0004
+	modWin      = 0x0008
+	modNoRepeat = 0x4000
+
+	wmQuit   = 0x0012
+	wmHotkey = 0x0312
+
+	mapvkVSCToVKEx = 3
+
+	vkLWin = 0x5B
+	vkRWin = 0x5C
+)
+
+type winMsg struct {
+	Hwnd    uintptr
+	Message uint32
+	WParam  uintptr
+	LParam  uintptr
+	Time    uint32
+	Pt      struct{ X, Y int32 }
+	Private uint32
+}
+
+// hotkeyVK returns the virtual key of the hotkey's key: letters and digits
+// are their own virtual keys, named keys are mapped from the scan code.
+func hotkeyVK(h keyplan.Hotkey) uint16 {
+	if h.Char != 0 {
+		return uint16(h.Char)
+	}
+	code := uintptr(h.Key.Code)
+	if h.Key.Extended {
+		code |= 0xE000
+	}
+	r, _, _ := procMapVirtualKeyExW.Call(code, mapvkVSCToVKEx, 0)
+	return uint16(r)
+}
+
+// startHotkeys registers the hotkeys and calls onPress with the index of
+// each hotkey pressed, on the hotkey thread. Hotkeys that cannot be
+// registered, for example because another program has, are listed in the
+// error while the others still work. The returned function unregisters
+// them and returns once the hotkey thread has ended, so the same keys can
+// be registered again right away.
+func startHotkeys(keys []keyplan.Hotkey, onPress func(i int)) (stop func(), err error) {
+	type started struct {
+		thread uint32
+		err    error
+	}
+	ready := make(chan started)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// hotkeys belong to the thread that registers them
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		var m winMsg
+		// create the thread's message queue before anyone posts to it
+		procPeekMessageW.Call(uintptr(unsafe.Pointer(&m)), 0, 0, 0, 0)
+
+		var failed []string
+		for i, h := range keys {
+			mods := uintptr(modNoRepeat)
+			if h.Mods&keyplan.ModAlt != 0 {
+				mods |= modAlt
+			}
+			if h.Mods&keyplan.ModCtrl != 0 {
+				mods |= modControl
+			}
+			if h.Mods&keyplan.ModShift != 0 {
+				mods |= modShift
+			}
+			if h.Mods&keyplan.ModMeta != 0 {
+				mods |= modWin
+			}
+			vk := hotkeyVK(h)
+			if vk == 0 {
+				failed = append(failed, h.String()+": no such key on this keyboard")
+				continue
+			}
+			if r, _, err := procRegisterHotKey.Call(0, uintptr(i+1), mods, uintptr(vk)); r == 0 {
+				failed = append(failed, fmt.Sprintf("%s: %v", h, err))
+			}
+		}
+		var err error
+		if len(failed) > 0 {
+			err = errors.New(strings.Join(failed, "; "))
+		}
+		ready <- started{thread: windows.GetCurrentThreadId(), err: err}
+
+		for {
+			r, _, _ := procGetMessageW.Call(uintptr(unsafe.Pointer(&m)), 0, 0, 0)
+			if int32(r) <= 0 {
+				break
+			}
+			if m.Message == wmHotkey && m.WParam >= 1 && int(m.WParam) <= len(keys) {
+				onPress(int(m.WParam) - 1)
+			}
+		}
+		for i := range keys {
+			procUnregisterHotKey.Call(0, uintptr(i+1))
+		}
+	}()
+
+	s := <-ready
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			procPostThreadMessageW.Call(uintptr(s.thread), wmQuit, 0, 0)
+			<-done
+		})
+	}, s.err
+}
+
+// hotkeyDown reports whether the key of h is held down, whatever the
+// modifiers.
+func hotkeyDown(h keyplan.Hotkey) bool {
+	vk := hotkeyVK(h)
+	if vk == 0 {
+		return false
+	}
+	r, _, _ := procGetAsyncKeyState.Call(uintptr(vk))
+	return r&0x8000 != 0
+}
+
+// modifiersDown reports whether Shift, Ctrl, Alt or Win is held.
+func modifiersDown() bool {
+	for _, vk := range []uintptr{vkShift, vkControl, vkMenu, vkLWin, vkRWin} {
+		if r, _, _ := procGetAsyncKeyState.Call(vk); r&0x8000 != 0 {
+			return true
+		}
+	}
+	return false
+}
+
 func getForegroundWindow() windows.Handle {
 	r, _, _ := procGetForegroundWindow.Call()
 	return windows.Handle(r)
@@ -569,6 +470,12 @@ func getWindowProcessExeBase(hwnd windows.Handle) string {
 	return exe
 }
 
+// getWindowProcessName returns the lower-case executable name of the
+// window's process, e.g. "wfica32.exe".
+func getWindowProcessName(hwnd windows.Handle) string {
+	return getWindowProcessExeBase(hwnd)
+}
+
 func shouldIgnoreWindow(hwnd windows.Handle, title string, selfExeLower string) bool {
 	t := strings.ToLower(strings.TrimSpace(title))
 	if t == "" {
@@ -605,20 +512,6 @@ func matchesModifierCompatibilityWindow(hwnd windows.Handle) bool {
 	return false
 }
 
-func resolveModifierCompatibility(hwnd windows.Handle, setting compatibilityModeSetting) bool {
-	switch setting {
-	case compatibilityModeForceOn:
-		return true
-	case compatibilityModeForceOff:
-		return false
-	default:
-		if hwnd == 0 {
-			return false
-		}
-		return matchesModifierCompatibilityWindow(hwnd)
-	}
-}
-
 func enumWindows(selfExeLower string) []windowInfo {
 	var wins []windowInfo
 	cb := windows.NewCallback(func(h uintptr, _ uintptr) uintptr {
@@ -640,6 +533,35 @@ func enumWindows(selfExeLower string) []windowInfo {
 	return wins
 }
 
+// attachParentConsole connects stdout and stderr to the console goclip was
+// started from. Built with -H=windowsgui, goclip has no console of its own
+// and the command-line mode would print nothing. Redirected output is left
+// alone.
+func attachParentConsole() {
+	missing := func(std uint32) bool {
+		h, err := windows.GetStdHandle(std)
+		return err != nil || h == 0 || h == windows.InvalidHandle
+	}
+	noOut, noErr := missing(windows.STD_OUTPUT_HANDLE), missing(windows.STD_ERROR_HANDLE)
+	if !noOut && !noErr {
+		return
+	}
+	const attachParentProcess = ^uintptr(0) // ATTACH_PARENT_PROCESS
+	if r, _, _ := procAttachConsole.Call(attachParentProcess); r == 0 {
+		return
+	}
+	con, err := os.OpenFile("CONOUT$", os.O_WRONLY, 0)
+	if err != nil {
+		return
+	}
+	if noOut {
+		os.Stdout = con
+	}
+	if noErr {
+		os.Stderr = con
+	}
+}
+
 func setForegroundWindow(hwnd windows.Handle) bool {
 	r, _, _ := procSetForegroundWindow.Call(uintptr(hwnd))
 	return r != 0
@@ -766,75 +688,16 @@ func mapVirtualKeyEx(vk uint16, hkl windows.Handle) uint16 {
 	return uint16(r & 0xFFFF)
 }
 
-func loadHKLByName(name string) windows.Handle {
-	if name == "Auto (Use System)" || name == "" {
-		h, _, _ := procGetKeyboardLayout.Call(0)
-		return windows.Handle(h)
-	}
-
-	klid := ""
-	switch name {
-	case "English (US)":
-		klid = "00000409"
-	case "US International":
-		klid = "00020409"
-	case "English (UK)":
-		klid = "00000809"
-	case "German (DE)":
-		klid = "00000407"
-	case "French (FR)":
-		klid = "0000040C"
-	case "Spanish (ES)":
-		klid = "0000040A"
-	case "Italian (IT)":
-		klid = "00000410"
-	case "Dutch (NL)":
-		klid = "00000413"
-	case "Portuguese (BR - ABNT2)":
-		klid = "00010416"
-	case "Portuguese (PT)":
-		klid = "00000816"
-	case "Danish (DA)":
-		klid = "00000406"
-	case "Swedish (SV)":
-		klid = "0000041D"
-	case "Finnish (FI)":
-		klid = "0000040B"
-	case "Norwegian (NO)":
-		klid = "00000414"
-	case "Swiss German (DE-CH)":
-		klid = "00000807"
-	case "Swiss French (FR-CH)":
-		klid = "0000100C"
-	case "Polish (Programmers)":
-		klid = "00000415"
-	case "Czech (CS)":
-		klid = "00000405"
-	case "Slovak (SK)":
-		klid = "0000041B"
-	case "Hungarian (HU)":
-		klid = "0000040E"
-	case "Turkish (Q)":
-		klid = "0000041F"
-	case "Russian (RU)":
-		klid = "00000419"
-	case "Ukrainian (UK)":
-		klid = "00000422"
-	case "Hebrew (HE)":
-		klid = "0000040D"
-	case "Arabic (AR)":
-		klid = "00000401"
-	case "Japanese (JP)":
-		klid = "00000411"
-	case "Korean (KO)":
-		klid = "00000412"
-	default:
-		h, _, _ := procGetKeyboardLayout.Call(0)
-		return windows.Handle(h)
-	}
+// isDeadVK reports whether vk is a dead key on hkl (MAPVK_VK_TO_CHAR sets
+// the top bit for those).
+func isDeadVK(vk uint16, hkl windows.Handle) bool {
+	r, _, _ := procMapVirtualKeyExW.Call(uintptr(vk), uintptr(mapvkVKToChar), uintptr(hkl))
+	return uint32(r)&0x80000000 != 0
+}
 
-	ptr, _ := windows.UTF16PtrFromString(klid)
-	h, _, _ := procLoadKeyboardLayoutW.Call(uintptr(unsafe.Pointer(ptr)), uintptr(0))
+// currentHKL returns the keyboard layout of the foreground thread.
+func currentHKL() windows.Handle {
+	h, _, _ := procGetKeyboardLayout.Call(0)
 	return windows.Handle(h)
 }
 
@@ -853,51 +716,6 @@ func vkKeyScanEx(r rune, hkl windows.Handle) (vk uint16, shift byte, ok bool) {
 	return vk, shift, true
 }
 
-func sendEnter(hkl windows.Handle) error {
-	sc := mapVirtualKeyEx(vkReturn, hkl)
-	if sc == 0 {
-		return tapScan(28, false)
-	}
-	return tapScan(sc, false)
-}
-
-func sendCharPhysicalFallback(r rune, perCharDelay time.Duration) error {
-	s := string(r)
-	utf16, err := windows.UTF16FromString(s)
-	if err != nil {
-		return err
-	}
-	for _, u := range utf16 {
-		if u == 0 {
-			continue
-		}
-		if err := sendUnicodeUnit(u); err != nil {
-			return err
-		}
-		time.Sleep(perCharDelay)
-	}
-	return nil
-}
-
-func releaseModifiers(shift byte, compat bool) {
-	// Check if AltGr (Ctrl+Alt = 0x06)
-	if (shift & 0x06) == 0x06 {
-		// Release Right Alt (AltGr) - scan code 0x38 with extended flag
-		_ = sendScan(0x38, true, false)
-	} else {
-		// Release individual modifiers
-		if (shift & 0x04) != 0 {
-			_ = pressAlt(false, compat)
-		}
-		if (shift & 0x02) != 0 {
-			_ = pressCtrl(false, compat)
-		}
-	}
-	if (shift & 0x01) != 0 {
-		_ = pressShift(false, compat)
-	}
-}
-
 func isExtendedVK(vk uint16) bool {
 	switch vk {
 	case 0x25, 0x26, 0x27, 0x28:
@@ -911,1252 +729,175 @@ func isExtendedVK(vk uint16) bool {
 	}
 }
 
-func sendCharPhysical(r rune, hkl windows.Handle, perCharDelay time.Duration, useModifierCompat bool) error {
-	vk, shift, ok := vkKeyScanEx(r, hkl)
-	if !ok {
-		return sendCharPhysicalFallback(r, perCharDelay)
-	}
-	sc := mapVirtualKeyEx(vk, hkl)
-	if sc == 0 {
-		return sendCharPhysicalFallback(r, perCharDelay)
-	}
-	if (shift & 0x01) != 0 {
-		if err := pressShift(true, useModifierCompat); err != nil {
-			return err
-		}
-	}
-	// Check if AltGr is needed (Ctrl+Alt = 0x06)
-	if (shift & 0x06) == 0x06 {
-		// Use Right Alt (AltGr) - scan code 0x38 with extended flag for better web console compatibility
-		if err := sendScan(0x38, true, true); err != nil {
-			releaseModifiers(shift, useModifierCompat)
-			return err
-		}
-	} else {
-		// Press Ctrl and/or Alt individually if needed
-		if (shift & 0x02) != 0 {
-			if err := pressCtrl(true, useModifierCompat); err != nil {
-				return err
-			}
-		}
-		if (shift & 0x04) != 0 {
-			if err := pressAlt(true, useModifierCompat); err != nil {
-				releaseModifiers(shift, useModifierCompat)
-				return err
-			}
-		}
-	}
-	if err := tapScan(sc, isExtendedVK(vk)); err != nil {
-		releaseModifiers(shift, useModifierCompat)
-		return err
-	}
-	releaseModifiers(shift, useModifierCompat)
-	time.Sleep(perCharDelay)
-	return nil
+// hklMapper resolves characters through a Windows keyboard layout.
+type hklMapper struct {
+	hkl windows.Handle
 }
 
-func sendText(text string, layout string, perCharDelay time.Duration, useModifierCompat bool, shouldStop func() bool) error {
-	hkl := loadHKLByName(layout)
-	text = strings.ReplaceAll(text, "\r\n", "\n")
-
-	for _, r := range text {
-		if shouldStop != nil && shouldStop() {
-			// cancelled by user
-			return nil
-		}
-
-		if r == '\n' {
-			if err := sendEnter(hkl); err != nil {
-				return err
-			}
-			time.Sleep(perCharDelay)
-			continue
-		}
-
-		if err := sendCharPhysical(r, hkl, perCharDelay, useModifierCompat); err != nil {
-			return err
-		}
-	}
-
-	return nil
+func (m hklMapper) Lookup(r rune) (keyplan.Stroke, bool) {
+	s, dead, ok := m.stroke(r)
+	return s, ok && !dead
 }
 
-// truncateRunes limits to n runes, appends "..." if truncated.
-func truncateRunes(s string, n int) string {
-	r := []rune(strings.TrimSpace(s))
-	if len(r) <= n {
-		return s
+// LookupDead composes r from a dead key of the layout and a base character.
+func (m hklMapper) LookupDead(r rune) (dead, base keyplan.Stroke, ok bool) {
+	if s, isDead, ok := m.stroke(r); ok && isDead {
+		space, ok := m.Lookup(' ')
+		return s, space, ok
 	}
-	if n <= 3 {
-		return string(r[:n])
+	b, deads, ok := layout.DeadKeysFor(r)
+	if !ok {
+		return keyplan.Stroke{}, keyplan.Stroke{}, false
 	}
-	return string(r[:n]) + "..."
-}
-
-// load ICO from embedded bytes, with a dev-time disk fallback
-func loadAppIcon() fyne.Resource {
-	if len(embeddedAppIco) > 0 {
-		return fyne.NewStaticResource("app.ico", embeddedAppIco)
+	if base, ok = m.Lookup(b); !ok {
+		return keyplan.Stroke{}, keyplan.Stroke{}, false
 	}
-	// fallback for `go run` from source
-	data, err := os.ReadFile("assets/logo/app.ico")
-	if err == nil {
-		return fyne.NewStaticResource("app.ico", data)
+	for _, d := range deads {
+		if s, isDead, ok := m.stroke(d); ok && isDead && m.composes(d, b, r) {
+			return s, base, true
+		}
 	}
-	return nil
+	return keyplan.Stroke{}, keyplan.Stroke{}, false
 }
 
-func main() {
-	// Load configuration from disk
-	if err := config.Load(); err != nil {
-		// Config load failed, continue with defaults
-		_ = err
+// composes asks the layout whether the dead key typing dead, followed by
+// base, produces r. Layouts only compose some of the pairs Unicode has a
+// character for, and some compose others (' and c is ç on US
+// International). The dead key state this leaves is on goclip's own
+// thread and is consumed by the base key.
+func (m hklMapper) composes(dead, base, r rune) bool {
+	type press struct {
+		vk, sc uint16
+		state  [256]byte
 	}
-	cfg := config.Get()
-
-	systemLanguageCode := localization.DetectSystemLanguage()
-	// Use saved language preference if set
-	selectedLanguageCode := cfg.Language
-	effectiveLanguage := selectedLanguageCode
-	if effectiveLanguage == "" {
-		effectiveLanguage = systemLanguageCode
-	}
-	setCurrentLabelSet(localization.Labels(effectiveLanguage))
-	languageMetas := localization.SupportedLanguages()
-
-	var applyLocalization func(localization.LabelSet)
-	var applyLanguageSelection func()
-
-	myApp := app.New()
-	myApp.Settings().SetTheme(theme.DarkTheme())
-
-	// set runtime icon (taskbar/window) from embedded resource
-	if res := loadAppIcon(); res != nil {
-		myApp.SetIcon(res)
-	}
-
-	// our own exe base name (lower) to avoid listing ourselves
-	selfPath, _ := os.Executable()
-	selfExeLower := strings.ToLower(filepath.Base(selfPath))
-
-	w := myApp.NewWindow("goclip")
-	w.Resize(fyne.NewSize(800, 460))
-
-	// also set it on the window explicitly
-	if res := loadAppIcon(); res != nil {
-		w.SetIcon(res)
-	}
-
-	// --- Input field with Hide/Show (eye) toggle ---
-	inputEntry := widget.NewMultiLineEntry()
-	inputEntry.Wrapping = fyne.TextWrapWord
-
-	masked := false
-	var eyeBtn *widget.Button
-	eyeBtn = widget.NewButtonWithIcon("", theme.VisibilityIcon(), func() {
-		masked = !masked
-		inputEntry.Password = masked
-		if masked {
-			eyeBtn.SetIcon(theme.VisibilityOffIcon())
-		} else {
-			eyeBtn.SetIcon(theme.VisibilityIcon())
-		}
-		inputEntry.Refresh()
-	})
-	eyeBtn.Importance = widget.LowImportance
-
-	inputRow := container.NewBorder(nil, nil, nil, eyeBtn, inputEntry)
-
-	statusLabel := widget.NewLabel("")
-	statusLabel.Wrapping = fyne.TextWrapWord
-	statusCtrl := newStatusController(statusLabel)
-
-	layoutSelect := widget.NewSelect([]string{
-		"Auto (Use System)",
-		"English (US)",
-		"US International",
-		"English (UK)",
-		"German (DE)",
-		"French (FR)",
-		"Spanish (ES)",
-		"Italian (IT)",
-		"Dutch (NL)",
-		"Portuguese (BR - ABNT2)",
-		"Portuguese (PT)",
-		"Danish (DA)",
-		"Swedish (SV)",
-		"Finnish (FI)",
-		"Norwegian (NO)",
-		"Swiss German (DE-CH)",
-		"Swiss French (FR-CH)",
-		"Polish (Programmers)",
-		"Czech (CS)",
-		"Slovak (SK)",
-		"Hungarian (HU)",
-		"Turkish (Q)",
-		"Russian (RU)",
-		"Ukrainian (UK)",
-		"Hebrew (HE)",
-		"Arabic (AR)",
-		"Japanese (JP)",
-		"Korean (KO)",
-	}, nil)
-	layoutSelect.Selected = cfg.KeyboardLayout
-	if layoutSelect.Selected == "" {
-		layoutSelect.Selected = "Auto (Use System)"
-	}
-
-	languageSelect := widget.NewSelect([]string{}, nil)
-	languageLabelToCode := make(map[string]string)
-	languageSelectUpdating := false
-	languageNativeName := func(code string) string {
-		for _, meta := range languageMetas {
-			if meta.Code == code {
-				return meta.NativeName
-			}
-		}
-		return ""
-	}
-
-	languageSelect.OnChanged = func(label string) {
-		if languageSelectUpdating {
-			return
-		}
-		code, ok := languageLabelToCode[label]
+	var keys [2]press
+	for i, c := range [2]rune{dead, base} {
+		vk, shift, ok := vkKeyScanEx(c, m.hkl)
 		if !ok {
-			return
-		}
-		if code == selectedLanguageCode {
-			return
-		}
-		selectedLanguageCode = code
-		applyLanguageSelection()
-	}
-
-	refreshLanguageSelectOptions := func(labels localization.LabelSet) {
-		languageSelectUpdating = true
-		autoLabel := labels.LanguageAutoOption
-		options := make([]string, 0, len(languageMetas)+1)
-		languageLabelToCode = map[string]string{autoLabel: ""}
-		options = append(options, autoLabel)
-		for _, meta := range languageMetas {
-			options = append(options, meta.NativeName)
-			languageLabelToCode[meta.NativeName] = meta.Code
-		}
-		selectedLabel := autoLabel
-		if selectedLanguageCode != "" {
-			if native := languageNativeName(selectedLanguageCode); native != "" {
-				selectedLabel = native
-			}
+			return false
 		}
-		languageSelect.Options = options
-		languageSelect.SetSelected(selectedLabel)
-		languageSelectUpdating = false
-	}
-
-	// --- Typing speed controls (dropdown + optional custom ms field) ---
-	speedSelect := widget.NewSelect([]string{}, nil)
-	// Initialize speed from config
-	currentSpeedOption := speedOptionID(cfg.DefaultSpeedOption)
-	if currentSpeedOption == "" {
-		currentSpeedOption = speedOptionDefault
-	}
-	speedLabelToID := make(map[string]speedOptionID)
-	speedIDToLabel := make(map[speedOptionID]string)
-	speedSelectUpdating := false
-
-	customMsEntry := widget.NewEntry()
-	// Initialize custom ms entry with saved value if custom speed is set
-	if cfg.CustomSpeedMs > 0 {
-		customMsEntry.SetText(strconv.Itoa(cfg.CustomSpeedMs))
-	}
-	if currentSpeedOption != speedOptionCustom {
-		customMsEntry.Hide()
-	}
-
-	// Dynamic per-character delay selection
-	getPerCharDelay := func(text string) time.Duration {
-		switch currentSpeedOption {
-		case speedOptionDefault:
-			runeCount := 0
-			lines := 1
-			for _, ch := range text {
-				runeCount++
-				if ch == '\n' {
-					lines++
-				}
-			}
-
-			if runeCount <= 200 && lines <= 5 {
-				return 0
-			}
-
-			msByLines := lines
-			msByChars := runeCount / 200
-			ms := msByLines
-			if msByChars > ms {
-				ms = msByChars
+		keys[i].vk, keys[i].sc = vk, mapVirtualKeyEx(vk, m.hkl)
+		for bit, mod := range [3]uint16{vkShift, vkControl, vkMenu} {
+			if shift&(1<<bit) != 0 {
+				keys[i].state[mod] = 0x80
 			}
-			if ms < 10 {
-				ms = 10
-			}
-			if ms > 50 {
-				ms = 50
-			}
-			return time.Duration(ms) * time.Millisecond
-		case speedOptionMedium:
-			return 50 * time.Millisecond
-		case speedOptionSlow:
-			return 100 * time.Millisecond
-		case speedOptionSuperSlow:
-			return 250 * time.Millisecond
-		case speedOptionCustom:
-			v := strings.TrimSpace(customMsEntry.Text)
-			if v == "" {
-				return 0
-			}
-			var acc int64
-			for _, ch := range v {
-				if ch < '0' || ch > '9' {
-					return 0
-				}
-				acc = acc*10 + int64(ch-'0')
-				if acc > 10000 {
-					acc = 10000
-					break
-				}
-			}
-			return time.Duration(acc) * time.Millisecond
-		default:
-			return 0
 		}
 	}
-
-	delayLabel := widget.NewLabel("")
-
-	updateDelayLabel := func() {
-		if currentSpeedOption != speedOptionDefault {
-			delayLabel.Hide()
-			return
-		}
-		delayLabel.Show()
-		d := getPerCharDelay(inputEntry.Text)
-		labels := getCurrentLabelSet()
-		delayLabel.SetText(fmt.Sprintf(labels.DelayLabelFormat, d.Milliseconds()))
-	}
-
-	speedSelect.OnChanged = func(label string) {
-		if speedSelectUpdating {
-			return
-		}
-		id, ok := speedLabelToID[label]
-		if !ok {
-			id = speedOptionDefault
-		}
-		if currentSpeedOption == id {
-			return
-		}
-		currentSpeedOption = id
-		if id == speedOptionCustom {
-			customMsEntry.Show()
-		} else {
-			customMsEntry.Hide()
-		}
-		updateDelayLabel()
-	}
-
-	customMsEntry.OnChanged = func(string) {
-		updateDelayLabel()
-	}
-
-	inputEntry.OnChanged = func(string) {
-		updateDelayLabel()
-	}
-
-	compatibilityModeSelect := widget.NewSelect([]string{}, nil)
-	// Initialize compatibility mode from config
-	currentCompatibilitySetting := compatibilityModeSetting(cfg.CompatibilityMode)
-	if currentCompatibilitySetting == "" {
-		currentCompatibilitySetting = compatibilityModeAuto
-	}
-	compatibilityLabelToSetting := make(map[string]compatibilityModeSetting)
-	compatibilitySettingToLabel := make(map[compatibilityModeSetting]string)
-	compatibilitySelectUpdating := false
-	compatibilityStatusLabel := widget.NewLabel("")
-
-	// Create a small tappable icon for help
-	helpSize := fyne.NewSize(18, 18)
-
-	helpImg := canvas.NewImageFromResource(theme.QuestionIcon())
-	helpImg.FillMode = canvas.ImageFillContain
-	helpImg.SetMinSize(helpSize)
-
-	compatibilityHelpBtn := widget.NewButton("", func() {
-		labels := getCurrentLabelSet()
-		dialog.NewInformation(labels.CompatibilityHelpTitle, labels.CompatibilityHelpMessage, w).Show()
-	})
-	compatibilityHelpBtn.Importance = widget.LowImportance
-
-	// Force the clickable area and the icon to the same fixed size
-	compatibilityHelpContainer := container.NewGridWrap(
-		helpSize,
-		container.NewMax(compatibilityHelpBtn, helpImg),
+	var (
+		buf [8]uint16
+		n   [2]int32
 	)
-
-	var updateCompatibilityStatus func()
-
-	refreshSpeedSelectOptions := func(labels localization.LabelSet) {
-		speedSelectUpdating = true
-		speedIDToLabel = map[speedOptionID]string{
-			speedOptionDefault:   labels.SpeedDefault,
-			speedOptionMedium:    labels.SpeedMedium,
-			speedOptionSlow:      labels.SpeedSlow,
-			speedOptionSuperSlow: labels.SpeedSuperSlow,
-			speedOptionCustom:    labels.SpeedCustom,
-		}
-		speedLabelToID = make(map[string]speedOptionID, len(speedIDToLabel))
-		options := make([]string, 0, len(speedOptionOrder))
-		for _, id := range speedOptionOrder {
-			label := speedIDToLabel[id]
-			options = append(options, label)
-			speedLabelToID[label] = id
-		}
-		speedSelect.Options = options
-		targetID := currentSpeedOption
-		if _, ok := speedIDToLabel[targetID]; !ok {
-			targetID = speedOptionDefault
-			currentSpeedOption = targetID
-		}
-		if label, ok := speedIDToLabel[targetID]; ok {
-			speedSelect.SetSelected(label)
-		}
-		if currentSpeedOption == speedOptionCustom {
-			customMsEntry.Show()
-		} else {
-			customMsEntry.Hide()
-		}
-		speedSelectUpdating = false
-	}
-
-	refreshCompatibilitySelectOptions := func(labels localization.LabelSet) {
-		compatibilitySelectUpdating = true
-		compatibilitySettingToLabel = map[compatibilityModeSetting]string{
-			compatibilityModeAuto:     labels.CompatibilityModeAuto,
-			compatibilityModeForceOn:  labels.CompatibilityModeOn,
-			compatibilityModeForceOff: labels.CompatibilityModeOff,
-		}
-		compatibilityLabelToSetting = make(map[string]compatibilityModeSetting, len(compatibilityModeOrder))
-		options := make([]string, 0, len(compatibilityModeOrder))
-		for _, setting := range compatibilityModeOrder {
-			label := compatibilitySettingToLabel[setting]
-			options = append(options, label)
-			compatibilityLabelToSetting[label] = setting
-		}
-		compatibilityModeSelect.Options = options
-		target := currentCompatibilitySetting
-		if _, ok := compatibilitySettingToLabel[target]; !ok {
-			target = compatibilityModeAuto
-			currentCompatibilitySetting = target
-		}
-		if label, ok := compatibilitySettingToLabel[target]; ok {
-			compatibilityModeSelect.SetSelected(label)
-		}
-		compatibilitySelectUpdating = false
-		updateCompatibilityStatus()
+	for i := range keys {
+		k := &keys[i]
+		ret, _, _ := procToUnicodeEx.Call(uintptr(k.vk), uintptr(k.sc), uintptr(unsafe.Pointer(&k.state[0])),
+			uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)), 0, uintptr(m.hkl))
+		n[i] = int32(ret)
 	}
+	return n[0] == -1 && n[1] == 1 && rune(buf[0]) == r
+}
 
-	winOptions := []string{}
-	winMap := map[string]windows.Handle{}
-
-	var laMu sync.RWMutex
-	lastActiveHandle := windows.Handle(0)
-	lastActiveTitle := ""
-	lastActiveText := binding.NewString()
-	updateLastActiveLabel := func() {
-		labels := getCurrentLabelSet()
-		laMu.RLock()
-		title := strings.TrimSpace(lastActiveTitle)
-		laMu.RUnlock()
-		if title == "" {
-			title = labels.LastActiveNone
-		}
-		_ = lastActiveText.Set(fmt.Sprintf(labels.LastActiveFormat, title))
+// stroke returns the key typing r and whether it is a dead key.
+func (m hklMapper) stroke(r rune) (keyplan.Stroke, bool, bool) {
+	vk, shift, ok := vkKeyScanEx(r, m.hkl)
+	if !ok {
+		return keyplan.Stroke{}, false, false
 	}
-	updateLastActiveLabel()
-	lastActiveLabel := widget.NewLabelWithData(lastActiveText)
-
-	windowSelect := widget.NewSelect(winOptions, nil)
-
-	updateCompatibilityStatus = func() {
-		labels := getCurrentLabelSet()
-		text := labels.CompatibilityStatusUnknown
-
-		var hwnd windows.Handle
-		selected := windowSelect.Selected
-		if selected == "" {
-			laMu.RLock()
-			hwnd = lastActiveHandle
-			laMu.RUnlock()
-		} else {
-			if h, ok := winMap[selected]; ok {
-				hwnd = h
-			}
-		}
-
-		switch currentCompatibilitySetting {
-		case compatibilityModeForceOn:
-			text = fmt.Sprintf(labels.CompatibilityStatusFormat, labels.CompatibilityStatusActive)
-		case compatibilityModeForceOff:
-			text = fmt.Sprintf(labels.CompatibilityStatusFormat, labels.CompatibilityStatusInactive)
-		default:
-			if hwnd == 0 {
-				text = labels.CompatibilityStatusUnknown
-			} else if matchesModifierCompatibilityWindow(hwnd) {
-				text = fmt.Sprintf(labels.CompatibilityStatusFormat, labels.CompatibilityStatusActive)
-			} else {
-				text = fmt.Sprintf(labels.CompatibilityStatusFormat, labels.CompatibilityStatusInactive)
-			}
-		}
-
-		fyne.Do(func() {
-			compatibilityStatusLabel.SetText(text)
-		})
+	sc := mapVirtualKeyEx(vk, m.hkl)
+	if sc == 0 {
+		return keyplan.Stroke{}, false, false
 	}
-
-	windowSelect.OnChanged = func(string) {
-		updateCompatibilityStatus()
+	var mods keyplan.Modifier
+	if (shift & 0x01) != 0 {
+		mods |= keyplan.ModShift
 	}
-
-	updateCompatibilityStatus()
-
-	compatibilityModeSelect.OnChanged = func(label string) {
-		if compatibilitySelectUpdating {
-			return
-		}
-		setting, ok := compatibilityLabelToSetting[label]
-		if !ok {
-			setting = compatibilityModeAuto
-		}
-		if currentCompatibilitySetting == setting {
-			return
+	// Ctrl+Alt (0x06) is AltGr
+	if (shift & 0x06) == 0x06 {
+		mods |= keyplan.ModAltGr
+	} else {
+		if (shift & 0x02) != 0 {
+			mods |= keyplan.ModCtrl
 		}
-		currentCompatibilitySetting = setting
-		updateCompatibilityStatus()
-	}
-
-	clearBtn := widget.NewButton("", func() {
-		windowSelect.Selected = ""
-		windowSelect.Refresh()
-		statusCtrl.Set(statusKeySelectionCleared)
-		updateCompatibilityStatus()
-	})
-
-	refreshWindows := func() {
-		wins := enumWindows(selfExeLower)
-		winOptions = []string{}
-		winMap = map[string]windows.Handle{}
-		for _, wi := range wins {
-			short := truncateRunes(wi.Title, 30) // limit to 30 chars in list
-			label := fmt.Sprintf("%s (0x%X)", short, uintptr(wi.Hwnd))
-			winOptions = append(winOptions, label)
-			winMap[label] = wi.Hwnd
+		if (shift & 0x04) != 0 {
+			mods |= keyplan.ModAlt
 		}
-		windowSelect.Options = winOptions
-		windowSelect.Refresh()
-		statusCtrl.Set(statusKeyFoundWindows, len(wins))
-		updateCompatibilityStatus()
-	}
-
-	refreshBtn := widget.NewButton("", refreshWindows)
-
-	// Start event-driven watcher of foreground windows
-	err := startForegroundWatcher(selfExeLower, func(hwnd windows.Handle, title string) {
-		t := truncateRunes(title, 30)
-
-		laMu.Lock()
-		lastActiveHandle = hwnd
-		lastActiveTitle = t
-		laMu.Unlock()
-
-		updateLastActiveLabel()
-		updateCompatibilityStatus()
-	})
-	if err != nil {
-		statusCtrl.Set(statusKeyWatcherWarning, err.Error())
 	}
+	return keyplan.Stroke{
+		Key:  keyplan.Key{Code: sc, Extended: isExtendedVK(vk)},
+		Mods: mods,
+	}, isDeadVK(vk, m.hkl), true
+}
 
-	// Ensure cleanup when main exits
-	defer stopForegroundWatcher()
-
-	// --- Typing state / stop handling ---
-	var typingMu sync.Mutex
-	typingStopRequested := false
-
-	setStopRequested := func(v bool) {
-		typingMu.Lock()
-		typingStopRequested = v
-		typingMu.Unlock()
-	}
+// sendInputInjector replays keystroke plans through SendInput.
+type sendInputInjector struct {
+	useModifierCompat bool
+}
 
-	shouldStop := func() bool {
-		typingMu.Lock()
-		v := typingStopRequested
-		typingMu.Unlock()
-		return v
-	}
+func (in sendInputInjector) PressKey(k keyplan.Key, down bool) error {
+	return sendScan(k.Code, k.Extended, down)
+}
 
-	// focus-change abort flag and checkbox
-	abortOnFocusChange := cfg.AbortOnFocusChange
-	abortFocusCheck := widget.NewCheck("", func(b bool) {
-		abortOnFocusChange = b
-	})
-	abortFocusCheck.SetChecked(cfg.AbortOnFocusChange)
-
-	// always on top flag and checkbox
-	var applyAlwaysOnTop func(bool)
-	alwaysOnTopCheck := widget.NewCheck("", nil)
-	applyAlwaysOnTop = func(topmost bool) {
-		// Find our own window by title and apply always on top setting
-		// We need to do this on the main thread after the window is created
-		go func() {
-			// Small delay to ensure the window title is set
-			time.Sleep(50 * time.Millisecond)
-			hwnd := findWindowByTitle(w.Title())
-			if hwnd != 0 {
-				setWindowAlwaysOnTop(hwnd, topmost)
-			}
-		}()
-	}
-	alwaysOnTopCheck.OnChanged = func(b bool) {
-		applyAlwaysOnTop(b)
-	}
-	alwaysOnTopCheck.SetChecked(cfg.AlwaysOnTop)
-	// Apply initial always on top setting
-	if cfg.AlwaysOnTop {
-		// Set window to always on top on startup
+func (in sendInputInjector) PressModifier(mod keyplan.Modifier, down bool) error {
+	switch mod {
+	case keyplan.ModShift:
+		return pressShift(down, in.useModifierCompat)
+	case keyplan.ModCtrl:
+		return pressCtrl(down, in.useModifierCompat)
+	case keyplan.ModAlt:
+		return pressAlt(down, in.useModifierCompat)
+	case keyplan.ModAltGr:
+		// Right Alt (AltGr) - scan code 0x38 with extended flag for better web console compatibility
+		return sendScan(0x38, true, down)
+	case keyplan.ModMeta:
+		return sendScan(0x5B, true, down)
+	default:
+		return nil
 	}
+}
 
-	var typeBtn *widget.Button
-	var typeClipboardBtn *widget.Button
-	var stopBtn *widget.Button
-	var actionContainer *fyne.Container
-
-	setTypingUI := func(active bool) {
-		if actionContainer == nil {
-			return
-		}
-		if active {
-			if stopBtn != nil {
-				actionContainer.Objects = []fyne.CanvasObject{stopBtn}
-				actionContainer.Refresh()
-			}
-		} else {
-			if typeBtn != nil && typeClipboardBtn != nil {
-				actionContainer.Objects = []fyne.CanvasObject{typeBtn, typeClipboardBtn}
-				actionContainer.Refresh()
-			}
-		}
+func (in sendInputInjector) TypeUnicode(r rune) error {
+	utf16, err := windows.UTF16FromString(string(r))
+	if err != nil {
+		return err
 	}
-
-	// Stop button (shown while typing)
-	stopBtn = widget.NewButton("", func() {
-		setStopRequested(true)
-		statusCtrl.Set(statusKeyStopping)
-	})
-	stopBtn.Importance = widget.DangerImportance
-
-	// --- Type Button ---
-	typeBtn = widget.NewButton("", func() {
-		selected := windowSelect.Selected
-
-		laMu.RLock()
-		curH := lastActiveHandle
-		curTitle := lastActiveTitle
-		laMu.RUnlock()
-
-		var hwnd windows.Handle
-		if selected == "" {
-			hwnd = curH
-		} else {
-			var ok bool
-			hwnd, ok = winMap[selected]
-			if !ok || hwnd == 0 {
-				statusCtrl.Set(statusKeyWindowUnavailable)
-				return
-			}
-		}
-
-		if hwnd == 0 {
-			statusCtrl.Set(statusKeyNoWindow)
-			return
-		}
-
-		setForegroundWindow(hwnd)
-		time.Sleep(150 * time.Millisecond)
-
-		txt := inputEntry.Text
-		if txt == "" {
-			statusCtrl.Set(statusKeyNothingToType)
-			return
-		}
-
-		useModifierCompat := resolveModifierCompatibility(hwnd, currentCompatibilitySetting)
-		perChar := getPerCharDelay(txt)
-		setStopRequested(false)
-		setTypingUI(true)
-		statusCtrl.Set(statusKeyTyping)
-
-		go func(hwnd windows.Handle, curTitle string, txt string, perChar time.Duration, modifierCompat bool) {
-			// stop on user cancel or focus change (if enabled)
-			shouldStopWithFocus := func() bool {
-				if shouldStop() {
-					return true
-				}
-				if abortOnFocusChange {
-					current := getForegroundWindow()
-					if current != 0 && current != hwnd {
-						return true
-					}
-				}
-				return false
-			}
-
-			err := sendText(txt, layoutSelect.Selected, perChar, modifierCompat, shouldStopWithFocus)
-			canceled := shouldStopWithFocus()
-
-			title := strings.TrimSpace(getWindowText(hwnd))
-			if title == "" {
-				title = curTitle
-			}
-			title = truncateRunes(title, 30)
-
-			fyne.Do(func() {
-				if canceled {
-					statusCtrl.Set(statusKeyTypingStopped)
-				} else if err != nil {
-					statusCtrl.Set(statusKeyTypingError, err.Error())
-				} else {
-					statusCtrl.Set(statusKeyTypedTo, title)
-				}
-				setTypingUI(false)
-				setStopRequested(false)
-			})
-		}(hwnd, curTitle, txt, perChar, useModifierCompat)
-	})
-
-	// --- Type Clipboard Button ---
-	typeClipboardBtn = widget.NewButton("", func() {
-		selected := windowSelect.Selected
-
-		laMu.RLock()
-		curH := lastActiveHandle
-		curTitle := lastActiveTitle
-		laMu.RUnlock()
-
-		var hwnd windows.Handle
-		if selected == "" {
-			hwnd = curH
-		} else {
-			var ok bool
-			hwnd, ok = winMap[selected]
-			if !ok || hwnd == 0 {
-				statusCtrl.Set(statusKeyWindowUnavailable)
-				return
-			}
-		}
-
-		if hwnd == 0 {
-			statusCtrl.Set(statusKeyNoWindow)
-			return
-		}
-
-		setForegroundWindow(hwnd)
-		time.Sleep(150 * time.Millisecond)
-
-		txt := w.Clipboard().Content()
-		if txt == "" {
-			statusCtrl.Set(statusKeyClipboardEmpty)
-			return
-		}
-
-		useModifierCompat := resolveModifierCompatibility(hwnd, currentCompatibilitySetting)
-		perChar := getPerCharDelay(txt)
-		setStopRequested(false)
-		setTypingUI(true)
-		statusCtrl.Set(statusKeyTypingClipboard)
-
-		go func(hwnd windows.Handle, curTitle string, txt string, perChar time.Duration, modifierCompat bool) {
-			// stop on user cancel or focus change (if enabled)
-			shouldStopWithFocus := func() bool {
-				if shouldStop() {
-					return true
-				}
-				if abortOnFocusChange {
-					current := getForegroundWindow()
-					if current != 0 && current != hwnd {
-						return true
-					}
-				}
-				return false
-			}
-
-			err := sendText(txt, layoutSelect.Selected, perChar, modifierCompat, shouldStopWithFocus)
-			canceled := shouldStopWithFocus()
-
-			title := strings.TrimSpace(getWindowText(hwnd))
-			if title == "" {
-				title = curTitle
-			}
-			title = truncateRunes(title, 30)
-
-			fyne.Do(func() {
-				if canceled {
-					statusCtrl.Set(statusKeyTypingStopped)
-				} else if err != nil {
-					statusCtrl.Set(statusKeyTypingClipboardError, err.Error())
-				} else {
-					statusCtrl.Set(statusKeyTypedClipboard, title)
-				}
-				setTypingUI(false)
-				setStopRequested(false)
-			})
-		}(hwnd, curTitle, txt, perChar, useModifierCompat)
-	})
-
-	// Action container that switches between [Type, Type Clipboard] and [Stop]
-	actionContainer = container.NewHBox(typeBtn, typeClipboardBtn)
-
-	// Left side: window selector + buttons
-	targetWindowLabel := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
-	keyboardLayoutLabel := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
-	typingSpeedLabel := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
-	compatibilityModeLabel := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
-	textToTypeLabel := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
-
-	// Version label + languageselector in bottom right
-	versionLabel := widget.NewLabel(Version)
-	versionLabel.TextStyle = fyne.TextStyle{Italic: true}
-	versionLabel.Alignment = fyne.TextAlignTrailing
-	languageHeadingLabel := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
-
-	// top/header section
-	// left side: window selector + buttons + last active
-	left := container.NewVBox(
-		targetWindowLabel,
-		container.NewHBox(windowSelect, clearBtn),
-		refreshBtn,
-		lastActiveLabel,
-	)
-	// right side: split into two columns
-	compatibilityHeader := container.NewHBox(
-		compatibilityModeLabel,
-		container.NewCenter(compatibilityHelpContainer),
-	)
-
-	// left column on the right side: layout + speed
-	rightRight := container.NewVBox(
-		keyboardLayoutLabel,
-		layoutSelect,
-		widget.NewSeparator(),
-		typingSpeedLabel,
-		speedSelect,
-		customMsEntry,
-	)
-
-	// right column on the right side: compatibility controls
-	rightLeft := container.NewVBox(
-		compatibilityHeader,
-		compatibilityModeSelect,
-		compatibilityStatusLabel,
-	)
-
-	// combine into a two-column container
-	right := container.NewHBox(
-		rightLeft,
-		rightRight,
-	)
-	// assemble header
-	header := container.NewBorder(nil, nil, left, right, nil)
-
-	// body/center section
-	// center: text to type + input area
-	body_center := container.NewBorder(
-		textToTypeLabel,
-		nil,
-		nil,
-		nil,
-		inputRow,
-	)
-	// assemble body
-	body := container.NewBorder(
-		nil,
-		nil,
-		nil,
-		nil,
-		body_center,
-	)
-
-	//bottom/footer section
-	//bottom left: delay label + checkbox + action buttons + status
-	bottom_left := container.NewVBox(
-		delayLabel,
-		actionContainer,
-		statusLabel,
-	)
-
-	// Settings button (gear icon)
-	var settingsBtn *widget.Button
-	showSettingsDialog := func() {
-		labels := getCurrentLabelSet()
-
-		// Create settings dialog
-		settingsWindow := myApp.NewWindow(labels.SettingsTitle)
-		settingsWindow.Resize(fyne.NewSize(600, 500))
-
-		// Copy current config
-		currentCfg := config.Get()
-
-		// Speed option selector
-		settingsSpeedSelect := widget.NewSelect([]string{}, nil)
-		settingsSpeedLabelToID := make(map[string]speedOptionID)
-		settingsSpeedIDToLabel := make(map[speedOptionID]string)
-		settingsCurrentSpeedOption := speedOptionID(currentCfg.DefaultSpeedOption)
-		if settingsCurrentSpeedOption == "" {
-			settingsCurrentSpeedOption = speedOptionDefault
-		}
-
-		// Custom ms entry for settings
-		settingsCustomMsEntry := widget.NewEntry()
-		if currentCfg.CustomSpeedMs > 0 {
-			settingsCustomMsEntry.SetText(strconv.Itoa(currentCfg.CustomSpeedMs))
-		}
-		settingsCustomMsEntry.SetPlaceHolder(labels.SettingsCustomSpeedMs)
-		if settingsCurrentSpeedOption != speedOptionCustom {
-			settingsCustomMsEntry.Hide()
-		}
-
-		settingsSpeedSelect.OnChanged = func(label string) {
-			if id, ok := settingsSpeedLabelToID[label]; ok {
-				settingsCurrentSpeedOption = id
-				if id == speedOptionCustom {
-					settingsCustomMsEntry.Show()
-				} else {
-					settingsCustomMsEntry.Hide()
-				}
-			}
-		}
-
-		// Populate speed options
-		settingsSpeedIDToLabel = map[speedOptionID]string{
-			speedOptionDefault:   labels.SpeedDefault,
-			speedOptionMedium:    labels.SpeedMedium,
-			speedOptionSlow:      labels.SpeedSlow,
-			speedOptionSuperSlow: labels.SpeedSuperSlow,
-			speedOptionCustom:    labels.SpeedCustom,
-		}
-		speedOptions := make([]string, 0, len(speedOptionOrder))
-		for _, id := range speedOptionOrder {
-			label := settingsSpeedIDToLabel[id]
-			speedOptions = append(speedOptions, label)
-			settingsSpeedLabelToID[label] = id
-		}
-		settingsSpeedSelect.Options = speedOptions
-		if label, ok := settingsSpeedIDToLabel[settingsCurrentSpeedOption]; ok {
-			settingsSpeedSelect.SetSelected(label)
-		}
-
-		// Keyboard layout selector
-		settingsLayoutSelect := widget.NewSelect(layoutSelect.Options, nil)
-		settingsLayoutSelect.SetSelected(currentCfg.KeyboardLayout)
-
-		// Compatibility mode selector
-		settingsCompatSelect := widget.NewSelect([]string{}, nil)
-		settingsCompatLabelToSetting := make(map[string]compatibilityModeSetting)
-		settingsCompatSettingToLabel := map[compatibilityModeSetting]string{
-			compatibilityModeAuto:     labels.CompatibilityModeAuto,
-			compatibilityModeForceOn:  labels.CompatibilityModeOn,
-			compatibilityModeForceOff: labels.CompatibilityModeOff,
-		}
-		compatOptions := make([]string, 0, len(compatibilityModeOrder))
-		for _, setting := range compatibilityModeOrder {
-			label := settingsCompatSettingToLabel[setting]
-			compatOptions = append(compatOptions, label)
-			settingsCompatLabelToSetting[label] = setting
-		}
-		settingsCompatSelect.Options = compatOptions
-		settingsCurrentCompatMode := compatibilityModeSetting(currentCfg.CompatibilityMode)
-		if label, ok := settingsCompatSettingToLabel[settingsCurrentCompatMode]; ok {
-			settingsCompatSelect.SetSelected(label)
-		}
-
-		// Abort on focus change checkbox
-		settingsAbortFocusCheck := widget.NewCheck(labels.SettingsAbortFocusLabel, nil)
-		settingsAbortFocusCheck.SetChecked(currentCfg.AbortOnFocusChange)
-
-		// Always on top checkbox
-		settingsAlwaysOnTopCheck := widget.NewCheck(labels.SettingsAlwaysOnTopLabel, nil)
-		settingsAlwaysOnTopCheck.SetChecked(currentCfg.AlwaysOnTop)
-
-		// Language selector
-		settingsLanguageSelect := widget.NewSelect(languageSelect.Options, nil)
-		settingsLanguageLabelToCode := make(map[string]string)
-		autoLabel := labels.LanguageAutoOption
-		settingsLanguageLabelToCode[autoLabel] = ""
-		for _, meta := range languageMetas {
-			settingsLanguageLabelToCode[meta.NativeName] = meta.Code
+	for _, u := range utf16 {
+		if u == 0 {
+			continue
 		}
-
-		selectedLabel := autoLabel
-		if currentCfg.Language != "" {
-			for _, meta := range languageMetas {
-				if meta.Code == currentCfg.Language {
-					selectedLabel = meta.NativeName
-					break
-				}
-			}
+		if err := sendUnicodeUnit(u); err != nil {
+			return err
 		}
-		settingsLanguageSelect.SetSelected(selectedLabel)
-
-		// Status label for save confirmation
-		settingsStatusLabel := widget.NewLabel("")
-
-		// Save button
-		saveBtn := widget.NewButton(labels.SettingsSaveButton, func() {
-			// Build new config from form
-			newCfg := config.Config{
-				DefaultSpeedOption: config.SpeedOption(settingsCurrentSpeedOption),
-				CustomSpeedMs:      0,
-				KeyboardLayout:     settingsLayoutSelect.Selected,
-				CompatibilityMode:  config.CompatibilityMode(settingsCurrentCompatMode),
-				AbortOnFocusChange: settingsAbortFocusCheck.Checked,
-				Language:           settingsLanguageLabelToCode[settingsLanguageSelect.Selected],
-				AlwaysOnTop:        settingsAlwaysOnTopCheck.Checked,
-			}
-
-			// Parse custom speed if custom is selected
-			if settingsCurrentSpeedOption == speedOptionCustom {
-				if val := strings.TrimSpace(settingsCustomMsEntry.Text); val != "" {
-					if ms, err := strconv.Atoi(val); err == nil && ms >= 0 && ms <= 10000 {
-						newCfg.CustomSpeedMs = ms
-					}
-				}
-			}
-
-			// Handle compatibility mode selection change
-			if label := settingsCompatSelect.Selected; label != "" {
-				if setting, ok := settingsCompatLabelToSetting[label]; ok {
-					newCfg.CompatibilityMode = config.CompatibilityMode(setting)
-				}
-			}
-
-			// Save config
-			if err := config.SaveConfig(newCfg); err != nil {
-				dialog.ShowError(err, settingsWindow)
-				return
-			}
-
-			// Apply settings to main window
-			currentSpeedOption = speedOptionID(newCfg.DefaultSpeedOption)
-			if currentSpeedOption == speedOptionCustom && newCfg.CustomSpeedMs > 0 {
-				customMsEntry.SetText(strconv.Itoa(newCfg.CustomSpeedMs))
-				customMsEntry.Show()
-			} else if currentSpeedOption != speedOptionCustom {
-				customMsEntry.Hide()
-			}
-			if label, ok := speedIDToLabel[currentSpeedOption]; ok {
-				speedSelect.SetSelected(label)
-			}
-
-			layoutSelect.SetSelected(newCfg.KeyboardLayout)
-
-			currentCompatibilitySetting = compatibilityModeSetting(newCfg.CompatibilityMode)
-			if label, ok := compatibilitySettingToLabel[currentCompatibilitySetting]; ok {
-				compatibilityModeSelect.SetSelected(label)
-			}
-
-			abortOnFocusChange = newCfg.AbortOnFocusChange
-			abortFocusCheck.SetChecked(newCfg.AbortOnFocusChange)
-
-			// Apply always on top setting
-			alwaysOnTopCheck.SetChecked(newCfg.AlwaysOnTop)
-			applyAlwaysOnTop(newCfg.AlwaysOnTop)
-
-			// Apply language change
-			selectedLanguageCode = newCfg.Language
-			applyLanguageSelection()
-
-			updateDelayLabel()
-			updateCompatibilityStatus()
-
-			settingsStatusLabel.SetText(labels.SettingsSavedStatus)
-			settingsStatusLabel.Refresh()
-
-			// Close the settings window after saving
-			settingsWindow.Close()
-		})
-		saveBtn.Importance = widget.HighImportance
-
-		// Cancel button
-		cancelBtn := widget.NewButton(labels.SettingsCancelButton, func() {
-			settingsWindow.Close()
-		})
-
-		// Reset button
-		resetBtn := widget.NewButton(labels.SettingsResetButton, func() {
-			dialog.ShowConfirm(
-				labels.SettingsResetConfirmTitle,
-				labels.SettingsResetConfirmMessage,
-				func(confirmed bool) {
-					if confirmed {
-						defaultCfg := config.DefaultConfig()
-						if err := config.SaveConfig(defaultCfg); err != nil {
-							dialog.ShowError(err, settingsWindow)
-							return
-						}
-						settingsWindow.Close()
-
-						// Reload and apply defaults
-						_ = config.Load()
-						cfg := config.Get()
-
-						currentSpeedOption = speedOptionID(cfg.DefaultSpeedOption)
-						if label, ok := speedIDToLabel[currentSpeedOption]; ok {
-							speedSelect.SetSelected(label)
-						}
-						customMsEntry.SetText("")
-						customMsEntry.Hide()
-
-						layoutSelect.SetSelected(cfg.KeyboardLayout)
-
-						currentCompatibilitySetting = compatibilityModeSetting(cfg.CompatibilityMode)
-						if label, ok := compatibilitySettingToLabel[currentCompatibilitySetting]; ok {
-							compatibilityModeSelect.SetSelected(label)
-						}
-
-						abortOnFocusChange = cfg.AbortOnFocusChange
-						abortFocusCheck.SetChecked(cfg.AbortOnFocusChange)
-
-						// Reset always on top
-						alwaysOnTopCheck.SetChecked(cfg.AlwaysOnTop)
-						applyAlwaysOnTop(cfg.AlwaysOnTop)
-
-						selectedLanguageCode = cfg.Language
-						applyLanguageSelection()
-
-						updateDelayLabel()
-						updateCompatibilityStatus()
-					}
-				},
-				settingsWindow,
-			)
-		})
-		resetBtn.Importance = widget.WarningImportance
-
-		// Create settings form
-		settingsContent := container.NewVBox(
-			widget.NewLabelWithStyle(labels.SettingsDefaultSpeedHeading, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
-			settingsSpeedSelect,
-			settingsCustomMsEntry,
-			widget.NewSeparator(),
-
-			widget.NewLabelWithStyle(labels.SettingsKeyboardLayoutLabel, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
-			settingsLayoutSelect,
-			widget.NewSeparator(),
-
-			widget.NewLabelWithStyle(labels.SettingsCompatibilityLabel, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
-			settingsCompatSelect,
-			widget.NewSeparator(),
-
-			settingsAbortFocusCheck,
-			settingsAlwaysOnTopCheck,
-			widget.NewSeparator(),
-
-			widget.NewLabelWithStyle(labels.SettingsLanguageLabel, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
-			settingsLanguageSelect,
-			widget.NewSeparator(),
-
-			container.NewHBox(saveBtn, cancelBtn, resetBtn),
-			settingsStatusLabel,
-		)
-
-		scrollContainer := container.NewVScroll(settingsContent)
-		settingsWindow.SetContent(scrollContainer)
-		settingsWindow.Show()
 	}
+	return nil
+}
 
-	settingsBtn = widget.NewButtonWithIcon("", theme.SettingsIcon(), showSettingsDialog)
-	settingsBtn.Importance = widget.LowImportance
-
-	// bottom right: language selector + version + settings button
-	bottom_right := container.NewVBox(
-		abortFocusCheck,
-		alwaysOnTopCheck,
-		languageHeadingLabel,
-		languageSelect,
-		settingsBtn,
-		versionLabel,
-	)
-	// assemble footer
-	footer := container.NewBorder(
-		nil,
-		nil,
-		bottom_left,
-		bottom_right,
-		nil,
-	)
-
-	content := container.NewBorder(header, footer, nil, nil, body)
-	w.SetContent(content)
-
-	applyLocalization = func(labels localization.LabelSet) {
-		w.SetTitle(labels.AppTitle)
-		inputEntry.SetPlaceHolder(labels.InputPlaceholder)
-		targetWindowLabel.SetText(labels.TargetWindowHeading)
-		keyboardLayoutLabel.SetText(labels.KeyboardLayoutHeading)
-		typingSpeedLabel.SetText(labels.TypingSpeedHeading)
-		compatibilityModeLabel.SetText(labels.CompatibilityModeHeading)
-		textToTypeLabel.SetText(labels.TextToTypeHeading)
-		languageHeadingLabel.SetText(labels.LanguageHeading)
-		clearBtn.SetText(labels.ClearButton)
-		refreshBtn.SetText(labels.RefreshWindowsButton)
-		typeBtn.SetText(labels.TypeButton)
-		typeClipboardBtn.SetText(labels.TypeClipboardButton)
-		stopBtn.SetText(labels.StopButton)
-		settingsBtn.SetText(labels.SettingsButton)
-		abortFocusCheck.SetText(labels.AbortOnFocusChange)
-		alwaysOnTopCheck.SetText(labels.AlwaysOnTop)
-		customMsEntry.SetPlaceHolder(labels.CustomMsPlaceholder)
-		windowSelect.PlaceHolder = labels.WindowPlaceholder
-		windowSelect.Refresh()
-		refreshSpeedSelectOptions(labels)
-		refreshCompatibilitySelectOptions(labels)
-		refreshLanguageSelectOptions(labels)
-		updateLastActiveLabel()
-		updateDelayLabel()
-		statusCtrl.Refresh()
-		updateCompatibilityStatus()
-	}
+func (in sendInputInjector) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
 
-	applyLanguageSelection = func() {
-		effectiveCode := selectedLanguageCode
-		if effectiveCode == "" {
-			effectiveCode = systemLanguageCode
-		}
-		effectiveCode = localization.ResolveCode(effectiveCode)
-		labels := localization.Labels(effectiveCode)
-		setCurrentLabelSet(labels)
-		applyLocalization(labels)
+// layoutMapper resolves characters on the named layout. Named layouts use
+// goclip's own table; "Auto" asks the system layout through
+// VkKeyScanExW/MapVirtualKeyExW.
+func layoutMapper(layout string) keyplan.Mapper {
+	if l := targetLayout(layout); l != nil {
+		return l
 	}
+	return hklMapper{hkl: currentHKL()}
+}
 
-	applyLanguageSelection()
+// planText builds the keystroke plan for text on the named layout.
+func planText(text string, layout string, opts keyplan.Options) (keyplan.Plan, error) {
+	return keyplan.Build(text, layoutMapper(layout), opts)
+}
 
-	updateDelayLabel()
-	refreshWindows()
+// analyzeText reports how planText would type each character of text.
+func analyzeText(text string, layout string, opts keyplan.Options) (keyplan.Report, error) {
+	return keyplan.Analyze(text, layoutMapper(layout), opts), nil
+}
 
-	// Apply initial always on top setting after window is shown
-	if cfg.AlwaysOnTop {
-		applyAlwaysOnTop(true)
+func sendText(text string, layout string, opts keyplan.Options, useModifierCompat bool, shouldStop func() bool) error {
+	plan, err := planText(text, layout, opts)
+	if err != nil {
+		return err
 	}
-
-	w.ShowAndRun()
+	return keyplan.Replay(plan, sendInputInjector{useModifierCompat: useModifierCompat}, shouldStop)
 }
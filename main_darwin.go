@@ -247,6 +247,7 @@ bool mapRuneToKey(UniChar target, uint16_t *outKeyCode, uint32_t *outMods) {
 import "C"
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -257,6 +258,11 @@ import (
 	"time"
 	"unsafe"
 
+	"goclip/config"
+	"goclip/ipc"
+	"goclip/keyplan"
+	"goclip/localization"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
@@ -371,152 +377,166 @@ func activateWindowToTitle(pid int, title string) bool {
 	return activateWindow(pid)
 }
 
-// sendText types the text using Core Graphics events
-func sendText(text string, layout string, perCharDelay time.Duration, shouldStop func() bool) error {
-	// Normalize line endings
-	text = strings.ReplaceAll(text, "\r\n", "\n")
-
-	for _, r := range text {
-		if shouldStop != nil && shouldStop() {
-			return nil
-		}
+// macKeyCodes maps set-1 scan code positions to macOS virtual keycodes (ANSI/ISO).
+var macKeyCodes = map[keyplan.Key]uint16{
+	{Code: 0x1E}: 0x00, {Code: 0x1F}: 0x01, {Code: 0x20}: 0x02, {Code: 0x21}: 0x03, // a s d f
+	{Code: 0x23}: 0x04, {Code: 0x22}: 0x05, {Code: 0x2C}: 0x06, {Code: 0x2D}: 0x07, // h g z x
+	{Code: 0x2E}: 0x08, {Code: 0x2F}: 0x09, {Code: 0x56}: 0x0A, {Code: 0x30}: 0x0B, // c v § b
+	{Code: 0x10}: 0x0C, {Code: 0x11}: 0x0D, {Code: 0x12}: 0x0E, {Code: 0x13}: 0x0F, // q w e r
+	{Code: 0x15}: 0x10, {Code: 0x14}: 0x11, {Code: 0x02}: 0x12, {Code: 0x03}: 0x13, // y t 1 2
+	{Code: 0x04}: 0x14, {Code: 0x05}: 0x15, {Code: 0x07}: 0x16, {Code: 0x06}: 0x17, // 3 4 6 5
+	{Code: 0x0D}: 0x18, {Code: 0x0A}: 0x19, {Code: 0x08}: 0x1A, {Code: 0x0C}: 0x1B, // = 9 7 -
+	{Code: 0x09}: 0x1C, {Code: 0x0B}: 0x1D, {Code: 0x1B}: 0x1E, {Code: 0x18}: 0x1F, // 8 0 ] o
+	{Code: 0x16}: 0x20, {Code: 0x1A}: 0x21, {Code: 0x17}: 0x22, {Code: 0x19}: 0x23, // u [ i p
+	{Code: 0x1C}: 0x24, {Code: 0x26}: 0x25, {Code: 0x24}: 0x26, {Code: 0x28}: 0x27, // return l j '
+	{Code: 0x25}: 0x28, {Code: 0x27}: 0x29, {Code: 0x2B}: 0x2A, {Code: 0x33}: 0x2B, // k ; \ ,
+	{Code: 0x35}: 0x2C, {Code: 0x31}: 0x2D, {Code: 0x32}: 0x2E, {Code: 0x34}: 0x2F, // / n m .
+	{Code: 0x0F}: 0x30, {Code: 0x39}: 0x31, {Code: 0x29}: 0x32, {Code: 0x0E}: 0x33, // tab space ` delete
+	{Code: 0x01}: 0x35, // escape
+
+	// Keypad
+	{Code: 0x53}: 0x41, {Code: 0x37}: 0x43, {Code: 0x4E}: 0x45, {Code: 0x45}: 0x47,
+	{Code: 0x35, Extended: true}: 0x4B, {Code: 0x1C, Extended: true}: 0x4C, {Code: 0x4A}: 0x4E,
+	{Code: 0x52}: 0x52, {Code: 0x4F}: 0x53, {Code: 0x50}: 0x54, {Code: 0x51}: 0x55,
+	{Code: 0x4B}: 0x56, {Code: 0x4C}: 0x57, {Code: 0x4D}: 0x58, {Code: 0x47}: 0x59,
+	{Code: 0x48}: 0x5B, {Code: 0x49}: 0x5C,
+
+	// JIS
+	{Code: 0x7D}: 0x5D, {Code: 0x73}: 0x5E, {Code: 0x70}: 0x68,
+
+	// Function keys
+	{Code: 0x3B}: 0x7A, {Code: 0x3C}: 0x78, {Code: 0x3D}: 0x63, {Code: 0x3E}: 0x76,
+	{Code: 0x3F}: 0x60, {Code: 0x40}: 0x61, {Code: 0x41}: 0x62, {Code: 0x42}: 0x64,
+	{Code: 0x43}: 0x65, {Code: 0x44}: 0x6D, {Code: 0x57}: 0x67, {Code: 0x58}: 0x6F,
+
+	// Navigation
+	{Code: 0x52, Extended: true}: 0x72, // insert (help)
+	{Code: 0x47, Extended: true}: 0x73, {Code: 0x49, Extended: true}: 0x74,
+	{Code: 0x53, Extended: true}: 0x75, {Code: 0x4F, Extended: true}: 0x77,
+	{Code: 0x51, Extended: true}: 0x79, {Code: 0x4B, Extended: true}: 0x7B,
+	{Code: 0x4D, Extended: true}: 0x7C, {Code: 0x50, Extended: true}: 0x7D,
+	{Code: 0x48, Extended: true}: 0x7E,
+}
 
-		if r == '\n' {
-			if err := sendKeyPress(0x24); err != nil { // kVK_Return = 0x24
-				return err
-			}
-			time.Sleep(perCharDelay)
-			continue
-		}
+// macScanCodes is the reverse of macKeyCodes.
+var macScanCodes = func() map[uint16]keyplan.Key {
+	m := make(map[uint16]keyplan.Key, len(macKeyCodes))
+	for k, code := range macKeyCodes {
+		m[code] = k
+	}
+	return m
+}()
+
+// macModifierKeyCodes maps plan modifiers to macOS virtual keycodes.
+var macModifierKeyCodes = map[keyplan.Modifier]uint16{
+	keyplan.ModShift: 0x38, // kVK_Shift
+	keyplan.ModCtrl:  0x3B, // kVK_Control
+	keyplan.ModAlt:   0x3A, // kVK_Option
+	keyplan.ModAltGr: 0x3D, // kVK_RightOption
+	keyplan.ModMeta:  0x37, // kVK_Command
+}
 
-		// Try layout-aware physical mapping first
-		layoutMapMu.RLock()
-		if km, ok := layoutMap[r]; ok {
-			layoutMapMu.RUnlock()
-			if err := sendKeyPressWithMods(km.code, km.shift, km.option); err != nil {
-				return err
-			}
-			time.Sleep(perCharDelay)
-			continue
-		}
-		layoutMapMu.RUnlock()
+// darwinMapper resolves characters through the layout mapping cache,
+// then the US ASCII table.
+type darwinMapper struct{}
 
+func (darwinMapper) Lookup(r rune) (keyplan.Stroke, bool) {
+	// Try layout-aware physical mapping first
+	layoutMapMu.RLock()
+	km, ok := layoutMap[r]
+	layoutMapMu.RUnlock()
+	if !ok {
 		// Try US ASCII physical mapping next
-		if handled, err := sendASCIICharUS(r); err != nil {
-			return err
-		} else if handled {
-			time.Sleep(perCharDelay)
-			continue
-		}
-
-		if err := sendChar(r); err != nil {
-			return err
-		}
-		time.Sleep(perCharDelay)
+		km, ok = usASCIIKey(r)
 	}
-
-	return nil
+	if !ok {
+		return keyplan.Stroke{}, false
+	}
+	key, ok := macScanCodes[km.code]
+	if !ok {
+		return keyplan.Stroke{}, false
+	}
+	var mods keyplan.Modifier
+	if km.shift {
+		mods |= keyplan.ModShift
+	}
+	if km.option {
+		mods |= keyplan.ModAlt
+	}
+	return keyplan.Stroke{Key: key, Mods: mods}, true
 }
 
-// sendKeyPress sends a key press and release
-func sendKeyPress(keyCode uint16) error {
-	// Create key down event
-	keyDown := C.CGEventCreateKeyboardEvent(C.CGEventSourceRef(0), C.CGKeyCode(keyCode), C.bool(true))
-	if keyDown == 0 {
-		return fmt.Errorf("failed to create key down event")
-	}
-	defer C.CFRelease(C.CFTypeRef(keyDown))
+// cgEventInjector replays keystroke plans as CGEvents.
+type cgEventInjector struct{}
 
-	// Create key up event
-	keyUp := C.CGEventCreateKeyboardEvent(C.CGEventSourceRef(0), C.CGKeyCode(keyCode), C.bool(false))
-	if keyUp == 0 {
-		return fmt.Errorf("failed to create key up event")
+func (cgEventInjector) PressKey(k keyplan.Key, down bool) error {
+	code, ok := macKeyCodes[k]
+	if !ok {
+		return fmt.Errorf("no macOS keycode for scan code %s", k)
 	}
-	defer C.CFRelease(C.CFTypeRef(keyUp))
+	return postKey(code, down)
+}
 
-	// Post events
-	C.CGEventPost(C.kCGHIDEventTap, keyDown)
-	C.CGEventPost(C.kCGHIDEventTap, keyUp)
+func (cgEventInjector) PressModifier(mod keyplan.Modifier, down bool) error {
+	code, ok := macModifierKeyCodes[mod]
+	if !ok {
+		return nil
+	}
+	return postKey(code, down)
+}
 
-	return nil
+func (cgEventInjector) TypeUnicode(r rune) error {
+	return sendChar(r)
 }
 
-// sendKeyPressWithMods presses modifiers (Shift/Option) as needed, taps key, then releases modifiers.
-func sendKeyPressWithMods(keyCode uint16, needShift bool, needOption bool) error {
-	const (
-		kVK_Shift  uint16 = 0x38
-		kVK_Option uint16 = 0x3A
-	)
+func (cgEventInjector) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
 
-	// Press modifiers down
-	if needOption {
-		evt := C.CGEventCreateKeyboardEvent(C.CGEventSourceRef(0), C.CGKeyCode(kVK_Option), C.bool(true))
-		if evt == 0 {
-			return fmt.Errorf("failed to create option down event")
-		}
-		C.CGEventPost(C.kCGHIDEventTap, evt)
-		C.CFRelease(C.CFTypeRef(evt))
-	}
-	if needShift {
-		evt := C.CGEventCreateKeyboardEvent(C.CGEventSourceRef(0), C.CGKeyCode(kVK_Shift), C.bool(true))
-		if evt == 0 {
-			// try to release option if pressed
-			if needOption {
-				up := C.CGEventCreateKeyboardEvent(C.CGEventSourceRef(0), C.CGKeyCode(kVK_Option), C.bool(false))
-				if up != 0 {
-					C.CGEventPost(C.kCGHIDEventTap, up)
-					C.CFRelease(C.CFTypeRef(up))
-				}
-			}
-			return fmt.Errorf("failed to create shift down event")
-		}
-		C.CGEventPost(C.kCGHIDEventTap, evt)
-		C.CFRelease(C.CFTypeRef(evt))
+// layoutMapper resolves characters on the named layout, or on the current
+// system layout (the layoutMap cache) for "Auto".
+func layoutMapper(layout string) keyplan.Mapper {
+	if l := targetLayout(layout); l != nil {
+		return l
 	}
+	return darwinMapper{}
+}
 
-	// Tap the key
-	if err := sendKeyPress(keyCode); err != nil {
-		// Release modifiers on error
-		if needShift {
-			up := C.CGEventCreateKeyboardEvent(C.CGEventSourceRef(0), C.CGKeyCode(kVK_Shift), C.bool(false))
-			if up != 0 {
-				C.CGEventPost(C.kCGHIDEventTap, up)
-				C.CFRelease(C.CFTypeRef(up))
-			}
-		}
-		if needOption {
-			up := C.CGEventCreateKeyboardEvent(C.CGEventSourceRef(0), C.CGKeyCode(kVK_Option), C.bool(false))
-			if up != 0 {
-				C.CGEventPost(C.kCGHIDEventTap, up)
-				C.CFRelease(C.CFTypeRef(up))
-			}
-		}
+// planText builds the keystroke plan for text on the named layout.
+func planText(text string, layout string, opts keyplan.Options) (keyplan.Plan, error) {
+	return keyplan.Build(text, layoutMapper(layout), opts)
+}
+
+// analyzeText reports how planText would type each character of text.
+func analyzeText(text string, layout string, opts keyplan.Options) (keyplan.Report, error) {
+	return keyplan.Analyze(text, layoutMapper(layout), opts), nil
+}
+
+// sendText types the text using Core Graphics events
+func sendText(text string, layout string, opts keyplan.Options, shouldStop func() bool) error {
+	plan, err := planText(text, layout, opts)
+	if err != nil {
 		return err
 	}
+	return keyplan.Replay(plan, cgEventInjector{}, shouldStop)
+}
 
-	// Release modifiers
-	if needShift {
-		up := C.CGEventCreateKeyboardEvent(C.CGEventSourceRef(0), C.CGKeyCode(kVK_Shift), C.bool(false))
-		if up == 0 {
-			return fmt.Errorf("failed to create shift up event")
+// postKey posts a single key down or key up event
+func postKey(keyCode uint16, down bool) error {
+	evt := C.CGEventCreateKeyboardEvent(C.CGEventSourceRef(0), C.CGKeyCode(keyCode), C.bool(down))
+	if evt == 0 {
+		if down {
+			return fmt.Errorf("failed to create key down event")
 		}
-		C.CGEventPost(C.kCGHIDEventTap, up)
-		C.CFRelease(C.CFTypeRef(up))
-	}
-	if needOption {
-		up := C.CGEventCreateKeyboardEvent(C.CGEventSourceRef(0), C.CGKeyCode(kVK_Option), C.bool(false))
-		if up == 0 {
-			return fmt.Errorf("failed to create option up event")
-		}
-		C.CGEventPost(C.kCGHIDEventTap, up)
-		C.CFRelease(C.CFTypeRef(up))
+		return fmt.Errorf("failed to create key up event")
 	}
+	C.CGEventPost(C.kCGHIDEventTap, evt)
+	C.CFRelease(C.CFTypeRef(evt))
 	return nil
 }
 
-// sendASCIICharUS tries to send a basic US-ANSI ASCII character using physical keycodes.
-// Returns (handled=true) if it sent it using keycodes; otherwise false to fall back.
-func sendASCIICharUS(r rune) (bool, error) {
+// usASCIIKey resolves a basic US-ANSI ASCII character to a physical keycode.
+func usASCIIKey(r rune) (keyMods, bool) {
 	// Mac virtual keycodes for US ANSI keyboard
 	// Letters: a=0, s=1, d=2, f=3, h=4, g=5, z=6, x=7, c=8, v=9, b=11,
 	// q=12, w=13, e=14, r=15, y=16, t=17, 1=18, 2=19, 3=20, 4=21, 6=22, 5=23,
@@ -558,15 +578,15 @@ func sendASCIICharUS(r rune) (bool, error) {
 	if r >= 'A' && r <= 'Z' {
 		lower := rune(r - 'A' + 'a')
 		if e, ok := m[lower]; ok {
-			return true, sendKeyPressWithMods(e.code, true, false)
+			return keyMods{code: e.code, shift: true}, true
 		}
-		return false, nil
+		return keyMods{}, false
 	}
 
 	if e, ok := m[r]; ok {
-		return true, sendKeyPressWithMods(e.code, e.shift, false)
+		return keyMods{code: e.code, shift: e.shift}, true
 	}
-	return false, nil
+	return keyMods{}, false
 }
 
 // sendChar sends a character using Unicode (reverted to stable path)
@@ -654,6 +674,8 @@ func getFrontmostApp() (int, string) {
 func main() {
 	myApp := app.New()
 	myApp.Settings().SetTheme(theme.DarkTheme())
+	// there is no language setting on macOS; follow the system's
+	setCurrentLabelSet(localization.Labels(localization.DetectSystemLanguage()))
 
 	// set runtime icon
 	if res := loadAppIcon(); res != nil {
@@ -748,6 +770,7 @@ func main() {
 	inputEntry.Wrapping = fyne.TextWrapWord
 
 	masked := false
+	var updateTypability func()
 	var eyeBtn *widget.Button
 	eyeBtn = widget.NewButtonWithIcon("", theme.VisibilityIcon(), func() {
 		masked = !masked
@@ -758,21 +781,70 @@ func main() {
 			eyeBtn.SetIcon(theme.VisibilityIcon())
 		}
 		inputEntry.Refresh()
+		updateTypability()
 	})
 	eyeBtn.Importance = widget.LowImportance
 
 	inputRow := container.NewBorder(nil, nil, nil, eyeBtn, inputEntry)
 
+	// pre-flight report with the problem characters highlighted
+	typabilityLabel := widget.NewLabel("")
+	typabilityLabel.Wrapping = fyne.TextWrapWord
+	typabilityText := widget.NewRichText()
+	typabilityText.Wrapping = fyne.TextWrapWord
+	typabilityScroll := container.NewVScroll(typabilityText)
+	typabilityScroll.SetMinSize(fyne.NewSize(0, 60))
+	typabilityScroll.Hide()
+
 	status := widget.NewLabel("Ready.")
 	status.Wrapping = fyne.TextWrapWord
 
-	// Note: Layout selection is simplified on macOS
-	// macOS handles keyboard layouts differently
-	layoutSelect := widget.NewSelect([]string{
-		"Auto (Use System)",
-	}, nil)
-	layoutSelect.Selected = "Auto (Use System)"
-	layoutSelect.Disable() // macOS uses system layout automatically
+	if err := config.Load(); err != nil {
+		status.SetText("Warning: could not read config.json: " + err.Error())
+	}
+	if err := loadUserLayouts(); err != nil {
+		status.SetText("Warning: could not import keyboard layout: " + err.Error())
+	}
+	layoutSelect := widget.NewSelect(keyboardLayoutOptions, nil)
+	layoutSelect.Selected = autoLayout
+
+	updateTypability = func() {
+		labels := getCurrentLabelSet()
+		txt := inputEntry.Text
+		if txt == "" {
+			typabilityLabel.SetText("")
+			typabilityScroll.Hide()
+			return
+		}
+		report, err := analyzeText(txt, layoutSelect.Selected, keyplan.Options{})
+		if err != nil {
+			typabilityLabel.SetText(fmt.Sprintf(labels.TypabilityErrorFormat, err.Error()))
+			typabilityScroll.Hide()
+			return
+		}
+		if len(report.Problems()) == 0 {
+			typabilityLabel.SetText(labels.TypabilityAllKeys)
+			typabilityScroll.Hide()
+			return
+		}
+		typabilityLabel.SetText(fmt.Sprintf(labels.TypabilityReportFormat,
+			report.Count(keyplan.MethodKey),
+			report.Count(keyplan.MethodDeadKey)+report.Count(keyplan.MethodTransliterated),
+			report.Count(keyplan.MethodFallback),
+			report.Count(keyplan.MethodSkipped)+report.Count(keyplan.MethodUntypable),
+		))
+		// never echo hidden text
+		if masked {
+			typabilityScroll.Hide()
+			return
+		}
+		typabilityText.Segments = typabilitySegments(report)
+		typabilityText.Refresh()
+		typabilityScroll.Show()
+	}
+	layoutSelect.OnChanged = func(string) {
+		updateTypability()
+	}
 
 	// --- Typing speed controls ---
 	speedSelect := widget.NewSelect([]string{
@@ -789,8 +861,8 @@ func main() {
 	customMsEntry.Hide()
 
 	// Dynamic per-character delay selection
-	getPerCharDelay := func(text string) time.Duration {
-		switch speedSelect.Selected {
+	perCharDelay := func(option, text string) time.Duration {
+		switch option {
 		case "Default (Auto)":
 			runeCount := 0
 			lines := 1
@@ -849,6 +921,9 @@ func main() {
 			return 0
 		}
 	}
+	getPerCharDelay := func(text string) time.Duration {
+		return perCharDelay(speedSelect.Selected, text)
+	}
 
 	delayLabel := widget.NewLabel("Per-character delay: 0 ms")
 
@@ -878,6 +953,7 @@ func main() {
 
 	inputEntry.OnChanged = func(s string) {
 		updateDelayLabel()
+		updateTypability()
 	}
 
 	winOptions := []string{}
@@ -974,8 +1050,10 @@ func main() {
 	var typeClipboardBtn *widget.Button
 	var stopBtn *widget.Button
 	var actionContainer *fyne.Container
+	typing := false // only used on the UI thread
 
 	setTypingUI := func(active bool) {
+		typing = active
 		if actionContainer == nil {
 			return
 		}
@@ -1053,7 +1131,7 @@ func main() {
 		status.SetText("Typing...")
 
 		go func(targetPID int, targetTitle string, txt string, perChar time.Duration) {
-			err := sendText(txt, layoutSelect.Selected, perChar, shouldStop)
+			err := sendText(txt, layoutSelect.Selected, keyplan.Options{PerCharDelay: perChar}, shouldStop)
 			canceled := shouldStop()
 
 			fyne.Do(func() {
@@ -1123,7 +1201,7 @@ func main() {
 		status.SetText("Typing clipboard...")
 
 		go func(targetPID int, targetTitle string, txt string, perChar time.Duration) {
-			err := sendText(txt, layoutSelect.Selected, perChar, shouldStop)
+			err := sendText(txt, layoutSelect.Selected, keyplan.Options{PerCharDelay: perChar}, shouldStop)
 			canceled := shouldStop()
 
 			fyne.Do(func() {
@@ -1140,6 +1218,74 @@ func main() {
 		}(targetPID, targetTitle, txt, perChar)
 	})
 
+	// startAPITyping types text for the automation API the way the Type
+	// button does, into target or else the selected or last active window
+	startAPITyping := func(target *windowInfo, text, layoutName, speed string, delay time.Duration) error {
+		var err error
+		fyne.DoAndWait(func() {
+			if typing {
+				err = errAlreadyTyping
+				return
+			}
+			byTitle := target != nil
+			if target == nil {
+				if wi, ok := winMap[windowSelect.Selected]; ok && wi.PID != 0 {
+					target, byTitle = &wi, true
+				} else {
+					laMu.RLock()
+					target = &windowInfo{PID: lastActivePID, Title: lastActiveTitle}
+					laMu.RUnlock()
+				}
+			}
+			if target.PID == 0 {
+				err = errNoTarget
+				return
+			}
+			var activated bool
+			if byTitle {
+				activated = activateWindowToTitle(target.PID, target.Title)
+			} else {
+				activated = activateWindow(target.PID)
+			}
+			if !activated {
+				err = errors.New("failed to activate the target window")
+				return
+			}
+			time.Sleep(150 * time.Millisecond)
+
+			if layoutName == "" {
+				layoutName = layoutSelect.Selected
+			}
+			if speed == "" {
+				speed = speedSelect.Selected
+			}
+			if delay <= 0 {
+				delay = perCharDelay(speed, text)
+			}
+			setStopRequested(false)
+			setTypingUI(true)
+			status.SetText("Typing...")
+
+			go func(targetTitle string) {
+				err := sendText(text, layoutName, keyplan.Options{PerCharDelay: delay}, shouldStop)
+				canceled := shouldStop()
+
+				fyne.Do(func() {
+					if canceled {
+						status.SetText("Typing stopped by user.")
+					} else if err != nil {
+						status.SetText("Error typing: " + err.Error())
+					} else {
+						status.SetText("Typed to: " + targetTitle)
+					}
+					setTypingUI(false)
+					setStopRequested(false)
+				})
+			}(target.Title)
+		})
+		return err
+	}
+
 	// Action container
 	actionContainer = container.NewHBox(typeBtn, typeClipboardBtn)
 
@@ -1155,7 +1301,7 @@ func main() {
 	right := container.NewVBox(
 		widget.NewLabelWithStyle("Keyboard Layout", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
 		layoutSelect,
-		widget.NewLabel("(macOS uses system layout)"),
+		widget.NewLabel("(layout of the target system)"),
 		widget.NewSeparator(),
 		widget.NewLabelWithStyle("Typing Speed", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
 		speedSelect,
@@ -1167,6 +1313,8 @@ func main() {
 	body := container.NewVBox(
 		widget.NewLabelWithStyle("Text to type", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
 		inputRow,
+		typabilityLabel,
+		typabilityScroll,
 		delayLabel,
 		actionContainer,
 		status,
@@ -1178,6 +1326,17 @@ func main() {
 	updateDelayLabel()
 	refreshWindows()
 
+	// the automation API is turned on with "automationApi" in config.json
+	if config.GetAutomationAPI() {
+		automation := &automationHandler{selfAppNameLower: selfAppNameLower, start: startAPITyping}
+		srv, err := ipc.Listen(config.GetAutomationSocketPath(), config.GetAutomationTokenPath(), automation)
+		if err != nil {
+			status.SetText("Warning: automation API unavailable: " + err.Error())
+		} else {
+			defer srv.Close()
+		}
+	}
+
 	w.ShowAndRun()
 
 	// Cleanup
@@ -0,0 +1,462 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"goclip/config"
+	"goclip/keyplan"
+	"goclip/layout"
+	"goclip/uinput"
+	"goclip/wayland"
+	"goclip/x11"
+)
+
+// windowHandle is an X11 window ID.
+type windowHandle = uint32
+
+type windowInfo struct {
+	Hwnd  windowHandle
+	Title string
+}
+
+// compatModifierSettle is the pause after every modifier change when
+// modifier compatibility mode is on.
+const compatModifierSettle = 10 * time.Millisecond
+
+// ---------- Ignore lists (lowercased) ----------
+var ignoredProcessNamesLower = map[string]struct{}{
+	"goclip": {}, // ignore itself
+}
+
+var ignoredTitlesLower = map[string]struct{}{
+	"desktop": {}, // desktop surfaces of file managers
+}
+
+var (
+	displayOnce sync.Once
+	displayConn *x11.Conn
+	displayErr  error
+)
+
+// display returns the shared X connection, opening it on first use.
+func display() (*x11.Conn, error) {
+	displayOnce.Do(func() {
+		displayConn, displayErr = x11.Open()
+	})
+	return displayConn, displayErr
+}
+
+var (
+	foregroundMu   sync.Mutex
+	foregroundStop func()
+)
+
+// startForegroundWatcher watches _NET_ACTIVE_WINDOW and calls onChange
+// whenever another (non-ignored) window becomes active.
+func startForegroundWatcher(
+	selfExeLower string,
+	onChange func(hwnd windowHandle, title string),
+) error {
+	stop, err := x11.WatchActiveWindow(func(id uint32) {
+		title := strings.TrimSpace(getWindowText(id))
+		if title != "" && !shouldIgnoreWindow(id, title, selfExeLower) {
+			onChange(id, title)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	foregroundMu.Lock()
+	foregroundStop = stop
+	foregroundMu.Unlock()
+	return nil
+}
+
+// stopForegroundWatcher stops the active window watcher.
+func stopForegroundWatcher() {
+	foregroundMu.Lock()
+	defer foregroundMu.Unlock()
+	if foregroundStop != nil {
+		foregroundStop()
+		foregroundStop = nil
+	}
+}
+
+// startHotkeys grabs the hotkeys with XGrabKey and calls onPress with the
+// index of each hotkey pressed, on a background goroutine. Hotkeys that
+// cannot be grabbed are listed in the error while the others still work.
+func startHotkeys(keys []keyplan.Hotkey, onPress func(i int)) (stop func(), err error) {
+	return x11.GrabHotkeys(keys, onPress)
+}
+
+// hotkeyDown reports whether the key of h is held down, whatever the
+// modifiers.
+func hotkeyDown(h keyplan.Hotkey) bool {
+	x, err := display()
+	if err != nil {
+		return false
+	}
+	return x.HotkeyDown(h)
+}
+
+// modifiersDown reports whether Shift, Ctrl, Alt, Win or AltGr is held.
+func modifiersDown() bool {
+	x, err := display()
+	if err != nil {
+		return false
+	}
+	return x.ModifiersDown()
+}
+
+func getForegroundWindow() windowHandle {
+	x, err := display()
+	if err != nil {
+		return 0
+	}
+	return x.ActiveWindow()
+}
+
+// setWindowAlwaysOnTop sets the given window to always on top or removes that state
+func setWindowAlwaysOnTop(hwnd windowHandle, topmost bool) {
+	if x, err := display(); err == nil {
+		_ = x.SetAbove(hwnd, topmost)
+	}
+}
+
+// findWindowByTitle finds a window by its title (exact match)
+func findWindowByTitle(title string) windowHandle {
+	x, err := display()
+	if err != nil {
+		return 0
+	}
+	return x.FindWindowByTitle(title)
+}
+
+func getWindowText(hwnd windowHandle) string {
+	x, err := display()
+	if err != nil {
+		return ""
+	}
+	return x.WindowTitle(hwnd)
+}
+
+func getWindowProcessName(hwnd windowHandle) string {
+	x, err := display()
+	if err != nil {
+		return ""
+	}
+	return x.WindowProcessName(hwnd)
+}
+
+func shouldIgnoreWindow(hwnd windowHandle, title string, selfExeLower string) bool {
+	t := strings.ToLower(strings.TrimSpace(title))
+	if t == "" {
+		return true
+	}
+	if _, ok := ignoredTitlesLower[t]; ok {
+		return true
+	}
+	exe := getWindowProcessName(hwnd)
+	if exe != "" {
+		if exe == selfExeLower {
+			return true
+		}
+		if _, ok := ignoredProcessNamesLower[exe]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesModifierCompatibilityWindow(hwnd windowHandle) bool {
+	title := strings.ToLower(strings.TrimSpace(getWindowText(hwnd)))
+	exe := getWindowProcessName(hwnd)
+	if title == "" && exe == "" {
+		return false
+	}
+	for _, rule := range modifierCompatibilityRules {
+		if rule.matches(title, exe) {
+			return true
+		}
+	}
+	return false
+}
+
+func enumWindows(selfExeLower string) []windowInfo {
+	x, err := display()
+	if err != nil {
+		return nil
+	}
+	list, err := x.Windows()
+	if err != nil {
+		return nil
+	}
+	var wins []windowInfo
+	for _, w := range list {
+		title := strings.TrimSpace(w.Title)
+		if shouldIgnoreWindow(w.ID, title, selfExeLower) {
+			continue
+		}
+		wins = append(wins, windowInfo{Hwnd: w.ID, Title: title})
+	}
+	sort.Slice(wins, func(i, j int) bool {
+		return strings.ToLower(wins[i].Title) < strings.ToLower(wins[j].Title)
+	})
+	return wins
+}
+
+// attachParentConsole is needed on Windows only; here goclip always has the
+// standard streams of the shell that started it.
+func attachParentConsole() {}
+
+func setForegroundWindow(hwnd windowHandle) bool {
+	x, err := display()
+	if err != nil {
+		return false
+	}
+	return x.Activate(hwnd) == nil
+}
+
+var (
+	uinputMu  sync.Mutex
+	uinputDev *uinput.Keyboard
+)
+
+// uinputKeyboard returns the shared virtual keyboard, creating it on first
+// use. A failed attempt is retried on the next call so fixing permissions
+// does not require a restart.
+func uinputKeyboard() (*uinput.Keyboard, error) {
+	uinputMu.Lock()
+	defer uinputMu.Unlock()
+	if uinputDev == nil {
+		kb, err := uinput.Open()
+		if err != nil {
+			return nil, err
+		}
+		uinputDev = kb
+	}
+	return uinputDev, nil
+}
+
+var (
+	waylandMu   sync.Mutex
+	waylandConn *wayland.Conn
+)
+
+// virtualKeyboard returns the shared Wayland virtual keyboard, connecting
+// on first use.
+func virtualKeyboard() (*wayland.Conn, error) {
+	waylandMu.Lock()
+	defer waylandMu.Unlock()
+	if waylandConn == nil {
+		c, err := wayland.Open()
+		if err != nil {
+			return nil, err
+		}
+		waylandConn = c
+	}
+	return waylandConn, nil
+}
+
+// dropVirtualKeyboard discards a connection that failed, e.g. because the
+// compositor restarted, so the next run reconnects.
+func dropVirtualKeyboard(c *wayland.Conn) {
+	waylandMu.Lock()
+	defer waylandMu.Unlock()
+	if waylandConn == c {
+		_ = c.Close()
+		waylandConn = nil
+	}
+}
+
+var (
+	probeMu sync.Mutex
+	// probedBackend is the setting the probe below was made for
+	probedBackend config.InputBackend
+	// noVirtualKeyboard is set once Auto found no virtual keyboard on the
+	// compositor, so the typing check does not dial it on every keystroke
+	noVirtualKeyboard bool
+)
+
+// inputBackend resolves the configured backend. Auto picks XTEST on X11
+// sessions, the virtual keyboard protocol on Wayland compositors that
+// offer it, and uinput otherwise. A compositor without the protocol is
+// only asked again after the setting changed.
+func inputBackend() config.InputBackend {
+	b := config.GetInputBackend()
+	probeMu.Lock()
+	defer probeMu.Unlock()
+	if b != probedBackend {
+		probedBackend = b
+		noVirtualKeyboard = false
+	}
+	if b != config.InputBackendAuto && b != "" {
+		return b
+	}
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		if !noVirtualKeyboard {
+			if _, err := virtualKeyboard(); err == nil {
+				return config.InputBackendWayland
+			}
+			noVirtualKeyboard = true
+		}
+		return config.InputBackendUinput
+	}
+	if os.Getenv("DISPLAY") != "" {
+		return config.InputBackendX11
+	}
+	return config.InputBackendUinput
+}
+
+// keymapMapper returns the mapper for the keymap that will interpret the
+// injected keys: goclip's table for a named layout, else the X server's (or
+// XWayland's) keymap, falling back to US. The Wayland backend uploads the
+// table itself, so it never asks the X server.
+func keymapMapper(name string) (keyplan.Mapper, error) {
+	if inputBackend() == config.InputBackendWayland {
+		// the uploaded keymap gets a key for every other character, so
+		// dead key sequences are never needed
+		return keyplan.MapperFunc(waylandLayout(name).Lookup), nil
+	}
+	if l := targetLayout(name); l != nil {
+		return l, nil
+	}
+	x, err := display()
+	if err != nil {
+		return layout.US, nil
+	}
+	return x.Mapper()
+}
+
+// waylandLayout returns the table the Wayland keymap is built from. The
+// compositor gives clients no access to the session's own layout, so "Auto"
+// means English (US) there.
+func waylandLayout(name string) *layout.Layout {
+	if l := targetLayout(name); l != nil {
+		return l
+	}
+	return layout.US
+}
+
+// backendOptions completes opts for the active backend. X11 and Wayland
+// type any character through a key they map for it, so the unmappable
+// policy only matters for uinput.
+func backendOptions(opts keyplan.Options) keyplan.Options {
+	switch inputBackend() {
+	case config.InputBackendX11, config.InputBackendWayland:
+		opts.UnicodeKeys = true
+	}
+	return opts
+}
+
+// planText builds the keystroke plan for text on the named layout, or on
+// the session's current keymap for "Auto".
+func planText(text string, layout string, opts keyplan.Options) (keyplan.Plan, error) {
+	m, err := keymapMapper(layout)
+	if err != nil {
+		return keyplan.Plan{}, err
+	}
+	return keyplan.Build(text, m, backendOptions(opts))
+}
+
+// analyzeText reports how sendText would type each character of text.
+// uinput cannot type characters without a key, so their Unicode fallback
+// is reported as untypable on that backend.
+func analyzeText(text string, layout string, opts keyplan.Options) (keyplan.Report, error) {
+	m, err := keymapMapper(layout)
+	if err != nil {
+		return nil, err
+	}
+	report := keyplan.Analyze(text, m, backendOptions(opts))
+	if inputBackend() == config.InputBackendUinput {
+		for i, c := range report {
+			if c.Method == keyplan.MethodFallback && c.Fallback == keyplan.FallbackUnicode {
+				report[i].Method = keyplan.MethodUntypable
+				report[i].Fallback = 0
+			}
+		}
+	}
+	return report, nil
+}
+
+func sendText(text string, layout string, opts keyplan.Options, useModifierCompat bool, shouldStop func() bool) error {
+	var settle time.Duration
+	if useModifierCompat {
+		settle = compatModifierSettle
+	}
+	switch inputBackend() {
+	case config.InputBackendUinput:
+		return sendTextUinput(text, layout, opts, settle, shouldStop)
+	case config.InputBackendWayland:
+		return sendTextWayland(text, layout, opts, settle, shouldStop)
+	}
+
+	x, err := display()
+	if err != nil {
+		return fmt.Errorf("X11 typing unavailable: %w", err)
+	}
+	// pick up layout switches made since the last run; this also
+	// restores the spare keycode used for unmapped characters afterwards
+	x.ReloadKeymap()
+	defer x.ReloadKeymap()
+
+	plan, err := planText(text, layout, opts)
+	if err != nil {
+		return err
+	}
+	inj, err := x.Injector(settle)
+	if err != nil {
+		return err
+	}
+	return keyplan.Replay(plan, inj, shouldStop)
+}
+
+func sendTextUinput(text string, layout string, opts keyplan.Options, settle time.Duration, shouldStop func() bool) error {
+	kb, err := uinputKeyboard()
+	if err != nil {
+		return err
+	}
+	if x, err := display(); err == nil {
+		x.ReloadKeymap()
+	}
+	plan, err := planText(text, layout, opts)
+	if err != nil {
+		return err
+	}
+	// uinput only has physical keys; refuse before typing half the text
+	for _, ev := range plan.Events {
+		if ev.Kind == keyplan.Unicode {
+			return fmt.Errorf("character %q has no key on the current keyboard layout", ev.Rune)
+		}
+	}
+	return keyplan.Replay(plan, kb.Injector(settle), shouldStop)
+}
+
+func sendTextWayland(text string, layout string, opts keyplan.Options, settle time.Duration, shouldStop func() bool) error {
+	c, err := virtualKeyboard()
+	if err != nil {
+		return err
+	}
+	plan, err := planText(text, layout, opts)
+	if err != nil {
+		return err
+	}
+	inj, err := c.Injector(plan, waylandLayout(layout), settle)
+	if err == nil {
+		err = keyplan.Replay(plan, inj, shouldStop)
+	}
+	if err == nil {
+		err = c.Sync()
+	}
+	if err != nil {
+		dropVirtualKeyboard(c)
+	}
+	return err
+}
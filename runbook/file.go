@@ -0,0 +1,277 @@
+package runbook
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"goclip/config"
+)
+
+// Procedure is a runbook read from a TOML file: named steps, typing
+// settings for all or single steps and ${VAR} placeholders that are filled
+// in before the first step. A file looks like this:
+//
+//	title = "Reinstall web01"
+//	layout = "German (DE)"       # settings for every step, all optional
+//	speed = "slow"               # default, medium, slow or superSlow
+//
+//	[[vars]]
+//	name = "HOST"
+//	prompt = "Hostname"
+//	default = "web01"
+//
+//	[[vars]]
+//	name = "PASSWORD"
+//	secret = true                # asked for with a masked entry
+//
+//	[[steps]]
+//	name = "Log in"
+//	text = "root"
+//
+//	[[steps]]
+//	text = "${PASSWORD}"
+//	delayMs = 120                # settings of this step only
+//	compat = "forceOn"           # auto, forceOn or forceOff
+//	markup = false               # {KEY} markup, default from the window
+//	enter = false                # press Enter after the text, default true
+type Procedure struct {
+	Title string
+	Vars  []Var
+	Steps []Step // texts still contain their placeholders
+}
+
+// Var is a value asked for before a procedure starts.
+type Var struct {
+	Name    string `toml:"name"`
+	Prompt  string `toml:"prompt"` // the name if the file gives none
+	Default string `toml:"default"`
+	Secret  bool   `toml:"secret"`
+}
+
+// settings are the typing settings of a file or of one of its steps.
+type settings struct {
+	Layout  string `toml:"layout"`
+	Speed   string `toml:"speed"`
+	DelayMs int    `toml:"delayMs"`
+	Compat  string `toml:"compat"`
+	Markup  *bool  `toml:"markup"`
+	Enter   *bool  `toml:"enter"`
+}
+
+type fileStep struct {
+	Name string `toml:"name"`
+	Text string `toml:"text"`
+	settings
+}
+
+type procedureFile struct {
+	Title string `toml:"title"`
+	settings
+	Vars  []Var      `toml:"vars"`
+	Steps []fileStep `toml:"steps"`
+}
+
+// maxDelayMs matches the limit of the custom speed entry.
+const maxDelayMs = 10000
+
+// ParseFile reads a procedure file. Placeholders for variables the file
+// does not declare become plain variables named after themselves.
+func ParseFile(data []byte) (*Procedure, error) {
+	var f procedureFile
+	md, err := toml.Decode(string(data), &f)
+	if err != nil {
+		return nil, err
+	}
+	if keys := md.Undecoded(); len(keys) > 0 {
+		return nil, fmt.Errorf("unknown key %q", keys[0].String())
+	}
+	if len(f.Steps) == 0 {
+		return nil, errors.New("no [[steps]]")
+	}
+	if err := f.settings.check(); err != nil {
+		return nil, err
+	}
+
+	p := &Procedure{Title: strings.TrimSpace(f.Title)}
+	declared := map[string]bool{}
+	for _, v := range f.Vars {
+		if !validName(v.Name) {
+			return nil, fmt.Errorf("variable name %q: use letters, digits and _", v.Name)
+		}
+		if declared[v.Name] {
+			return nil, fmt.Errorf("variable %s is declared twice", v.Name)
+		}
+		declared[v.Name] = true
+		if v.Prompt == "" {
+			v.Prompt = v.Name
+		}
+		p.Vars = append(p.Vars, v)
+	}
+
+	for i, fs := range f.Steps {
+		fail := func(err error) (*Procedure, error) {
+			return nil, fmt.Errorf("step %d: %w", i+1, err)
+		}
+		if fs.Text == "" {
+			return fail(errors.New("no text"))
+		}
+		if err := fs.settings.check(); err != nil {
+			return fail(err)
+		}
+		_, err := expand(fs.Text, func(name string) (string, error) {
+			if !declared[name] {
+				declared[name] = true
+				p.Vars = append(p.Vars, Var{Name: name, Prompt: name})
+			}
+			return "", nil
+		})
+		if err != nil {
+			return fail(err)
+		}
+
+		s := fs.settings.over(f.settings)
+		step := Step{
+			Name:    strings.TrimSpace(fs.Name),
+			Text:    strings.ReplaceAll(fs.Text, "\r\n", "\n"),
+			Layout:  s.Layout,
+			Speed:   config.SpeedOption(s.Speed),
+			DelayMs: s.DelayMs,
+			Compat:  config.CompatibilityMode(s.Compat),
+			Markup:  s.Markup,
+			NoEnter: s.Enter != nil && !*s.Enter,
+		}
+		if step.Name == "" {
+			// the text before expansion, so secrets never show up in the
+			// step list
+			step.Name = strings.TrimSpace(strings.SplitN(step.Text, "\n", 2)[0])
+		}
+		p.Steps = append(p.Steps, step)
+	}
+	return p, nil
+}
+
+// check validates the setting values that do not depend on the machine.
+// Layout names are checked by the caller.
+func (s settings) check() error {
+	switch config.SpeedOption(s.Speed) {
+	case "", config.SpeedDefault, config.SpeedMedium, config.SpeedSlow, config.SpeedSuperSlow:
+	default:
+		return fmt.Errorf("speed %q: use default, medium, slow, superSlow or delayMs", s.Speed)
+	}
+	if s.DelayMs < 0 || s.DelayMs > maxDelayMs {
+		return fmt.Errorf("delayMs %d: use 0 to %d", s.DelayMs, maxDelayMs)
+	}
+	switch config.CompatibilityMode(s.Compat) {
+	case "", config.CompatibilityAuto, config.CompatibilityForceOn, config.CompatibilityForceOff:
+	default:
+		return fmt.Errorf("compat %q: use auto, forceOn or forceOff", s.Compat)
+	}
+	return nil
+}
+
+// over returns s with the settings it leaves unset taken from base.
+func (s settings) over(base settings) settings {
+	if s.Layout == "" {
+		s.Layout = base.Layout
+	}
+	if s.Speed == "" && s.DelayMs == 0 {
+		s.Speed, s.DelayMs = base.Speed, base.DelayMs
+	}
+	if s.Compat == "" {
+		s.Compat = base.Compat
+	}
+	if s.Markup == nil {
+		s.Markup = base.Markup
+	}
+	if s.Enter == nil {
+		s.Enter = base.Enter
+	}
+	return s
+}
+
+// Expand returns the steps with their placeholders replaced by values.
+// markup is the window's setting, used by steps that leave it unset; the
+// steps keep it, since values typed with markup have their braces doubled
+// so a password such as "a{b}" is typed as it is.
+func (p *Procedure) Expand(values map[string]string, markup bool) ([]Step, error) {
+	steps := make([]Step, len(p.Steps))
+	for i, step := range p.Steps {
+		if step.Markup == nil {
+			step.Markup = &markup
+		}
+		escape := *step.Markup
+		text, err := expand(step.Text, func(name string) (string, error) {
+			v, ok := values[name]
+			if !ok {
+				return "", fmt.Errorf("no value for ${%s}", name)
+			}
+			if escape {
+				v = escapeMarkup(v)
+			}
+			return v, nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %w", i+1, err)
+		}
+		step.Text = text
+		steps[i] = step
+	}
+	return steps, nil
+}
+
+// expand replaces every ${NAME} in text with the result of value. $${
+// stands for a literal ${.
+func expand(text string, value func(name string) (string, error)) (string, error) {
+	var b strings.Builder
+	for {
+		i := strings.Index(text, "${")
+		if i < 0 {
+			b.WriteString(text)
+			return b.String(), nil
+		}
+		if i > 0 && text[i-1] == '$' {
+			b.WriteString(text[:i-1])
+			b.WriteString("${")
+			text = text[i+2:]
+			continue
+		}
+		end := strings.IndexByte(text[i:], '}')
+		if end < 0 {
+			return "", errors.New("${ without } (write $${ for a literal ${)")
+		}
+		name := text[i+2 : i+end]
+		if !validName(name) {
+			return "", fmt.Errorf("bad placeholder ${%s}", name)
+		}
+		v, err := value(name)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(text[:i])
+		b.WriteString(v)
+		text = text[i+end+1:]
+	}
+}
+
+// escapeMarkup doubles the braces of s, so key markup types them.
+func escapeMarkup(s string) string {
+	return strings.NewReplacer("{", "{{", "}", "}}").Replace(s)
+}
+
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package runbook
+
+import (
+	"testing"
+
+	"goclip/keyplan"
+	"goclip/layout"
+)
+
+func TestExpandEscapesValuesTypedWithMarkup(t *testing.T) {
+	p, err := ParseFile([]byte(`
+[[vars]]
+name = "PW"
+secret = true
+
+[[steps]]
+text = "login{TAB}${PW}"
+
+[[steps]]
+text = "${PW}"
+markup = false
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := "a{b}}c{"
+	steps, err := p.Expand(map[string]string{"PW": secret}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := steps[0].Text, "login{TAB}a{{b}}}}c{{"; got != want {
+		t.Errorf("markup step: got %q, want %q", got, want)
+	}
+	if steps[0].Markup == nil || !*steps[0].Markup {
+		t.Error("markup step does not keep the window's markup setting")
+	}
+	if got := steps[1].Text; got != secret {
+		t.Errorf("plain step: got %q, want %q", got, secret)
+	}
+
+	// the secret is typed as it is
+	typed, err := keyplan.Build(steps[0].Text, layout.US, keyplan.Options{Markup: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := keyplan.Build("login\t"+secret, layout.US, keyplan.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got, wantRec keyplan.Recorder
+	keyplan.Replay(typed, &got, nil)
+	keyplan.Replay(want, &wantRec, nil)
+	if got.String() != wantRec.String() {
+		t.Errorf("typed %s\nwant  %s", got.String(), wantRec.String())
+	}
+}
+
+func TestExpandWithoutMarkup(t *testing.T) {
+	p := &Procedure{Steps: []Step{{Text: "${PW}"}}}
+	steps, err := p.Expand(map[string]string{"PW": "{x}"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := steps[0].Text; got != "{x}" {
+		t.Errorf("got %q, want the value unchanged", got)
+	}
+}
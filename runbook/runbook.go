@@ -0,0 +1,142 @@
+// Package runbook splits text into steps that are typed one at a time and
+// keeps track of the current step. Steps can also come from TOML procedure
+// files.
+package runbook
+
+import (
+	"strings"
+
+	"goclip/config"
+)
+
+// SplitMode selects how Split divides text into steps.
+type SplitMode int
+
+const (
+	// ByLine makes every non-blank line a step.
+	ByLine SplitMode = iota
+	// ByBlock makes every group of lines between blank lines a step.
+	ByBlock
+)
+
+// Step is one unit of a runbook. The settings come from procedure files;
+// their zero values keep the window's settings.
+type Step struct {
+	Name string // shown instead of the text if set
+	Text string
+	Line int // 1-based line of the text where the step starts, 0 in files
+
+	Layout  string
+	Speed   config.SpeedOption
+	DelayMs int // custom speed, wins over Speed
+	Compat  config.CompatibilityMode
+	Markup  *bool
+	NoEnter bool // do not press Enter after the text
+}
+
+// Split divides text into steps. Blank lines never become steps; leading
+// indentation is kept.
+func Split(text string, mode SplitMode) []Step {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	var (
+		steps []Step
+		block []string
+		start int
+	)
+	flush := func() {
+		if len(block) > 0 {
+			steps = append(steps, Step{Text: strings.Join(block, "\n"), Line: start})
+			block = nil
+		}
+	}
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		if mode == ByLine {
+			steps = append(steps, Step{Text: line, Line: i + 1})
+			continue
+		}
+		if len(block) == 0 {
+			start = i + 1
+		}
+		block = append(block, line)
+	}
+	flush()
+	return steps
+}
+
+// Runbook is a list of steps with a cursor on the next step to type.
+type Runbook struct {
+	steps []Step
+	pos   int
+	last  int // most recently typed step, -1 if none
+	done  []bool
+}
+
+// New returns a runbook positioned on the first step.
+func New(steps []Step) *Runbook {
+	return &Runbook{steps: steps, last: -1, done: make([]bool, len(steps))}
+}
+
+// Len returns the number of steps.
+func (r *Runbook) Len() int {
+	return len(r.steps)
+}
+
+// Step returns step i.
+func (r *Runbook) Step(i int) Step {
+	return r.steps[i]
+}
+
+// Pos returns the index of the next step; Len() once every step is past.
+func (r *Runbook) Pos() int {
+	return r.pos
+}
+
+// Current returns the next step, or false at the end of the runbook.
+func (r *Runbook) Current() (Step, bool) {
+	if r.pos >= len(r.steps) {
+		return Step{}, false
+	}
+	return r.steps[r.pos], true
+}
+
+// Last returns the index of the most recently typed step, or -1.
+func (r *Runbook) Last() int {
+	return r.last
+}
+
+// Done reports whether step i has been typed.
+func (r *Runbook) Done(i int) bool {
+	return r.done[i]
+}
+
+// Typed records that step i was typed and moves the cursor past it.
+func (r *Runbook) Typed(i int) {
+	r.done[i] = true
+	r.last = i
+	r.pos = i + 1
+}
+
+// Skip moves the cursor to the following step without typing.
+func (r *Runbook) Skip() {
+	if r.pos < len(r.steps) {
+		r.pos++
+	}
+}
+
+// Back moves the cursor to the previous step.
+func (r *Runbook) Back() {
+	if r.pos > 0 {
+		r.pos--
+	}
+}
+
+// Seek moves the cursor to step i.
+func (r *Runbook) Seek(i int) {
+	if i >= 0 && i <= len(r.steps) {
+		r.pos = i
+	}
+}
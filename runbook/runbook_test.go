@@ -0,0 +1,140 @@
+package runbook
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		mode SplitMode
+		want []Step
+	}{
+		{
+			name: "lines",
+			text: "uname -a\n\n  cd /tmp\nls",
+			mode: ByLine,
+			want: []Step{{Text: "uname -a", Line: 1}, {Text: "  cd /tmp", Line: 3}, {Text: "ls", Line: 4}},
+		},
+		{
+			name: "blocks",
+			text: "cat <<EOF\nline\nEOF\n\necho done",
+			mode: ByBlock,
+			want: []Step{{Text: "cat <<EOF\nline\nEOF", Line: 1}, {Text: "echo done", Line: 5}},
+		},
+		{
+			name: "several blank lines",
+			text: "a\n\n\n\nb\nc",
+			mode: ByBlock,
+			want: []Step{{Text: "a", Line: 1}, {Text: "b\nc", Line: 5}},
+		},
+		{
+			name: "whitespace-only lines are blank",
+			text: "a\n \t\nb",
+			mode: ByBlock,
+			want: []Step{{Text: "a", Line: 1}, {Text: "b", Line: 3}},
+		},
+		{
+			name: "leading and trailing blank lines",
+			text: "\n\na\nb\n\n\n",
+			mode: ByLine,
+			want: []Step{{Text: "a", Line: 3}, {Text: "b", Line: 4}},
+		},
+		{
+			name: "trailing blank lines after a block",
+			text: "a\nb\n\n",
+			mode: ByBlock,
+			want: []Step{{Text: "a\nb", Line: 1}},
+		},
+		{
+			name: "crlf lines",
+			text: "a\r\nb\r\n",
+			mode: ByLine,
+			want: []Step{{Text: "a", Line: 1}, {Text: "b", Line: 2}},
+		},
+		{
+			name: "crlf blocks",
+			text: "a\r\nb\r\n\r\nc",
+			mode: ByBlock,
+			want: []Step{{Text: "a\nb", Line: 1}, {Text: "c", Line: 4}},
+		},
+		{
+			name: "empty",
+			text: "\n \n",
+			mode: ByBlock,
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Split(tt.text, tt.mode); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunbookCursor(t *testing.T) {
+	r := New(Split("a\nb\nc", ByLine))
+
+	// Back on the first step stays there
+	r.Back()
+	if r.Pos() != 0 || r.Last() != -1 {
+		t.Fatalf("after Back on step 0: pos %d, last %d", r.Pos(), r.Last())
+	}
+	if s, ok := r.Current(); !ok || s.Text != "a" {
+		t.Fatalf("Current = %q, %v, want a", s.Text, ok)
+	}
+
+	r.Typed(0)
+	r.Skip()
+	if r.Pos() != 2 || r.Last() != 0 || !r.Done(0) || r.Done(1) {
+		t.Fatalf("after Typed(0) and Skip: pos %d, last %d", r.Pos(), r.Last())
+	}
+
+	// repeat types the last step again and keeps the cursor after it
+	r.Typed(r.Last())
+	if r.Pos() != 1 || r.Last() != 0 {
+		t.Fatalf("after repeat: pos %d, last %d", r.Pos(), r.Last())
+	}
+
+	r.Back()
+	if s, _ := r.Current(); r.Pos() != 0 || s.Text != "a" {
+		t.Fatalf("after Back: pos %d, step %q", r.Pos(), s.Text)
+	}
+
+	r.Typed(2)
+	if _, ok := r.Current(); ok || r.Pos() != r.Len() {
+		t.Fatalf("after the last step: pos %d, Current still returns a step", r.Pos())
+	}
+	// Next and Skip past the end stay at the end
+	r.Skip()
+	if r.Pos() != r.Len() {
+		t.Errorf("Skip after the last step: pos %d, want %d", r.Pos(), r.Len())
+	}
+	r.Back()
+	if s, ok := r.Current(); !ok || s.Text != "c" {
+		t.Errorf("Back from the end: Current = %q, %v, want c", s.Text, ok)
+	}
+
+	r.Seek(-1)
+	r.Seek(4)
+	if r.Pos() != 2 {
+		t.Errorf("Seek out of range moved the cursor to %d", r.Pos())
+	}
+	r.Seek(3)
+	if r.Pos() != 3 {
+		t.Errorf("Seek(Len()) = %d, want the end", r.Pos())
+	}
+}
+
+func TestRunbookEmpty(t *testing.T) {
+	r := New(nil)
+	r.Skip()
+	r.Back()
+	if _, ok := r.Current(); ok || r.Pos() != 0 || r.Last() != -1 {
+		t.Errorf("empty runbook: pos %d, last %d", r.Pos(), r.Last())
+	}
+}
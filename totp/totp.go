@@ -0,0 +1,169 @@
+// Package totp computes RFC 6238 time-based one-time passwords from seeds
+// given as otpauth:// URIs or as plain base32 secrets.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults of the otpauth format.
+const (
+	DefaultDigits = 6
+	DefaultPeriod = 30 * time.Second
+)
+
+// Key is a TOTP seed with its parameters.
+type Key struct {
+	Label     string // "Issuer:account" from the URI, may be empty
+	Issuer    string
+	Secret    []byte
+	Algorithm string // SHA1, SHA256 or SHA512
+	Digits    int
+	Period    time.Duration
+}
+
+// IsURI reports whether s is an otpauth:// URI of a TOTP seed.
+func IsURI(s string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(s)), "otpauth://totp/")
+}
+
+// Parse reads a seed: an otpauth://totp/ URI, or a base32 secret with the
+// default parameters. Spaces, dashes, lower case and missing padding are
+// accepted in base32.
+func Parse(s string) (*Key, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(strings.ToLower(s), "otpauth://") {
+		return parseURI(s)
+	}
+	secret, err := decodeSecret(s)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{Secret: secret, Algorithm: "SHA1", Digits: DefaultDigits, Period: DefaultPeriod}, nil
+}
+
+func parseURI(s string) (*Key, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(u.Host, "totp") {
+		return nil, fmt.Errorf("otpauth type %q is not supported, only totp", u.Host)
+	}
+	q := u.Query()
+	k := &Key{
+		Label:     strings.TrimPrefix(u.Path, "/"),
+		Issuer:    q.Get("issuer"),
+		Algorithm: "SHA1",
+		Digits:    DefaultDigits,
+		Period:    DefaultPeriod,
+	}
+	if k.Secret, err = decodeSecret(q.Get("secret")); err != nil {
+		return nil, err
+	}
+	if a := q.Get("algorithm"); a != "" {
+		k.Algorithm = strings.ToUpper(a)
+		if newHash(k.Algorithm) == nil {
+			return nil, fmt.Errorf("algorithm %q: use SHA1, SHA256 or SHA512", a)
+		}
+	}
+	if d := q.Get("digits"); d != "" {
+		n, err := strconv.Atoi(d)
+		if err != nil || n < 6 || n > 8 {
+			return nil, fmt.Errorf("digits %q: use 6 to 8", d)
+		}
+		k.Digits = n
+	}
+	if p := q.Get("period"); p != "" {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 1 || n > 3600 {
+			return nil, fmt.Errorf("period %q: use 1 to 3600 seconds", p)
+		}
+		k.Period = time.Duration(n) * time.Second
+	}
+	return k, nil
+}
+
+func decodeSecret(s string) ([]byte, error) {
+	s = strings.ToUpper(strings.NewReplacer(" ", "", "-", "", "\t", "").Replace(s))
+	s = strings.TrimRight(s, "=")
+	if s == "" {
+		return nil, errors.New("no TOTP secret")
+	}
+	b, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(s)
+	if err != nil {
+		return nil, errors.New("the TOTP secret is not valid base32")
+	}
+	return b, nil
+}
+
+func newHash(algorithm string) func() hash.Hash {
+	switch algorithm {
+	case "SHA1":
+		return sha1.New
+	case "SHA256":
+		return sha256.New
+	case "SHA512":
+		return sha512.New
+	}
+	return nil
+}
+
+// URI returns the key as an otpauth:// URI labelled label.
+func (k *Key) URI(label string) string {
+	q := url.Values{}
+	q.Set("secret", base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(k.Secret))
+	if k.Issuer != "" {
+		q.Set("issuer", k.Issuer)
+	}
+	q.Set("algorithm", k.Algorithm)
+	q.Set("digits", strconv.Itoa(k.Digits))
+	q.Set("period", strconv.Itoa(int(k.Period/time.Second)))
+	u := url.URL{Scheme: "otpauth", Host: "totp", Path: "/" + label, RawQuery: q.Encode()}
+	return u.String()
+}
+
+// Code returns the code valid at t.
+func (k *Key) Code(t time.Time) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(t.Unix())/uint64(k.Period/time.Second))
+	mac := hmac.New(newHash(k.Algorithm), k.Secret)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0F
+	v := binary.BigEndian.Uint32(sum[offset:]) & 0x7FFFFFFF
+	mod := uint32(1)
+	for i := 0; i < k.Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", k.Digits, v%mod)
+}
+
+// Remaining returns how long the code valid at t stays valid.
+func (k *Key) Remaining(t time.Time) time.Duration {
+	period := k.Period.Nanoseconds()
+	return time.Duration(period - t.UnixNano()%period)
+}
+
+// Wait returns how long to wait at t for a code that stays valid for at
+// least need: 0 if the current code does, otherwise the time until the
+// next one.
+func (k *Key) Wait(t time.Time, need time.Duration) time.Duration {
+	if r := k.Remaining(t); r < need {
+		return r
+	}
+	return 0
+}
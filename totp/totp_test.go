@@ -0,0 +1,171 @@
+package totp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 Appendix B
+func TestCode(t *testing.T) {
+	seeds := map[string][]byte{
+		"SHA1":   []byte("12345678901234567890"),
+		"SHA256": []byte("12345678901234567890123456789012"),
+		"SHA512": []byte("1234567890123456789012345678901234567890123456789012345678901234"),
+	}
+	tests := []struct {
+		unix int64
+		want map[string]string
+	}{
+		{59, map[string]string{"SHA1": "94287082", "SHA256": "46119246", "SHA512": "90693936"}},
+		{1111111109, map[string]string{"SHA1": "07081804", "SHA256": "68084774", "SHA512": "25091201"}},
+		{1111111111, map[string]string{"SHA1": "14050471", "SHA256": "67062674", "SHA512": "99943326"}},
+		{1234567890, map[string]string{"SHA1": "89005924", "SHA256": "91819424", "SHA512": "93441116"}},
+		{2000000000, map[string]string{"SHA1": "69279037", "SHA256": "90698825", "SHA512": "38618901"}},
+		{20000000000, map[string]string{"SHA1": "65353130", "SHA256": "77737706", "SHA512": "47863826"}},
+	}
+	for _, tt := range tests {
+		for alg, want := range tt.want {
+			k := &Key{Secret: seeds[alg], Algorithm: alg, Digits: 8, Period: DefaultPeriod}
+			if got := k.Code(time.Unix(tt.unix, 0)); got != want {
+				t.Errorf("%s at %d: got %s, want %s", alg, tt.unix, got, want)
+			}
+		}
+	}
+
+	// six digits are the last six of the eight
+	k := &Key{Secret: seeds["SHA1"], Algorithm: "SHA1", Digits: 6, Period: DefaultPeriod}
+	if got := k.Code(time.Unix(1111111109, 0)); got != "081804" {
+		t.Errorf("6 digits: got %s, want 081804", got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	// base32 of "12345678901234567890"
+	const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	want := []byte("12345678901234567890")
+
+	tests := []struct {
+		name   string
+		in     string
+		alg    string
+		digits int
+		period time.Duration
+		issuer string
+		label  string
+	}{
+		{name: "base32", in: secret, alg: "SHA1", digits: 6, period: 30 * time.Second},
+		{name: "spaces and lower case", in: "  gezd gnbv gy3t qojq-gezd gnbv gy3t qojq ", alg: "SHA1", digits: 6, period: 30 * time.Second},
+		{name: "padding", in: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ====", alg: "SHA1", digits: 6, period: 30 * time.Second},
+		{
+			name: "uri defaults",
+			in:   "otpauth://totp/Example:alice@example.com?secret=" + secret + "&issuer=Example",
+			alg:  "SHA1", digits: 6, period: 30 * time.Second,
+			issuer: "Example", label: "Example:alice@example.com",
+		},
+		{
+			name: "uri parameters",
+			in:   "OTPAUTH://TOTP/ACME:bob?secret=" + strings.ToLower(secret) + "&algorithm=sha256&digits=8&period=60",
+			alg:  "SHA256", digits: 8, period: 60 * time.Second,
+			label: "ACME:bob",
+		},
+		{
+			name: "uri sha512",
+			in:   "otpauth://totp/x?secret=" + secret + "&algorithm=SHA512&digits=7&period=15",
+			alg:  "SHA512", digits: 7, period: 15 * time.Second,
+			label: "x",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := Parse(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(k.Secret, want) {
+				t.Errorf("secret %q, want %q", k.Secret, want)
+			}
+			if k.Algorithm != tt.alg || k.Digits != tt.digits || k.Period != tt.period {
+				t.Errorf("got %s, %d digits, %v, want %s, %d digits, %v", k.Algorithm, k.Digits, k.Period, tt.alg, tt.digits, tt.period)
+			}
+			if k.Issuer != tt.issuer || k.Label != tt.label {
+				t.Errorf("issuer %q, label %q, want %q, %q", k.Issuer, k.Label, tt.issuer, tt.label)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"empty", "  ", "no TOTP secret"},
+		{"not base32", "GEZDGNBV1890", "not valid base32"},
+		{"hotp", "otpauth://hotp/x?secret=" + secret + "&counter=1", "only totp"},
+		{"uri without secret", "otpauth://totp/x?issuer=y", "no TOTP secret"},
+		{"uri bad secret", "otpauth://totp/x?secret=!!", "not valid base32"},
+		{"algorithm", "otpauth://totp/x?secret=" + secret + "&algorithm=MD5", "algorithm"},
+		{"too few digits", "otpauth://totp/x?secret=" + secret + "&digits=4", "digits"},
+		{"too many digits", "otpauth://totp/x?secret=" + secret + "&digits=10", "digits"},
+		{"digits not a number", "otpauth://totp/x?secret=" + secret + "&digits=six", "digits"},
+		{"zero period", "otpauth://totp/x?secret=" + secret + "&period=0", "period"},
+		{"long period", "otpauth://totp/x?secret=" + secret + "&period=3601", "period"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.in)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestURIRoundTrip(t *testing.T) {
+	k := &Key{Issuer: "ACME Corp", Secret: []byte("12345678901234567890"), Algorithm: "SHA256", Digits: 8, Period: 60 * time.Second}
+	got, err := Parse(k.URI("ACME Corp:alice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Label != "ACME Corp:alice" || got.Issuer != k.Issuer || !bytes.Equal(got.Secret, k.Secret) ||
+		got.Algorithm != k.Algorithm || got.Digits != k.Digits || got.Period != k.Period {
+		t.Errorf("got %+v, want %+v", got, k)
+	}
+}
+
+func TestWait(t *testing.T) {
+	k := &Key{Secret: []byte("x"), Algorithm: "SHA1", Digits: 6, Period: 30 * time.Second}
+	base := time.Unix(1111111110, 0) // a period boundary
+	tests := []struct {
+		name string
+		at   time.Duration // after the boundary
+		need time.Duration
+		want time.Duration
+	}{
+		{"start of a period", 0, 5 * time.Second, 0},
+		{"enough left", 20 * time.Second, 5 * time.Second, 0},
+		{"exactly enough left", 25 * time.Second, 5 * time.Second, 0},
+		{"just too little left", 25*time.Second + time.Millisecond, 5 * time.Second, 5*time.Second - time.Millisecond},
+		{"last moment", 30*time.Second - time.Nanosecond, 5 * time.Second, time.Nanosecond},
+		{"need the whole period", 0, 30 * time.Second, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := base.Add(tt.at)
+			if got := k.Wait(at, tt.need); got != tt.want {
+				t.Errorf("Wait = %v, want %v", got, tt.want)
+			}
+			// after waiting, the code is the next one
+			if w := k.Wait(at, tt.need); w > 0 && k.Code(at.Add(w)) == k.Code(at) {
+				t.Error("the code does not change after the wait")
+			}
+		})
+	}
+	if got := k.Remaining(base); got != 30*time.Second {
+		t.Errorf("Remaining at a boundary = %v, want the whole period", got)
+	}
+}
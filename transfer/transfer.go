@@ -0,0 +1,214 @@
+// Package transfer turns a file into shell commands that recreate it when
+// typed into a console: the file is base64-encoded, appended to a temporary
+// file in chunks and decoded at the end, followed by a SHA-256 check.
+//
+// Every chunk is a single command that only runs once it is complete, so a
+// transfer stopped in the middle of a chunk resumes by clearing the
+// half-typed command (Ctrl+C) and typing that chunk again.
+package transfer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Shell is the command interpreter on the target.
+type Shell int
+
+const (
+	// Bash appends with a heredoc and decodes with base64 -d.
+	Bash Shell = iota
+	// PowerShell appends with Add-Content and decodes with
+	// [Convert]::FromBase64String.
+	PowerShell
+	// Cmd appends with echo and decodes with certutil -decode.
+	Cmd
+)
+
+// Shells lists the shells in the order they are offered.
+var Shells = []Shell{Bash, PowerShell, Cmd}
+
+func (s Shell) String() string {
+	switch s {
+	case Bash:
+		return "bash"
+	case PowerShell:
+		return "PowerShell"
+	case Cmd:
+		return "cmd"
+	}
+	return fmt.Sprintf("Shell(%d)", int(s))
+}
+
+const (
+	// DefaultChunkSize is the number of base64 characters per chunk.
+	DefaultChunkSize = 4096
+	// lineLen is the length of the base64 lines in bash and cmd chunks.
+	lineLen = 76
+	// maxCmdChunk keeps a cmd chunk below the 8191 character line limit.
+	maxCmdChunk = 6080
+	// heredocEnd ends the heredoc of a bash chunk.
+	heredocEnd = "GOCLIP_EOF"
+)
+
+// Options configure a transfer.
+type Options struct {
+	Shell     Shell
+	Name      string // file name or path on the target
+	Gzip      bool   // compress before encoding; not available for Cmd
+	ChunkSize int    // base64 characters per chunk, DefaultChunkSize if 0
+}
+
+// Transfer is a file prepared for typing.
+type Transfer struct {
+	// Chunks are typed in order. The first creates an empty temporary
+	// file, the last decodes it and verifies the checksum. Each one ends
+	// with a newline.
+	Chunks  []string
+	Size    int    // bytes of the file
+	Encoded int    // base64 characters typed
+	SHA256  string // hex checksum of the file
+}
+
+// New prepares data for typing into opts.Shell.
+func New(data []byte, opts Options) (*Transfer, error) {
+	if err := checkName(opts.Name, opts.Shell); err != nil {
+		return nil, err
+	}
+	if opts.Gzip && opts.Shell == Cmd {
+		return nil, errors.New("cmd has no gzip decoder; turn compression off")
+	}
+	size := opts.ChunkSize
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	if opts.Shell == Cmd && size > maxCmdChunk {
+		size = maxCmdChunk
+	}
+	// whole lines
+	size = max(size/lineLen*lineLen, lineLen)
+
+	sum := sha256.Sum256(data)
+	payload := data
+	if opts.Gzip {
+		var buf bytes.Buffer
+		zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+		if _, err := zw.Write(data); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		payload = buf.Bytes()
+	}
+	encoded := base64.StdEncoding.EncodeToString(payload)
+
+	t := &Transfer{Size: len(data), Encoded: len(encoded), SHA256: hex.EncodeToString(sum[:])}
+	sh := shells[opts.Shell]
+	tmp := opts.Name + ".b64"
+	t.Chunks = append(t.Chunks, sh.create(tmp))
+	for len(encoded) > 0 {
+		n := min(size, len(encoded))
+		t.Chunks = append(t.Chunks, sh.appendChunk(tmp, encoded[:n]))
+		encoded = encoded[n:]
+	}
+	t.Chunks = append(t.Chunks, sh.decode(tmp, opts.Name, opts.Gzip, t.SHA256))
+	return t, nil
+}
+
+// checkName rejects names the commands cannot quote safely.
+func checkName(name string, sh Shell) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("no target file name")
+	}
+	bad := `'"`
+	if sh == Cmd {
+		bad += "%!"
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) || strings.ContainsRune(bad, r) {
+			return fmt.Errorf("target file name %q: %q is not allowed", name, r)
+		}
+	}
+	return nil
+}
+
+// lines splits s into base64 lines of lineLen characters.
+func lines(s string) []string {
+	var ls []string
+	for len(s) > lineLen {
+		ls = append(ls, s[:lineLen])
+		s = s[lineLen:]
+	}
+	return append(ls, s)
+}
+
+// shell writes the commands of one shell.
+type shell struct {
+	create      func(tmp string) string
+	appendChunk func(tmp, data string) string
+	decode      func(tmp, name string, gz bool, sum string) string
+}
+
+var shells = map[Shell]shell{
+	Bash: {
+		create: func(tmp string) string {
+			return fmt.Sprintf(": > '%s'\n", tmp)
+		},
+		appendChunk: func(tmp, data string) string {
+			return fmt.Sprintf("cat >> '%s' <<'%s'\n%s\n%s\n", tmp, heredocEnd, strings.Join(lines(data), "\n"), heredocEnd)
+		},
+		decode: func(tmp, name string, gz bool, sum string) string {
+			unzip := ""
+			if gz {
+				unzip = " | gunzip"
+			}
+			return fmt.Sprintf("base64 -d '%[1]s'%[3]s > '%[2]s' && rm -f '%[1]s' && printf '%%s  %%s\\n' %[4]s '%[2]s' | sha256sum -c -\n",
+				tmp, name, unzip, sum)
+		},
+	},
+	// PowerShell takes -Path as a wildcard pattern, which [ and ] in a name
+	// would turn into one
+	PowerShell: {
+		create: func(tmp string) string {
+			return fmt.Sprintf("Set-Content -NoNewline -LiteralPath '%s' -Value ''\n", tmp)
+		},
+		appendChunk: func(tmp, data string) string {
+			return fmt.Sprintf("Add-Content -NoNewline -LiteralPath '%s' -Value '%s'\n", tmp, data)
+		},
+		decode: func(tmp, name string, gz bool, sum string) string {
+			raw := fmt.Sprintf("[Convert]::FromBase64String((Get-Content -Raw -LiteralPath '%s'))", tmp)
+			// .NET resolves relative paths against the process, not the
+			// PowerShell location
+			path := fmt.Sprintf("$ExecutionContext.SessionState.Path.GetUnresolvedProviderPathFromPSPath('%s')", name)
+			write := fmt.Sprintf("[IO.File]::WriteAllBytes(%s, %s)", path, raw)
+			if gz {
+				write = fmt.Sprintf("$i = New-Object IO.MemoryStream(,%s); "+
+					"$g = New-Object IO.Compression.GZipStream($i, [IO.Compression.CompressionMode]::Decompress); "+
+					"$o = [IO.File]::Create(%s); $g.CopyTo($o); $o.Close(); $g.Close()", raw, path)
+			}
+			return fmt.Sprintf("%s; Remove-Item -LiteralPath '%s'; if ((Get-FileHash -Algorithm SHA256 -LiteralPath '%s').Hash -eq '%s') { 'SHA256 OK' } else { 'SHA256 MISMATCH' }\n",
+				write, tmp, name, sum)
+		},
+	},
+	Cmd: {
+		create: func(tmp string) string {
+			return fmt.Sprintf("type nul > \"%s\"\n", tmp)
+		},
+		appendChunk: func(tmp, data string) string {
+			// one line, so nothing is appended unless it is complete
+			return fmt.Sprintf("(echo %s)>> \"%s\"\n", strings.Join(lines(data), "&echo "), tmp)
+		},
+		decode: func(tmp, name string, _ bool, sum string) string {
+			return fmt.Sprintf("certutil -f -decode \"%[1]s\" \"%[2]s\" >nul && del \"%[1]s\" && certutil -hashfile \"%[2]s\" SHA256 | find /i \"%[3]s\" >nul && echo SHA256 OK || echo SHA256 MISMATCH\n",
+				tmp, name, sum)
+		},
+	},
+}
@@ -0,0 +1,230 @@
+package transfer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// payload returns the base64 lines an append chunk adds to the temporary
+// file.
+func payload(t *testing.T, sh Shell, chunk string) []string {
+	t.Helper()
+	switch sh {
+	case Bash:
+		start := fmt.Sprintf("<<'%s'\n", heredocEnd)
+		i := strings.Index(chunk, start)
+		end := "\n" + heredocEnd + "\n"
+		if i < 0 || !strings.HasSuffix(chunk, end) {
+			t.Fatalf("not a heredoc: %q", chunk)
+		}
+		return strings.Split(chunk[i+len(start):len(chunk)-len(end)], "\n")
+	case PowerShell:
+		i := strings.Index(chunk, "-Value '")
+		if i < 0 || !strings.HasSuffix(chunk, "'\n") {
+			t.Fatalf("not an Add-Content: %q", chunk)
+		}
+		return []string{chunk[i+len("-Value '") : len(chunk)-2]}
+	case Cmd:
+		i := strings.Index(chunk, ")>> ")
+		if !strings.HasPrefix(chunk, "(echo ") || i < 0 {
+			t.Fatalf("not an echo group: %q", chunk)
+		}
+		return strings.Split(chunk[len("(echo "):i], "&echo ")
+	}
+	t.Fatalf("unknown shell %v", sh)
+	return nil
+}
+
+func TestNew(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	random := make([]byte, 20000)
+	rnd.Read(random)
+	text := bytes.Repeat([]byte("goclip sends files as base64\n"), 700)
+
+	type test struct {
+		shell     Shell
+		gzip      bool
+		chunkSize int
+		data      []byte
+		wantSize  int // base64 characters per full chunk
+	}
+	var tests []test
+	for _, sh := range Shells {
+		for _, gz := range []bool{false, true} {
+			if gz && sh == Cmd {
+				continue
+			}
+			tests = append(tests,
+				test{sh, gz, 0, random, DefaultChunkSize / lineLen * lineLen},
+				test{sh, gz, 1000, text, 1000 / lineLen * lineLen},
+				test{sh, gz, 10, random[:500], lineLen},
+				test{sh, gz, 0, nil, 0},
+			)
+		}
+	}
+	tests = append(tests, test{Cmd, false, 100000, random, maxCmdChunk / lineLen * lineLen})
+
+	for _, tt := range tests {
+		name := fmt.Sprintf("%v gzip=%v size=%d len=%d", tt.shell, tt.gzip, tt.chunkSize, len(tt.data))
+		t.Run(name, func(t *testing.T) {
+			tr, err := New(tt.data, Options{Shell: tt.shell, Name: "out.bin", Gzip: tt.gzip, ChunkSize: tt.chunkSize})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tr.Chunks) < 2 {
+				t.Fatalf("%d chunks, want at least create and decode", len(tr.Chunks))
+			}
+			if tr.Chunks[0] != shells[tt.shell].create("out.bin.b64") {
+				t.Errorf("first chunk %q does not create the temporary file", tr.Chunks[0])
+			}
+			last := tr.Chunks[len(tr.Chunks)-1]
+			if last != shells[tt.shell].decode("out.bin.b64", "out.bin", tt.gzip, tr.SHA256) {
+				t.Errorf("last chunk %q does not decode", last)
+			}
+			if !strings.Contains(strings.ToLower(last), tr.SHA256) {
+				t.Errorf("last chunk does not check %s", tr.SHA256)
+			}
+
+			var encoded strings.Builder
+			data := tr.Chunks[1 : len(tr.Chunks)-1]
+			for i, chunk := range data {
+				if !strings.HasSuffix(chunk, "\n") {
+					t.Errorf("chunk %d does not end with a newline", i+1)
+				}
+				if tt.shell != Bash && strings.Count(chunk, "\n") != 1 {
+					t.Errorf("chunk %d spans several lines", i+1)
+				}
+				if tt.shell == Cmd && len(chunk) >= 8191 {
+					t.Errorf("chunk %d has %d characters, over cmd's line limit", i+1, len(chunk))
+				}
+				n := 0
+				lines := payload(t, tt.shell, chunk)
+				for j, l := range lines {
+					// whole base64 lines, only the very last one shorter
+					if tt.shell != PowerShell && len(l) != lineLen && (i < len(data)-1 || j < len(lines)-1) {
+						t.Errorf("chunk %d line %d has %d characters, want %d", i+1, j+1, len(l), lineLen)
+					}
+					n += len(l)
+					encoded.WriteString(l)
+				}
+				if i < len(data)-1 && n != tt.wantSize {
+					t.Errorf("chunk %d has %d base64 characters, want %d", i+1, n, tt.wantSize)
+				}
+			}
+			if encoded.Len() != tr.Encoded {
+				t.Errorf("typed %d base64 characters, Encoded = %d", encoded.Len(), tr.Encoded)
+			}
+
+			got, err := base64.StdEncoding.DecodeString(encoded.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.gzip {
+				zr, err := gzip.NewReader(bytes.NewReader(got))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, err = io.ReadAll(zr); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !bytes.Equal(got, tt.data) || tr.Size != len(tt.data) {
+				t.Errorf("the chunks decode to %d bytes that differ from the %d sent", len(got), len(tt.data))
+			}
+		})
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"cmd with gzip", Options{Shell: Cmd, Name: "out.bin", Gzip: true}, "no gzip"},
+		{"no name", Options{Shell: Bash, Name: " "}, "no target file name"},
+		{"quote", Options{Shell: Bash, Name: "it's.txt"}, "not allowed"},
+		{"double quote", Options{Shell: PowerShell, Name: `a"b.txt`}, "not allowed"},
+		{"newline", Options{Shell: Bash, Name: "a\nb"}, "not allowed"},
+		{"cmd percent", Options{Shell: Cmd, Name: "%TEMP%.txt"}, "not allowed"},
+		{"cmd bang", Options{Shell: Cmd, Name: "a!.txt"}, "not allowed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New([]byte("x"), tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+	// % is only special to cmd
+	if _, err := New([]byte("x"), Options{Shell: Bash, Name: "100%.txt"}); err != nil {
+		t.Errorf("bash: %v", err)
+	}
+}
+
+func TestPowerShellLiteralPaths(t *testing.T) {
+	tr, err := New([]byte("hello"), Options{Shell: PowerShell, Name: `C:\tmp\report[1].txt`, Gzip: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cmd := range []string{"Set-Content", "Add-Content", "Get-Content", "Remove-Item", "Get-FileHash"} {
+		found := false
+		for _, chunk := range tr.Chunks {
+			for _, stmt := range strings.Split(chunk, ";") {
+				if i := strings.Index(stmt, cmd+" "); i >= 0 {
+					found = true
+					if !strings.Contains(stmt[i:], "-LiteralPath '") {
+						t.Errorf("%s without -LiteralPath: %s", cmd, strings.TrimSpace(stmt))
+					}
+				}
+			}
+		}
+		if !found {
+			t.Errorf("no %s command", cmd)
+		}
+	}
+}
+
+func TestBashRoundTrip(t *testing.T) {
+	for _, tool := range []string{"bash", "base64", "gunzip", "sha256sum"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not installed", tool)
+		}
+	}
+	data := bytes.Repeat([]byte("line with 'quotes' and $vars\n"), 300)
+	for _, gz := range []bool{false, true} {
+		t.Run(fmt.Sprintf("gzip=%v", gz), func(t *testing.T) {
+			tr, err := New(data, Options{Shell: Bash, Name: "out.txt", Gzip: gz, ChunkSize: 500})
+			if err != nil {
+				t.Fatal(err)
+			}
+			cmd := exec.Command("bash")
+			cmd.Dir = t.TempDir()
+			cmd.Stdin = strings.NewReader(strings.Join(tr.Chunks, ""))
+			out, err := cmd.CombinedOutput()
+			if err != nil || !strings.Contains(string(out), "out.txt: OK") {
+				t.Fatalf("bash: %v\n%s", err, out)
+			}
+			got, err := os.ReadFile(filepath.Join(cmd.Dir, "out.txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Error("the file differs from the one sent")
+			}
+			if _, err := os.Stat(filepath.Join(cmd.Dir, "out.txt.b64")); !os.IsNotExist(err) {
+				t.Error("the temporary file is left behind")
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+//go:build windows || darwin || linux
+
+package main
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"goclip/keyplan"
+)
+
+// typabilityColor returns the color a character typed with m is shown in:
+// plain for its own key, primary for dead keys and replacements, warning
+// for fallbacks and error for characters that will not be typed.
+func typabilityColor(m keyplan.Method) fyne.ThemeColorName {
+	switch m {
+	case keyplan.MethodKey:
+		return theme.ColorNameForeground
+	case keyplan.MethodDeadKey, keyplan.MethodTransliterated:
+		return theme.ColorNamePrimary
+	case keyplan.MethodFallback:
+		return theme.ColorNameWarning
+	default:
+		return theme.ColorNameError
+	}
+}
+
+// typabilitySegments renders the analyzed text for a RichText, one
+// paragraph per line, with the characters that have no key of their own
+// highlighted.
+func typabilitySegments(report keyplan.Report) []widget.RichTextSegment {
+	var (
+		segs  []widget.RichTextSegment
+		run   []rune
+		color = theme.ColorNameForeground
+	)
+	flush := func(inline bool) {
+		style := widget.RichTextStyleInline
+		style.Inline = inline
+		style.ColorName = color
+		style.TextStyle.Bold = color != theme.ColorNameForeground
+		segs = append(segs, &widget.TextSegment{Text: string(run), Style: style})
+		run = run[:0]
+	}
+	for _, c := range report {
+		if c.Rune == '\n' {
+			flush(false)
+			continue
+		}
+		if col := typabilityColor(c.Method); col != color {
+			if len(run) > 0 {
+				flush(true)
+			}
+			color = col
+		}
+		run = append(run, c.Rune)
+	}
+	flush(false)
+	return segs
+}
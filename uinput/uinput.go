@@ -0,0 +1,204 @@
+//go:build linux
+
+// Package uinput creates a virtual keyboard through the Linux uinput module
+// and sends evdev key events through it. The kernel delivers those events to
+// whatever consumes input devices (Wayland compositor, X server, console),
+// so it works where X11 injection is not available.
+package uinput
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+	"unsafe"
+
+	"goclip/keyplan"
+
+	"golang.org/x/sys/unix"
+)
+
+// DevicePath is the uinput control device.
+const DevicePath = "/dev/uinput"
+
+const deviceName = "goclip virtual keyboard"
+
+// Event types and codes from <linux/input-event-codes.h>.
+const (
+	evSyn     = 0x00
+	evKey     = 0x01
+	synReport = 0
+	keyMax    = 0x2ff
+)
+
+// ioctl requests from <linux/uinput.h>. They use the generic _IOC layout
+// shared by x86, arm and riscv.
+const (
+	uiDevCreate  = 0x5501     // _IO('U', 1)
+	uiDevDestroy = 0x5502     // _IO('U', 2)
+	uiDevSetup   = 0x405c5503 // _IOW('U', 3, struct uinput_setup)
+	uiSetEvBit   = 0x40045564 // _IOW('U', 100, int)
+	uiSetKeyBit  = 0x40045565 // _IOW('U', 101, int)
+)
+
+// createSettle gives the compositor or X server time to pick up the new
+// device before the first key arrives; early events are silently lost.
+const createSettle = 300 * time.Millisecond
+
+// ErrPermission is returned (wrapped) when /dev/uinput cannot be opened
+// because the user lacks access.
+var ErrPermission = errors.New("no permission to use " + DevicePath)
+
+type inputID struct {
+	Bustype, Vendor, Product, Version uint16
+}
+
+type uinputSetup struct {
+	ID           inputID
+	Name         [80]byte
+	FFEffectsMax uint32
+}
+
+type inputEvent struct {
+	Time  unix.Timeval
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+// Keyboard is a virtual keyboard device.
+type Keyboard struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// Open creates the virtual keyboard. Permission problems are reported as
+// ErrPermission with a hint on how to grant access.
+func Open() (*Keyboard, error) {
+	f, err := os.OpenFile(DevicePath, os.O_WRONLY|unix.O_NONBLOCK, 0)
+	if err != nil {
+		switch {
+		case errors.Is(err, os.ErrPermission):
+			return nil, fmt.Errorf("%w: add your user to the group owning %s (usually \"input\") "+
+				"or install a udev rule such as KERNEL==\"uinput\", GROUP=\"input\", MODE=\"0660\", then log in again",
+				ErrPermission, DevicePath)
+		case errors.Is(err, os.ErrNotExist):
+			return nil, fmt.Errorf("%s not found: load the uinput kernel module (modprobe uinput)", DevicePath)
+		default:
+			return nil, err
+		}
+	}
+
+	kb := &Keyboard{f: f}
+	if err := kb.setup(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	time.Sleep(createSettle)
+	return kb, nil
+}
+
+func (kb *Keyboard) ioctl(req, arg uintptr) error {
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, kb.f.Fd(), req, arg); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func (kb *Keyboard) setup() error {
+	if err := kb.ioctl(uiSetEvBit, evKey); err != nil {
+		return fmt.Errorf("UI_SET_EVBIT: %w", err)
+	}
+	for code := uintptr(1); code < 256; code++ {
+		if err := kb.ioctl(uiSetKeyBit, code); err != nil {
+			return fmt.Errorf("UI_SET_KEYBIT: %w", err)
+		}
+	}
+
+	setup := uinputSetup{ID: inputID{Bustype: 0x06 /* BUS_VIRTUAL */, Version: 1}}
+	copy(setup.Name[:], deviceName)
+	if err := kb.ioctl(uiDevSetup, uintptr(unsafe.Pointer(&setup))); err != nil {
+		return fmt.Errorf("UI_DEV_SETUP (needs Linux 4.5+): %w", err)
+	}
+	if err := kb.ioctl(uiDevCreate, 0); err != nil {
+		return fmt.Errorf("UI_DEV_CREATE: %w", err)
+	}
+	return nil
+}
+
+// Close removes the virtual keyboard.
+func (kb *Keyboard) Close() error {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	_ = kb.ioctl(uiDevDestroy, 0)
+	return kb.f.Close()
+}
+
+func (kb *Keyboard) emit(typ, code uint16, value int32) error {
+	ev := inputEvent{Type: typ, Code: code, Value: value}
+	buf := (*[unsafe.Sizeof(inputEvent{})]byte)(unsafe.Pointer(&ev))[:]
+	_, err := kb.f.Write(buf)
+	return err
+}
+
+// Key presses or releases the key with the given evdev code.
+func (kb *Keyboard) Key(code uint16, down bool) error {
+	if code == 0 || code > keyMax {
+		return fmt.Errorf("invalid evdev key code %d", code)
+	}
+	value := int32(0)
+	if down {
+		value = 1
+	}
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	if err := kb.emit(evKey, code, value); err != nil {
+		return err
+	}
+	return kb.emit(evSyn, synReport, 0)
+}
+
+// Injector returns a keyplan.Injector that types through the device. The
+// system's active keymap decides which character a key produces, so plans
+// must be built with a mapper for that keymap. Characters without a key
+// cannot be typed. If settle is non-zero, the injector pauses that long
+// after every modifier change.
+func (kb *Keyboard) Injector(settle time.Duration) keyplan.Injector {
+	return &injector{kb: kb, settle: settle}
+}
+
+type injector struct {
+	kb     *Keyboard
+	settle time.Duration
+}
+
+func (in *injector) PressKey(k keyplan.Key, down bool) error {
+	code, ok := keyplan.EvdevCode(k)
+	if !ok {
+		return fmt.Errorf("no evdev key code for scan code %s", k)
+	}
+	return in.kb.Key(code, down)
+}
+
+func (in *injector) PressModifier(mod keyplan.Modifier, down bool) error {
+	code, ok := keyplan.EvdevModifier(mod)
+	if !ok {
+		return nil
+	}
+	if err := in.kb.Key(code, down); err != nil {
+		return err
+	}
+	if in.settle > 0 {
+		time.Sleep(in.settle)
+	}
+	return nil
+}
+
+func (in *injector) TypeUnicode(r rune) error {
+	return fmt.Errorf("uinput cannot type U+%04X: the keyboard layout has no key for it", r)
+}
+
+func (in *injector) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
@@ -0,0 +1,413 @@
+// Package vault keeps named secrets in a file encrypted with a master
+// passphrase. The key is derived with Argon2id and the secrets are sealed
+// with AES-256-GCM; while the vault is locked only the encrypted file
+// exists.
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+var (
+	// ErrLocked is returned when the vault must be unlocked first.
+	ErrLocked = errors.New("the vault is locked")
+	// ErrWrongPassphrase is returned when the passphrase does not open
+	// the vault (or the file was modified).
+	ErrWrongPassphrase = errors.New("wrong passphrase")
+	// ErrNotFound is returned for a secret name the vault does not hold.
+	ErrNotFound = errors.New("no such secret")
+)
+
+// fileVersion is the version of the vault file format.
+const fileVersion = 1
+
+// kdfParams are the Argon2id parameters stored in the file.
+type kdfParams struct {
+	Name    string `json:"name"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"` // KiB
+	Threads uint8  `json:"threads"`
+	Salt    []byte `json:"salt"`
+}
+
+// defaultKDF costs about half a second and 64 MiB on a current machine.
+var defaultKDF = kdfParams{Name: "argon2id", Time: 3, Memory: 64 * 1024, Threads: 4}
+
+// Bounds for the parameters read from a file. They keep a damaged or
+// hostile file from making Argon2 panic (no passes or threads) or asking
+// for more memory or time than a desktop has.
+const (
+	maxArgon2Time   = 100
+	maxArgon2Memory = 4 << 20 // KiB
+	minSaltLen      = 8
+	maxSaltLen      = 64
+	gcmNonceSize    = 12 // the standard AES-GCM nonce
+)
+
+// check validates parameters read from a file before a key is derived.
+func (p kdfParams) check() error {
+	switch {
+	case p.Time < 1 || p.Time > maxArgon2Time:
+		return fmt.Errorf("bad Argon2 time %d", p.Time)
+	case p.Threads < 1:
+		return fmt.Errorf("bad Argon2 threads %d", p.Threads)
+	case p.Memory < 8*uint32(p.Threads) || p.Memory > maxArgon2Memory:
+		return fmt.Errorf("Argon2 memory of %d KiB is not supported", p.Memory)
+	case len(p.Salt) < minSaltLen || len(p.Salt) > maxSaltLen:
+		return fmt.Errorf("bad salt length %d", len(p.Salt))
+	}
+	return nil
+}
+
+func (p kdfParams) key(passphrase string) []byte {
+	return argon2.IDKey([]byte(passphrase), p.Salt, p.Time, p.Memory, p.Threads, 32)
+}
+
+// file is the JSON layout of the vault file. Data is the sealed contents.
+type file struct {
+	Version int       `json:"version"`
+	KDF     kdfParams `json:"kdf"`
+	Nonce   []byte    `json:"nonce"`
+	Data    []byte    `json:"data"`
+}
+
+// Secret is a named value.
+type Secret struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type contents struct {
+	Secrets []Secret `json:"secrets"`
+}
+
+// Vault is a vault file and, while unlocked, its key and secrets.
+type Vault struct {
+	path string
+
+	mu      sync.Mutex
+	kdf     kdfParams
+	key     []byte // nil while locked
+	secrets []Secret
+
+	autoLock time.Duration
+	timer    *time.Timer
+	onLock   func()
+}
+
+// New returns a locked vault stored at path.
+func New(path string) *Vault {
+	return &Vault{path: path}
+}
+
+// Path returns the vault file.
+func (v *Vault) Path() string {
+	return v.path
+}
+
+// Exists reports whether the vault file has been created.
+func (v *Vault) Exists() bool {
+	_, err := os.Stat(v.path)
+	return err == nil
+}
+
+// Create writes a new, empty vault protected by passphrase and leaves it
+// unlocked. An existing vault file is replaced.
+func (v *Vault) Create(passphrase string) error {
+	if passphrase == "" {
+		return errors.New("the passphrase is empty")
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	p := defaultKDF
+	p.Salt = make([]byte, 16)
+	if _, err := rand.Read(p.Salt); err != nil {
+		return err
+	}
+	v.kdf, v.key, v.secrets = p, p.key(passphrase), nil
+	v.touch()
+	return v.save()
+}
+
+// Unlock decrypts the vault with passphrase.
+func (v *Vault) Unlock(passphrase string) error {
+	data, err := os.ReadFile(v.path)
+	if err != nil {
+		return err
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+	if f.Version != fileVersion || f.KDF.Name != defaultKDF.Name {
+		return errors.New("unsupported vault file")
+	}
+	if err := f.KDF.check(); err != nil {
+		return fmt.Errorf("damaged vault file: %w", err)
+	}
+	if len(f.Nonce) != gcmNonceSize {
+		return fmt.Errorf("damaged vault file: bad nonce length %d", len(f.Nonce))
+	}
+	key := f.KDF.key(passphrase)
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	plain, err := aead.Open(nil, f.Nonce, f.Data, additionalData(f))
+	if err != nil {
+		return ErrWrongPassphrase
+	}
+	var c contents
+	if err := json.Unmarshal(plain, &c); err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.kdf, v.key, v.secrets = f.KDF, key, c.Secrets
+	v.touch()
+	return nil
+}
+
+// Lock forgets the key and the secrets.
+func (v *Vault) Lock() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.lock()
+}
+
+func (v *Vault) lock() {
+	clear(v.key)
+	v.key, v.secrets = nil, nil
+	if v.timer != nil {
+		v.timer.Stop()
+		v.timer = nil
+	}
+}
+
+// Locked reports whether the vault is locked.
+func (v *Vault) Locked() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.key == nil
+}
+
+// SetAutoLock makes the vault lock itself once it has not been used for
+// d; 0 turns this off. onLock, if set, runs on a timer goroutine after an
+// automatic lock.
+func (v *Vault) SetAutoLock(d time.Duration, onLock func()) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.autoLock, v.onLock = d, onLock
+	if v.key != nil {
+		v.touch()
+	}
+}
+
+// touch restarts the auto-lock timer. v.mu must be held.
+func (v *Vault) touch() {
+	if v.timer != nil {
+		v.timer.Stop()
+		v.timer = nil
+	}
+	if v.autoLock <= 0 {
+		return
+	}
+	var t *time.Timer
+	t = time.AfterFunc(v.autoLock, func() {
+		v.mu.Lock()
+		if v.timer != t {
+			// used again since
+			v.mu.Unlock()
+			return
+		}
+		v.lock()
+		onLock := v.onLock
+		v.mu.Unlock()
+		if onLock != nil {
+			onLock()
+		}
+	})
+	v.timer = t
+}
+
+// Names returns the names of the secrets in order.
+func (v *Vault) Names() ([]string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.key == nil {
+		return nil, ErrLocked
+	}
+	v.touch()
+	names := make([]string, len(v.secrets))
+	for i, s := range v.secrets {
+		names[i] = s.Name
+	}
+	return names, nil
+}
+
+// Get returns the value of a secret.
+func (v *Vault) Get(name string) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.key == nil {
+		return "", ErrLocked
+	}
+	v.touch()
+	for _, s := range v.secrets {
+		if s.Name == name {
+			return s.Value, nil
+		}
+	}
+	return "", ErrNotFound
+}
+
+// Set adds or replaces a secret and saves the vault.
+func (v *Vault) Set(name, value string) error {
+	if name == "" {
+		return errors.New("the secret has no name")
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.key == nil {
+		return ErrLocked
+	}
+	v.touch()
+	secrets := make([]Secret, 0, len(v.secrets)+1)
+	for _, s := range v.secrets {
+		if s.Name != name {
+			secrets = append(secrets, s)
+		}
+	}
+	secrets = append(secrets, Secret{Name: name, Value: value})
+	sort.Slice(secrets, func(i, j int) bool { return secrets[i].Name < secrets[j].Name })
+	old := v.secrets
+	v.secrets = secrets
+	if err := v.save(); err != nil {
+		v.secrets = old
+		return err
+	}
+	return nil
+}
+
+// Delete removes a secret and saves the vault.
+func (v *Vault) Delete(name string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.key == nil {
+		return ErrLocked
+	}
+	v.touch()
+	secrets := make([]Secret, 0, len(v.secrets))
+	for _, s := range v.secrets {
+		if s.Name != name {
+			secrets = append(secrets, s)
+		}
+	}
+	if len(secrets) == len(v.secrets) {
+		return ErrNotFound
+	}
+	old := v.secrets
+	v.secrets = secrets
+	if err := v.save(); err != nil {
+		v.secrets = old
+		return err
+	}
+	return nil
+}
+
+// ChangePassphrase re-encrypts the unlocked vault with a new passphrase.
+func (v *Vault) ChangePassphrase(passphrase string) error {
+	if passphrase == "" {
+		return errors.New("the passphrase is empty")
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.key == nil {
+		return ErrLocked
+	}
+	v.touch()
+	p := defaultKDF
+	p.Salt = make([]byte, 16)
+	if _, err := rand.Read(p.Salt); err != nil {
+		return err
+	}
+	oldKDF, oldKey := v.kdf, v.key
+	v.kdf, v.key = p, p.key(passphrase)
+	if err := v.save(); err != nil {
+		v.kdf, v.key = oldKDF, oldKey
+		return err
+	}
+	clear(oldKey)
+	return nil
+}
+
+// save encrypts the secrets into the vault file, replacing it atomically.
+// v.mu must be held.
+func (v *Vault) save() error {
+	plain, err := json.Marshal(contents{Secrets: v.secrets})
+	if err != nil {
+		return err
+	}
+	defer clear(plain)
+	aead, err := newAEAD(v.key)
+	if err != nil {
+		return err
+	}
+	f := file{Version: fileVersion, KDF: v.kdf, Nonce: make([]byte, aead.NonceSize())}
+	if _, err := rand.Read(f.Nonce); err != nil {
+		return err
+	}
+	f.Data = aead.Seal(nil, f.Nonce, plain, additionalData(f))
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(v.path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(v.path), ".vault-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), v.path)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// additionalData binds the version and KDF parameters to the ciphertext,
+// so they cannot be changed without the passphrase.
+func additionalData(f file) []byte {
+	b, _ := json.Marshal(struct {
+		Version int       `json:"version"`
+		KDF     kdfParams `json:"kdf"`
+	}{f.Version, f.KDF})
+	return b
+}
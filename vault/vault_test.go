@@ -0,0 +1,79 @@
+package vault
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnlock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.json")
+	v := New(path)
+	if err := v.Create("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Set("db", "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+
+	v = New(path)
+	if err := v.Unlock("wrong"); err != ErrWrongPassphrase {
+		t.Errorf("wrong passphrase: got %v", err)
+	}
+	if err := v.Unlock("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := v.Get("db"); err != nil || got != "s3cret" {
+		t.Errorf("Get = %q, %v", got, err)
+	}
+}
+
+func TestUnlockRejectsBadParameters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.json")
+	if err := New(path).Create("pw"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(f *file)
+		want   string
+	}{
+		{"no passes", func(f *file) { f.KDF.Time = 0 }, "time"},
+		{"too many passes", func(f *file) { f.KDF.Time = 1 << 31 }, "time"},
+		{"no threads", func(f *file) { f.KDF.Threads = 0 }, "threads"},
+		{"too little memory", func(f *file) { f.KDF.Memory = 8 }, "memory"},
+		{"too much memory", func(f *file) { f.KDF.Memory = 1 << 31 }, "memory"},
+		{"no salt", func(f *file) { f.KDF.Salt = nil }, "salt"},
+		{"huge salt", func(f *file) { f.KDF.Salt = make([]byte, 1<<20) }, "salt"},
+		{"short nonce", func(f *file) { f.Nonce = f.Nonce[:4] }, "nonce"},
+		{"long nonce", func(f *file) { f.Nonce = append(f.Nonce, 0) }, "nonce"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var f file
+			if err := json.Unmarshal(data, &f); err != nil {
+				t.Fatal(err)
+			}
+			tt.modify(&f)
+			bad, err := json.Marshal(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := filepath.Join(t.TempDir(), "vault.json")
+			if err := os.WriteFile(p, bad, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			err = New(p).Unlock("pw")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want an error about the %s", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+//go:build linux
+
+package wayland
+
+import (
+	"fmt"
+	"time"
+
+	"goclip/keyplan"
+	"goclip/layout"
+
+	"golang.org/x/sys/unix"
+)
+
+// xkbKeymapFormatTextV1 is wl_keyboard.keymap_format.xkb_v1.
+const xkbKeymapFormatTextV1 = 1
+
+// upload sends km to the compositor through a memfd.
+func (c *Conn) upload(km *keymap) error {
+	data := append([]byte(km.text()), 0)
+	fd, err := unix.MemfdCreate("goclip-keymap", unix.MFD_CLOEXEC)
+	if err != nil {
+		return fmt.Errorf("memfd_create: %w", err)
+	}
+	defer unix.Close(fd)
+	if _, err := unix.Write(fd, data); err != nil {
+		return fmt.Errorf("write keymap: %w", err)
+	}
+	m := (&message{}).uint(xkbKeymapFormatTextV1).fd(fd).uint(uint32(len(data)))
+	return c.w.send(c.keyboard, keyboardKeymap, m)
+}
+
+// Injector uploads a keymap with the keys of l plus every character of p
+// that l cannot type, and returns an injector for it. p must be built with
+// l as its mapper. If settle is non-zero, the injector pauses that long
+// after every modifier change.
+func (c *Conn) Injector(p keyplan.Plan, l *layout.Layout, settle time.Duration) (keyplan.Injector, error) {
+	km := newKeymap(l)
+	for _, ev := range p.Events {
+		if ev.Kind == keyplan.Unicode && !km.full() {
+			km.assign(ev.Rune)
+		}
+	}
+	c.mu.Lock()
+	err := c.upload(km)
+	if err == nil {
+		err = c.roundtrip(nil)
+	}
+	c.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return &injector{c: c, km: km, start: time.Now(), settle: settle}, nil
+}
+
+type injector struct {
+	c         *Conn
+	km        *keymap
+	start     time.Time
+	depressed uint32
+	settle    time.Duration
+}
+
+func (in *injector) key(code uint16, down bool) error {
+	state := uint32(0)
+	if down {
+		state = 1
+	}
+	ms := uint32(time.Since(in.start).Milliseconds())
+	in.c.mu.Lock()
+	defer in.c.mu.Unlock()
+	return in.c.w.send(in.c.keyboard, keyboardKey, (&message{}).uint(ms).uint(uint32(code)).uint(state))
+}
+
+func (in *injector) PressKey(k keyplan.Key, down bool) error {
+	code, ok := keyplan.EvdevCode(k)
+	if !ok {
+		return fmt.Errorf("no evdev key code for scan code %s", k)
+	}
+	return in.key(code, down)
+}
+
+func (in *injector) PressModifier(mod keyplan.Modifier, down bool) error {
+	code, ok := keyplan.EvdevModifier(mod)
+	if !ok {
+		return nil
+	}
+	if err := in.key(code, down); err != nil {
+		return err
+	}
+	// the compositor does not derive modifier state from virtual key events
+	if down {
+		in.depressed |= modifierMask(mod)
+	} else {
+		in.depressed &^= modifierMask(mod)
+	}
+	in.c.mu.Lock()
+	err := in.c.w.send(in.c.keyboard, keyboardModifiers, (&message{}).uint(in.depressed).uint(0).uint(0).uint(0))
+	in.c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if in.settle > 0 {
+		time.Sleep(in.settle)
+	}
+	return nil
+}
+
+func (in *injector) TypeUnicode(r rune) error {
+	code, changed := in.km.assign(r)
+	if changed {
+		in.c.mu.Lock()
+		err := in.c.upload(in.km)
+		in.c.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	if err := in.key(code, true); err != nil {
+		return err
+	}
+	return in.key(code, false)
+}
+
+func (in *injector) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
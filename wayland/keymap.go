@@ -0,0 +1,210 @@
+//go:build linux
+
+package wayland
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"goclip/keyplan"
+	"goclip/keysym"
+	"goclip/layout"
+)
+
+// maxEvdev keeps generated keycodes (evdev + 8) within the 8-255 range that
+// XWayland clients understand.
+const maxEvdev = 247
+
+// namedKeys are the non-character keys of the uploaded keymap, by evdev code.
+var namedKeys = map[uint16]keysym.Keysym{
+	1:   0xff1b, // Escape
+	14:  0xff08, // BackSpace
+	15:  0xff09, // Tab
+	28:  0xff0d, // Return
+	58:  0xffe5, // Caps_Lock
+	59:  0xffbe, // F1
+	60:  0xffbf,
+	61:  0xffc0,
+	62:  0xffc1,
+	63:  0xffc2,
+	64:  0xffc3,
+	65:  0xffc4,
+	66:  0xffc5,
+	67:  0xffc6,
+	68:  0xffc7, // F10
+	71:  0xffb7, // KP_7, kept for Alt+Numpad sequences
+	72:  0xffb8,
+	73:  0xffb9,
+	75:  0xffb4,
+	76:  0xffb5,
+	77:  0xffb6,
+	79:  0xffb1,
+	80:  0xffb2,
+	81:  0xffb3,
+	82:  0xffb0, // KP_0
+	87:  0xffc8, // F11
+	88:  0xffc9, // F12
+	96:  0xff8d, // KP_Enter
+	102: 0xff50, // Home
+	103: 0xff52, // Up
+	104: 0xff55, // Prior
+	105: 0xff51, // Left
+	106: 0xff53, // Right
+	107: 0xff57, // End
+	108: 0xff54, // Down
+	109: 0xff56, // Next
+	110: 0xff63, // Insert
+	111: 0xffff, // Delete
+	127: 0xff67, // Menu
+}
+
+// modifierKeys binds each keyplan modifier to its key, keysym and real
+// modifier (name and mask bit in the "complete" compat map).
+var modifierKeys = []struct {
+	mod  keyplan.Modifier
+	sym  keysym.Keysym
+	name string
+	mask uint32
+}{
+	{keyplan.ModShift, keysym.ShiftL, "Shift", 1 << 0},
+	{keyplan.ModCtrl, keysym.ControlL, "Control", 1 << 2},
+	{keyplan.ModAlt, keysym.AltL, "Mod1", 1 << 3},
+	{keyplan.ModMeta, keysym.SuperL, "Mod4", 1 << 6},
+	{keyplan.ModAltGr, keysym.ISOLevel3Shift, "Mod5", 1 << 7},
+}
+
+func modifierMask(mod keyplan.Modifier) uint32 {
+	for _, mk := range modifierKeys {
+		if mk.mod == mod {
+			return mk.mask
+		}
+	}
+	return 0
+}
+
+// keymap is the XKB keymap uploaded to the compositor: the keys of a goclip
+// layout table plus one extra key for every other character being typed.
+type keymap struct {
+	levels  map[uint16][]keysym.Keysym // evdev code -> keysyms per level
+	fixed   map[uint16]bool
+	dynamic map[rune]uint16
+	free    []uint16
+}
+
+func newKeymap(l *layout.Layout) *keymap {
+	km := &keymap{
+		levels:  map[uint16][]keysym.Keysym{},
+		fixed:   map[uint16]bool{},
+		dynamic: map[rune]uint16{},
+	}
+	set := func(code uint16, level int, sym keysym.Keysym) {
+		for len(km.levels[code]) <= level {
+			km.levels[code] = append(km.levels[code], keysym.NoSymbol)
+		}
+		km.levels[code][level] = sym
+		km.fixed[code] = true
+	}
+
+	for _, e := range l.Entries() {
+		level, ok := xkbLevel(e.Stroke.Mods)
+		if !ok || e.Dead {
+			continue
+		}
+		code, ok := keyplan.EvdevCode(e.Stroke.Key)
+		if !ok {
+			continue
+		}
+		set(code, level, keysym.FromRune(e.Rune))
+	}
+	for code, sym := range namedKeys {
+		set(code, 0, sym)
+	}
+	for _, mk := range modifierKeys {
+		code, _ := keyplan.EvdevModifier(mk.mod)
+		set(code, 0, mk.sym)
+	}
+
+	for code := uint16(1); code <= maxEvdev; code++ {
+		if !km.fixed[code] {
+			km.free = append(km.free, code)
+		}
+	}
+	return km
+}
+
+// xkbLevel returns the shift level selected by mods in the "complete" types.
+func xkbLevel(mods keyplan.Modifier) (int, bool) {
+	switch mods {
+	case 0:
+		return 0, true
+	case keyplan.ModShift:
+		return 1, true
+	case keyplan.ModAltGr:
+		return 2, true
+	case keyplan.ModShift | keyplan.ModAltGr:
+		return 3, true
+	}
+	return 0, false
+}
+
+// assign returns the key typing r, adding one if needed. changed reports
+// that the keymap must be uploaded again. When every spare key is taken,
+// earlier assignments are dropped.
+func (km *keymap) assign(r rune) (code uint16, changed bool) {
+	if code, ok := km.dynamic[r]; ok {
+		return code, false
+	}
+	if len(km.dynamic) == len(km.free) {
+		for _, code := range km.dynamic {
+			delete(km.levels, code)
+		}
+		km.dynamic = map[rune]uint16{}
+	}
+	code = km.free[len(km.dynamic)]
+	km.dynamic[r] = code
+	km.levels[code] = []keysym.Keysym{keysym.FromRune(r)}
+	return code, true
+}
+
+// full reports whether another character would evict an assignment.
+func (km *keymap) full() bool {
+	return len(km.dynamic) == len(km.free)
+}
+
+// text renders the keymap in xkb_keymap text format (XKB_KEYMAP_FORMAT_TEXT_V1).
+func (km *keymap) text() string {
+	codes := make([]int, 0, len(km.levels))
+	for code := range km.levels {
+		codes = append(codes, int(code))
+	}
+	sort.Ints(codes)
+
+	var b strings.Builder
+	b.WriteString("xkb_keymap {\n")
+	b.WriteString("xkb_keycodes \"goclip\" {\n\tminimum = 8;\n\tmaximum = 255;\n")
+	for _, code := range codes {
+		fmt.Fprintf(&b, "\t<I%d> = %d;\n", code+8, code+8)
+	}
+	b.WriteString("};\n")
+	b.WriteString("xkb_types \"goclip\" { include \"complete\" };\n")
+	b.WriteString("xkb_compatibility \"goclip\" { include \"complete\" };\n")
+	b.WriteString("xkb_symbols \"goclip\" {\n")
+	for _, code := range codes {
+		syms := make([]string, 0, 2)
+		for _, sym := range km.levels[uint16(code)] {
+			if sym == keysym.NoSymbol {
+				syms = append(syms, "NoSymbol")
+			} else {
+				syms = append(syms, fmt.Sprintf("0x%x", uint32(sym)))
+			}
+		}
+		fmt.Fprintf(&b, "\tkey <I%d> { [ %s ] };\n", code+8, strings.Join(syms, ", "))
+	}
+	for _, mk := range modifierKeys {
+		code, _ := keyplan.EvdevModifier(mk.mod)
+		fmt.Fprintf(&b, "\tmodifier_map %s { <I%d> };\n", mk.name, code+8)
+	}
+	b.WriteString("};\n};\n")
+	return b.String()
+}
@@ -0,0 +1,170 @@
+//go:build linux
+
+// Package wayland types text through the zwp_virtual_keyboard_v1 protocol
+// offered by wlroots-based compositors (sway, Hyprland, labwc, ...). The
+// client uploads its own XKB keymap, so characters the user's layout lacks
+// can still be typed without root or /dev/uinput access.
+package wayland
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const (
+	displayID = 1
+
+	// wl_display
+	displaySync        = 0
+	displayGetRegistry = 1
+	displayErrorEvent  = 0
+
+	// wl_registry
+	registryBind        = 0
+	registryGlobalEvent = 0
+
+	// wl_callback
+	callbackDoneEvent = 0
+
+	// zwp_virtual_keyboard_manager_v1
+	managerCreateKeyboard = 0
+
+	// zwp_virtual_keyboard_v1
+	keyboardKeymap    = 0
+	keyboardKey       = 1
+	keyboardModifiers = 2
+	keyboardDestroy   = 3
+)
+
+const (
+	seatInterface    = "wl_seat"
+	managerInterface = "zwp_virtual_keyboard_manager_v1"
+)
+
+// ErrUnsupported is returned by Open when the compositor does not offer
+// the virtual keyboard protocol (e.g. GNOME, KDE).
+var ErrUnsupported = errors.New("compositor does not support " + managerInterface)
+
+// Conn is a Wayland connection with one virtual keyboard.
+type Conn struct {
+	mu       sync.Mutex
+	w        *wire
+	registry uint32
+	seat     uint32
+	manager  uint32
+	keyboard uint32
+}
+
+type global struct {
+	name    uint32
+	version uint32
+}
+
+// Open connects to $WAYLAND_DISPLAY and creates a virtual keyboard on the
+// first seat.
+func Open() (*Conn, error) {
+	w, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	c := &Conn{w: w}
+	if err := c.init(); err != nil {
+		w.close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Conn) init() error {
+	c.registry = c.w.newID()
+	if err := c.w.send(displayID, displayGetRegistry, (&message{}).uint(c.registry)); err != nil {
+		return err
+	}
+	globals := map[string]global{}
+	err := c.roundtrip(func(ev event) {
+		if ev.sender == c.registry && ev.opcode == registryGlobalEvent {
+			name := ev.uint()
+			iface := ev.string()
+			version := ev.uint()
+			if _, seen := globals[iface]; !seen {
+				globals[iface] = global{name: name, version: version}
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	seat, ok := globals[seatInterface]
+	if !ok {
+		return errors.New("compositor has no wl_seat")
+	}
+	manager, ok := globals[managerInterface]
+	if !ok {
+		return ErrUnsupported
+	}
+	if c.seat, err = c.bind(seat, seatInterface, 1); err != nil {
+		return err
+	}
+	if c.manager, err = c.bind(manager, managerInterface, 1); err != nil {
+		return err
+	}
+
+	c.keyboard = c.w.newID()
+	if err := c.w.send(c.manager, managerCreateKeyboard, (&message{}).uint(c.seat).uint(c.keyboard)); err != nil {
+		return err
+	}
+	// surfaces an "unauthorized" protocol error right away
+	return c.roundtrip(nil)
+}
+
+func (c *Conn) bind(g global, iface string, version uint32) (uint32, error) {
+	if g.version < version {
+		version = g.version
+	}
+	id := c.w.newID()
+	m := (&message{}).uint(g.name).string(iface).uint(version).uint(id)
+	return id, c.w.send(c.registry, registryBind, m)
+}
+
+// roundtrip waits until the compositor has processed every request sent so
+// far, passing other events to handle. Protocol errors are returned.
+func (c *Conn) roundtrip(handle func(event)) error {
+	callback := c.w.newID()
+	if err := c.w.send(displayID, displaySync, (&message{}).uint(callback)); err != nil {
+		return err
+	}
+	for {
+		ev, err := c.w.read()
+		if err != nil {
+			return fmt.Errorf("read from Wayland display: %w", err)
+		}
+		switch {
+		case ev.sender == displayID && ev.opcode == displayErrorEvent:
+			object := ev.uint()
+			code := ev.uint()
+			return fmt.Errorf("Wayland protocol error on object %d (code %d): %s", object, code, ev.string())
+		case ev.sender == callback && ev.opcode == callbackDoneEvent:
+			return nil
+		case handle != nil:
+			handle(ev)
+		}
+	}
+}
+
+// Sync waits for the compositor to process all keys sent so far and
+// reports protocol errors.
+func (c *Conn) Sync() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.roundtrip(nil)
+}
+
+// Close destroys the virtual keyboard and disconnects.
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_ = c.w.send(c.keyboard, keyboardDestroy, nil)
+	return c.w.close()
+}
@@ -0,0 +1,263 @@
+//go:build linux
+
+package wayland
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"goclip/keyplan"
+	"goclip/keysym"
+	"goclip/layout"
+
+	"golang.org/x/sys/unix"
+)
+
+// fakeCompositor speaks just enough of the Wayland protocol to offer a
+// seat and the virtual keyboard manager, and records what the virtual
+// keyboard is sent.
+type fakeCompositor struct {
+	ln          *net.UnixListener
+	withManager bool
+
+	mu      sync.Mutex
+	keys    []string // "+30", "-30"
+	mods    []uint32 // depressed masks
+	keymaps []string
+}
+
+func startFakeCompositor(t *testing.T, withManager bool) *fakeCompositor {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "wayland-test")
+	ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	t.Setenv("WAYLAND_DISPLAY", path)
+	fc := &fakeCompositor{ln: ln, withManager: withManager}
+	go fc.serve()
+	return fc
+}
+
+func (fc *fakeCompositor) serve() {
+	for {
+		conn, err := fc.ln.AcceptUnix()
+		if err != nil {
+			return
+		}
+		go fc.client(conn)
+	}
+}
+
+func (fc *fakeCompositor) client(conn *net.UnixConn) {
+	defer conn.Close()
+	send := func(sender uint32, opcode uint16, m *message) {
+		hdr := make([]byte, 8)
+		binary.NativeEndian.PutUint32(hdr, sender)
+		binary.NativeEndian.PutUint32(hdr[4:], uint32(8+len(m.buf))<<16|uint32(opcode))
+		conn.Write(append(hdr, m.buf...))
+	}
+
+	var registry, manager, keyboard uint32
+	var pending []byte
+	var fds []int
+	buf := make([]byte, 4096)
+	oob := make([]byte, unix.CmsgSpace(4*4))
+	for {
+		n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+		if err != nil {
+			return
+		}
+		pending = append(pending, buf[:n]...)
+		if msgs, err := unix.ParseSocketControlMessage(oob[:oobn]); err == nil {
+			for _, msg := range msgs {
+				if rights, err := unix.ParseUnixRights(&msg); err == nil {
+					fds = append(fds, rights...)
+				}
+			}
+		}
+		for len(pending) >= 8 {
+			size := int(binary.NativeEndian.Uint32(pending[4:]) >> 16)
+			if len(pending) < size {
+				break
+			}
+			ev := event{
+				sender: binary.NativeEndian.Uint32(pending),
+				opcode: uint16(binary.NativeEndian.Uint32(pending[4:])),
+				body:   append([]byte(nil), pending[8:size]...),
+			}
+			pending = pending[size:]
+
+			switch {
+			case ev.sender == displayID && ev.opcode == displayGetRegistry:
+				registry = ev.uint()
+				send(registry, registryGlobalEvent, (&message{}).uint(1).string(seatInterface).uint(7))
+				if fc.withManager {
+					send(registry, registryGlobalEvent, (&message{}).uint(2).string(managerInterface).uint(1))
+				}
+			case ev.sender == displayID && ev.opcode == displaySync:
+				send(ev.uint(), callbackDoneEvent, (&message{}).uint(0))
+			case ev.sender == registry && ev.opcode == registryBind:
+				name := ev.uint()
+				ev.string()
+				ev.uint()
+				if id := ev.uint(); name == 2 {
+					manager = id
+				}
+			case ev.sender == manager && ev.opcode == managerCreateKeyboard:
+				ev.uint()
+				keyboard = ev.uint()
+			case ev.sender == keyboard && ev.opcode == keyboardKeymap:
+				ev.uint()
+				size := ev.uint()
+				if len(fds) > 0 {
+					data := make([]byte, size)
+					unix.Pread(fds[0], data, 0)
+					unix.Close(fds[0])
+					fds = fds[1:]
+					fc.mu.Lock()
+					fc.keymaps = append(fc.keymaps, strings.TrimRight(string(data), "\x00"))
+					fc.mu.Unlock()
+				}
+			case ev.sender == keyboard && ev.opcode == keyboardKey:
+				ev.uint()
+				code := ev.uint()
+				sign := "-"
+				if ev.uint() == 1 {
+					sign = "+"
+				}
+				fc.mu.Lock()
+				fc.keys = append(fc.keys, fmt.Sprintf("%s%d", sign, code))
+				fc.mu.Unlock()
+			case ev.sender == keyboard && ev.opcode == keyboardModifiers:
+				fc.mu.Lock()
+				fc.mods = append(fc.mods, ev.uint())
+				fc.mu.Unlock()
+			}
+		}
+	}
+}
+
+// typeText types text on l through c the way goclip's Wayland backend does.
+func typeText(t *testing.T, c *Conn, text string, l *layout.Layout) {
+	t.Helper()
+	p, err := keyplan.Build(text, keyplan.MapperFunc(l.Lookup), keyplan.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inj, err := c.Injector(p, l, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := keyplan.Replay(p, inj, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Sync(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVirtualKeyboard(t *testing.T) {
+	fc := startFakeCompositor(t, true)
+	c, err := Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	typeText(t, c, "aA€", layout.US)
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if len(fc.keymaps) != 1 {
+		t.Fatalf("got %d keymaps, want 1", len(fc.keymaps))
+	}
+	// € has no key on US, so the keymap gets one for it
+	euro := fmt.Sprintf("0x%x", uint32(keysym.FromRune('€')))
+	if !strings.Contains(fc.keymaps[0], euro) {
+		t.Errorf("keymap has no key for € (%s)", euro)
+	}
+	// a, then Shift (evdev 42) around a, then the key added for €
+	got := strings.Join(fc.keys, " ")
+	if want := "+30 -30 +42 +30 -30 -42 +"; !strings.HasPrefix(got, want) {
+		t.Errorf("keys %s, want them to start with %s", got, want)
+	}
+	if len(fc.keys) != 8 {
+		t.Errorf("got %d key events, want 8: %s", len(fc.keys), got)
+	}
+	if want := []uint32{1, 0}; fmt.Sprint(fc.mods) != fmt.Sprint(want) {
+		t.Errorf("modifier states %v, want %v", fc.mods, want)
+	}
+}
+
+func TestOpenUnsupported(t *testing.T) {
+	startFakeCompositor(t, false)
+	if _, err := Open(); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("got %v, want ErrUnsupported", err)
+	}
+}
+
+// TestHeadlessSway types through a real wlroots compositor when sway is
+// installed, started with the headless backend.
+func TestHeadlessSway(t *testing.T) {
+	swayPath, err := exec.LookPath("sway")
+	if err != nil {
+		t.Skip("sway not installed")
+	}
+	runtime := t.TempDir()
+	if err := os.Chmod(runtime, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	config := filepath.Join(runtime, "config")
+	if err := os.WriteFile(config, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(swayPath, "-c", config)
+	cmd.Env = append(os.Environ(),
+		"XDG_RUNTIME_DIR="+runtime,
+		"WLR_BACKENDS=headless",
+		"WLR_LIBINPUT_NO_DEVICES=1",
+		"WAYLAND_DISPLAY=",
+	)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+
+	t.Setenv("XDG_RUNTIME_DIR", runtime)
+	var socket string
+	for deadline := time.Now().Add(10 * time.Second); socket == ""; {
+		if time.Now().After(deadline) {
+			t.Fatal("sway did not create a Wayland socket")
+		}
+		time.Sleep(100 * time.Millisecond)
+		matches, _ := filepath.Glob(filepath.Join(runtime, "wayland-*"))
+		for _, m := range matches {
+			if !strings.HasSuffix(m, ".lock") {
+				socket = filepath.Base(m)
+			}
+		}
+	}
+	t.Setenv("WAYLAND_DISPLAY", socket)
+
+	c, err := Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	de, _ := layout.Get("German (DE)")
+	typeText(t, c, "Hallo Welt! ß€@ 漢", de)
+}
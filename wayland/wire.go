@@ -0,0 +1,150 @@
+//go:build linux
+
+package wayland
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// message is an encoder for one request on the wire.
+type message struct {
+	buf []byte
+	fds []int
+}
+
+func (m *message) uint(v uint32) *message {
+	m.buf = binary.NativeEndian.AppendUint32(m.buf, v)
+	return m
+}
+
+func (m *message) string(s string) *message {
+	m.uint(uint32(len(s) + 1))
+	m.buf = append(m.buf, s...)
+	m.buf = append(m.buf, 0)
+	for len(m.buf)%4 != 0 {
+		m.buf = append(m.buf, 0)
+	}
+	return m
+}
+
+func (m *message) fd(fd int) *message {
+	m.fds = append(m.fds, fd)
+	return m
+}
+
+// event is a decoded message from the compositor.
+type event struct {
+	sender uint32
+	opcode uint16
+	body   []byte
+}
+
+func (e *event) uint() uint32 {
+	if len(e.body) < 4 {
+		return 0
+	}
+	v := binary.NativeEndian.Uint32(e.body)
+	e.body = e.body[4:]
+	return v
+}
+
+func (e *event) string() string {
+	n := int(e.uint())
+	padded := (n + 3) &^ 3
+	if n == 0 || padded > len(e.body) {
+		return ""
+	}
+	s := string(e.body[:n-1])
+	e.body = e.body[padded:]
+	return s
+}
+
+// wire is a raw Wayland socket connection with object ID allocation.
+type wire struct {
+	conn   *net.UnixConn
+	nextID uint32
+}
+
+func socketPath() (string, error) {
+	name := os.Getenv("WAYLAND_DISPLAY")
+	if name == "" {
+		name = "wayland-0"
+	}
+	if filepath.IsAbs(name) {
+		return name, nil
+	}
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		return "", errors.New("XDG_RUNTIME_DIR is not set")
+	}
+	return filepath.Join(dir, name), nil
+}
+
+func dial() (*wire, error) {
+	path, err := socketPath()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialUnix("unix", nil, &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		return nil, fmt.Errorf("connect to Wayland display: %w", err)
+	}
+	// ID 1 is wl_display
+	return &wire{conn: conn, nextID: 2}, nil
+}
+
+func (w *wire) newID() uint32 {
+	id := w.nextID
+	w.nextID++
+	return id
+}
+
+func (w *wire) send(object uint32, opcode uint16, m *message) error {
+	if m == nil {
+		m = &message{}
+	}
+	size := 8 + len(m.buf)
+	hdr := make([]byte, 8, size)
+	binary.NativeEndian.PutUint32(hdr, object)
+	binary.NativeEndian.PutUint32(hdr[4:], uint32(size)<<16|uint32(opcode))
+	data := append(hdr, m.buf...)
+	var oob []byte
+	if len(m.fds) > 0 {
+		oob = unix.UnixRights(m.fds...)
+	}
+	_, _, err := w.conn.WriteMsgUnix(data, oob, nil)
+	return err
+}
+
+func (w *wire) read() (event, error) {
+	var hdr [8]byte
+	if _, err := io.ReadFull(w.conn, hdr[:]); err != nil {
+		return event{}, err
+	}
+	sizeOp := binary.NativeEndian.Uint32(hdr[4:])
+	size := int(sizeOp >> 16)
+	if size < 8 {
+		return event{}, fmt.Errorf("malformed Wayland message (size %d)", size)
+	}
+	body := make([]byte, size-8)
+	if _, err := io.ReadFull(w.conn, body); err != nil {
+		return event{}, err
+	}
+	return event{
+		sender: binary.NativeEndian.Uint32(hdr[:]),
+		opcode: uint16(sizeOp),
+		body:   body,
+	}, nil
+}
+
+func (w *wire) close() error {
+	return w.conn.Close()
+}
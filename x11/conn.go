@@ -0,0 +1,74 @@
+//go:build linux
+
+// Package x11 talks to an X server for goclip: it lists and activates
+// top-level windows through EWMH and injects key events through the XTEST
+// extension, resolving characters with the server's keyboard mapping.
+package x11
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xproto"
+	"github.com/jezek/xgb/xtest"
+)
+
+// Conn is a connection to the X server named by $DISPLAY.
+type Conn struct {
+	c    *xgb.Conn
+	root xproto.Window
+
+	atomMu sync.Mutex
+	atoms  map[string]xproto.Atom
+
+	kbMu sync.Mutex
+	kb   *keymap
+}
+
+// Open connects to the display in $DISPLAY and initializes XTEST.
+func Open() (*Conn, error) {
+	c, err := xgb.NewConn()
+	if err != nil {
+		return nil, fmt.Errorf("connect to X display: %w", err)
+	}
+	if err := xtest.Init(c); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("XTEST extension unavailable: %w", err)
+	}
+	screen := xproto.Setup(c).DefaultScreen(c)
+	return &Conn{
+		c:     c,
+		root:  screen.Root,
+		atoms: map[string]xproto.Atom{},
+	}, nil
+}
+
+// Close restores any temporarily remapped keycode and closes the connection.
+func (x *Conn) Close() {
+	x.kbMu.Lock()
+	if x.kb != nil {
+		x.kb.restoreSpare(x.c)
+	}
+	x.kbMu.Unlock()
+	x.c.Close()
+}
+
+func (x *Conn) atom(name string) (xproto.Atom, error) {
+	x.atomMu.Lock()
+	defer x.atomMu.Unlock()
+	if a, ok := x.atoms[name]; ok {
+		return a, nil
+	}
+	reply, err := xproto.InternAtom(x.c, false, uint16(len(name)), name).Reply()
+	if err != nil {
+		return 0, err
+	}
+	x.atoms[name] = reply.Atom
+	return reply.Atom, nil
+}
+
+// sync waits until the server has processed all previous requests.
+func (x *Conn) sync() {
+	_, _ = xproto.GetInputFocus(x.c).Reply()
+}
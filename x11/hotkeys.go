@@ -0,0 +1,196 @@
+//go:build linux
+
+package x11
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"unicode"
+
+	"goclip/keyplan"
+	"goclip/keysym"
+
+	"github.com/jezek/xgb/xproto"
+)
+
+// lockMasks are the Caps Lock and Num Lock (Mod2 on nearly every server)
+// states a hotkey is grabbed with, so it works whatever they are.
+var lockMasks = []uint16{0, xproto.ModMaskLock, xproto.ModMask2, xproto.ModMaskLock | xproto.ModMask2}
+
+// hotkeyMods are the modifier bits that tell hotkeys apart.
+const hotkeyMods = xproto.ModMaskShift | xproto.ModMaskControl | xproto.ModMask1 | xproto.ModMask4
+
+// hotkeyMask returns the X modifier mask of the hotkey's modifiers.
+func hotkeyMask(mods keyplan.Modifier) uint16 {
+	var mask uint16
+	if mods&keyplan.ModShift != 0 {
+		mask |= xproto.ModMaskShift
+	}
+	if mods&keyplan.ModCtrl != 0 {
+		mask |= xproto.ModMaskControl
+	}
+	if mods&keyplan.ModAlt != 0 {
+		mask |= xproto.ModMask1
+	}
+	if mods&keyplan.ModMeta != 0 {
+		mask |= xproto.ModMask4
+	}
+	return mask
+}
+
+// hotkeyCode returns the keycode of the hotkey's key: named keys by scan
+// code, letters and digits through the server's keyboard mapping.
+func (km *keymap) hotkeyCode(h keyplan.Hotkey) (xproto.Keycode, bool) {
+	if h.Char == 0 {
+		ev, ok := keyplan.EvdevCode(h.Key)
+		return xproto.Keycode(ev + 8), ok
+	}
+	sym := keysym.FromRune(unicode.ToLower(h.Char))
+	for code := int(km.min); code <= int(km.max); code++ {
+		for column := 0; column < 2; column++ {
+			if km.sym(xproto.Keycode(code), column) == sym {
+				return xproto.Keycode(code), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// GrabHotkeys grabs the hotkeys on the root window, so they reach goclip
+// whichever window has the focus, and calls onPress with the index of
+// each hotkey pressed; holding it down does not repeat. It uses its own
+// connection. Hotkeys that cannot be grabbed, for example because another
+// program has, are listed in the error while the others still work. The
+// returned function releases them and returns once the server has, so the
+// same keys can be grabbed again right away.
+func GrabHotkeys(keys []keyplan.Hotkey, onPress func(i int)) (stop func(), err error) {
+	g, err := Open()
+	if err != nil {
+		return nil, err
+	}
+	km, err := loadKeymap(g.c)
+	if err != nil {
+		g.c.Close()
+		return nil, err
+	}
+
+	type grab struct {
+		code xproto.Keycode
+		mask uint16
+	}
+	grabs := make([]grab, len(keys))
+	var failed []string
+	for i, h := range keys {
+		code, ok := km.hotkeyCode(h)
+		if !ok {
+			failed = append(failed, h.String()+": no such key on this keyboard")
+			continue
+		}
+		mask := hotkeyMask(h.Mods)
+		var grabErr error
+		for _, lock := range lockMasks {
+			if err := xproto.GrabKeyChecked(g.c, true, g.root, mask|lock, code,
+				xproto.GrabModeAsync, xproto.GrabModeAsync).Check(); err != nil {
+				grabErr = err
+			}
+		}
+		if grabErr != nil {
+			for _, lock := range lockMasks {
+				xproto.UngrabKey(g.c, code, g.root, mask|lock)
+			}
+			failed = append(failed, h.String()+": in use by another program")
+			continue
+		}
+		grabs[i] = grab{code: code, mask: mask}
+	}
+
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		// auto-repeat sends a release and a press with the same time
+		var lastRelease xproto.Timestamp
+		var lastCode xproto.Keycode
+		for {
+			ev, xerr := g.c.WaitForEvent()
+			if ev == nil && xerr == nil {
+				// connection closed
+				return
+			}
+			select {
+			case <-done:
+				return
+			default:
+			}
+			switch e := ev.(type) {
+			case xproto.KeyReleaseEvent:
+				lastRelease, lastCode = e.Time, e.Detail
+			case xproto.KeyPressEvent:
+				if e.Detail == lastCode && e.Time == lastRelease {
+					continue
+				}
+				for i, gr := range grabs {
+					if gr.code != 0 && gr.code == e.Detail && gr.mask == e.State&hotkeyMods {
+						onPress(i)
+					}
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			close(done)
+			// closing the connection would release the grabs too, but
+			// only once the server notices, which may be after the next
+			// grab of the same keys
+			for _, gr := range grabs {
+				if gr.code == 0 {
+					continue
+				}
+				for _, lock := range lockMasks {
+					xproto.UngrabKey(g.c, gr.code, g.root, gr.mask|lock)
+				}
+			}
+			// a round trip, so the ungrabs are done when it returns
+			xproto.GetInputFocus(g.c).Reply()
+			g.c.Close()
+			<-exited
+		})
+	}
+	if len(failed) > 0 {
+		err = errors.New(strings.Join(failed, "; "))
+	}
+	return stop, err
+}
+
+// HotkeyDown reports whether the key of h is held down, whatever the
+// modifiers.
+func (x *Conn) HotkeyDown(h keyplan.Hotkey) bool {
+	km, err := x.keymap()
+	if err != nil {
+		return false
+	}
+	x.kbMu.Lock()
+	code, ok := km.hotkeyCode(h)
+	x.kbMu.Unlock()
+	if !ok {
+		return false
+	}
+	reply, err := xproto.QueryKeymap(x.c).Reply()
+	if err != nil {
+		return false
+	}
+	return reply.Keys[code/8]&(1<<(code%8)) != 0
+}
+
+// ModifiersDown reports whether Shift, Ctrl, Alt, Win or AltGr is held.
+func (x *Conn) ModifiersDown() bool {
+	reply, err := xproto.QueryPointer(x.c, x.root).Reply()
+	if err != nil {
+		return false
+	}
+	return reply.Mask&(hotkeyMods|xproto.ModMask5) != 0
+}
@@ -0,0 +1,291 @@
+//go:build linux
+
+package x11
+
+import (
+	"fmt"
+	"time"
+	"unicode"
+
+	"goclip/keyplan"
+	"goclip/keysym"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xproto"
+	"github.com/jezek/xgb/xtest"
+)
+
+// Core protocol columns of a keycode's keysym list. XKB servers expose
+// group 1 levels 1/2 in columns 0/1 and levels 3/4 (AltGr) in 4/5.
+var levelColumns = []struct {
+	column int
+	mods   keyplan.Modifier
+}{
+	{0, 0},
+	{1, keyplan.ModShift},
+	{4, keyplan.ModAltGr},
+	{5, keyplan.ModShift | keyplan.ModAltGr},
+}
+
+// spareSettle gives clients time to process MappingNotify after the spare
+// keycode was remapped.
+const spareSettle = 20 * time.Millisecond
+
+// keymap is a snapshot of the server's keyboard mapping.
+type keymap struct {
+	min, max xproto.Keycode
+	perCode  int
+	syms     []xproto.Keysym
+
+	strokes   map[rune]keyplan.Stroke
+	modifiers map[keyplan.Modifier]xproto.Keycode
+
+	spare       xproto.Keycode // keycode without keysyms, 0 if none
+	spareMapped rune           // character currently bound to spare
+}
+
+func loadKeymap(c *xgb.Conn) (*keymap, error) {
+	setup := xproto.Setup(c)
+	km := &keymap{min: setup.MinKeycode, max: setup.MaxKeycode}
+	count := byte(int(km.max) - int(km.min) + 1)
+	reply, err := xproto.GetKeyboardMapping(c, km.min, count).Reply()
+	if err != nil {
+		return nil, fmt.Errorf("GetKeyboardMapping: %w", err)
+	}
+	km.perCode = int(reply.KeysymsPerKeycode)
+	km.syms = reply.Keysyms
+	km.index()
+	return km, nil
+}
+
+func (km *keymap) sym(code xproto.Keycode, column int) keysym.Keysym {
+	if column >= km.perCode {
+		return keysym.NoSymbol
+	}
+	i := int(code-km.min)*km.perCode + column
+	if i >= len(km.syms) {
+		return keysym.NoSymbol
+	}
+	return keysym.Keysym(km.syms[i])
+}
+
+func (km *keymap) index() {
+	km.strokes = map[rune]keyplan.Stroke{}
+	km.modifiers = map[keyplan.Modifier]xproto.Keycode{}
+
+	for code := int(km.min); code <= int(km.max); code++ {
+		kc := xproto.Keycode(code)
+
+		empty := true
+		for col := 0; col < km.perCode; col++ {
+			if km.sym(kc, col) != keysym.NoSymbol {
+				empty = false
+				break
+			}
+		}
+		if empty {
+			if km.spare == 0 {
+				km.spare = kc
+			}
+			continue
+		}
+
+		switch km.sym(kc, 0) {
+		case keysym.ShiftL:
+			km.setModifier(keyplan.ModShift, kc)
+		case keysym.ControlL:
+			km.setModifier(keyplan.ModCtrl, kc)
+		case keysym.AltL:
+			km.setModifier(keyplan.ModAlt, kc)
+		case keysym.ISOLevel3Shift, keysym.ModeSwitch:
+			km.setModifier(keyplan.ModAltGr, kc)
+		case keysym.SuperL:
+			km.setModifier(keyplan.ModMeta, kc)
+		}
+
+		key, ok := keyplan.KeyFromEvdev(uint16(code - 8))
+		if !ok {
+			continue
+		}
+		for _, lv := range levelColumns {
+			ks := km.sym(kc, lv.column)
+			if ks == keysym.NoSymbol && lv.column == 1 {
+				// A lone lowercase keysym implies its uppercase on level 2
+				if lower, ok := keysym.ToRune(km.sym(kc, 0)); ok && unicode.IsLower(lower) && km.sym(kc, 1) == keysym.NoSymbol {
+					km.addStroke(unicode.ToUpper(lower), keyplan.Stroke{Key: key, Mods: lv.mods})
+				}
+				continue
+			}
+			if keysym.IsKeypad(ks) {
+				continue
+			}
+			if r, ok := keysym.ToRune(ks); ok {
+				km.addStroke(r, keyplan.Stroke{Key: key, Mods: lv.mods})
+			}
+		}
+	}
+}
+
+func (km *keymap) setModifier(mod keyplan.Modifier, code xproto.Keycode) {
+	if _, ok := km.modifiers[mod]; !ok {
+		km.modifiers[mod] = code
+	}
+}
+
+// addStroke keeps the first (lowest level, lowest keycode) way to type r.
+func (km *keymap) addStroke(r rune, s keyplan.Stroke) {
+	if _, ok := km.strokes[r]; !ok {
+		km.strokes[r] = s
+	}
+}
+
+func (km *keymap) modifierCode(mod keyplan.Modifier) (xproto.Keycode, bool) {
+	if code, ok := km.modifiers[mod]; ok {
+		return code, true
+	}
+	// Fall back to the standard evdev keycodes
+	if ev, ok := keyplan.EvdevModifier(mod); ok {
+		return xproto.Keycode(ev + 8), true
+	}
+	return 0, false
+}
+
+// bindSpare points the spare keycode at r's keysym on every column.
+func (km *keymap) bindSpare(c *xgb.Conn, r rune) error {
+	if km.spare == 0 {
+		return fmt.Errorf("no spare keycode available to type U+%04X", r)
+	}
+	if km.spareMapped == r {
+		return nil
+	}
+	syms := make([]xproto.Keysym, km.perCode)
+	for i := range syms {
+		syms[i] = xproto.Keysym(keysym.FromRune(r))
+	}
+	if err := xproto.ChangeKeyboardMappingChecked(c, 1, km.spare, byte(km.perCode), syms).Check(); err != nil {
+		return fmt.Errorf("ChangeKeyboardMapping: %w", err)
+	}
+	km.spareMapped = r
+	return nil
+}
+
+func (km *keymap) restoreSpare(c *xgb.Conn) {
+	if km.spare == 0 || km.spareMapped == 0 {
+		return
+	}
+	// let the target consume the last event before the keysym disappears
+	time.Sleep(spareSettle)
+	syms := make([]xproto.Keysym, km.perCode)
+	_ = xproto.ChangeKeyboardMappingChecked(c, 1, km.spare, byte(km.perCode), syms).Check()
+	km.spareMapped = 0
+}
+
+func (x *Conn) keymap() (*keymap, error) {
+	x.kbMu.Lock()
+	defer x.kbMu.Unlock()
+	if x.kb == nil {
+		km, err := loadKeymap(x.c)
+		if err != nil {
+			return nil, err
+		}
+		x.kb = km
+	}
+	return x.kb, nil
+}
+
+// ReloadKeymap restores the spare keycode and discards the cached keyboard
+// mapping, so the next Mapper or Injector picks up layout changes.
+func (x *Conn) ReloadKeymap() {
+	x.kbMu.Lock()
+	if x.kb != nil {
+		x.kb.restoreSpare(x.c)
+	}
+	x.kb = nil
+	x.kbMu.Unlock()
+}
+
+// Mapper returns a keyplan.Mapper backed by the server's current keymap.
+func (x *Conn) Mapper() (keyplan.Mapper, error) {
+	km, err := x.keymap()
+	if err != nil {
+		return nil, err
+	}
+	return keyplan.MapperFunc(func(r rune) (keyplan.Stroke, bool) {
+		s, ok := km.strokes[r]
+		return s, ok
+	}), nil
+}
+
+// Injector returns a keyplan.Injector that sends XTEST fake key events.
+// Characters without a key are typed by temporarily binding them to a
+// spare keycode. If settle is non-zero, the injector pauses that long after
+// every modifier change for clients that sample modifier state late.
+func (x *Conn) Injector(settle time.Duration) (keyplan.Injector, error) {
+	km, err := x.keymap()
+	if err != nil {
+		return nil, err
+	}
+	return &injector{x: x, km: km, settle: settle}, nil
+}
+
+type injector struct {
+	x      *Conn
+	km     *keymap
+	settle time.Duration
+}
+
+func (in *injector) fake(code xproto.Keycode, down bool) error {
+	typ := byte(xproto.KeyRelease)
+	if down {
+		typ = xproto.KeyPress
+	}
+	return xtest.FakeInputChecked(in.x.c, typ, byte(code), xproto.TimeCurrentTime, in.x.root, 0, 0, 0).Check()
+}
+
+func (in *injector) PressKey(k keyplan.Key, down bool) error {
+	ev, ok := keyplan.EvdevCode(k)
+	if !ok {
+		return fmt.Errorf("no X keycode for scan code %s", k)
+	}
+	return in.fake(xproto.Keycode(ev+8), down)
+}
+
+func (in *injector) PressModifier(mod keyplan.Modifier, down bool) error {
+	in.x.kbMu.Lock()
+	code, ok := in.km.modifierCode(mod)
+	in.x.kbMu.Unlock()
+	if !ok {
+		return nil
+	}
+	if err := in.fake(code, down); err != nil {
+		return err
+	}
+	if in.settle > 0 {
+		in.x.sync()
+		time.Sleep(in.settle)
+	}
+	return nil
+}
+
+func (in *injector) TypeUnicode(r rune) error {
+	in.x.kbMu.Lock()
+	already := in.km.spareMapped == r
+	err := in.km.bindSpare(in.x.c, r)
+	spare := in.km.spare
+	in.x.kbMu.Unlock()
+	if err != nil {
+		return err
+	}
+	if !already {
+		in.x.sync()
+		time.Sleep(spareSettle)
+	}
+	if err := in.fake(spare, true); err != nil {
+		return err
+	}
+	return in.fake(spare, false)
+}
+
+func (in *injector) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
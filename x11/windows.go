@@ -0,0 +1,211 @@
+//go:build linux
+
+package x11
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jezek/xgb/xproto"
+)
+
+// Window describes a top-level client window.
+type Window struct {
+	ID    uint32
+	Title string
+	PID   int
+}
+
+func (x *Conn) property(win xproto.Window, name string, typ xproto.Atom) (*xproto.GetPropertyReply, error) {
+	a, err := x.atom(name)
+	if err != nil {
+		return nil, err
+	}
+	return xproto.GetProperty(x.c, false, win, a, typ, 0, 1<<16).Reply()
+}
+
+func (x *Conn) windowList(win xproto.Window, name string) []uint32 {
+	reply, err := x.property(win, name, xproto.AtomWindow)
+	if err != nil || reply.Format != 32 {
+		return nil
+	}
+	ids := make([]uint32, 0, len(reply.Value)/4)
+	for i := 0; i+4 <= len(reply.Value); i += 4 {
+		ids = append(ids, binary.LittleEndian.Uint32(reply.Value[i:]))
+	}
+	return ids
+}
+
+// Windows returns the managed top-level windows in stacking order, as
+// announced by the window manager in _NET_CLIENT_LIST.
+func (x *Conn) Windows() ([]Window, error) {
+	if _, err := x.atom("_NET_CLIENT_LIST"); err != nil {
+		return nil, err
+	}
+	ids := x.windowList(x.root, "_NET_CLIENT_LIST")
+	if ids == nil {
+		return nil, fmt.Errorf("window manager does not publish _NET_CLIENT_LIST")
+	}
+	wins := make([]Window, 0, len(ids))
+	for _, id := range ids {
+		wins = append(wins, Window{ID: id, Title: x.WindowTitle(id), PID: x.WindowPID(id)})
+	}
+	return wins, nil
+}
+
+// ActiveWindow returns the window the window manager reports as focused.
+func (x *Conn) ActiveWindow() uint32 {
+	ids := x.windowList(x.root, "_NET_ACTIVE_WINDOW")
+	if len(ids) == 0 {
+		return 0
+	}
+	return ids[0]
+}
+
+// WindowTitle returns _NET_WM_NAME, falling back to WM_NAME.
+func (x *Conn) WindowTitle(id uint32) string {
+	utf8Atom, err := x.atom("UTF8_STRING")
+	if err == nil {
+		if reply, err := x.property(xproto.Window(id), "_NET_WM_NAME", utf8Atom); err == nil && len(reply.Value) > 0 {
+			return strings.ToValidUTF8(string(reply.Value), "")
+		}
+	}
+	reply, err := xproto.GetProperty(x.c, false, xproto.Window(id), xproto.AtomWmName, xproto.AtomString, 0, 1<<16).Reply()
+	if err != nil || len(reply.Value) == 0 {
+		return ""
+	}
+	if utf8.Valid(reply.Value) {
+		return string(reply.Value)
+	}
+	// WM_NAME of type STRING is Latin-1
+	runes := make([]rune, len(reply.Value))
+	for i, b := range reply.Value {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+// WindowPID returns _NET_WM_PID, or 0 if the client does not set it.
+func (x *Conn) WindowPID(id uint32) int {
+	reply, err := x.property(xproto.Window(id), "_NET_WM_PID", xproto.AtomCardinal)
+	if err != nil || reply.Format != 32 || len(reply.Value) < 4 {
+		return 0
+	}
+	return int(binary.LittleEndian.Uint32(reply.Value))
+}
+
+// WindowProcessName returns the lower-cased executable name of the
+// window's process, read from /proc.
+func (x *Conn) WindowProcessName(id uint32) string {
+	pid := x.WindowPID(id)
+	if pid == 0 {
+		return ""
+	}
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/comm")
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(string(data)))
+}
+
+func (x *Conn) clientMessage(win uint32, name string, data []uint32) error {
+	typ, err := x.atom(name)
+	if err != nil {
+		return err
+	}
+	for len(data) < 5 {
+		data = append(data, 0)
+	}
+	ev := xproto.ClientMessageEvent{
+		Format: 32,
+		Window: xproto.Window(win),
+		Type:   typ,
+		Data:   xproto.ClientMessageDataUnionData32New(data),
+	}
+	mask := uint32(xproto.EventMaskSubstructureRedirect | xproto.EventMaskSubstructureNotify)
+	return xproto.SendEventChecked(x.c, false, x.root, mask, string(ev.Bytes())).Check()
+}
+
+// Activate asks the window manager to raise and focus the window.
+func (x *Conn) Activate(id uint32) error {
+	// source indication 2 = pager, which window managers do not second-guess
+	if err := x.clientMessage(id, "_NET_ACTIVE_WINDOW", []uint32{2, xproto.TimeCurrentTime, 0}); err != nil {
+		return err
+	}
+	x.sync()
+	return nil
+}
+
+// SetAbove adds or removes the _NET_WM_STATE_ABOVE (always on top) state.
+func (x *Conn) SetAbove(id uint32, above bool) error {
+	state, err := x.atom("_NET_WM_STATE_ABOVE")
+	if err != nil {
+		return err
+	}
+	action := uint32(0) // _NET_WM_STATE_REMOVE
+	if above {
+		action = 1 // _NET_WM_STATE_ADD
+	}
+	return x.clientMessage(id, "_NET_WM_STATE", []uint32{action, uint32(state), 0, 1})
+}
+
+// FindWindowByTitle returns the first managed window with exactly this title.
+func (x *Conn) FindWindowByTitle(title string) uint32 {
+	for _, id := range x.windowList(x.root, "_NET_CLIENT_LIST") {
+		if x.WindowTitle(id) == title {
+			return id
+		}
+	}
+	return 0
+}
+
+// WatchActiveWindow calls onChange whenever _NET_ACTIVE_WINDOW changes. It
+// uses its own connection so events never interleave with requests on x.
+// The returned function stops the watcher.
+func WatchActiveWindow(onChange func(id uint32)) (stop func(), err error) {
+	w, err := Open()
+	if err != nil {
+		return nil, err
+	}
+	activeAtom, err := w.atom("_NET_ACTIVE_WINDOW")
+	if err != nil {
+		w.c.Close()
+		return nil, err
+	}
+	err = xproto.ChangeWindowAttributesChecked(w.c, w.root, xproto.CwEventMask,
+		[]uint32{xproto.EventMaskPropertyChange}).Check()
+	if err != nil {
+		w.c.Close()
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			ev, xerr := w.c.WaitForEvent()
+			if ev == nil && xerr == nil {
+				// connection closed
+				return
+			}
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if pn, ok := ev.(xproto.PropertyNotifyEvent); ok && pn.Atom == activeAtom {
+				if id := w.ActiveWindow(); id != 0 {
+					onChange(id)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		w.c.Close()
+	}, nil
+}